@@ -0,0 +1,51 @@
+// Command backfill-thumbnails queues thumbnail renders for every whiteboard
+// that has no thumbnail or whose thumbnail is out of date. The server's
+// thumbnail workers pick the jobs up.
+package main
+
+import (
+	"context"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/cache"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/thumbnail"
+)
+
+func main() {
+	cfg := config.Load()
+	logger.Init(cfg.Env)
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to connect to database")
+	}
+	defer database.Close()
+
+	redisClient, err := cache.Connect(cfg.RedisURL)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to connect to Redis")
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	repo := thumbnail.NewRepository(db)
+	queue := thumbnail.NewQueue(redisClient)
+
+	ids, err := repo.FindStale(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to find whiteboards needing thumbnails")
+	}
+
+	queued := 0
+	for _, id := range ids {
+		if err := queue.Enqueue(ctx, id); err != nil {
+			logger.Error().Err(err).Str("whiteboard_id", id.String()).Msg("Failed to queue thumbnail")
+			continue
+		}
+		queued++
+	}
+
+	logger.Info().Int("found", len(ids)).Int("queued", queued).Msg("✅ Thumbnail backfill queued")
+}
@@ -1,24 +1,51 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/google/uuid"
 
+	"github.com/AnupamSingh2004/SysDes/backend/internal/activity"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/admin"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/ai"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/asset"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/auth"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/preview"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/project"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/blobstore"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/bulk"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/cache"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/concurrency"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database/migrate"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/deprecation"
+	apperrors "github.com/AnupamSingh2004/SysDes/backend/internal/shared/errors"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/framing"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/maintenance"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/metrics"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/outbox"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/ratelimit"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/thumbnail"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+	"github.com/AnupamSingh2004/SysDes/backend/migrations"
 )
 
 func main() {
+	migrateOnly := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.Load()
 
@@ -26,59 +53,292 @@ func main() {
 	logger.Init(cfg.Env)
 	logger.Info().Str("env", cfg.Env).Msg("🚀 Starting SysDes Backend")
 
+	warnings, err := cfg.Validate()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Invalid configuration")
+	}
+	for _, warning := range warnings {
+		logger.Warn().Str("env", cfg.Env).Msg("⚠️ Not fit for production: " + warning)
+	}
+
 	// Connect to database
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("❌ Failed to connect to database")
 	}
-	defer database.Close()
+
+	// Bring the schema up to date (always with -migrate, otherwise per DB_MIGRATE_ON_STARTUP)
+	if *migrateOnly || cfg.DBMigrateOnStartup {
+		if cfg.DBMigrateBaseline > 0 {
+			if _, err := migrate.Baseline(context.Background(), db, migrations.FS, cfg.DBMigrateBaseline); err != nil {
+				logger.Fatal().Err(err).Msg("❌ Failed to record baseline migrations")
+			}
+		}
+		applied, err := migrate.Up(context.Background(), db, migrations.FS)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("❌ Failed to apply database migrations")
+		}
+		version, err := migrate.Version(context.Background(), db)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("❌ Failed to read schema version")
+		}
+		logger.Info().Int("applied", applied).Int("version", version).Msg("✅ Database schema up to date")
+		if *migrateOnly {
+			database.Close()
+			return
+		}
+	}
+
+	// Connect to Redis (optional unless REDIS_REQUIRED: background jobs, token
+	// revocation and rate limits are disabled without it)
+	redisClient, err := cache.Connect(cfg.RedisURL)
+	if err != nil {
+		if cfg.RedisRequired {
+			logger.Fatal().Err(err).Msg("❌ Failed to connect to Redis")
+		}
+		logger.Warn().Err(err).Msg("⚠️ Redis unavailable, background jobs, token revocation and rate limits disabled")
+	}
+
+	// Background workers stop when this is cancelled on shutdown; the stores
+	// are closed only once they've returned (or ShutdownTimeoutSeconds passed)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
 
 	// Initialize auth domain
 	// Repository -> Service -> Handler pattern (dependency injection)
 	authRepo := auth.NewRepository(db)
 	authService := auth.NewService(authRepo, cfg)
+	if cfg.JWTAlgorithm == "RS256" {
+		err := authService.UseRSAKeys(auth.RSAKeys{
+			PrivateKey:             cfg.JWTPrivateKey,
+			PrivateKeyPath:         cfg.JWTPrivateKeyPath,
+			PreviousPublicKeyPaths: cfg.JWTPreviousPublicKeyPaths,
+			AcceptHS256:            cfg.JWTAcceptHS256,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("❌ Failed to load JWT signing keys")
+		}
+	} else if cfg.JWTAlgorithm != "HS256" {
+		logger.Fatal().Str("algorithm", cfg.JWTAlgorithm).Msg("❌ JWT_ALGORITHM must be HS256 or RS256")
+	}
+	if redisClient != nil {
+		authService.UseBlacklist(auth.NewBlacklist(redisClient))
+		authService.UsePKCE(auth.NewPKCEStore(redisClient))
+		authService.UseChallengeAttempts(auth.NewChallengeAttempts(redisClient))
+	}
 	authHandler := auth.NewHandler(authService, cfg)
+	if redisClient != nil {
+		authHandler.UseExchangeStore(auth.NewExchangeStore(redisClient))
+	}
 	authMiddleware := auth.NewMiddleware(authService)
 
+	// Bulk guard: caps batch request sizes and bounds their transactions
+	bulkGuard := bulk.NewGuard(cfg.BulkMaxItems, time.Duration(cfg.BulkStatementTimeoutSeconds)*time.Second)
+
 	// Initialize project domain
-	projectRepo := project.NewRepository(db)
-	projectService := project.NewService(projectRepo)
+	projectRepo := project.NewRepository(db, bulkGuard)
+	projectService := project.NewService(projectRepo, cfg)
 	projectHandler := project.NewHandler(projectService)
 
 	// Initialize whiteboard domain
-	whiteboardRepo := whiteboard.NewRepository(db)
-	whiteboardService := whiteboard.NewService(whiteboardRepo)
-	whiteboardHandler := whiteboard.NewHandler(whiteboardService)
+	whiteboardRepo := whiteboard.NewRepository(db, cfg.CanvasCompressThresholdBytes, bulkGuard)
+	whiteboardService := whiteboard.NewService(whiteboardRepo, cfg)
+	liveHub := whiteboard.NewHub(whiteboardService, time.Duration(cfg.LivePersistIntervalSeconds)*time.Second, cfg.LiveMaxConnectionsPerUser)
+	whiteboardHandler := whiteboard.NewHandler(whiteboardService, liveHub, cfg.EmbedFrameAncestors, cfg.FrontendURL)
+	// Drop cached access data when a project's visibility changes or it is deleted
+	projectService.OnAccessChange(whiteboardService.InvalidateProjectAccess)
+	// and re-check who may stay in its live rooms (e.g. a removed collaborator)
+	projectService.OnAccessChange(liveHub.AccessChanged)
+	// Imported canvases go through the same checks as saved ones
+	projectService.SetCanvasPreparer(whiteboardService.PrepareCanvas)
+	projectService.OnDelete(whiteboardService.InvalidateProjectAccess)
+	authService.OnProjectDelete(whiteboardService.InvalidateProjectAccess)
+
+	// Initialize activity domain (project audit trail, recorded by the project and whiteboard services)
+	activityRepo := activity.NewRepository(db)
+	activityRecorder := activity.NewRecorder(activityRepo, cfg.ActivityBufferSize)
+	// The recorder outlives the other workers so entries from draining requests are still written
+	activityCtx, stopActivity := context.WithCancel(context.Background())
+	activityDone := make(chan struct{})
+	go func() {
+		defer close(activityDone)
+		activityRecorder.Run(activityCtx)
+	}()
+	projectService.SetActivityRecorder(activityRecorder)
+	whiteboardService.SetActivityRecorder(activityRecorder)
+	activityService := activity.NewService(activityRepo, cfg)
+	activityHandler := activity.NewHandler(activityService)
+
+	// Initialize preview domain (OpenGraph cards for public projects)
+	previewRepo := preview.NewRepository(db)
+	previewService := preview.NewService(previewRepo)
+	previewHandler := preview.NewHandler(previewService, cfg)
+
+	// Initialize asset domain (uploaded images stored in the blob store, one backend per region)
+	regionStores := make(map[string]blobstore.Store)
+	for region, dir := range cfg.StorageRegions() {
+		store, err := blobstore.NewLocal(dir)
+		if err != nil {
+			logger.Fatal().Err(err).Str("region", region).Msg("❌ Failed to initialize blob store")
+		}
+		regionStores[region] = store
+	}
+	blobRouter, err := blobstore.NewRouter(cfg.BlobDefaultRegion, regionStores)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to initialize blob store")
+	}
+	assetRepo := asset.NewRepository(db)
+	assetService := asset.NewService(assetRepo, blobRouter, int64(cfg.AssetMaxBytes), int64(cfg.ProjectThumbnailMaxBytes), cfg.HideForbidden)
+	assetHandler := asset.NewHandler(assetService, cfg.AssetUploadsPerMinute)
+	projectService.OnDelete(assetService.DeleteProjectAssets)
+	authService.OnProjectDelete(assetService.DeleteProjectAssets)
+
+	// Initialize thumbnail rendering (queued on save, rendered by background workers)
+	if redisClient != nil {
+		thumbnailQueue := thumbnail.NewQueue(redisClient)
+		whiteboardService.OnSave(func(_ context.Context, whiteboardID uuid.UUID) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := thumbnailQueue.Enqueue(ctx, whiteboardID); err != nil {
+				logger.Warn().Err(err).Str("whiteboard_id", whiteboardID.String()).Msg("Failed to queue thumbnail")
+			}
+		})
+
+		// Reindexing "thumbnail" queues renders for whiteboards with missing or stale thumbnails
+		thumbnailRepo := thumbnail.NewRepository(db)
+		whiteboardService.RegisterDerivation("thumbnail", func(ctx context.Context, w *whiteboard.Whiteboard) (bool, error) {
+			stale, err := thumbnailRepo.IsStale(ctx, w.ID)
+			if err != nil || !stale {
+				return false, err
+			}
+			return true, thumbnailQueue.Enqueue(ctx, w.ID)
+		})
+
+		if cfg.ThumbnailWorkers > 0 {
+			thumbnailWorker := thumbnail.NewWorker(thumbnailQueue, thumbnailRepo, blobRouter, cfg.ThumbnailWorkers, cfg.ThumbnailMaxAttempts)
+			runWorker(thumbnailWorker.Run)
+		}
+	}
+
+	// Deliver domain events from the outbox (whiteboard changes feed the project event streams,
+	// relayed through Redis to every instance's subscribers when it's available)
+	if redisClient != nil {
+		whiteboardService.UseEventRelay(redisClient)
+		runWorker(whiteboardService.RelayEvents)
+	}
+	dispatcher := outbox.NewDispatcher(db, time.Duration(cfg.OutboxPollIntervalMs)*time.Millisecond, cfg.OutboxMaxAttempts)
+	dispatcher.Handle("whiteboard.", whiteboardService.DeliverEvent)
+	runWorker(dispatcher.Run)
+
+	// Build data exports queued with POST /auth/me/export
+	exportWorker := auth.NewExportWorker(authService)
+	runWorker(exportWorker.Run)
+
+	// Initialize admin domain (maintenance mode toggle, slug regeneration, orphaned whiteboard repair, reindexing)
+	maintenanceMode := maintenance.New(redisClient, cfg.MaintenanceMode)
+	reindexer := whiteboard.NewReindexer(whiteboardService, cfg.ReindexBatchSize, time.Duration(cfg.ReindexBatchIntervalMs)*time.Millisecond)
+	runWorker(reindexer.Run)
+	trashSweeper := whiteboard.NewTrashSweeper(whiteboardService, time.Duration(cfg.WhiteboardTrashSweepIntervalSeconds)*time.Second)
+	runWorker(trashSweeper.Run)
+	adminHandler := admin.NewHandler(maintenanceMode, projectService, whiteboardService, reindexer)
+
+	// Initialize AI domain (Gemini diagram generation and design review)
+	aiService := ai.NewService(cfg, whiteboardService)
+	aiHandler := ai.NewHandler(aiService, cfg.AIGenerationsPerMinute)
+
+	// Deprecation notices for /api/v1 routes
+	deprecations, err := deprecation.New(cfg.APIV1Deprecated, cfg.APIV1Sunset, cfg.APIDeprecatedRoutes, cfg.APIDeprecationLink)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Invalid API deprecation config")
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "SysDes API",
 		ErrorHandler: errorHandler,
+		// Leave headroom above the upload and canvas limits for multipart
+		// overhead and the JSON envelope around saved canvas data
+		BodyLimit: max(cfg.AssetMaxBytes, cfg.ProjectThumbnailMaxBytes, cfg.CanvasMaxBytes) + 1024*1024,
+		// Bound slow clients and idle keep-alive connections
+		ReadTimeout:  time.Duration(cfg.ServerReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(cfg.ServerWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:  time.Duration(cfg.ServerIdleTimeoutSeconds) * time.Second,
 	})
 
 	// Middleware
+	// First, so every log line of a request (panics included) carries its request_id
+	app.Use(logger.RequestID())
+	// Outside recover, so panics are counted with the 500 they turn into
+	if cfg.MetricsEnabled {
+		metrics.RegisterDBPool(db)
+		app.Use(metrics.Middleware())
+	}
 	app.Use(recover.New())
-	app.Use(fiberlogger.New(fiberlogger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} (${latency})\n",
+	app.Use(logger.RequestLogger(logger.RequestConfig{
+		SkipPaths:     cfg.LogSkipPaths,
+		RouteLevels:   cfg.LogRouteLevels,
+		SlowThreshold: time.Duration(cfg.LogSlowRequestMs) * time.Millisecond,
 	}))
+	// Inside the request logger, so abandoned requests are logged as 499 rather than 5xx
+	app.Use(apperrors.ClientAborted())
+	// No response may be framed by another site, except whiteboard embeds
+	app.Use(framing.Middleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.FrontendURL,
 		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,Prefer,X-Request-ID,X-API-Key",
+		ExposeHeaders:    "Location,ETag,Preference-Applied,Deprecation,Sunset,Link,Retry-After,X-RateLimit-Limit,X-RateLimit-Remaining,X-Request-ID",
 		AllowCredentials: true,
 	}))
 
+	// Block writes during maintenance, but keep sessions alive and the admin toggle reachable
+	app.Use(maintenanceMode.Middleware(
+		"/api/v1/auth/refresh",
+		"/api/v1/auth/exchange",
+		"/api/v1/auth/logout",
+		"/api/v1/admin/",
+	))
+
 	// Setup routes
-	setupRoutes(app, cfg, authHandler, authMiddleware, projectHandler, whiteboardHandler)
+	setupRoutes(app, cfg, deprecations, ratelimit.New(redisClient), bulkGuard, authHandler, authMiddleware, projectHandler, whiteboardHandler, previewHandler, assetHandler, activityHandler, adminHandler, aiHandler)
 
-	// Graceful shutdown
+	// Graceful shutdown: stop accepting connections and let in-flight requests
+	// finish for up to SHUTDOWN_TIMEOUT_SECONDS; a second signal exits at once
+	shutdownDone := make(chan struct{})
 	go func() {
-		sigChan := make(chan os.Signal, 1)
+		defer close(shutdownDone)
+
+		sigChan := make(chan os.Signal, 2)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		logger.Info().Msg("🛑 Shutting down server...")
-		_ = app.Shutdown()
+		go func() {
+			<-sigChan
+			logger.Warn().Msg("⚠️ Second signal received, exiting without draining")
+			os.Exit(1)
+		}()
+
+		stopWorkers()
+		liveHub.Shutdown()
+
+		open := app.Server().GetOpenConnectionsCount()
+		timeout := time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second
+		err := app.ShutdownWithTimeout(timeout)
+		remaining := app.Server().GetOpenConnectionsCount()
+		if err != nil {
+			logger.Warn().Err(err).Int32("drained", open-remaining).Int32("abandoned", remaining).Msg("⚠️ Shutdown timed out, abandoning open connections")
+			return
+		}
+		logger.Info().Int32("drained", open).Msg("✅ Drained in-flight connections")
 	}()
 
 	// Start server
@@ -86,11 +346,37 @@ func main() {
 	if err := app.Listen(":" + cfg.Port); err != nil {
 		logger.Fatal().Err(err).Msg("❌ Server failed to start")
 	}
+
+	// Listen returns once shutdown begins; close the stores only after draining
+	<-shutdownDone
+	waitForWorkers(&workers, time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	stopActivity()
+	<-activityDone
+	database.Close()
+	cache.Close()
 }
 
-func setupRoutes(app *fiber.App, cfg *config.Config, authHandler *auth.Handler, authMiddleware *auth.Middleware, projectHandler *project.Handler, whiteboardHandler *whiteboard.Handler) {
+func setupRoutes(app *fiber.App, cfg *config.Config, deprecations *deprecation.Policy, rateLimiter *ratelimit.Limiter, bulkGuard *bulk.Guard, authHandler *auth.Handler, authMiddleware *auth.Middleware, projectHandler *project.Handler, whiteboardHandler *whiteboard.Handler, previewHandler *preview.Handler, assetHandler *asset.Handler, activityHandler *activity.Handler, adminHandler *admin.Handler, aiHandler *ai.Handler) {
 	// API v1
 	api := app.Group("/api/v1")
+	api.Use(deprecations.Middleware())
+
+	// Rate limits: strict for auth routes (OAuth callbacks, refreshes), looser for the rest.
+	// OptionalAuth runs first so signed-in users are counted per user rather than per IP.
+	api.Use(authMiddleware.OptionalAuth)
+	api.Use("/auth", rateLimiter.Middleware(ratelimit.Options{
+		Name:   "auth",
+		Max:    cfg.RateLimitAuthPerMinute,
+		Window: time.Minute,
+	}))
+	api.Use(rateLimiter.Middleware(ratelimit.Options{
+		Name:   "api",
+		Max:    cfg.RateLimitAPIPerMinute,
+		Window: time.Minute,
+		Next: func(c *fiber.Ctx) bool {
+			return strings.HasPrefix(c.Path(), "/api/v1/auth/") || c.Path() == "/api/v1/health"
+		},
+	}))
 
 	// Health check
 	api.Get("/health", func(c *fiber.Ctx) error {
@@ -103,14 +389,39 @@ func setupRoutes(app *fiber.App, cfg *config.Config, authHandler *auth.Handler,
 			})
 		}
 
+		// Redis is only fatal when required; otherwise the API runs degraded without it
+		if err := cache.Health(); err != nil {
+			if cfg.RedisRequired {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"status":   "unhealthy",
+					"database": "connected",
+					"redis":    "disconnected",
+					"error":    err.Error(),
+				})
+			}
+			return c.JSON(fiber.Map{
+				"status":   "degraded",
+				"service":  "sysdes-api",
+				"version":  "1.0.0",
+				"database": "connected",
+				"redis":    "disconnected",
+			})
+		}
+
 		return c.JSON(fiber.Map{
 			"status":   "healthy",
 			"service":  "sysdes-api",
 			"version":  "1.0.0",
 			"database": "connected",
+			"redis":    "connected",
 		})
 	})
 
+	// Prometheus metrics, outside /api/v1 so scrapes skip rate limits and deprecation headers
+	if cfg.MetricsEnabled {
+		app.Get("/metrics", metrics.Handler(cfg.MetricsToken))
+	}
+
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -124,10 +435,51 @@ func setupRoutes(app *fiber.App, cfg *config.Config, authHandler *auth.Handler,
 	authHandler.RegisterRoutes(api, authMiddleware.RequireAuth)
 
 	// Project routes
-	projectHandler.RegisterRoutes(api, authMiddleware.RequireAuth)
+	projectHandler.RegisterRoutes(api, authMiddleware.RequireAuth, bulkGuard.Middleware())
+
+	// Whiteboard routes (embeds share the render limit with previews)
+	exportLimit := concurrency.New(cfg.ConcurrencyExportPerUser)
+	renderLimit := concurrency.New(cfg.ConcurrencyRenderPerUser)
+	whiteboardHandler.RegisterRoutes(api, authMiddleware.RequireAuth, exportLimit.Middleware(), bulkGuard.Middleware(), renderLimit.Middleware())
+
+	// Public preview routes
+	previewHandler.RegisterRoutes(api, renderLimit.Middleware())
+
+	// Asset routes
+	assetHandler.RegisterRoutes(api, authMiddleware.RequireAuth, authMiddleware.OptionalAuth)
+
+	// Activity routes
+	activityHandler.RegisterRoutes(api, authMiddleware.RequireAuth)
 
-	// Whiteboard routes
-	whiteboardHandler.RegisterRoutes(api, authMiddleware.RequireAuth)
+	// Admin routes (users with the admin role)
+	adminHandler.RegisterRoutes(api, authMiddleware.RequireAuth, authMiddleware.RequireRole(auth.RoleAdmin))
+
+	// AI routes
+	aiLimit := concurrency.New(cfg.ConcurrencyAIPerUser)
+	aiHandler.RegisterRoutes(api, authMiddleware.RequireAuth, aiLimit.Middleware())
+}
+
+// waitForWorkers waits for the background workers to return after they were
+// stopped, so a thumbnail render or outbox delivery in progress can finish
+// before the stores close. It gives up after timeout (0 waits as long as it takes).
+func waitForWorkers(workers *sync.WaitGroup, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	select {
+	case <-done:
+		logger.Info().Msg("✅ Background workers stopped")
+	case <-expired:
+		logger.Warn().Dur("timeout", timeout).Msg("⚠️ Background workers still running, closing stores anyway")
+	}
 }
 
 // Custom error handler
@@ -140,7 +492,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	logger.Error().Err(err).Int("code", code).Str("path", c.Path()).Msg("Request error")
+	logger.For(c).Error().Err(err).Int("code", code).Str("path", logger.Path(c)).Msg("Request error")
 
 	return c.Status(code).JSON(fiber.Map{
 		"error":   true,
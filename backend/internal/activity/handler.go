@@ -0,0 +1,102 @@
+package activity
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Handler handles HTTP requests for the activity log
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new activity handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the activity routes
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth fiber.Handler) {
+	api.Get("/projects/:id/activity", requireAuth, h.List)
+}
+
+// List handles GET /api/v1/projects/:id/activity
+// @Summary List a project's activity
+// @Description Who changed what in the project, newest first. Owner and collaborators only.
+// @Tags activity
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Param limit query int false "Page size (default 50, at most 100)"
+// @Param offset query int false "Entries to skip"
+// @Success 200 {object} ListResponse
+// @Router /projects/{id}/activity [get]
+func (h *Handler) List(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	limit, offset := 0, 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit must be a positive integer",
+			})
+		}
+	}
+	if raw := c.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "offset must be a non-negative integer",
+			})
+		}
+	}
+
+	page, err := h.service.ListProjectActivity(c.Context(), projectID, userID, limit, offset)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		logger.FailureFor(c, err).Str("project_id", projectID.String()).Msg("Failed to list project activity")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list project activity",
+		})
+	}
+
+	return c.JSON(page)
+}
+
+// getUserID extracts the user ID from the context (set by auth middleware)
+func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid user ID format")
+	}
+
+	return userID, nil
+}
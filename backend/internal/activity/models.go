@@ -0,0 +1,78 @@
+package activity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the activity log
+const (
+	ProjectCreated    = "project.created"
+	ProjectUpdated    = "project.updated"
+	ProjectArchived   = "project.archived"
+	ProjectRestored   = "project.restored"
+	WhiteboardCreated = "whiteboard.created"
+	WhiteboardUpdated = "whiteboard.updated"
+	WhiteboardDeleted = "whiteboard.deleted"
+	// WhiteboardRestored is a whiteboard brought back from the trash
+	WhiteboardRestored = "whiteboard.restored"
+	CanvasSaved        = "whiteboard.canvas_saved"
+)
+
+// Entry is one recorded change to a project
+type Entry struct {
+	ID        int64
+	ProjectID uuid.UUID
+	// UserID is who made the change, nil once their account is deleted
+	UserID *uuid.UUID
+	// UserName is loaded by list queries
+	UserName  *string
+	Action    string
+	Metadata  map[string]interface{}
+	CreatedAt time.Time
+}
+
+// EntryResponse is an activity entry returned to clients
+type EntryResponse struct {
+	ID        int64           `json:"id"`
+	ProjectID string          `json:"project_id"`
+	UserID    *string         `json:"user_id"`
+	UserName  *string         `json:"user_name,omitempty"`
+	Action    string          `json:"action"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ToResponse converts Entry to EntryResponse
+func (e *Entry) ToResponse() *EntryResponse {
+	var userID *string
+	if e.UserID != nil {
+		id := e.UserID.String()
+		userID = &id
+	}
+
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil || e.Metadata == nil {
+		metadata = json.RawMessage(`{}`)
+	}
+
+	return &EntryResponse{
+		ID:        e.ID,
+		ProjectID: e.ProjectID.String(),
+		UserID:    userID,
+		UserName:  e.UserName,
+		Action:    e.Action,
+		Metadata:  metadata,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// ListResponse is one page of a project's activity, newest first
+type ListResponse struct {
+	Activity []*EntryResponse `json:"activity"`
+	Total    int              `json:"total"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
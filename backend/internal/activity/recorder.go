@@ -0,0 +1,100 @@
+package activity
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+const (
+	// maxBatch is how many queued entries one write takes
+	maxBatch = 100
+	// writeTimeout bounds one batch write
+	writeTimeout = 5 * time.Second
+)
+
+// Recorder queues activity entries for services and writes them in the
+// background, so a slow or failing activity log never holds up or fails the
+// change being recorded. A nil Recorder records nothing.
+type Recorder struct {
+	repo    *Repository
+	entries chan *Entry
+}
+
+// NewRecorder creates a recorder that queues up to bufferSize entries.
+// Start writing them with Run.
+func NewRecorder(repo *Repository, bufferSize int) *Recorder {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Recorder{
+		repo:    repo,
+		entries: make(chan *Entry, bufferSize),
+	}
+}
+
+// Record queues an entry without blocking. If the queue is full the entry is
+// dropped with a warning.
+func (r *Recorder) Record(ctx context.Context, projectID, userID uuid.UUID, action string, metadata map[string]interface{}) {
+	if r == nil {
+		return
+	}
+
+	entry := &Entry{
+		ProjectID: projectID,
+		Action:    action,
+		Metadata:  metadata,
+	}
+	if userID != uuid.Nil {
+		entry.UserID = &userID
+	}
+
+	select {
+	case r.entries <- entry:
+	default:
+		logger.Ctx(ctx).Warn().Str("project_id", projectID.String()).Str("action", action).Msg("Activity queue full, dropping entry")
+	}
+}
+
+// Run writes queued entries until ctx is cancelled, then writes whatever is
+// still queued
+func (r *Recorder) Run(ctx context.Context) {
+	logger.Info().Int("buffer", cap(r.entries)).Msg("📝 Activity recorder started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			for len(r.entries) > 0 {
+				r.writeBatch(<-r.entries)
+			}
+			return
+		case entry := <-r.entries:
+			r.writeBatch(entry)
+		}
+	}
+}
+
+// writeBatch writes first along with any entries queued behind it, up to maxBatch
+func (r *Recorder) writeBatch(first *Entry) {
+	batch := []*Entry{first}
+collect:
+	for len(batch) < maxBatch {
+		select {
+		case entry := <-r.entries:
+			batch = append(batch, entry)
+		default:
+			break collect
+		}
+	}
+
+	// Writes use their own context so shutdown doesn't abort the final flush
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := r.repo.Insert(ctx, batch); err != nil {
+		logger.Error().Err(err).Int("entries", len(batch)).Msg("Failed to write activity")
+	}
+}
@@ -0,0 +1,115 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles database operations for the activity log
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new activity repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Insert writes entries in one round trip. Entries whose project has been
+// deleted since they were recorded are skipped rather than failing the rest.
+func (r *Repository) Insert(ctx context.Context, entries []*Entry) error {
+	query := `
+		INSERT INTO activity_log (project_id, user_id, action, metadata)
+		SELECT $1::uuid, $2::uuid, $3::varchar, $4::jsonb
+		WHERE EXISTS (SELECT 1 FROM projects WHERE id = $1)
+	`
+
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		metadata := e.Metadata
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		batch.Queue(query, e.ProjectID, e.UserID, e.Action, metadata)
+	}
+
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert activity: %w", err)
+	}
+	return nil
+}
+
+// FindByProject returns one page of a project's activity, newest first, with
+// the name of who made each change. It also returns the number of entries
+// across pages.
+func (r *Repository) FindByProject(ctx context.Context, projectID uuid.UUID, limit, offset int) ([]*Entry, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM activity_log WHERE project_id = $1`
+	if err := r.db.QueryRow(ctx, countQuery, projectID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count activity: %w", err)
+	}
+
+	query := `
+		SELECT a.id, a.project_id, a.user_id, u.name, a.action, a.metadata, a.created_at
+		FROM activity_log a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.project_id = $1
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find activity: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		var e Entry
+		err := rows.Scan(
+			&e.ID,
+			&e.ProjectID,
+			&e.UserID,
+			&e.UserName,
+			&e.Action,
+			&e.Metadata,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate activity: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// GetProjectAccess gets the owner and visibility of a project and whether
+// userID collaborates on it (for authorization)
+func (r *Repository) GetProjectAccess(ctx context.Context, projectID, userID uuid.UUID) (ownerID uuid.UUID, isPublic, isCollaborator bool, err error) {
+	query := `
+		SELECT p.user_id, p.is_public,
+			EXISTS (SELECT 1 FROM project_collaborators c WHERE c.project_id = p.id AND c.user_id = $2)
+		FROM projects p
+		WHERE p.id = $1
+	`
+
+	err = r.db.QueryRow(ctx, query, projectID, userID).Scan(&ownerID, &isPublic, &isCollaborator)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, false, ErrProjectNotFound
+	}
+	if err != nil {
+		return uuid.Nil, false, false, fmt.Errorf("failed to get project access: %w", err)
+	}
+
+	return ownerID, isPublic, isCollaborator, nil
+}
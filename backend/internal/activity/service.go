@@ -0,0 +1,77 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+)
+
+// Common errors
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrUnauthorized    = errors.New("unauthorized to view this project's activity")
+)
+
+// defaultPageSize and maxPageSize bound pages of a project's activity
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// Service handles business logic for the activity log
+type Service struct {
+	repo          *Repository
+	hideForbidden bool
+}
+
+// NewService creates a new activity service
+func NewService(repo *Repository, cfg *config.Config) *Service {
+	return &Service{
+		repo:          repo,
+		hideForbidden: cfg.HideForbidden,
+	}
+}
+
+// ListProjectActivity lists one page of a project's activity. Only the owner
+// and collaborators can see it, even on public projects. A limit of 0 means
+// the default page size; larger limits are capped at maxPageSize.
+func (s *Service) ListProjectActivity(ctx context.Context, projectID, userID uuid.UUID, limit, offset int) (*ListResponse, error) {
+	ownerID, isPublic, isCollaborator, err := s.repo.GetProjectAccess(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != userID && !isCollaborator {
+		if s.hideForbidden && !isPublic {
+			return nil, ErrProjectNotFound
+		}
+		return nil, ErrUnauthorized
+	}
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	entries, total, err := s.repo.FindByProject(ctx, projectID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project activity: %w", err)
+	}
+
+	responses := make([]*EntryResponse, len(entries))
+	for i, e := range entries {
+		responses[i] = e.ToResponse()
+	}
+
+	return &ListResponse{
+		Activity: responses,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}, nil
+}
@@ -0,0 +1,350 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/project"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/maintenance"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/validate"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+)
+
+// Handler handles operator-only HTTP requests
+type Handler struct {
+	maintenance *maintenance.Mode
+	projects    *project.Service
+	whiteboards *whiteboard.Service
+	reindexer   *whiteboard.Reindexer
+}
+
+// NewHandler creates a new admin handler
+func NewHandler(mode *maintenance.Mode, projects *project.Service, whiteboards *whiteboard.Service, reindexer *whiteboard.Reindexer) *Handler {
+	return &Handler{maintenance: mode, projects: projects, whiteboards: whiteboards, reindexer: reindexer}
+}
+
+// MaintenanceRequest is the request body for toggling maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// RegisterRoutes registers the admin routes
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth, requireAdmin fiber.Handler) {
+	admin := api.Group("/admin")
+	admin.Use(requireAuth, requireAdmin)
+	admin.Get("/maintenance", h.GetMaintenance)
+	admin.Put("/maintenance", h.SetMaintenance)
+	admin.Post("/projects/regenerate-slugs", h.RegenerateSlugs)
+	admin.Get("/whiteboards/orphans", h.GetOrphans)
+	admin.Post("/whiteboards/orphans/repair", h.RepairOrphans)
+	admin.Get("/reindex", h.ListReindex)
+	admin.Post("/reindex", h.StartReindex)
+	admin.Get("/reindex/:id", h.GetReindex)
+	admin.Post("/reindex/:id/cancel", h.CancelReindex)
+	admin.Post("/reindex/:id/resume", h.ResumeReindex)
+}
+
+// GetMaintenance handles GET /api/v1/admin/maintenance
+// @Summary Get maintenance mode state
+// @Tags admin
+// @Success 200 {object} map[string]bool
+// @Router /admin/maintenance [get]
+func (h *Handler) GetMaintenance(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"enabled": h.maintenance.Enabled(c.Context()),
+	})
+}
+
+// SetMaintenance handles PUT /api/v1/admin/maintenance
+// @Summary Turn maintenance (read-only) mode on or off
+// @Tags admin
+// @Param body body MaintenanceRequest true "Desired state"
+// @Success 200 {object} map[string]bool
+// @Router /admin/maintenance [put]
+func (h *Handler) SetMaintenance(c *fiber.Ctx) error {
+	var req MaintenanceRequest
+	if err := c.BodyParser(&req); err != nil || req.Enabled == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "enabled is required",
+		})
+	}
+
+	if err := h.maintenance.Set(c.Context(), *req.Enabled); err != nil {
+		logger.For(c).Error().Err(err).Msg("Failed to set maintenance mode")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to set maintenance mode",
+		})
+	}
+	logger.For(c).Warn().Bool("enabled", *req.Enabled).Str("ip", c.IP()).Msg("Maintenance mode changed")
+
+	return c.JSON(fiber.Map{
+		"enabled": h.maintenance.Enabled(c.Context()),
+	})
+}
+
+// RegenerateSlugs handles POST /api/v1/admin/projects/regenerate-slugs
+// @Summary Give public projects with missing or invalid slugs new ones
+// @Description Works through public projects in batches. If a run fails, send its last_project_id as after to resume.
+// @Tags admin
+// @Param body body project.RegenerateSlugsRequest false "Resume point and batch size"
+// @Success 200 {object} project.RegenerateSlugsResponse
+// @Router /admin/projects/regenerate-slugs [post]
+func (h *Handler) RegenerateSlugs(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req project.RegenerateSlugsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	result, err := h.projects.RegenerateSlugs(c.Context(), adminID, &req)
+	if err != nil {
+		if errors.Is(err, project.ErrInvalidSlugRepairCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).
+			Str("audit", "admin.slugs_regenerated").
+			Str("user_id", adminID.String()).
+			Int("updated", result.Updated).
+			Str("last_project_id", result.LastProjectID).
+			Msg("Failed to regenerate slugs")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":           "failed to regenerate slugs",
+			"updated":         result.Updated,
+			"last_project_id": result.LastProjectID,
+		})
+	}
+
+	logger.For(c).Warn().
+		Str("audit", "admin.slugs_regenerated").
+		Str("user_id", adminID.String()).
+		Int("scanned", result.Scanned).
+		Int("updated", result.Updated).
+		Int("skipped", result.Skipped).
+		Str("ip", c.IP()).
+		Msg("Public slugs regenerated")
+
+	return c.JSON(result)
+}
+
+// GetOrphans handles GET /api/v1/admin/whiteboards/orphans
+// @Summary List whiteboards whose project no longer exists
+// @Tags admin
+// @Success 200 {object} whiteboard.OrphanReport
+// @Router /admin/whiteboards/orphans [get]
+func (h *Handler) GetOrphans(c *fiber.Ctx) error {
+	report, err := h.whiteboards.FindOrphanedWhiteboards(c.Context())
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to find orphaned whiteboards")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to find orphaned whiteboards",
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// RepairOrphans handles POST /api/v1/admin/whiteboards/orphans/repair
+// @Summary Delete orphaned whiteboards or reassign them to a project
+// @Description confirm_count must equal the total from GET /admin/whiteboards/orphans; nothing changes otherwise
+// @Tags admin
+// @Param body body whiteboard.RepairOrphansRequest true "Repair action"
+// @Success 200 {object} whiteboard.RepairOrphansResponse
+// @Router /admin/whiteboards/orphans/repair [post]
+func (h *Handler) RepairOrphans(c *fiber.Ctx) error {
+	var req whiteboard.RepairOrphansRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if req.ConfirmCount == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "confirm_count is required",
+		})
+	}
+
+	result, err := h.whiteboards.RepairOrphanedWhiteboards(c.Context(), &req)
+	if err != nil {
+		if errors.Is(err, whiteboard.ErrInvalidRepairAction) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, whiteboard.ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "target project not found",
+			})
+		}
+		if errors.Is(err, whiteboard.ErrOrphanCountChanged) || errors.Is(err, whiteboard.ErrOrphanNameConflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).Msg("Failed to repair orphaned whiteboards")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to repair orphaned whiteboards",
+		})
+	}
+
+	logger.For(c).Warn().
+		Str("action", result.Action).
+		Str("target_project_id", req.TargetProjectID).
+		Int("repaired", result.Repaired).
+		Str("ip", c.IP()).
+		Msg("Orphaned whiteboards repaired")
+
+	return c.JSON(result)
+}
+
+// ListReindex handles GET /api/v1/admin/reindex
+// @Summary List rebuildable derivations and recent reindex jobs
+// @Tags admin
+// @Success 200 {object} whiteboard.ReindexListResponse
+// @Router /admin/reindex [get]
+func (h *Handler) ListReindex(c *fiber.Ctx) error {
+	list, err := h.reindexer.List(c.Context())
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to list reindex jobs")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list reindex jobs",
+		})
+	}
+
+	return c.JSON(list)
+}
+
+// StartReindex handles POST /api/v1/admin/reindex
+// @Summary Rebuild one kind of derived data across all whiteboards
+// @Description Runs in the background in batches; poll GET /admin/reindex/{id} for progress
+// @Tags admin
+// @Param body body whiteboard.StartReindexRequest true "Derivation to rebuild"
+// @Success 202 {object} whiteboard.ReindexJob
+// @Router /admin/reindex [post]
+func (h *Handler) StartReindex(c *fiber.Ctx) error {
+	var req whiteboard.StartReindexRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	job, err := h.reindexer.Start(c.Context(), &req)
+	if err != nil {
+		if errors.Is(err, whiteboard.ErrUnknownDerivation) || errors.Is(err, whiteboard.ErrInvalidReindexBatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, whiteboard.ErrReindexActive) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).Msg("Failed to start reindex")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to start reindex",
+		})
+	}
+
+	logger.For(c).Warn().
+		Str("job_id", job.ID).
+		Str("derivation", job.Derivation).
+		Str("ip", c.IP()).
+		Msg("Reindex started")
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetReindex handles GET /api/v1/admin/reindex/:id
+// @Summary Get a reindex job's progress
+// @Tags admin
+// @Param id path string true "Job ID"
+// @Success 200 {object} whiteboard.ReindexJob
+// @Router /admin/reindex/{id} [get]
+func (h *Handler) GetReindex(c *fiber.Ctx) error {
+	return h.reindexJob(c, h.reindexer.Get)
+}
+
+// CancelReindex handles POST /api/v1/admin/reindex/:id/cancel
+// @Summary Cancel a queued or running reindex job
+// @Tags admin
+// @Param id path string true "Job ID"
+// @Success 200 {object} whiteboard.ReindexJob
+// @Router /admin/reindex/{id}/cancel [post]
+func (h *Handler) CancelReindex(c *fiber.Ctx) error {
+	return h.reindexJob(c, h.reindexer.Cancel)
+}
+
+// ResumeReindex handles POST /api/v1/admin/reindex/:id/resume
+// @Summary Resume a failed or cancelled reindex job from where it stopped
+// @Tags admin
+// @Param id path string true "Job ID"
+// @Success 200 {object} whiteboard.ReindexJob
+// @Router /admin/reindex/{id}/resume [post]
+func (h *Handler) ResumeReindex(c *fiber.Ctx) error {
+	return h.reindexJob(c, h.reindexer.Resume)
+}
+
+// reindexJob runs one reindex job operation and writes the job or the error
+func (h *Handler) reindexJob(c *fiber.Ctx, op func(ctx context.Context, id uuid.UUID) (*whiteboard.ReindexJob, error)) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid job id",
+		})
+	}
+
+	job, err := op(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, whiteboard.ErrReindexNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, whiteboard.ErrReindexFinished) || errors.Is(err, whiteboard.ErrReindexNotResumable) || errors.Is(err, whiteboard.ErrReindexActive) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).Str("job_id", id.String()).Msg("Reindex job request failed")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reindex job request failed",
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// getUserID extracts the admin's user ID from the context (set by auth middleware)
+func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid user ID format")
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+)
+
+// Diagram layout, in canvas units
+const (
+	nodeWidth      = 180.0
+	nodeHeight     = 80.0
+	columnGap      = 120.0
+	rowGap         = 60.0
+	labelInset     = 10.0
+	labelHeight    = 24.0
+	maxLabelLength = 40
+)
+
+// defaultStyle matches the editor's default shape style
+var defaultStyle = whiteboard.ShapeStyle{
+	StrokeColor: "#ffffff",
+	StrokeWidth: 2,
+	StrokeStyle: "solid",
+	FillColor:   "transparent",
+	FillStyle:   "none",
+	Opacity:     1,
+	Roughness:   1,
+}
+
+// roundKinds are drawn as ellipses rather than rectangles
+var roundKinds = map[string]bool{
+	"database": true,
+	"cache":    true,
+}
+
+// parseDiagram decodes the model's reply, dropping nodes without ids,
+// duplicate nodes and edges that don't join two known nodes
+func parseDiagram(text string) (*diagram, error) {
+	var raw diagram
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &raw); err != nil {
+		return nil, ErrEmptyDiagram
+	}
+
+	d := &diagram{}
+	seen := make(map[string]bool, len(raw.Nodes))
+	for _, n := range raw.Nodes {
+		n.ID = strings.TrimSpace(n.ID)
+		if n.ID == "" || seen[n.ID] {
+			continue
+		}
+		if len(d.Nodes) == MaxDiagramNodes {
+			break
+		}
+		seen[n.ID] = true
+		n.Label = truncate(strings.TrimSpace(n.Label))
+		if n.Label == "" {
+			n.Label = truncate(n.ID)
+		}
+		n.Kind = strings.ToLower(strings.TrimSpace(n.Kind))
+		d.Nodes = append(d.Nodes, n)
+	}
+	if len(d.Nodes) == 0 {
+		return nil, ErrEmptyDiagram
+	}
+
+	for _, e := range raw.Edges {
+		e.From, e.To = strings.TrimSpace(e.From), strings.TrimSpace(e.To)
+		if !seen[e.From] || !seen[e.To] || e.From == e.To {
+			continue
+		}
+		e.Label = truncate(strings.TrimSpace(e.Label))
+		d.Edges = append(d.Edges, e)
+	}
+
+	return d, nil
+}
+
+// buildShapes lays a diagram out left to right, each node one column after
+// the furthest node pointing at it, and draws it as canvas shapes: a box and
+// a label per node, and a bound arrow (plus label) per edge
+func buildShapes(d *diagram, now int64) []whiteboard.Shape {
+	level := make(map[string]int, len(d.Nodes))
+	// Longest-path layering; the cap keeps cycles from pushing nodes out forever
+	for pass := 0; pass < len(d.Nodes); pass++ {
+		changed := false
+		for _, e := range d.Edges {
+			if next := level[e.From] + 1; next > level[e.To] && next < len(d.Nodes) {
+				level[e.To] = next
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	columns := map[int][]string{}
+	tallest := 0
+	for _, n := range d.Nodes {
+		columns[level[n.ID]] = append(columns[level[n.ID]], n.ID)
+		tallest = max(tallest, len(columns[level[n.ID]]))
+	}
+
+	type box struct{ x, y float64 }
+	boxes := make(map[string]box, len(d.Nodes))
+	for col, ids := range columns {
+		offset := float64(tallest-len(ids)) * (nodeHeight + rowGap) / 2
+		for row, id := range ids {
+			boxes[id] = box{
+				x: float64(col) * (nodeWidth + columnGap),
+				y: offset + float64(row)*(nodeHeight+rowGap),
+			}
+		}
+	}
+
+	shapes := make([]whiteboard.Shape, 0, 2*len(d.Nodes)+2*len(d.Edges))
+	shapeIDs := make(map[string]string, len(d.Nodes))
+	for _, n := range d.Nodes {
+		b := boxes[n.ID]
+		shapeType := "rectangle"
+		if roundKinds[n.Kind] {
+			shapeType = "ellipse"
+		}
+
+		node := newShape(shapeType, b.x, b.y, nodeWidth, nodeHeight, now)
+		node["label"] = n.Label
+		if shapeType == "rectangle" {
+			node["cornerRadius"] = 8
+		}
+		shapeIDs[n.ID] = node["id"].(string)
+		shapes = append(shapes, node)
+		shapes = append(shapes, newText(n.Label, b.x+labelInset, b.y+(nodeHeight-labelHeight)/2, nodeWidth-2*labelInset, now))
+	}
+
+	for _, e := range d.Edges {
+		from, to := boxes[e.From], boxes[e.To]
+		startX, startY := from.x+nodeWidth, from.y+nodeHeight/2
+		endX, endY := to.x, to.y+nodeHeight/2
+		if to.x <= from.x {
+			// Back or same-column edges leave from the bottom and enter from the top
+			startX, startY = from.x+nodeWidth/2, from.y+nodeHeight
+			endX, endY = to.x+nodeWidth/2, to.y
+		}
+		dx, dy := endX-startX, endY-startY
+
+		arrow := newShape("arrow", startX, startY, math.Abs(dx), math.Abs(dy), now)
+		arrow["points"] = []whiteboard.Point{{X: 0, Y: 0}, {X: dx, Y: dy}}
+		arrow["startArrowhead"] = "none"
+		arrow["endArrowhead"] = "arrow"
+		arrow["startBinding"] = shapeIDs[e.From]
+		arrow["endBinding"] = shapeIDs[e.To]
+		if e.Label != "" {
+			arrow["label"] = e.Label
+		}
+		shapes = append(shapes, arrow)
+
+		if e.Label != "" {
+			width := columnGap - labelInset
+			shapes = append(shapes, newText(e.Label, startX+dx/2-width/2, startY+dy/2-labelHeight, width, now))
+		}
+	}
+
+	return shapes
+}
+
+// newShape creates a shape with the editor's default style
+func newShape(shapeType string, x, y, width, height float64, now int64) whiteboard.Shape {
+	return whiteboard.Shape{
+		"id":          newShapeID(now),
+		"type":        shapeType,
+		"x":           x,
+		"y":           y,
+		"width":       width,
+		"height":      height,
+		"angle":       0,
+		"strokeColor": defaultStyle.StrokeColor,
+		"strokeWidth": defaultStyle.StrokeWidth,
+		"strokeStyle": defaultStyle.StrokeStyle,
+		"fillColor":   defaultStyle.FillColor,
+		"fillStyle":   defaultStyle.FillStyle,
+		"opacity":     defaultStyle.Opacity,
+		"roughness":   defaultStyle.Roughness,
+		"isLocked":    false,
+		"seed":        rand.Int31(),
+		"createdAt":   now,
+		"updatedAt":   now,
+	}
+}
+
+// newText creates a centered text shape
+func newText(text string, x, y, width float64, now int64) whiteboard.Shape {
+	shape := newShape("text", x, y, width, labelHeight, now)
+	shape["text"] = text
+	shape["fontSize"] = 16
+	shape["fontFamily"] = "Virgil, Segoe UI Emoji"
+	shape["textAlign"] = "center"
+	shape["verticalAlign"] = "middle"
+	shape["lineHeight"] = 1.25
+	shape["autoResize"] = false
+	return shape
+}
+
+// newShapeID creates an ID in the editor's shape_<millis>_<random> format
+func newShapeID(now int64) string {
+	return fmt.Sprintf("shape_%d_%s", now, strings.ReplaceAll(uuid.NewString(), "-", "")[:9])
+}
+
+// stripCodeFence removes the Markdown code fence models sometimes wrap JSON in
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxLabelLength {
+		return s
+	}
+	return string(runes[:maxLabelLength-1]) + "…"
+}
@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/google/uuid"
+)
+
+// Handler handles HTTP requests for AI features
+type Handler struct {
+	service              *Service
+	generationsPerMinute int
+}
+
+// NewHandler creates a new AI handler
+func NewHandler(service *Service, generationsPerMinute int) *Handler {
+	return &Handler{
+		service:              service,
+		generationsPerMinute: generationsPerMinute,
+	}
+}
+
+// RegisterRoutes registers the AI routes. aiLimit caps concurrent Gemini calls per user.
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth, aiLimit fiber.Handler) {
+	// Each generation or review spends Gemini quota, so they are limited per user
+	generateLimiter := limiter.New(limiter.Config{
+		Max:        h.generationsPerMinute,
+		Expiration: time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userID, ok := c.Locals("userID").(string); ok {
+				return userID
+			}
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "too many AI requests, try again later",
+			})
+		},
+	})
+
+	api.Post("/ai/generate-diagram", requireAuth, generateLimiter, aiLimit, h.GenerateDiagram)
+	api.Post("/ai/review", requireAuth, generateLimiter, aiLimit, h.Review)
+}
+
+// GenerateDiagram handles POST /api/v1/ai/generate-diagram
+// @Summary Generate a system design diagram from a prompt
+// @Description Returns canvas shapes; with project_id the diagram also replaces the project's default whiteboard canvas.
+// @Tags ai
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body GenerateDiagramRequest true "Prompt"
+// @Success 200 {object} GenerateDiagramResponse
+// @Failure 429 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /ai/generate-diagram [post]
+func (h *Handler) GenerateDiagram(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req GenerateDiagramRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	resp, err := h.service.GenerateDiagram(c.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrEmptyPrompt) || errors.Is(err, ErrPromptTooLong) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrNotConfigured) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrRateLimited) {
+			c.Set(fiber.HeaderRetryAfter, "60")
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			logger.For(c).Warn().Err(err).Msg("Gemini request failed")
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "diagram generation failed",
+			})
+		}
+		if errors.Is(err, ErrEmptyDiagram) {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, whiteboard.ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, whiteboard.ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		logger.For(c).Error().Err(err).Msg("Failed to generate diagram")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to generate diagram",
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// Review handles POST /api/v1/ai/review
+// @Summary Review a whiteboard's system design
+// @Description Asks Gemini for bottlenecks, single points of failure and scaling suggestions.
+// @Description Reviews of an unchanged canvas are cached briefly.
+// @Tags ai
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ReviewRequest true "Whiteboard to review"
+// @Success 200 {object} ReviewResponse
+// @Failure 429 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /ai/review [post]
+func (h *Handler) Review(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req ReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(req.WhiteboardID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	review, err := h.service.ReviewWhiteboard(c.Context(), whiteboardID, userID)
+	if err != nil {
+		if errors.Is(err, ErrEmptyWhiteboard) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrNotConfigured) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrRateLimited) {
+			c.Set(fiber.HeaderRetryAfter, "60")
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			logger.For(c).Warn().Err(err).Msg("Gemini request failed")
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "design review failed",
+			})
+		}
+		if errors.Is(err, ErrEmptyReview) {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, whiteboard.ErrWhiteboardNotFound) || errors.Is(err, whiteboard.ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, whiteboard.ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		logger.For(c).Error().Err(err).Msg("Failed to review whiteboard")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to review whiteboard",
+		})
+	}
+
+	return c.JSON(review)
+}
+
+// getUserID extracts the user ID from the context (set by auth middleware)
+func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid user ID format")
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+)
+
+// Limits on generation requests
+const (
+	MaxPromptLength = 2000
+	MaxDiagramNodes = 50
+)
+
+// Errors returned by the AI service
+var (
+	ErrNotConfigured = errors.New("AI features are not configured")
+	ErrEmptyPrompt   = errors.New("prompt is required")
+	ErrPromptTooLong = fmt.Errorf("prompt must be at most %d characters", MaxPromptLength)
+	ErrRateLimited   = errors.New("the AI service is busy, try again later")
+	ErrEmptyDiagram  = errors.New("the model did not return a usable diagram")
+)
+
+// Errors returned when reviewing whiteboards
+var (
+	ErrEmptyWhiteboard = errors.New("whiteboard has no components to review")
+	ErrEmptyReview     = errors.New("the model did not return a usable review")
+)
+
+// UpstreamError is returned when the Gemini API fails or can't be reached
+type UpstreamError struct {
+	// Status is the Gemini HTTP status, or 0 if there was no response
+	Status  int
+	Message string
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Status == 0 {
+		return "gemini request failed: " + e.Message
+	}
+	return fmt.Sprintf("gemini returned %d: %s", e.Status, e.Message)
+}
+
+// GenerateDiagramRequest is the request body for generating a diagram
+type GenerateDiagramRequest struct {
+	Prompt string `json:"prompt"`
+	// ProjectID, if set, saves the diagram as the project's default whiteboard canvas
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// GenerateDiagramResponse is a generated diagram. Shapes use the whiteboard
+// canvas format; Whiteboard is set when the diagram was saved to a project.
+type GenerateDiagramResponse struct {
+	Shapes     []whiteboard.Shape             `json:"shapes"`
+	Whiteboard *whiteboard.WhiteboardResponse `json:"whiteboard,omitempty"`
+}
+
+// ReviewRequest is the request body for reviewing a whiteboard
+type ReviewRequest struct {
+	WhiteboardID string `json:"whiteboard_id"`
+}
+
+// ReviewSuggestion is one piece of architectural feedback
+type ReviewSuggestion struct {
+	Category   string   `json:"category"`
+	Severity   string   `json:"severity"`
+	Title      string   `json:"title"`
+	Detail     string   `json:"detail"`
+	Components []string `json:"components,omitempty"`
+}
+
+// ReviewResponse is the model's feedback on a whiteboard's design
+type ReviewResponse struct {
+	Summary     string             `json:"summary"`
+	Suggestions []ReviewSuggestion `json:"suggestions"`
+}
+
+// diagram is the structure the model is asked to return
+type diagram struct {
+	Nodes []diagramNode `json:"nodes"`
+	Edges []diagramEdge `json:"edges"`
+}
+
+type diagramNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// Kind is "service", "database", "queue", "cache", "client" or "external"
+	Kind string `json:"kind"`
+}
+
+type diagramEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label"`
+}
@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+)
+
+// maxReviewNodes caps how much of a canvas is described to the model
+const maxReviewNodes = 200
+
+// reviewInstructions asks the model for feedback on a described design
+const reviewInstructions = `You are a senior software architect reviewing a system design diagram.
+Point out bottlenecks, single points of failure and scaling problems, and suggest fixes.
+Reply with only a JSON object of this form:
+{"summary":"One paragraph overview","suggestions":[{"category":"single_point_of_failure","severity":"high","title":"Single database","detail":"...","components":["Postgres"]}]}
+category is one of bottleneck, single_point_of_failure, scaling, reliability, security or other;
+severity is low, medium or high; components lists the labels of the components concerned.
+
+Diagram:
+%s`
+
+// reviewKey identifies a review: the same canvas of the same whiteboard gets the same answer
+type reviewKey struct {
+	whiteboardID uuid.UUID
+	data         [sha256.Size]byte
+}
+
+// ReviewWhiteboard asks Gemini for feedback on a whiteboard's design. Reviews
+// of an unchanged canvas are served from a short-lived cache, after the
+// access check, so repeated clicks don't spend quota.
+func (s *Service) ReviewWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID) (*ReviewResponse, error) {
+	if s.apiKey == "" {
+		return nil, ErrNotConfigured
+	}
+
+	// GetWhiteboard checks project access before returning any data
+	wb, err := s.whiteboards.GetWhiteboard(ctx, whiteboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := reviewKey{whiteboardID: whiteboardID, data: sha256.Sum256(wb.Data)}
+	if cached, ok := s.reviews.Get(key); ok {
+		return cached, nil
+	}
+
+	description, err := describeCanvas(wb.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := s.generate(ctx, fmt.Sprintf(reviewInstructions, description))
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := parseReview(text)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reviews.Add(key, review)
+	return review, nil
+}
+
+// describeCanvas writes a canvas's components and connections as plain text.
+// Boxes drawn in the editor carry no label of their own, so a box is named
+// after the text placed inside it; other free-floating text is left out.
+func describeCanvas(data json.RawMessage) (string, error) {
+	var canvas whiteboard.CanvasData
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &canvas); err != nil {
+			return "", ErrEmptyWhiteboard
+		}
+	}
+
+	bounds := make(map[string][4]float64, len(canvas.Shapes))
+	for _, shape := range canvas.Shapes {
+		if id, ok := shape["id"].(string); ok {
+			bounds[id] = [4]float64{number(shape, "x"), number(shape, "y"), number(shape, "width"), number(shape, "height")}
+		}
+	}
+
+	graph := whiteboard.ToGraph(&canvas)
+	var texts []whiteboard.GraphNode
+	for _, n := range graph.Nodes {
+		if n.Type == "text" && n.Label != "" {
+			texts = append(texts, n)
+		}
+	}
+
+	labels := make(map[string]string, len(graph.Nodes))
+	described := 0
+	var b strings.Builder
+	b.WriteString("Components:\n")
+	for _, n := range graph.Nodes {
+		if n.Type == "text" {
+			continue
+		}
+		if described == maxReviewNodes {
+			break
+		}
+		label := n.Label
+		if label == "" {
+			if text, ok := textInside(texts, bounds, bounds[n.ID]); ok {
+				label = text.Label
+				// Connectors ending on the label count as ending on the box
+				labels[text.ID] = label
+			}
+		}
+		if label == "" {
+			label = "unnamed " + n.Type
+		}
+		labels[n.ID] = label
+		fmt.Fprintf(&b, "- %s (%s)\n", label, n.Type)
+		described++
+	}
+	if described == 0 {
+		return "", ErrEmptyWhiteboard
+	}
+
+	b.WriteString("Connections:\n")
+	for _, e := range graph.Edges {
+		from, to := labels[e.Source], labels[e.Target]
+		if from == "" || to == "" {
+			continue
+		}
+		if e.Label != "" {
+			fmt.Fprintf(&b, "- %s -> %s: %s\n", from, to, e.Label)
+		} else {
+			fmt.Fprintf(&b, "- %s -> %s\n", from, to)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// textInside returns the first text shape centered within box
+func textInside(texts []whiteboard.GraphNode, bounds map[string][4]float64, box [4]float64) (whiteboard.GraphNode, bool) {
+	for _, t := range texts {
+		tb := bounds[t.ID]
+		cx, cy := tb[0]+tb[2]/2, tb[1]+tb[3]/2
+		if cx >= math.Min(box[0], box[0]+box[2]) && cx <= math.Max(box[0], box[0]+box[2]) &&
+			cy >= math.Min(box[1], box[1]+box[3]) && cy <= math.Max(box[1], box[1]+box[3]) {
+			return t, true
+		}
+	}
+	return whiteboard.GraphNode{}, false
+}
+
+func number(shape whiteboard.Shape, key string) float64 {
+	if v, ok := shape[key].(float64); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return 0
+}
+
+// parseReview decodes the model's review, requiring at least a summary or a suggestion
+func parseReview(text string) (*ReviewResponse, error) {
+	var review ReviewResponse
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &review); err != nil {
+		return nil, ErrEmptyReview
+	}
+
+	review.Summary = strings.TrimSpace(review.Summary)
+	kept := make([]ReviewSuggestion, 0, len(review.Suggestions))
+	for _, suggestion := range review.Suggestions {
+		if strings.TrimSpace(suggestion.Title) == "" && strings.TrimSpace(suggestion.Detail) == "" {
+			continue
+		}
+		kept = append(kept, suggestion)
+	}
+	review.Suggestions = kept
+
+	if review.Summary == "" && len(review.Suggestions) == 0 {
+		return nil, ErrEmptyReview
+	}
+
+	return &review, nil
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/lru"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/whiteboard"
+)
+
+// geminiBaseURL is the Gemini REST API
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// diagramInstructions tells the model what to return for a prompt
+const diagramInstructions = `You are a software architect drawing a system design diagram.
+Reply with only a JSON object of this form:
+{"nodes":[{"id":"api","label":"API Gateway","kind":"service"}],"edges":[{"from":"client","to":"api","label":"HTTPS"}]}
+Each node is one component; kind is one of service, database, queue, cache, client or external.
+Each edge is a request or data flow between two node ids, with an optional short label.
+Use at most %d nodes and keep labels under 30 characters.
+
+Design: %s`
+
+// Service generates whiteboard diagrams with Gemini
+type Service struct {
+	apiKey      string
+	model       string
+	client      *http.Client
+	whiteboards *whiteboard.Service
+	reviews     *lru.Cache[reviewKey, *ReviewResponse]
+}
+
+// NewService creates a new AI service. Generation is disabled without a Gemini API key.
+func NewService(cfg *config.Config, whiteboards *whiteboard.Service) *Service {
+	return &Service{
+		apiKey:      cfg.GeminiAPIKey,
+		model:       cfg.GeminiModel,
+		client:      &http.Client{Timeout: time.Duration(cfg.GeminiTimeoutSeconds) * time.Second},
+		whiteboards: whiteboards,
+		reviews:     lru.New[reviewKey, *ReviewResponse](cfg.AIReviewCacheSize, time.Duration(cfg.AIReviewCacheTTLSeconds)*time.Second),
+	}
+}
+
+// GenerateDiagram turns a natural-language prompt into canvas shapes. With a
+// project ID, the shapes replace that project's default whiteboard canvas.
+func (s *Service) GenerateDiagram(ctx context.Context, userID uuid.UUID, req *GenerateDiagramRequest) (*GenerateDiagramResponse, error) {
+	if s.apiKey == "" {
+		return nil, ErrNotConfigured
+	}
+
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" {
+		return nil, ErrEmptyPrompt
+	}
+	if len([]rune(prompt)) > MaxPromptLength {
+		return nil, ErrPromptTooLong
+	}
+
+	// Check the project before spending Gemini quota on a diagram that can't be saved
+	var projectID uuid.UUID
+	if req.ProjectID != "" {
+		id, err := uuid.Parse(req.ProjectID)
+		if err != nil {
+			return nil, whiteboard.ErrProjectNotFound
+		}
+		if err := s.whiteboards.CheckCanvasEditAccess(ctx, id, userID); err != nil {
+			return nil, err
+		}
+		projectID = id
+	}
+
+	text, err := s.generate(ctx, fmt.Sprintf(diagramInstructions, MaxDiagramNodes, prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseDiagram(text)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	shapes := buildShapes(d, now)
+	resp := &GenerateDiagramResponse{Shapes: shapes}
+
+	if projectID == uuid.Nil {
+		return resp, nil
+	}
+
+	data, err := json.Marshal(whiteboard.CanvasData{
+		Version:   whiteboard.CurrentCanvasVersion,
+		Shapes:    shapes,
+		Viewport:  whiteboard.Viewport{Zoom: 1},
+		Style:     defaultStyle,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode diagram: %w", err)
+	}
+
+	saved, err := s.whiteboards.SaveCanvasDataByProject(ctx, projectID, userID, data)
+	if err != nil {
+		return nil, err
+	}
+	resp.Whiteboard = saved
+
+	return resp, nil
+}
+
+// generate sends one prompt to Gemini and returns the text of its reply
+func (s *Service) generate(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{"parts": []map[string]string{{"text": prompt}}},
+		},
+		"generationConfig": map[string]interface{}{
+			"responseMimeType": "application/json",
+			"temperature":      0.2,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", geminiBaseURL, url.PathEscape(s.model))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", s.apiKey)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", &UpstreamError{Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", &UpstreamError{Status: resp.StatusCode, Message: "failed to read response"}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", ErrRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &apiErr)
+		return "", &UpstreamError{Status: resp.StatusCode, Message: apiErr.Error.Message}
+	}
+
+	var result struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", &UpstreamError{Status: resp.StatusCode, Message: "invalid response"}
+	}
+
+	var text strings.Builder
+	if len(result.Candidates) > 0 {
+		for _, part := range result.Candidates[0].Content.Parts {
+			text.WriteString(part.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", ErrEmptyDiagram
+	}
+
+	return text.String(), nil
+}
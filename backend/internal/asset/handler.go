@@ -0,0 +1,348 @@
+package asset
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/google/uuid"
+)
+
+// Handler handles HTTP requests for assets
+type Handler struct {
+	service          *Service
+	uploadsPerMinute int
+}
+
+// NewHandler creates a new asset handler
+func NewHandler(service *Service, uploadsPerMinute int) *Handler {
+	return &Handler{
+		service:          service,
+		uploadsPerMinute: uploadsPerMinute,
+	}
+}
+
+// RegisterRoutes registers the asset routes
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth, optionalAuth fiber.Handler) {
+	// Uploads are limited per user, so the limiter runs after requireAuth
+	uploadLimiter := limiter.New(limiter.Config{
+		Max:        h.uploadsPerMinute,
+		Expiration: time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userID, ok := c.Locals("userID").(string); ok {
+				return userID
+			}
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "too many uploads, try again later",
+			})
+		},
+	})
+
+	api.Post("/projects/:id/assets", requireAuth, uploadLimiter, h.Upload)
+	api.Post("/projects/:id/thumbnail", requireAuth, uploadLimiter, h.SetThumbnail)
+
+	// Assets and thumbnails of public projects are readable without logging in
+	// (outside /projects, whose routes all require auth)
+	api.Get("/assets/:id", optionalAuth, h.Get)
+	api.Get("/project-thumbnails/:id", optionalAuth, h.GetThumbnail)
+}
+
+// Upload handles POST /api/v1/projects/:id/assets
+// @Summary Upload an image asset for a project
+// @Tags assets
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param id path string true "Project ID"
+// @Param file formData file true "Image file (png, jpeg, gif, webp)"
+// @Success 201 {object} AssetResponse
+// @Router /projects/{id}/assets [post]
+func (h *Handler) Upload(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "file is required",
+		})
+	}
+	if fileHeader.Size > h.service.MaxBytes() {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":     "file too large",
+			"max_bytes": h.service.MaxBytes(),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "failed to read file",
+		})
+	}
+	defer file.Close()
+
+	// Read one byte past the limit so oversized files are caught even if the header lied
+	data, err := io.ReadAll(io.LimitReader(file, h.service.MaxBytes()+1))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "failed to read file",
+		})
+	}
+
+	asset, err := h.service.Upload(c.Context(), projectID, userID, fileHeader.Filename, data)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		if errors.Is(err, ErrTooLarge) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error":     "file too large",
+				"max_bytes": h.service.MaxBytes(),
+			})
+		}
+		if errors.Is(err, ErrUnknownRegion) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrEmptyUpload) {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).Str("project_id", projectID.String()).Msg("Failed to upload asset")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to upload asset",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(asset)
+}
+
+// Get handles GET /api/v1/assets/:id
+// @Summary Get an asset's bytes
+// @Tags assets
+// @Param id path string true "Asset ID"
+// @Success 200 {file} binary
+// @Router /assets/{id} [get]
+func (h *Handler) Get(c *fiber.Ctx) error {
+	assetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid asset id",
+		})
+	}
+
+	// Anonymous requests are allowed; they can only see assets of public projects
+	userID, _ := getUserID(c)
+
+	asset, data, err := h.service.Get(c.Context(), assetID, userID)
+	if err != nil {
+		if errors.Is(err, ErrAssetNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "asset not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		logger.FailureFor(c, err).Str("asset_id", assetID.String()).Msg("Failed to get asset")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get asset",
+		})
+	}
+
+	// Asset bytes never change for a given ID
+	c.Set(fiber.HeaderContentType, asset.ContentType)
+	c.Set(fiber.HeaderContentLength, strconv.FormatInt(asset.SizeBytes, 10))
+	c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+	c.Set("X-Content-Type-Options", "nosniff")
+	return c.Send(data)
+}
+
+// SetThumbnail handles POST /api/v1/projects/:id/thumbnail
+// @Summary Set a project's thumbnail
+// @Description Uploads a PNG as the thumbnail when sent as multipart/form-data;
+// @Description with no body, renders the project's default whiteboard instead.
+// @Tags assets
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param id path string true "Project ID"
+// @Param file formData file false "PNG image"
+// @Success 200 {object} ThumbnailResponse
+// @Router /projects/{id}/thumbnail [post]
+func (h *Handler) SetThumbnail(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	var result *ThumbnailResponse
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "file is required",
+			})
+		}
+		if fileHeader.Size > h.service.ThumbnailMaxBytes() {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error":     "file too large",
+				"max_bytes": h.service.ThumbnailMaxBytes(),
+			})
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "failed to read file",
+			})
+		}
+		defer file.Close()
+
+		// Read one byte past the limit so oversized files are caught even if the header lied
+		data, err := io.ReadAll(io.LimitReader(file, h.service.ThumbnailMaxBytes()+1))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "failed to read file",
+			})
+		}
+
+		result, err = h.service.UploadThumbnail(c.Context(), projectID, userID, data)
+	} else {
+		result, err = h.service.RenderThumbnail(c.Context(), projectID, userID)
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		if errors.Is(err, ErrTooLarge) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error":     "file too large",
+				"max_bytes": h.service.ThumbnailMaxBytes(),
+			})
+		}
+		if errors.Is(err, ErrUnknownRegion) || errors.Is(err, ErrNoWhiteboard) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrThumbnailNotPNG) || errors.Is(err, ErrEmptyUpload) {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		logger.FailureFor(c, err).Str("project_id", projectID.String()).Msg("Failed to set project thumbnail")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to set project thumbnail",
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// GetThumbnail handles GET /api/v1/project-thumbnails/:id
+// @Summary Get a project's thumbnail
+// @Tags assets
+// @Param id path string true "Project ID"
+// @Success 200 {file} binary
+// @Router /project-thumbnails/{id} [get]
+func (h *Handler) GetThumbnail(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	// Anonymous requests are allowed; they can only see thumbnails of public projects
+	userID, _ := getUserID(c)
+
+	data, err := h.service.GetThumbnail(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrThumbnailNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		logger.FailureFor(c, err).Str("project_id", projectID.String()).Msg("Failed to get project thumbnail")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get project thumbnail",
+		})
+	}
+
+	// thumbnail_url is versioned, so a versioned URL's bytes never change
+	if c.Query("v") != "" {
+		c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	}
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set("X-Content-Type-Options", "nosniff")
+	return c.Send(data)
+}
+
+// getUserID extracts the user ID from the context (set by auth middleware)
+func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid user ID format")
+	}
+
+	return userID, nil
+}
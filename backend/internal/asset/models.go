@@ -0,0 +1,45 @@
+package asset
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Asset is an uploaded image that canvases can reference
+type Asset struct {
+	ID          uuid.UUID `json:"id"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	StorageKey  string    `json:"-"`
+	// StorageRegion is the region the bytes were written to ('' means the default region)
+	StorageRegion string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AssetResponse is the asset data returned to clients. URL is what shape JSON should reference.
+type AssetResponse struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	URL         string    `json:"url"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse converts Asset to AssetResponse
+func (a *Asset) ToResponse() *AssetResponse {
+	return &AssetResponse{
+		ID:          a.ID.String(),
+		ProjectID:   a.ProjectID.String(),
+		URL:         "/api/v1/assets/" + a.ID.String(),
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		SizeBytes:   a.SizeBytes,
+		CreatedAt:   a.CreatedAt,
+	}
+}
@@ -0,0 +1,126 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles database operations for assets
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new asset repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Create records an uploaded asset
+func (r *Repository) Create(ctx context.Context, a *Asset) (*Asset, error) {
+	query := `
+		INSERT INTO assets (id, project_id, user_id, filename, content_type, size_bytes, storage_key, storage_region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, project_id, user_id, filename, content_type, size_bytes, storage_key, COALESCE(storage_region, ''), created_at
+	`
+
+	var asset Asset
+	err := r.db.QueryRow(ctx, query, a.ID, a.ProjectID, a.UserID, a.Filename, a.ContentType, a.SizeBytes, a.StorageKey, a.StorageRegion).Scan(
+		&asset.ID,
+		&asset.ProjectID,
+		&asset.UserID,
+		&asset.Filename,
+		&asset.ContentType,
+		&asset.SizeBytes,
+		&asset.StorageKey,
+		&asset.StorageRegion,
+		&asset.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	return &asset, nil
+}
+
+// FindByID finds an asset by its ID
+func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Asset, error) {
+	query := `
+		SELECT id, project_id, user_id, filename, content_type, size_bytes, storage_key, COALESCE(storage_region, ''), created_at
+		FROM assets
+		WHERE id = $1
+	`
+
+	var asset Asset
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&asset.ID,
+		&asset.ProjectID,
+		&asset.UserID,
+		&asset.Filename,
+		&asset.ContentType,
+		&asset.SizeBytes,
+		&asset.StorageKey,
+		&asset.StorageRegion,
+		&asset.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find asset by id: %w", err)
+	}
+
+	return &asset, nil
+}
+
+// GetProjectRegion gets the storage region of a project ("" means the default region)
+func (r *Repository) GetProjectRegion(ctx context.Context, projectID uuid.UUID) (string, error) {
+	query := `SELECT COALESCE(storage_region, '') FROM projects WHERE id = $1`
+
+	var region string
+	err := r.db.QueryRow(ctx, query, projectID).Scan(&region)
+	if err != nil {
+		return "", fmt.Errorf("failed to get project region: %w", err)
+	}
+
+	return region, nil
+}
+
+// GetProjectAccess gets the owner and visibility of a project (for authorization)
+func (r *Repository) GetProjectAccess(ctx context.Context, projectID uuid.UUID) (uuid.UUID, bool, error) {
+	query := `SELECT user_id, is_public FROM projects WHERE id = $1`
+
+	var ownerID uuid.UUID
+	var isPublic bool
+	err := r.db.QueryRow(ctx, query, projectID).Scan(&ownerID, &isPublic)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, ErrProjectNotFound
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to get project access: %w", err)
+	}
+
+	return ownerID, isPublic, nil
+}
+
+// GetCollaboratorRole gets a user's collaborator role on a project, or "" if they aren't one
+func (r *Repository) GetCollaboratorRole(ctx context.Context, projectID, userID uuid.UUID) (string, error) {
+	query := `SELECT role FROM project_collaborators WHERE project_id = $1 AND user_id = $2`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, projectID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get collaborator role: %w", err)
+	}
+
+	return role, nil
+}
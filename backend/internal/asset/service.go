@@ -0,0 +1,231 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/blobstore"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Common errors
+var (
+	ErrAssetNotFound   = errors.New("asset not found")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrUnauthorized    = errors.New("unauthorized to access this asset")
+	ErrTooLarge        = errors.New("asset is too large")
+	ErrUnsupportedType = errors.New("unsupported asset type, allowed: png, jpeg, gif, webp")
+	ErrEmptyUpload     = errors.New("uploaded file is empty")
+	ErrUnknownRegion   = errors.New("project storage region is not configured on this server")
+)
+
+// allowedContentTypes are the sniffed types accepted for upload
+var allowedContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Service handles business logic for assets
+type Service struct {
+	repo              *Repository
+	stores            *blobstore.Router
+	maxBytes          int64
+	thumbnailMaxBytes int64
+	hideForbidden     bool
+}
+
+// NewService creates a new asset service. Blobs are stored in the backend for
+// their project's storage region. With hideForbidden, users without access to
+// a private project are told it doesn't exist.
+func NewService(repo *Repository, stores *blobstore.Router, maxBytes, thumbnailMaxBytes int64, hideForbidden bool) *Service {
+	return &Service{
+		repo:              repo,
+		stores:            stores,
+		maxBytes:          maxBytes,
+		thumbnailMaxBytes: thumbnailMaxBytes,
+		hideForbidden:     hideForbidden,
+	}
+}
+
+// MaxBytes returns the maximum upload size
+func (s *Service) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Upload validates and stores an image for a project. Only the owner can upload.
+func (s *Service) Upload(ctx context.Context, projectID, userID uuid.UUID, filename string, data []byte) (*AssetResponse, error) {
+	if err := s.authorizeOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyUpload
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Sniff the type from the bytes rather than trusting the client's header
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	region, store, err := s.writeStore(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New()
+	key := projectPrefix(projectID) + id.String()
+	if err := store.Put(ctx, key, data); err != nil {
+		return nil, fmt.Errorf("failed to store asset: %w", err)
+	}
+
+	asset, err := s.repo.Create(ctx, &Asset{
+		ID:            id,
+		ProjectID:     projectID,
+		UserID:        userID,
+		Filename:      filename,
+		ContentType:   contentType,
+		SizeBytes:     int64(len(data)),
+		StorageKey:    key,
+		StorageRegion: region,
+	})
+	if err != nil {
+		// Don't leave an unreferenced blob behind
+		_ = store.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to record asset: %w", err)
+	}
+
+	return asset.ToResponse(), nil
+}
+
+// Get returns an asset and its bytes if the user can view its project.
+// userID may be uuid.Nil for anonymous requests, which only see public projects.
+func (s *Service) Get(ctx context.Context, assetID, userID uuid.UUID) (*Asset, []byte, error) {
+	asset, err := s.repo.FindByID(ctx, assetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get asset: %w", err)
+	}
+	if asset == nil {
+		return nil, nil, ErrAssetNotFound
+	}
+
+	if err := s.authorizeViewer(ctx, asset.ProjectID, userID); err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return nil, nil, ErrAssetNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Reads use the region recorded at upload time, not the project's current one
+	store, err := s.stores.Store(asset.StorageRegion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	data, err := store.Get(ctx, asset.StorageKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		logger.Ctx(ctx).Warn().Str("asset_id", assetID.String()).Msg("Asset row exists but blob is missing")
+		return nil, nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	return asset, data, nil
+}
+
+// DeleteProjectAssets removes all stored blobs for a project in every region,
+// since its region may have changed over time. Asset rows are removed by the
+// database cascade when the project is deleted.
+func (s *Service) DeleteProjectAssets(ctx context.Context, projectID uuid.UUID) {
+	for _, region := range s.stores.Regions() {
+		store, err := s.stores.Store(region)
+		if err != nil {
+			continue
+		}
+		if err := store.DeletePrefix(ctx, projectPrefix(projectID)); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("project_id", projectID.String()).Str("region", region).Msg("Failed to delete project assets")
+		}
+	}
+}
+
+// authorizeOwner checks that userID owns the project
+func (s *Service) authorizeOwner(ctx context.Context, projectID, userID uuid.UUID) error {
+	ownerID, isPublic, err := s.repo.GetProjectAccess(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return s.forbidden(isPublic)
+	}
+	return nil
+}
+
+// authorizeViewer checks that userID owns or collaborates on the project, or
+// that it is public. userID may be uuid.Nil, which is never a collaborator.
+func (s *Service) authorizeViewer(ctx context.Context, projectID, userID uuid.UUID) error {
+	ownerID, isPublic, err := s.repo.GetProjectAccess(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if isPublic || (userID != uuid.Nil && ownerID == userID) {
+		return nil
+	}
+	if userID == uuid.Nil {
+		return s.forbidden(isPublic)
+	}
+
+	role, err := s.repo.GetCollaboratorRole(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return s.forbidden(isPublic)
+	}
+	return nil
+}
+
+// forbidden returns the error for a user without access to a project. With
+// hideForbidden, private projects are reported as missing so their IDs can't
+// be probed; public projects are already visible, so they keep ErrUnauthorized.
+func (s *Service) forbidden(isPublic bool) error {
+	if s.hideForbidden && !isPublic {
+		return ErrProjectNotFound
+	}
+	return ErrUnauthorized
+}
+
+// writeStore returns the region and store that new blobs of a project go to.
+// Writes go to the project's current region; unknown regions are rejected rather
+// than silently falling back, so data never lands outside its pinned region.
+func (s *Service) writeStore(ctx context.Context, projectID uuid.UUID) (string, blobstore.Store, error) {
+	region, err := s.repo.GetProjectRegion(ctx, projectID)
+	if err != nil {
+		return "", nil, err
+	}
+	region, err = s.stores.Resolve(region)
+	if errors.Is(err, blobstore.ErrUnknownRegion) {
+		return "", nil, ErrUnknownRegion
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	store, err := s.stores.Store(region)
+	if err != nil {
+		return "", nil, err
+	}
+	return region, store, nil
+}
+
+func projectPrefix(projectID uuid.UUID) string {
+	return "projects/" + projectID.String() + "/assets/"
+}
@@ -0,0 +1,257 @@
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/blobstore"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/thumbnail"
+)
+
+// Project thumbnail errors
+var (
+	ErrThumbnailNotFound = errors.New("project has no thumbnail")
+	ErrThumbnailNotPNG   = errors.New("project thumbnails must be png images")
+	ErrNoWhiteboard      = errors.New("project has no whiteboard to render a thumbnail from")
+)
+
+// ProjectThumbnail is where a project's thumbnail is stored
+type ProjectThumbnail struct {
+	Key string
+	// Region is the region the bytes were written to ('' means the default region)
+	Region string
+}
+
+// ThumbnailResponse is returned after a project thumbnail is set
+type ThumbnailResponse struct {
+	ProjectID    string    `json:"project_id"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ==================== Repository ====================
+
+// FindThumbnail returns where a project's thumbnail is stored, nil if it has none
+func (r *Repository) FindThumbnail(ctx context.Context, projectID uuid.UUID) (*ProjectThumbnail, error) {
+	query := `
+		SELECT thumbnail_key, COALESCE(thumbnail_region, '')
+		FROM projects
+		WHERE id = $1 AND thumbnail_key IS NOT NULL
+	`
+
+	var t ProjectThumbnail
+	err := r.db.QueryRow(ctx, query, projectID).Scan(&t.Key, &t.Region)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project thumbnail: %w", err)
+	}
+
+	return &t, nil
+}
+
+// SetThumbnail records a project's stored thumbnail and the URL it's served at
+func (r *Repository) SetThumbnail(ctx context.Context, projectID uuid.UUID, t *ProjectThumbnail, url string, updatedAt time.Time) error {
+	query := `
+		UPDATE projects
+		SET thumbnail_key = $2, thumbnail_region = $3, thumbnail_url = $4, thumbnail_updated_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, projectID, t.Key, t.Region, url, updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set project thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+// FindDefaultCanvas returns the canvas data of a project's default (earliest)
+// whiteboard. found is false if the project has no whiteboards.
+func (r *Repository) FindDefaultCanvas(ctx context.Context, projectID uuid.UUID) (data json.RawMessage, found bool, err error) {
+	query := `
+		SELECT data, data_compressed, data_encoding
+		FROM whiteboards
+		WHERE project_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		LIMIT 1
+	`
+
+	var stored compress.Stored
+	err = r.db.QueryRow(ctx, query, projectID).Scan(&stored.Plain, &stored.Compressed, &stored.Encoding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find default whiteboard: %w", err)
+	}
+
+	if data, err = stored.Unpack(); err != nil {
+		return nil, false, fmt.Errorf("failed to read default whiteboard: %w", err)
+	}
+	return data, true, nil
+}
+
+// ==================== Service ====================
+
+// ThumbnailMaxBytes returns the maximum uploaded thumbnail size
+func (s *Service) ThumbnailMaxBytes() int64 {
+	return s.thumbnailMaxBytes
+}
+
+// UploadThumbnail sets a project's thumbnail to an uploaded PNG. Only the owner can set it.
+func (s *Service) UploadThumbnail(ctx context.Context, projectID, userID uuid.UUID, data []byte) (*ThumbnailResponse, error) {
+	if err := s.authorizeOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyUpload
+	}
+	if int64(len(data)) > s.thumbnailMaxBytes {
+		return nil, ErrTooLarge
+	}
+	if http.DetectContentType(data) != "image/png" {
+		return nil, ErrThumbnailNotPNG
+	}
+
+	return s.storeThumbnail(ctx, projectID, data)
+}
+
+// RenderThumbnail sets a project's thumbnail to a render of its default
+// whiteboard, at the same size as whiteboard thumbnails. Only the owner can set it.
+func (s *Service) RenderThumbnail(ctx context.Context, projectID, userID uuid.UUID) (*ThumbnailResponse, error) {
+	if err := s.authorizeOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	data, found, err := s.repo.FindDefaultCanvas(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNoWhiteboard
+	}
+
+	png, err := renderCanvas(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeThumbnail(ctx, projectID, png)
+}
+
+// GetThumbnail returns a project's thumbnail bytes if the user can view the project.
+// userID may be uuid.Nil for anonymous requests, which only see public projects.
+func (s *Service) GetThumbnail(ctx context.Context, projectID, userID uuid.UUID) ([]byte, error) {
+	if err := s.authorizeViewer(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	t, err := s.repo.FindThumbnail(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrThumbnailNotFound
+	}
+
+	store, err := s.stores.Store(t.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project thumbnail: %w", err)
+	}
+
+	data, err := store.Get(ctx, t.Key)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		logger.Ctx(ctx).Warn().Str("project_id", projectID.String()).Msg("Project thumbnail is recorded but blob is missing")
+		return nil, ErrThumbnailNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project thumbnail: %w", err)
+	}
+
+	return data, nil
+}
+
+// storeThumbnail writes a project's thumbnail to its region and records it.
+// The URL carries the write time, so clients can cache each version forever.
+func (s *Service) storeThumbnail(ctx context.Context, projectID uuid.UUID, png []byte) (*ThumbnailResponse, error) {
+	previous, err := s.repo.FindThumbnail(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	region, store, err := s.writeStore(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ProjectThumbnail{Key: thumbnailKey(projectID), Region: region}
+	if err := store.Put(ctx, t.Key, png); err != nil {
+		return nil, fmt.Errorf("failed to store project thumbnail: %w", err)
+	}
+
+	now := time.Now()
+	url := "/api/v1/project-thumbnails/" + projectID.String() + "?v=" + strconv.FormatInt(now.Unix(), 10)
+	if err := s.repo.SetThumbnail(ctx, projectID, t, url, now); err != nil {
+		return nil, err
+	}
+
+	// The key is the same in every region, so only a region move leaves an old blob behind
+	if previous != nil && previous.Region != region {
+		if old, err := s.stores.Store(previous.Region); err == nil {
+			if err := old.Delete(ctx, previous.Key); err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+				logger.Ctx(ctx).Warn().Err(err).Str("project_id", projectID.String()).Str("region", previous.Region).Msg("Failed to delete previous project thumbnail")
+			}
+		}
+	}
+
+	return &ThumbnailResponse{
+		ProjectID:    projectID.String(),
+		ThumbnailURL: url,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// renderCanvas renders whiteboard canvas data to a PNG
+func renderCanvas(data json.RawMessage) (png []byte, err error) {
+	// A malformed canvas must not take the request down with it
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("render panicked: %v", r)
+		}
+	}()
+
+	var canvas struct {
+		Shapes []map[string]interface{} `json:"shapes"`
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &canvas); err != nil {
+			return nil, fmt.Errorf("failed to parse canvas: %w", err)
+		}
+	}
+
+	png, err = render.EncodePNG(render.Canvas(canvas.Shapes, thumbnail.Width, thumbnail.Height))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode project thumbnail: %w", err)
+	}
+	return png, nil
+}
+
+// thumbnailKey is under the project's asset prefix, so deleting the project's
+// assets deletes its thumbnail too
+func thumbnailKey(projectID uuid.UUID) string {
+	return projectPrefix(projectID) + "thumbnail.png"
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// An API key is "sdk_" + 12 hex characters (the prefix, stored to find the
+// key) + "_" + a random secret. Only the SHA-256 of the whole key is stored.
+
+// APIKeyHeader is the header scripts send their API key in
+const APIKeyHeader = "X-API-Key"
+
+const (
+	apiKeyScheme = "sdk_"
+	// apiKeyPrefixLength is the scheme plus 6 random bytes in hex
+	apiKeyPrefixLength = len(apiKeyScheme) + 12
+	apiKeySecretBytes  = 32
+	maxAPIKeyName      = 100
+	// maxAPIKeysPerUser bounds how many active keys one user can hold
+	maxAPIKeysPerUser = 25
+	// apiKeyTouchInterval is how stale last_used_at may get before a request updates it
+	apiKeyTouchInterval = time.Minute
+)
+
+// API key errors
+var (
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrInvalidAPIKey     = errors.New("invalid api key")
+	ErrInvalidAPIKeyName = errors.New("name is required and must be at most 100 characters")
+	ErrTooManyAPIKeys    = fmt.Errorf("at most %d api keys can be active at once; revoke one first", maxAPIKeysPerUser)
+	ErrAPIKeyNotAllowed  = errors.New("api keys can't manage the account; sign in instead")
+)
+
+// APIKey is one of a user's active API keys as listed to them
+type APIKey struct {
+	ID string `json:"id"`
+	// Prefix is the start of the key, to tell keys apart
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKey is a new API key. Key is the only time the full key is shown.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest is the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// apiKeyOwner is the user an API key authenticates as
+type apiKeyOwner struct {
+	keyID   uuid.UUID
+	keyHash string
+	userID  uuid.UUID
+	email   string
+	role    string
+}
+
+// ==================== Repository ====================
+
+// CountAPIKeys returns how many active API keys a user holds
+func (r *Repository) CountAPIKeys(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	return count, nil
+}
+
+// CreateAPIKey stores a new API key by its prefix and hash
+func (r *Repository) CreateAPIKey(ctx context.Context, userID uuid.UUID, name, prefix, keyHash string) (*APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, prefix, name, last_used_at, created_at
+	`
+
+	var key APIKey
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, userID, name, prefix, keyHash).Scan(&id, &key.Prefix, &key.Name, &key.LastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	key.ID = id.String()
+
+	return &key, nil
+}
+
+// ListAPIKeys returns a user's active API keys, newest first
+func (r *Repository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, prefix, name, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		var id uuid.UUID
+		if err := rows.Scan(&id, &key.Prefix, &key.Name, &key.LastUsedAt, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		key.ID = id.String()
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of a user's active API keys. It returns
+// ErrAPIKeyNotFound if the user has no such key.
+func (r *Repository) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserAPIKeys revokes all of a user's active API keys
+func (r *Repository) RevokeUserAPIKeys(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api keys: %w", err)
+	}
+	return nil
+}
+
+// findAPIKeyOwner finds the active key with the given prefix and its user.
+// Returns nil if there is none.
+func (r *Repository) findAPIKeyOwner(ctx context.Context, prefix string) (*apiKeyOwner, error) {
+	query := `
+		SELECT k.id, k.key_hash, u.id, u.email, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL
+	`
+
+	var owner apiKeyOwner
+	err := r.db.QueryRow(ctx, query, prefix).Scan(&owner.keyID, &owner.keyHash, &owner.userID, &owner.email, &owner.role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+
+	return &owner, nil
+}
+
+// touchAPIKey records that a key was used, at most once per minInterval
+func (r *Repository) touchAPIKey(ctx context.Context, keyID uuid.UUID, minInterval time.Duration) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 second')
+	`, keyID, int64(minInterval.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+	return nil
+}
+
+// ==================== Service ====================
+
+// CreateAPIKey creates an API key for the user. The returned key is not stored
+// and can't be shown again.
+func (s *Service) CreateAPIKey(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxAPIKeyName {
+		return nil, ErrInvalidAPIKeyName
+	}
+
+	count, err := s.repo.CountAPIKeys(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	prefixBytes := make([]byte, (apiKeyPrefixLength-len(apiKeyScheme))/2)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix := apiKeyScheme + hex.EncodeToString(prefixBytes)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	stored, err := s.repo.CreateAPIKey(ctx, id, name, prefix, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{APIKey: *stored, Key: key}, nil
+}
+
+// ListAPIKeys returns the user's active API keys, without the keys themselves
+func (s *Service) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	return s.repo.ListAPIKeys(ctx, id)
+}
+
+// RevokeAPIKey revokes one of the user's API keys; requests made with it fail from then on
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	kid, err := uuid.Parse(keyID)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	return s.repo.RevokeAPIKey(ctx, id, kid)
+}
+
+// ValidateAPIKey returns claims for the user an API key belongs to, the way
+// ValidateAccessToken does for tokens. The claims' ID is the key's ID.
+func (s *Service) ValidateAPIKey(ctx context.Context, key string) (*JWTClaims, error) {
+	if len(key) <= apiKeyPrefixLength || !strings.HasPrefix(key, apiKeyScheme) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	owner, err := s.repo.findAPIKeyOwner(ctx, key[:apiKeyPrefixLength])
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil || subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(owner.keyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Last-used is informational, so failing to record it doesn't fail the request
+	_ = s.repo.touchAPIKey(ctx, owner.keyID, apiKeyTouchInterval)
+
+	return &JWTClaims{
+		UserID:    owner.userID.String(),
+		Email:     owner.email,
+		TokenType: TokenTypeAPIKey,
+		Role:      owner.role,
+		ID:        owner.keyID.String(),
+	}, nil
+}
+
+// hashAPIKey is the stored form of a key. Keys are long and random, so an
+// unsalted fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
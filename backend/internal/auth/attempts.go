@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// A 2FA challenge allows a few wrong codes before it's spent and the login
+// has to start again, so its five minutes can't be used to guess codes.
+// Failures are counted by the challenge's jti until the challenge expires.
+
+// maxChallengeAttempts is how many wrong codes a login challenge allows
+const maxChallengeAttempts = 5
+
+// challengeAttemptsPrefix is the Redis key prefix for failed code counts
+const challengeAttemptsPrefix = "auth:2fa-attempts:"
+
+// ErrTooManyAttempts is returned once a challenge has used up its attempts
+var ErrTooManyAttempts = errors.New("too many invalid codes, log in again")
+
+// attemptCounter counts failed codes per challenge; ChallengeAttempts is the
+// Redis one, memoryAttempts the single-instance default
+type attemptCounter interface {
+	// Failures returns how many wrong codes the challenge has had
+	Failures(ctx context.Context, jti string) (int, error)
+	// AddFailure records a wrong code and returns the new count
+	AddFailure(ctx context.Context, jti string, expiresAt time.Time) (int, error)
+}
+
+// ChallengeAttempts counts failed codes in Redis, shared by every instance
+type ChallengeAttempts struct {
+	client *redis.Client
+}
+
+// NewChallengeAttempts creates a failed code counter backed by Redis
+func NewChallengeAttempts(client *redis.Client) *ChallengeAttempts {
+	return &ChallengeAttempts{client: client}
+}
+
+// Failures returns how many wrong codes the challenge has had
+func (a *ChallengeAttempts) Failures(ctx context.Context, jti string) (int, error) {
+	count, err := a.client.Get(ctx, challengeAttemptsPrefix+jti).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load 2fa attempts: %w", err)
+	}
+	return count, nil
+}
+
+// AddFailure records a wrong code until the challenge expires
+func (a *ChallengeAttempts) AddFailure(ctx context.Context, jti string, expiresAt time.Time) (int, error) {
+	key := challengeAttemptsPrefix + jti
+
+	pipe := a.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireAt(ctx, key, expiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record 2fa attempt: %w", err)
+	}
+	return int(incr.Val()), nil
+}
+
+type attemptEntry struct {
+	count     int
+	expiresAt time.Time
+}
+
+// memoryAttempts counts failed codes in process, for servers without Redis
+type memoryAttempts struct {
+	mu      sync.Mutex
+	entries map[string]attemptEntry
+}
+
+func newMemoryAttempts() *memoryAttempts {
+	return &memoryAttempts{entries: make(map[string]attemptEntry)}
+}
+
+func (m *memoryAttempts) Failures(_ context.Context, jti string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[jti]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, nil
+	}
+	return entry.count, nil
+}
+
+func (m *memoryAttempts) AddFailure(_ context.Context, jti string, expiresAt time.Time) (int, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop expired challenges so abandoned logins don't accumulate
+	for k, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+
+	entry := m.entries[jti]
+	entry.count++
+	entry.expiresAt = expiresAt
+	m.entries[jti] = entry
+	return entry.count, nil
+}
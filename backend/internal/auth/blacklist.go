@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys for revoked tokens. Single tokens are keyed by jti; logging out
+// everywhere stores a per-user cutoff, so tokens without a jti (issued before
+// it existed) are revoked too. Ending a session revokes its family, which
+// covers access tokens the server never sees again.
+const (
+	revokedTokenPrefix  = "auth:revoked:"
+	revokedBeforePrefix = "auth:revoked-before:"
+	revokedFamilyPrefix = "auth:revoked-family:"
+)
+
+// Blacklist records revoked tokens in Redis. Entries expire when the tokens
+// they revoke would have, so the set doesn't grow without bound.
+type Blacklist struct {
+	client *redis.Client
+}
+
+// NewBlacklist creates a token blacklist backed by Redis
+func NewBlacklist(client *redis.Client) *Blacklist {
+	return &Blacklist{client: client}
+}
+
+// Revoke blacklists a single token until it expires
+func (b *Blacklist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+
+	if err := b.client.Set(ctx, revokedTokenPrefix+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUser revokes every token issued to a user up to now. maxTTL is the
+// longest lifetime of any token, after which the cutoff is no longer needed.
+func (b *Blacklist) RevokeUser(ctx context.Context, userID string, maxTTL time.Duration) error {
+	cutoff := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := b.client.Set(ctx, revokedBeforePrefix+userID, cutoff, maxTTL).Err(); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes every token of a session. ttl is the lifetime of its
+// access tokens; its refresh tokens are revoked in the database.
+func (b *Blacklist) RevokeFamily(ctx context.Context, familyID string, ttl time.Duration) error {
+	if familyID == "" || ttl <= 0 {
+		return nil
+	}
+
+	if err := b.client.Set(ctx, revokedFamilyPrefix+familyID, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether a token was revoked, individually, with its
+// session or by logging out everywhere
+func (b *Blacklist) IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error) {
+	values, err := b.client.MGet(ctx,
+		revokedTokenPrefix+claims.ID,
+		revokedBeforePrefix+claims.UserID,
+		revokedFamilyPrefix+claims.FamilyID,
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token blacklist: %w", err)
+	}
+
+	if claims.ID != "" && values[0] != nil {
+		return true, nil
+	}
+	if claims.FamilyID != "" && values[2] != nil {
+		return true, nil
+	}
+
+	if cutoff, ok := values[1].(string); ok {
+		revokedBefore, err := strconv.ParseInt(cutoff, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid revocation cutoff: %w", err)
+		}
+		// iat has second precision, so a token from the same second as the cutoff is revoked
+		if claims.IssuedAt.Unix() <= revokedBefore {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
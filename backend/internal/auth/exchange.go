@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// exchangeCodeTTL is how long a one-time exchange code stays valid after the OAuth callback
+const exchangeCodeTTL = 60 * time.Second
+
+// exchangeCodePrefix is the Redis key prefix for exchange codes
+const exchangeCodePrefix = "auth:exchange:"
+
+// exchangeCodes holds one-time codes issued by OAuth callbacks in body delivery mode.
+// The SPA trades a code for its tokens once, so tokens never appear in redirect URLs.
+// ExchangeStore is the Redis one; exchangeStore keeps codes in this process.
+type exchangeCodes interface {
+	// Issue stores an auth response and returns the one-time code for it
+	Issue(ctx context.Context, response *AuthResponse) (string, error)
+	// Consume returns the auth response for a code and invalidates it.
+	// It returns nil if the code is unknown or expired.
+	Consume(ctx context.Context, code string) (*AuthResponse, error)
+}
+
+// ExchangeStore keeps exchange codes in Redis, so the SPA can redeem a code
+// on any instance, not only the one that handled the callback
+type ExchangeStore struct {
+	client *redis.Client
+}
+
+// NewExchangeStore creates an exchange code store backed by Redis
+func NewExchangeStore(client *redis.Client) *ExchangeStore {
+	return &ExchangeStore{client: client}
+}
+
+// Issue stores an auth response until the code expires
+func (s *ExchangeStore) Issue(ctx context.Context, response *AuthResponse) (string, error) {
+	code, err := generateState()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode exchange code: %w", err)
+	}
+	if err := s.client.Set(ctx, exchangeCodePrefix+code, data, exchangeCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to save exchange code: %w", err)
+	}
+
+	return code, nil
+}
+
+// Consume takes the auth response for a code, so each code can be used once
+func (s *ExchangeStore) Consume(ctx context.Context, code string) (*AuthResponse, error) {
+	data, err := s.client.GetDel(ctx, exchangeCodePrefix+code).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange code: %w", err)
+	}
+
+	var response AuthResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange code: %w", err)
+	}
+
+	return &response, nil
+}
+
+type exchangeEntry struct {
+	response  *AuthResponse
+	expiresAt time.Time
+}
+
+// exchangeStore keeps exchange codes in memory, for deployments without Redis
+type exchangeStore struct {
+	mu      sync.Mutex
+	entries map[string]exchangeEntry
+}
+
+func newExchangeStore() *exchangeStore {
+	return &exchangeStore{entries: make(map[string]exchangeEntry)}
+}
+
+// Issue stores an auth response and returns the one-time code for it
+func (s *exchangeStore) Issue(_ context.Context, response *AuthResponse) (string, error) {
+	code, err := generateState()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired codes so abandoned logins don't accumulate
+	for k, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+
+	s.entries[code] = exchangeEntry{
+		response:  response,
+		expiresAt: now.Add(exchangeCodeTTL),
+	}
+
+	return code, nil
+}
+
+// Consume returns the auth response for a code and invalidates it
+// Returns nil if the code is unknown or expired
+func (s *exchangeStore) Consume(_ context.Context, code string) (*AuthResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[code]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.entries, code)
+
+	if time.Now().After(entry.expiresAt) {
+		return nil, nil
+	}
+
+	return entry.response, nil
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExchangeCodesAreSingleUse(t *testing.T) {
+	ctx := context.Background()
+	store := newExchangeStore()
+	response := &AuthResponse{Tokens: &TokenPair{AccessToken: "access"}}
+
+	code, err := store.Issue(ctx, response)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if got, err := store.Consume(ctx, code); err != nil || got != response {
+		t.Fatalf("Consume = %v, %v; want the issued response", got, err)
+	}
+	if got, _ := store.Consume(ctx, code); got != nil {
+		t.Error("code redeemed twice")
+	}
+}
+
+func TestExpiredExchangeCodesAreRejected(t *testing.T) {
+	ctx := context.Background()
+	store := newExchangeStore()
+
+	code, err := store.Issue(ctx, &AuthResponse{})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	entry := store.entries[code]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	store.entries[code] = entry
+
+	if got, _ := store.Consume(ctx, code); got != nil {
+		t.Error("expired code redeemed")
+	}
+}
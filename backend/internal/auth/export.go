@@ -0,0 +1,367 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+)
+
+// DataExport is everything stored about a user, for data-portability requests
+type DataExport struct {
+	ExportedAt     time.Time               `json:"exported_at"`
+	User           *User                   `json:"user"`
+	Projects       []*ExportedProject      `json:"projects"`
+	SharedProjects []*SharedProject        `json:"shared_projects"`
+	Assets         []*ExportedAsset        `json:"assets"`
+	Favorites      []*ExportedFavorite     `json:"favorites"`
+	Activity       []*ExportedActivity     `json:"activity"`
+	RefreshTokens  []*ExportedRefreshToken `json:"refresh_tokens"`
+	APIKeys        []*ExportedAPIKey       `json:"api_keys"`
+}
+
+// ExportedProject is a project the user owns, with its whiteboards and collaborators
+type ExportedProject struct {
+	ID            uuid.UUID               `json:"id"`
+	Name          string                  `json:"name"`
+	Description   string                  `json:"description"`
+	IsPublic      bool                    `json:"is_public"`
+	PublicSlug    *string                 `json:"public_slug,omitempty"`
+	CreatedAt     time.Time               `json:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+	Whiteboards   []*ExportedWhiteboard   `json:"whiteboards"`
+	Collaborators []*ExportedCollaborator `json:"collaborators"`
+}
+
+// ExportedWhiteboard is a whiteboard with its full canvas data
+type ExportedWhiteboard struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ExportedCollaborator is another user with access to one of the user's projects
+type ExportedCollaborator struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Role    string    `json:"role"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// SharedProject is a project owned by someone else that the user collaborates on
+type SharedProject struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// ExportedAsset describes an uploaded file (the bytes are available from the asset URL)
+type ExportedAsset struct {
+	ID          uuid.UUID `json:"id"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExportedFavorite is a project the user has starred
+type ExportedFavorite struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	StarredAt time.Time `json:"starred_at"`
+}
+
+// ExportedActivity is a change the user made in a project
+type ExportedActivity struct {
+	ProjectID uuid.UUID       `json:"project_id"`
+	Action    string          `json:"action"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ExportedRefreshToken is a refresh token issued to the user, without the
+// token itself. Tokens of one login share a session ID.
+type ExportedRefreshToken struct {
+	SessionID  uuid.UUID  `json:"session_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ExportedAPIKey describes one of the user's API keys (never the key or its hash)
+type ExportedAPIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ExportUserData collects a user's data. Projects, account records and
+// everything under them are read in one read-only snapshot so the parts agree
+// with each other. Returns nil if the
+// user doesn't exist.
+func (r *Repository) ExportUserData(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	user, err := r.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	export := &DataExport{
+		ExportedAt:     time.Now().UTC(),
+		User:           user,
+		Projects:       []*ExportedProject{},
+		SharedProjects: []*SharedProject{},
+		Assets:         []*ExportedAsset{},
+		Favorites:      []*ExportedFavorite{},
+		Activity:       []*ExportedActivity{},
+		RefreshTokens:  []*ExportedRefreshToken{},
+		APIKeys:        []*ExportedAPIKey{},
+	}
+
+	// Owned projects
+	rows, err := tx.Query(ctx, `
+		SELECT id, name, COALESCE(description, ''), is_public, public_slug, created_at, updated_at
+		FROM projects
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export projects: %w", err)
+	}
+	byID := make(map[uuid.UUID]*ExportedProject)
+	for rows.Next() {
+		p := &ExportedProject{Whiteboards: []*ExportedWhiteboard{}, Collaborators: []*ExportedCollaborator{}}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.IsPublic, &p.PublicSlug, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported project: %w", err)
+		}
+		export.Projects = append(export.Projects, p)
+		byID[p.ID] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export projects: %w", err)
+	}
+
+	// Whiteboards of owned projects
+	rows, err = tx.Query(ctx, `
+		SELECT w.project_id, w.id, w.name, w.data, w.data_compressed, w.data_encoding, w.created_at, w.updated_at
+		FROM whiteboards w
+		JOIN projects p ON p.id = w.project_id
+		WHERE p.user_id = $1
+		ORDER BY w.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export whiteboards: %w", err)
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		w := &ExportedWhiteboard{}
+		var stored compress.Stored
+		if err := rows.Scan(&projectID, &w.ID, &w.Name, &stored.Plain, &stored.Compressed, &stored.Encoding, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported whiteboard: %w", err)
+		}
+		if w.Data, err = stored.Unpack(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read exported whiteboard %s: %w", w.ID, err)
+		}
+		if p := byID[projectID]; p != nil {
+			p.Whiteboards = append(p.Whiteboards, w)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export whiteboards: %w", err)
+	}
+
+	// Collaborators on owned projects
+	rows, err = tx.Query(ctx, `
+		SELECT c.project_id, c.user_id, c.role, c.created_at
+		FROM project_collaborators c
+		JOIN projects p ON p.id = c.project_id
+		WHERE p.user_id = $1
+		ORDER BY c.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export collaborators: %w", err)
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		c := &ExportedCollaborator{}
+		if err := rows.Scan(&projectID, &c.UserID, &c.Role, &c.AddedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported collaborator: %w", err)
+		}
+		if p := byID[projectID]; p != nil {
+			p.Collaborators = append(p.Collaborators, c)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export collaborators: %w", err)
+	}
+
+	// Projects shared with the user
+	rows, err = tx.Query(ctx, `
+		SELECT c.project_id, p.name, c.role, c.created_at
+		FROM project_collaborators c
+		JOIN projects p ON p.id = c.project_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export shared projects: %w", err)
+	}
+	for rows.Next() {
+		s := &SharedProject{}
+		if err := rows.Scan(&s.ProjectID, &s.Name, &s.Role, &s.AddedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shared project: %w", err)
+		}
+		export.SharedProjects = append(export.SharedProjects, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export shared projects: %w", err)
+	}
+
+	// Uploaded assets
+	rows, err = tx.Query(ctx, `
+		SELECT id, project_id, filename, content_type, size_bytes, created_at
+		FROM assets
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export assets: %w", err)
+	}
+	for rows.Next() {
+		a := &ExportedAsset{}
+		if err := rows.Scan(&a.ID, &a.ProjectID, &a.Filename, &a.ContentType, &a.SizeBytes, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported asset: %w", err)
+		}
+		a.URL = "/api/v1/assets/" + a.ID.String()
+		export.Assets = append(export.Assets, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export assets: %w", err)
+	}
+
+	// Starred projects
+	rows, err = tx.Query(ctx, `
+		SELECT f.project_id, p.name, f.created_at
+		FROM project_favorites f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export favorites: %w", err)
+	}
+	for rows.Next() {
+		f := &ExportedFavorite{}
+		if err := rows.Scan(&f.ProjectID, &f.Name, &f.StarredAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported favorite: %w", err)
+		}
+		export.Favorites = append(export.Favorites, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export favorites: %w", err)
+	}
+
+	// Activity the user was the author of
+	rows, err = tx.Query(ctx, `
+		SELECT project_id, action, metadata, created_at
+		FROM activity_log
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export activity: %w", err)
+	}
+	for rows.Next() {
+		a := &ExportedActivity{}
+		if err := rows.Scan(&a.ProjectID, &a.Action, &a.Metadata, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported activity: %w", err)
+		}
+		export.Activity = append(export.Activity, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export activity: %w", err)
+	}
+
+	// Refresh tokens, which record each sign-in and where it came from
+	rows, err = tx.Query(ctx, `
+		SELECT family_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, expires_at, consumed_at, revoked_at
+		FROM refresh_tokens
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export refresh tokens: %w", err)
+	}
+	for rows.Next() {
+		t := &ExportedRefreshToken{}
+		if err := rows.Scan(&t.SessionID, &t.UserAgent, &t.IPAddress, &t.CreatedAt, &t.ExpiresAt, &t.ConsumedAt, &t.RevokedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported refresh token: %w", err)
+		}
+		export.RefreshTokens = append(export.RefreshTokens, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export refresh tokens: %w", err)
+	}
+
+	// API keys, metadata only
+	rows, err = tx.Query(ctx, `
+		SELECT id, name, prefix, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export api keys: %w", err)
+	}
+	for rows.Next() {
+		k := &ExportedAPIKey{}
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan exported api key: %w", err)
+		}
+		export.APIKeys = append(export.APIKeys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export api keys: %w", err)
+	}
+
+	return export, nil
+}
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Data export job statuses
+const (
+	ExportQueued    = "queued"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+const (
+	// exportLease is how long a running export may take before another worker
+	// treats it as abandoned (e.g. its server restarted) and builds it again
+	exportLease = 10 * time.Minute
+	exportPoll  = 10 * time.Second
+)
+
+// Data export job errors
+var (
+	ErrExportNotFound = errors.New("data export not found")
+	ErrExportActive   = errors.New("a data export is already queued or running")
+	ErrExportNotReady = errors.New("data export has not finished")
+)
+
+// ExportJob is a data export built in the background and its progress
+type ExportJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	SizeBytes  int64      `json:"size_bytes"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL is set once the export has completed
+	DownloadURL string `json:"download_url,omitempty"`
+
+	userID uuid.UUID
+}
+
+// ==================== Repository ====================
+
+const exportJobColumns = `id, user_id, status, size_bytes, COALESCE(last_error, ''), created_at, finished_at, expires_at`
+
+func scanExportJob(row pgx.Row) (*ExportJob, error) {
+	var job ExportJob
+	var id uuid.UUID
+	err := row.Scan(&id, &job.userID, &job.Status, &job.SizeBytes, &job.LastError, &job.CreatedAt, &job.FinishedAt, &job.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = id.String()
+	if job.Status == ExportCompleted {
+		job.DownloadURL = "/api/v1/auth/me/export/" + job.ID + "/download"
+	}
+	return &job, nil
+}
+
+// CreateExportJob queues an export, unless the user already has one queued or running
+func (r *Repository) CreateExportJob(ctx context.Context, userID uuid.UUID) (*ExportJob, error) {
+	query := `INSERT INTO data_exports (user_id) VALUES ($1) RETURNING ` + exportJobColumns
+
+	job, err := scanExportJob(r.db.QueryRow(ctx, query, userID))
+	if database.IsUniqueViolation(err, "idx_data_exports_active") {
+		return nil, ErrExportActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return job, nil
+}
+
+// FindExportJob finds one of a user's unexpired exports. Returns nil if there is none.
+func (r *Repository) FindExportJob(ctx context.Context, userID, id uuid.UUID) (*ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + ` FROM data_exports
+		WHERE id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > NOW())`
+
+	job, err := scanExportJob(r.db.QueryRow(ctx, query, id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data export: %w", err)
+	}
+
+	return job, nil
+}
+
+// FindExportData returns the compressed JSON of a user's completed, unexpired
+// export. Returns nil if there is none.
+func (r *Repository) FindExportData(ctx context.Context, userID, id uuid.UUID) ([]byte, error) {
+	var data []byte
+	err := r.db.QueryRow(ctx, `
+		SELECT data FROM data_exports
+		WHERE id = $1 AND user_id = $2 AND status = 'completed' AND expires_at > NOW()
+	`, id, userID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data export: %w", err)
+	}
+
+	return data, nil
+}
+
+// ClaimExportJob marks the oldest queued export, or a running one whose lease
+// has expired, as running and returns it. Returns nil if there is none.
+func (r *Repository) ClaimExportJob(ctx context.Context, lease time.Duration) (*ExportJob, error) {
+	query := `
+		UPDATE data_exports
+		SET status = 'running', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'queued'
+				OR (status = 'running' AND updated_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportJobColumns
+
+	job, err := scanExportJob(r.db.QueryRow(ctx, query, lease.Seconds()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+
+	return job, nil
+}
+
+// CompleteExportJob stores a running export's compressed data
+func (r *Repository) CompleteExportJob(ctx context.Context, id uuid.UUID, data []byte, size int64, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE data_exports
+		SET status = 'completed', data = $2, size_bytes = $3, expires_at = $4, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id, data, size, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	return nil
+}
+
+// FailExportJob marks a running export failed; it's kept until expiresAt so
+// the user can see why
+func (r *Repository) FailExportJob(ctx context.Context, id uuid.UUID, lastError string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE data_exports
+		SET status = 'failed', last_error = $2, expires_at = $3, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id, lastError, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to fail data export: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredExports deletes finished exports past their expiry
+func (r *Repository) DeleteExpiredExports(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM data_exports WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// ==================== Service ====================
+
+// StartDataExport queues a background export of everything stored about the user
+func (s *Service) StartDataExport(ctx context.Context, userID string) (*ExportJob, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	job, err := s.repo.CreateExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.exportWake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetDataExport returns the progress of one of the user's exports
+func (s *Service) GetDataExport(ctx context.Context, userID, exportID string) (*ExportJob, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+	id, err := uuid.Parse(exportID)
+	if err != nil {
+		return nil, ErrExportNotFound
+	}
+
+	job, err := s.repo.FindExportJob(ctx, uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrExportNotFound
+	}
+	return job, nil
+}
+
+// DownloadDataExport returns the JSON of one of the user's completed exports.
+// It returns ErrExportNotReady while the export is still being built.
+func (s *Service) DownloadDataExport(ctx context.Context, userID, exportID string) (*ExportJob, json.RawMessage, error) {
+	job, err := s.GetDataExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != ExportCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+
+	compressed, err := s.repo.FindExportData(ctx, job.userID, uuid.MustParse(job.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if compressed == nil {
+		// Expired between the two reads
+		return nil, nil, ErrExportNotFound
+	}
+
+	encoding := compress.Gzip
+	stored := compress.Stored{Compressed: compressed, Encoding: &encoding}
+	data, err := stored.Unpack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, data, nil
+}
+
+// buildDataExport assembles a claimed export and stores it compressed
+func (s *Service) buildDataExport(ctx context.Context, job *ExportJob) error {
+	export, err := s.repo.ExportUserData(ctx, job.userID)
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		return ErrUserNotFound
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("failed to encode data export: %w", err)
+	}
+	stored, err := compress.Pack(data, 1)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.CompleteExportJob(ctx, uuid.MustParse(job.ID), stored.Compressed, int64(len(data)), time.Now().Add(s.exportRetention()))
+}
+
+// exportRetention is how long finished exports are kept
+func (s *Service) exportRetention() time.Duration {
+	return time.Duration(s.config.DataExportRetentionHours) * time.Hour
+}
+
+// ExportWorker builds queued data exports in the background, one at a time,
+// and deletes exports past their retention
+type ExportWorker struct {
+	service *Service
+}
+
+// NewExportWorker creates a worker for the service's queued data exports
+func NewExportWorker(service *Service) *ExportWorker {
+	return &ExportWorker{service: service}
+}
+
+// Run builds exports as they're queued until ctx is cancelled. An export
+// interrupted by shutdown is built again once its lease expires.
+func (w *ExportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportPoll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.service.repo.DeleteExpiredExports(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn().Err(err).Msg("Failed to delete expired data exports")
+		}
+
+		for {
+			job, err := w.service.repo.ClaimExportJob(ctx, exportLease)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn().Err(err).Msg("Failed to claim data export")
+				}
+				break
+			}
+			if job == nil {
+				break
+			}
+			w.run(ctx, job)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.service.exportWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run builds one claimed export, recording a failure unless the server is shutting down
+func (w *ExportWorker) run(ctx context.Context, job *ExportJob) {
+	err := w.service.buildDataExport(ctx, job)
+	if err == nil {
+		logger.Info().Str("export_id", job.ID).Str("user_id", job.userID.String()).Msg("Data export completed")
+		return
+	}
+	if ctx.Err() != nil {
+		// The lease will let it be built again
+		return
+	}
+
+	logger.Error().Err(err).Str("export_id", job.ID).Str("user_id", job.userID.String()).Msg("Data export failed")
+
+	// Own context so the failure is recorded even if the build used up ctx's deadline
+	failCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.service.repo.FailExportJob(failCtx, uuid.MustParse(job.ID), err.Error(), time.Now().Add(w.service.exportRetention())); err != nil {
+		logger.Warn().Err(err).Str("export_id", job.ID).Msg("Failed to mark data export failed")
+	}
+}
@@ -3,32 +3,48 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/metrics"
 )
 
 // Handler handles HTTP requests for authentication
 type Handler struct {
-	service *Service
-	config  *config.Config
+	service  *Service
+	config   *config.Config
+	exchange exchangeCodes
 }
 
 // NewHandler creates a new auth handler
 func NewHandler(service *Service, cfg *config.Config) *Handler {
 	return &Handler{
-		service: service,
-		config:  cfg,
+		service:  service,
+		config:   cfg,
+		exchange: newExchangeStore(),
 	}
 }
 
+// UseExchangeStore keeps OAuth exchange codes in Redis instead of this
+// process, so a code can be redeemed on any instance
+func (h *Handler) UseExchangeStore(store *ExchangeStore) {
+	h.exchange = store
+}
+
 // generateState creates a random state string for OAuth
-func generateState() string {
+func generateState() (string, error) {
 	b := make([]byte, 32)
-	rand.Read(b)
-	return base64.URLEncoding.EncodeToString(b)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
 }
 
 // ==================== GitHub OAuth Endpoints ====================
@@ -36,7 +52,11 @@ func generateState() string {
 // GitHubLogin redirects to GitHub OAuth authorization page
 // GET /api/v1/auth/github
 func (h *Handler) GitHubLogin(c *fiber.Ctx) error {
-	state := generateState()
+	state, err := generateState()
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start GitHub login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
 
 	// Store state in cookie for CSRF protection
 	c.Cookie(&fiber.Cookie{
@@ -49,7 +69,11 @@ func (h *Handler) GitHubLogin(c *fiber.Ctx) error {
 		SameSite: "Lax",
 	})
 
-	authURL := h.service.GetGitHubAuthURL(state)
+	authURL, err := h.service.GetGitHubAuthURL(c.Context(), state)
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start GitHub login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
 	return c.Redirect(authURL)
 }
 
@@ -64,7 +88,7 @@ func (h *Handler) GitHubCallback(c *fiber.Ctx) error {
 	// Check for OAuth error
 	if errorParam != "" {
 		errorDesc := c.Query("error_description")
-		logger.Error().Str("error", errorParam).Str("description", errorDesc).Msg("GitHub OAuth error")
+		logger.For(c).Error().Str("error", errorParam).Str("description", errorDesc).Msg("GitHub OAuth error")
 		return c.Redirect(h.config.FrontendURL + "/login?error=" + errorParam)
 	}
 
@@ -73,11 +97,11 @@ func (h *Handler) GitHubCallback(c *fiber.Ctx) error {
 	// So we only enforce state validation when the cookie is actually present
 	storedState := c.Cookies("oauth_state")
 	if storedState != "" && state != storedState {
-		logger.Warn().Str("expected", storedState).Str("received", state).Msg("Google OAuth state mismatch")
+		logger.For(c).Warn().Str("expected", logger.Secret(storedState)).Str("received", logger.Secret(state)).Msg("GitHub OAuth state mismatch")
 		return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
 	}
 	if storedState == "" {
-		logger.Warn().Str("received", state).Msg("Google OAuth state cookie not found (cross-domain issue)")
+		logger.For(c).Warn().Str("received", logger.Secret(state)).Msg("GitHub OAuth state cookie not found (cross-domain issue)")
 	}
 
 	// Clear state cookie
@@ -86,6 +110,7 @@ func (h *Handler) GitHubCallback(c *fiber.Ctx) error {
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
 		HTTPOnly: true,
 	})
 
@@ -94,19 +119,25 @@ func (h *Handler) GitHubCallback(c *fiber.Ctx) error {
 	}
 
 	// Exchange code for tokens and user info
-	authResponse, err := h.service.ExchangeGitHubCode(c.Context(), code)
+	authResponse, err := h.service.ExchangeGitHubCode(withClient(c), code, state)
+	metrics.AuthAttempt("github", err)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to exchange GitHub code")
+		logger.FailureFor(c, err).Msg("Failed to exchange GitHub code")
+		if errors.Is(err, ErrPKCEVerifierMissing) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
+		}
+		if errors.Is(err, ErrProviderAlreadyLinked) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=account_already_linked")
+		}
+		if errors.Is(err, ErrSignupDisabled) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=signup_disabled")
+		}
 		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
 	}
 
-	// Set tokens in HTTP-only cookies for security (works for same-domain)
-	h.setAuthCookies(c, authResponse.Tokens)
-
-	logger.Info().Str("user_id", authResponse.User.ID).Str("email", authResponse.User.Email).Msg("User logged in via GitHub")
+	logger.For(c).Info().Str("user_id", authResponse.User.ID).Str("email", logger.Email(authResponse.User.Email)).Msg("User logged in via GitHub")
 
-	// Redirect to frontend with token in URL (for cross-domain support)
-	return c.Redirect(h.config.FrontendURL + "/auth/callback?provider=github&token=" + authResponse.Tokens.AccessToken)
+	return h.completeLogin(c, "github", authResponse)
 }
 
 // ==================== Google OAuth Endpoints ====================
@@ -114,7 +145,11 @@ func (h *Handler) GitHubCallback(c *fiber.Ctx) error {
 // GoogleLogin redirects to Google OAuth authorization page
 // GET /api/v1/auth/google
 func (h *Handler) GoogleLogin(c *fiber.Ctx) error {
-	state := generateState()
+	state, err := generateState()
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start Google login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
 
 	// Store state in cookie for CSRF protection
 	c.Cookie(&fiber.Cookie{
@@ -127,7 +162,11 @@ func (h *Handler) GoogleLogin(c *fiber.Ctx) error {
 		SameSite: "Lax",
 	})
 
-	authURL := h.service.GetGoogleAuthURL(state)
+	authURL, err := h.service.GetGoogleAuthURL(c.Context(), state)
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start Google login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
 	return c.Redirect(authURL)
 }
 
@@ -141,7 +180,7 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 
 	// Check for OAuth error
 	if errorParam != "" {
-		logger.Error().Str("error", errorParam).Msg("Google OAuth error")
+		logger.For(c).Error().Str("error", errorParam).Msg("Google OAuth error")
 		return c.Redirect(h.config.FrontendURL + "/login?error=" + errorParam)
 	}
 
@@ -150,11 +189,11 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 	// So we only enforce state validation when the cookie is actually present
 	storedState := c.Cookies("oauth_state")
 	if storedState != "" && state != storedState {
-		logger.Warn().Str("expected", storedState).Str("received", state).Msg("Google OAuth state mismatch")
+		logger.For(c).Warn().Str("expected", logger.Secret(storedState)).Str("received", logger.Secret(state)).Msg("Google OAuth state mismatch")
 		return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
 	}
 	if storedState == "" {
-		logger.Warn().Str("received", state).Msg("Google OAuth state cookie not found (cross-domain issue)")
+		logger.For(c).Warn().Str("received", logger.Secret(state)).Msg("Google OAuth state cookie not found (cross-domain issue)")
 	}
 
 	// Clear state cookie
@@ -163,6 +202,7 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
 		HTTPOnly: true,
 	})
 
@@ -171,19 +211,129 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 	}
 
 	// Exchange code for tokens and user info
-	authResponse, err := h.service.ExchangeGoogleCode(c.Context(), code)
+	authResponse, err := h.service.ExchangeGoogleCode(withClient(c), code, state)
+	metrics.AuthAttempt("google", err)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to exchange Google code")
+		logger.FailureFor(c, err).Msg("Failed to exchange Google code")
+		if errors.Is(err, ErrPKCEVerifierMissing) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
+		}
+		if errors.Is(err, ErrProviderAlreadyLinked) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=account_already_linked")
+		}
+		if errors.Is(err, ErrSignupDisabled) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=signup_disabled")
+		}
 		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
 	}
 
-	// Set tokens in HTTP-only cookies for security (works for same-domain)
-	h.setAuthCookies(c, authResponse.Tokens)
+	logger.For(c).Info().Str("user_id", authResponse.User.ID).Str("email", logger.Email(authResponse.User.Email)).Msg("User logged in via Google")
+
+	return h.completeLogin(c, "google", authResponse)
+}
 
-	logger.Info().Str("user_id", authResponse.User.ID).Str("email", authResponse.User.Email).Msg("User logged in via Google")
+// ==================== Microsoft OAuth Endpoints ====================
 
-	// Redirect to frontend with token in URL (for cross-domain support)
-	return c.Redirect(h.config.FrontendURL + "/auth/callback?provider=google&token=" + authResponse.Tokens.AccessToken)
+// MicrosoftLogin redirects to the Microsoft sign-in page (personal and work accounts)
+// GET /api/v1/auth/microsoft
+func (h *Handler) MicrosoftLogin(c *fiber.Ctx) error {
+	state, err := generateState()
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start Microsoft login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
+
+	// Store state in cookie for CSRF protection
+	c.Cookie(&fiber.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		Path:     "/",
+		MaxAge:   300, // 5 minutes
+		HTTPOnly: true,
+		Secure:   !h.config.IsDevelopment(),
+		SameSite: "Lax",
+	})
+
+	authURL, err := h.service.GetMicrosoftAuthURL(c.Context(), state)
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to start Microsoft login")
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
+	return c.Redirect(authURL)
+}
+
+// MicrosoftCallback handles the Microsoft OAuth callback
+// GET /api/v1/auth/microsoft/callback
+func (h *Handler) MicrosoftCallback(c *fiber.Ctx) error {
+	// Get authorization code and state from query params
+	code := c.Query("code")
+	state := c.Query("state")
+	errorParam := c.Query("error")
+
+	// Check for OAuth error
+	if errorParam != "" {
+		errorDesc := c.Query("error_description")
+		logger.For(c).Error().Str("error", errorParam).Str("description", errorDesc).Msg("Microsoft OAuth error")
+		return c.Redirect(h.config.FrontendURL + "/login?error=" + errorParam)
+	}
+
+	// Validate state (CSRF protection)
+	// Note: In production with HTTP (no HTTPS), cross-site cookies don't work reliably
+	// So we only enforce state validation when the cookie is actually present
+	storedState := c.Cookies("oauth_state")
+	if storedState != "" && state != storedState {
+		logger.For(c).Warn().Str("expected", logger.Secret(storedState)).Str("received", logger.Secret(state)).Msg("Microsoft OAuth state mismatch")
+		return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
+	}
+	if storedState == "" {
+		logger.For(c).Warn().Str("received", logger.Secret(state)).Msg("Microsoft OAuth state cookie not found (cross-domain issue)")
+	}
+
+	// Clear state cookie
+	c.Cookie(&fiber.Cookie{
+		Name:     "oauth_state",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+	})
+
+	if code == "" {
+		return c.Redirect(h.config.FrontendURL + "/login?error=no_code")
+	}
+
+	// Exchange code for tokens and user info
+	authResponse, err := h.service.ExchangeMicrosoftCode(withClient(c), code, state)
+	metrics.AuthAttempt("microsoft", err)
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to exchange Microsoft code")
+		if errors.Is(err, ErrPKCEVerifierMissing) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=invalid_state")
+		}
+		if errors.Is(err, ErrProviderAlreadyLinked) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=account_already_linked")
+		}
+		if errors.Is(err, ErrSignupDisabled) {
+			return c.Redirect(h.config.FrontendURL + "/login?error=signup_disabled")
+		}
+		return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+	}
+
+	logger.For(c).Info().Str("user_id", authResponse.User.ID).Str("email", logger.Email(authResponse.User.Email)).Msg("User logged in via Microsoft")
+
+	return h.completeLogin(c, "microsoft", authResponse)
+}
+
+// ==================== Key Endpoints ====================
+
+// JWKS lists the public keys tokens are signed with, for services that verify them
+// GET /api/v1/auth/jwks.json
+func (h *Handler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{
+		"keys": h.service.JWKS(),
+	})
 }
 
 // ==================== User Endpoints ====================
@@ -191,11 +341,17 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 // GetMe returns the current authenticated user
 // GET /api/v1/auth/me
 func (h *Handler) GetMe(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
 
 	user, err := h.service.GetUserByID(c.Context(), userID)
 	if err != nil {
-		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to get user")
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to get user")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get user",
@@ -214,19 +370,298 @@ func (h *Handler) GetMe(c *fiber.Ctx) error {
 	})
 }
 
+// UpdateMe updates the current user's display name and avatar
+// PATCH /api/v1/auth/me
+func (h *Handler) UpdateMe(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
+
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	user, err := h.service.UpdateProfile(c.Context(), userID, &req)
+	if errors.Is(err, ErrInvalidName) || errors.Is(err, ErrInvalidAvatarURL) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to update profile")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to update profile",
+		})
+	}
+
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "User not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user.ToResponse(),
+	})
+}
+
+// DeleteMe permanently deletes the current user's account and all their projects.
+// The body must contain {"confirm": true}.
+// DELETE /api/v1/auth/me
+func (h *Handler) DeleteMe(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
+
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil || !req.Confirm {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Confirm account deletion with {\"confirm\": true}",
+		})
+	}
+
+	err := h.service.DeleteAccount(c.Context(), userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "User not found",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to delete account")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to delete account",
+		})
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.delete").
+		Str("user_id", userID).
+		Str("ip", c.IP()).
+		Msg("User account deleted")
+
+	h.clearAuthCookies(c)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UnlinkProvider removes an OAuth provider from the current user's account
+// DELETE /api/v1/auth/providers/:provider
+func (h *Handler) UnlinkProvider(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
+
+	provider := c.Params("provider")
+	if provider != ProviderGitHub && provider != ProviderGoogle && provider != ProviderMicrosoft {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Unknown provider",
+		})
+	}
+
+	err := h.service.UnlinkProvider(c.Context(), userID, provider)
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrProviderNotLinked) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if errors.Is(err, ErrLastProvider) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Str("provider", provider).Msg("Failed to unlink provider")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to unlink provider",
+		})
+	}
+
+	providers, err := h.service.GetLinkedProviders(c.Context(), userID)
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to get linked providers")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get linked providers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"providers": providers,
+	})
+}
+
+// ExportMe downloads everything stored about the current user as JSON, built
+// while the request waits. Large accounts should use StartExport instead.
+// GET /api/v1/auth/me/export
+func (h *Handler) ExportMe(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
+
+	// Audit trail for data-portability requests
+	logger.For(c).Info().
+		Str("audit", "user.data_export").
+		Str("user_id", userID).
+		Str("ip", c.IP()).
+		Msg("User data export requested")
+
+	export, err := h.service.ExportUserData(c.Context(), userID)
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to export user data")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to export user data",
+		})
+	}
+
+	if export == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "User not found",
+		})
+	}
+
+	c.Attachment("sysdes-data-export-" + export.ExportedAt.Format("20060102") + ".json")
+	return c.JSON(export)
+}
+
+// StartExport queues a background export of everything stored about the
+// current user; its status, and download link once built, are at the returned URL
+// POST /api/v1/auth/me/export
+func (h *Handler) StartExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+
+	job, err := h.service.StartDataExport(c.Context(), userID)
+	if errors.Is(err, ErrExportActive) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": "A data export is already being prepared",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to start data export")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to start data export",
+		})
+	}
+
+	// Audit trail for data-portability requests
+	logger.For(c).Info().
+		Str("audit", "user.data_export").
+		Str("user_id", userID).
+		Str("export_id", job.ID).
+		Str("ip", c.IP()).
+		Msg("User data export requested")
+
+	c.Location("/api/v1/auth/me/export/" + job.ID)
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetExport returns the status of one of the current user's background exports
+// GET /api/v1/auth/me/export/:id
+func (h *Handler) GetExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+
+	job, err := h.service.GetDataExport(c.Context(), userID, c.Params("id"))
+	if errors.Is(err, ErrExportNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "Data export not found",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to get data export")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get data export",
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// DownloadExport downloads a finished background export as JSON
+// GET /api/v1/auth/me/export/:id/download
+func (h *Handler) DownloadExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+
+	job, data, err := h.service.DownloadDataExport(c.Context(), userID, c.Params("id"))
+	if errors.Is(err, ErrExportNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "Data export not found",
+		})
+	}
+	if errors.Is(err, ErrExportNotReady) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": "Data export is not ready yet",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to download data export")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to download data export",
+		})
+	}
+
+	c.Attachment("sysdes-data-export-" + job.CreatedAt.UTC().Format("20060102") + ".json")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(data)
+}
+
 // RefreshTokens generates new access and refresh tokens
 // POST /api/v1/auth/refresh
 func (h *Handler) RefreshTokens(c *fiber.Ctx) error {
-	// Get refresh token from cookie or body
-	refreshToken := c.Cookies("refresh_token")
-
-	if refreshToken == "" {
-		var body struct {
-			RefreshToken string `json:"refresh_token"`
-		}
-		if err := c.BodyParser(&body); err == nil {
-			refreshToken = body.RefreshToken
-		}
+	refreshToken, cookieToken := presentedRefreshToken(c)
+	if cookieToken != "" && cookieToken != refreshToken {
+		// Refresh tokens are single-use, so at most one of the two can be the
+		// client's current token; refuse rather than guess which
+		logger.For(c).Warn().Msg("Refresh cookie differs from body token")
+		c.Cookie(&fiber.Cookie{
+			Name:     "refresh_token",
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Expires:  time.Unix(0, 0),
+			HTTPOnly: true,
+		})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Conflicting refresh tokens",
+		})
 	}
 
 	if refreshToken == "" {
@@ -236,17 +671,103 @@ func (h *Handler) RefreshTokens(c *fiber.Ctx) error {
 		})
 	}
 
-	authResponse, err := h.service.RefreshTokens(c.Context(), refreshToken)
+	authResponse, err := h.service.RefreshTokens(withClient(c), refreshToken)
+	metrics.AuthAttempt("refresh", err)
 	if err != nil {
-		logger.Warn().Err(err).Msg("Failed to refresh tokens")
+		logger.For(c).Warn().Err(err).Msg("Failed to refresh tokens")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid refresh token",
 		})
 	}
 
-	// Set new tokens in cookies
-	h.setAuthCookies(c, authResponse.Tokens)
+	// Set new tokens in cookies unless the deployment only delivers them in the body
+	if h.config.DeliversTokensInCookies() {
+		h.setAuthCookies(c, authResponse.Tokens)
+	}
+
+	return c.JSON(fiber.Map{
+		"user":   authResponse.User,
+		"tokens": authResponse.Tokens,
+	})
+}
+
+// presentedRefreshToken returns the refresh token a request carries, along with the
+// cookie value. An explicitly provided body token is returned over the cookie;
+// RefreshTokens rejects requests where the two differ.
+func presentedRefreshToken(c *fiber.Ctx) (token, cookie string) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.BodyParser(&body)
+
+	cookie = c.Cookies("refresh_token")
+	if body.RefreshToken != "" {
+		return body.RefreshToken, cookie
+	}
+	return cookie, cookie
+}
+
+// presentedAccessToken returns the access token from the Authorization header, or else the cookie
+func presentedAccessToken(c *fiber.Ctx) string {
+	parts := strings.Split(c.Get("Authorization"), " ")
+	if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+		return parts[1]
+	}
+	return c.Cookies("access_token")
+}
+
+// refreshLimiter limits token refreshes per user. The key is the subject of the
+// presented refresh token; only the signature is checked here (no database
+// lookups), so subjects can't be forged to use up another user's allowance.
+// Requests without a usable token are limited by IP.
+func (h *Handler) refreshLimiter() fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        h.config.AuthRefreshPerMinute,
+		Expiration: time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			token, _ := presentedRefreshToken(c)
+			if claims, err := h.service.ValidateRefreshToken(c.Context(), token); err == nil {
+				return "user:" + claims.UserID
+			}
+			return "ip:" + c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":   true,
+				"message": "Too many token refreshes, try again later",
+			})
+		},
+	})
+}
+
+// ExchangeCode trades a one-time code from an OAuth callback for tokens
+// POST /api/v1/auth/exchange
+func (h *Handler) ExchangeCode(c *fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Exchange code required",
+		})
+	}
+
+	authResponse, err := h.exchange.Consume(c.Context(), body.Code)
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to redeem exchange code")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to redeem exchange code",
+		})
+	}
+	if authResponse == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid or expired exchange code",
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"user":   authResponse.User,
@@ -254,15 +775,431 @@ func (h *Handler) RefreshTokens(c *fiber.Ctx) error {
 	})
 }
 
-// Logout clears auth cookies
+// Logout revokes the presented tokens and clears auth cookies
 // POST /api/v1/auth/logout
 func (h *Handler) Logout(c *fiber.Ctx) error {
-	// Clear auth cookies
+	refreshToken, _ := presentedRefreshToken(c)
+	if err := h.service.Logout(c.Context(), presentedAccessToken(c), refreshToken); err != nil {
+		// The cookies are cleared regardless; the tokens expire on their own
+		logger.FailureFor(c, err).Msg("Failed to revoke tokens on logout")
+	}
+
+	h.clearAuthCookies(c)
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll revokes every token and API key of the current user, signing out all devices
+// POST /api/v1/auth/logout-all
+func (h *Handler) LogoutAll(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Authentication required",
+		})
+	}
+
+	err := h.service.LogoutAll(c.Context(), userID)
+	if errors.Is(err, ErrRevocationUnavailable) {
+		h.clearAuthCookies(c)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   true,
+			"message": "Sessions can no longer be refreshed, but access tokens stay valid until they expire",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to log out everywhere")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to log out everywhere",
+		})
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.logout_all").
+		Str("user_id", userID).
+		Str("ip", c.IP()).
+		Msg("User logged out everywhere")
+
+	h.clearAuthCookies(c)
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out everywhere",
+	})
+}
+
+// CompleteTwoFactorLogin trades a login challenge and a 2FA code for tokens
+// POST /api/v1/auth/2fa/login
+func (h *Handler) CompleteTwoFactorLogin(c *fiber.Ctx) error {
+	var req TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Challenge token and code required",
+		})
+	}
+
+	authResponse, err := h.service.CompleteTwoFactorLogin(withClient(c), req.ChallengeToken, req.Code)
+	metrics.AuthAttempt("2fa", err)
+	if errors.Is(err, ErrInvalidChallenge) || errors.Is(err, ErrInvalidTwoFactorCode) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if errors.Is(err, ErrTooManyAttempts) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Msg("Failed to complete two-factor login")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to complete login",
+		})
+	}
+
+	if h.config.DeliversTokensInCookies() {
+		h.setAuthCookies(c, authResponse.Tokens)
+	}
+
+	return c.JSON(fiber.Map{
+		"user":   authResponse.User,
+		"tokens": authResponse.Tokens,
+	})
+}
+
+// GetTwoFactor returns the current user's 2FA status
+// GET /api/v1/auth/2fa
+func (h *Handler) GetTwoFactor(c *fiber.Ctx) error {
+	status, err := h.service.GetTwoFactorStatus(c.Context(), GetUserID(c))
+	if err != nil {
+		return h.twoFactorError(c, err, "Failed to get two-factor status")
+	}
+
+	return c.JSON(status)
+}
+
+// SetupTwoFactor creates a TOTP secret for the current user to confirm with VerifyTwoFactor
+// POST /api/v1/auth/2fa/setup
+func (h *Handler) SetupTwoFactor(c *fiber.Ctx) error {
+	setup, err := h.service.SetupTwoFactor(c.Context(), GetUserID(c))
+	if err != nil {
+		return h.twoFactorError(c, err, "Failed to set up two-factor authentication")
+	}
+
+	return c.JSON(setup)
+}
+
+// VerifyTwoFactor confirms 2FA setup with a code and returns the backup codes
+// POST /api/v1/auth/2fa/verify
+func (h *Handler) VerifyTwoFactor(c *fiber.Ctx) error {
+	code, ok := twoFactorCode(c)
+	if !ok {
+		return twoFactorCodeRequired(c)
+	}
+
+	userID := GetUserID(c)
+	codes, err := h.service.EnableTwoFactor(c.Context(), userID, code)
+	if err != nil {
+		return h.twoFactorError(c, err, "Failed to enable two-factor authentication")
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.2fa_enabled").
+		Str("user_id", userID).
+		Str("ip", c.IP()).
+		Msg("Two-factor authentication enabled")
+
+	return c.JSON(fiber.Map{
+		"backup_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off, confirmed with a code or backup code
+// POST /api/v1/auth/2fa/disable
+func (h *Handler) DisableTwoFactor(c *fiber.Ctx) error {
+	code, ok := twoFactorCode(c)
+	if !ok {
+		return twoFactorCodeRequired(c)
+	}
+
+	userID := GetUserID(c)
+	if err := h.service.DisableTwoFactor(c.Context(), userID, code); err != nil {
+		return h.twoFactorError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.2fa_disabled").
+		Str("user_id", userID).
+		Str("ip", c.IP()).
+		Msg("Two-factor authentication disabled")
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateBackupCodes replaces the current user's backup codes
+// POST /api/v1/auth/2fa/backup-codes
+func (h *Handler) RegenerateBackupCodes(c *fiber.Ctx) error {
+	code, ok := twoFactorCode(c)
+	if !ok {
+		return twoFactorCodeRequired(c)
+	}
+
+	codes, err := h.service.RegenerateBackupCodes(c.Context(), GetUserID(c), code)
+	if err != nil {
+		return h.twoFactorError(c, err, "Failed to regenerate backup codes")
+	}
+
+	return c.JSON(fiber.Map{
+		"backup_codes": codes,
+	})
+}
+
+// ListSessions lists the current user's active sessions
+// GET /api/v1/auth/sessions
+func (h *Handler) ListSessions(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	sessions, err := h.service.ListSessions(c.Context(), userID, GetSessionID(c))
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to list sessions")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to list sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession signs out one of the current user's sessions
+// DELETE /api/v1/auth/sessions/:id
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	sessionID := c.Params("id")
+
+	err := h.service.RevokeSession(c.Context(), userID, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "Session not found",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to revoke session")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to revoke session",
+		})
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.session_revoked").
+		Str("user_id", userID).
+		Str("session_id", sessionID).
+		Str("ip", c.IP()).
+		Msg("Session revoked")
+
+	// Signing out this device
+	if sessionID == GetSessionID(c) {
+		h.clearAuthCookies(c)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked",
+	})
+}
+
+// ListAPIKeys lists the current user's active API keys
+// GET /api/v1/auth/api-keys
+func (h *Handler) ListAPIKeys(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	keys, err := h.service.ListAPIKeys(c.Context(), userID)
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to list API keys")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to list API keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+// CreateAPIKey creates an API key for the current user. The full key is in
+// this response only.
+// POST /api/v1/auth/api-keys
+func (h *Handler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	key, err := h.service.CreateAPIKey(c.Context(), userID, &req)
+	if errors.Is(err, ErrInvalidAPIKeyName) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if errors.Is(err, ErrTooManyAPIKeys) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to create API key")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to create API key",
+		})
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.api_key_created").
+		Str("user_id", userID).
+		Str("api_key_id", key.ID).
+		Str("ip", c.IP()).
+		Msg("API key created")
+
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+// RevokeAPIKey revokes one of the current user's API keys
+// DELETE /api/v1/auth/api-keys/:id
+func (h *Handler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	keyID := c.Params("id")
+
+	err := h.service.RevokeAPIKey(c.Context(), userID, keyID)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "API key not found",
+		})
+	}
+	if err != nil {
+		logger.FailureFor(c, err).Str("user_id", userID).Msg("Failed to revoke API key")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to revoke API key",
+		})
+	}
+
+	logger.For(c).Info().
+		Str("audit", "user.api_key_revoked").
+		Str("user_id", userID).
+		Str("api_key_id", keyID).
+		Str("ip", c.IP()).
+		Msg("API key revoked")
+
+	return c.JSON(fiber.Map{
+		"message": "API key revoked",
+	})
+}
+
+// ==================== Helper Methods ====================
+
+// twoFactorCode reads the code from a TwoFactorCodeRequest body
+func twoFactorCode(c *fiber.Ctx) (string, bool) {
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return "", false
+	}
+	return req.Code, true
+}
+
+func twoFactorCodeRequired(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   true,
+		"message": "Code required",
+	})
+}
+
+// twoFactorError maps 2FA service errors to responses
+func (h *Handler) twoFactorError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, ErrInvalidTwoFactorCode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrTwoFactorEnabled), errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrTwoFactorNotSetUp):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": "User not found",
+		})
+	}
+
+	logger.FailureFor(c, err).Str("user_id", GetUserID(c)).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": message,
+	})
+}
+
+// completeLogin hands the tokens to the frontend according to AUTH_TOKEN_DELIVERY
+//   - cookie: HTTP-only cookies, plus the access token in the redirect URL (cross-domain support)
+//   - body:   no cookies, a one-time code in the redirect URL that the SPA exchanges for tokens
+//   - both:   HTTP-only cookies and a one-time code
+func (h *Handler) completeLogin(c *fiber.Ctx, provider string, authResponse *AuthResponse) error {
+	redirectURL := h.config.FrontendURL + "/auth/callback?provider=" + provider
+
+	// Users with 2FA finish the login on the code entry page
+	if authResponse.ChallengeToken != "" {
+		return c.Redirect(h.config.FrontendURL + "/auth/2fa?provider=" + provider +
+			"&challenge=" + url.QueryEscape(authResponse.ChallengeToken))
+	}
+
+	// Set tokens in HTTP-only cookies for security (works for same-domain)
+	if h.config.DeliversTokensInCookies() {
+		h.setAuthCookies(c, authResponse.Tokens)
+	}
+
+	if h.config.DeliversTokensInBody() {
+		code, err := h.exchange.Issue(c.Context(), authResponse)
+		if err != nil {
+			logger.FailureFor(c, err).Str("provider", provider).Msg("Failed to issue exchange code")
+			return c.Redirect(h.config.FrontendURL + "/login?error=auth_failed")
+		}
+		return c.Redirect(redirectURL + "&code=" + code)
+	}
+
+	// Redirect to frontend with token in URL (for cross-domain support)
+	return c.Redirect(redirectURL + "&token=" + authResponse.Tokens.AccessToken)
+}
+
+// clearAuthCookies removes the access and refresh token cookies, and the
+// logged_in flag so the frontend stops treating the user as signed in
+func (h *Handler) clearAuthCookies(c *fiber.Ctx) {
 	c.Cookie(&fiber.Cookie{
 		Name:     "access_token",
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
 		HTTPOnly: true,
 	})
 
@@ -271,16 +1208,20 @@ func (h *Handler) Logout(c *fiber.Ctx) error {
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
 		HTTPOnly: true,
 	})
 
-	return c.JSON(fiber.Map{
-		"message": "Logged out successfully",
+	c.Cookie(&fiber.Cookie{
+		Name:     "logged_in",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: false,
 	})
 }
 
-// ==================== Helper Methods ====================
-
 // setAuthCookies sets access and refresh tokens in HTTP-only cookies
 func (h *Handler) setAuthCookies(c *fiber.Ctx, tokens *TokenPair) {
 	// Access token cookie - shorter expiry
@@ -306,7 +1247,7 @@ func (h *Handler) setAuthCookies(c *fiber.Ctx, tokens *TokenPair) {
 	})
 
 	// Also set a non-httponly cookie so frontend JS can check if logged in
-	// This doesn't contain the actual token, just a flag
+	// This doesn't contain the actual token, just a flag, and expires with the access token
 	c.Cookie(&fiber.Cookie{
 		Name:     "logged_in",
 		Value:    "true",
@@ -327,11 +1268,38 @@ func (h *Handler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handl
 	auth.Get("/github/callback", h.GitHubCallback)
 	auth.Get("/google", h.GoogleLogin)
 	auth.Get("/google/callback", h.GoogleCallback)
+	auth.Get("/microsoft", h.MicrosoftLogin)
+	auth.Get("/microsoft/callback", h.MicrosoftCallback)
 
 	// Public routes - Token management
-	auth.Post("/refresh", h.RefreshTokens)
+	auth.Post("/exchange", h.ExchangeCode)
+	auth.Post("/refresh", h.refreshLimiter(), h.RefreshTokens)
 	auth.Post("/logout", h.Logout)
+	auth.Get("/jwks.json", h.JWKS)
+	auth.Post("/2fa/login", h.CompleteTwoFactorLogin)
 
 	// Protected routes
 	auth.Get("/me", authMiddleware, h.GetMe)
+
+	// Account and security routes; a leaked API key mustn't be able to take
+	// over or delete the account
+	account := []fiber.Handler{authMiddleware, RequireInteractiveSession}
+	auth.Post("/logout-all", append(account, h.LogoutAll)...)
+	auth.Get("/sessions", append(account, h.ListSessions)...)
+	auth.Delete("/sessions/:id", append(account, h.RevokeSession)...)
+	auth.Get("/api-keys", append(account, h.ListAPIKeys)...)
+	auth.Post("/api-keys", append(account, h.CreateAPIKey)...)
+	auth.Delete("/api-keys/:id", append(account, h.RevokeAPIKey)...)
+	auth.Patch("/me", append(account, h.UpdateMe)...)
+	auth.Delete("/me", append(account, h.DeleteMe)...)
+	auth.Delete("/providers/:provider", append(account, h.UnlinkProvider)...)
+	auth.Get("/me/export", append(account, h.ExportMe)...)
+	auth.Post("/me/export", append(account, h.StartExport)...)
+	auth.Get("/me/export/:id", append(account, h.GetExport)...)
+	auth.Get("/me/export/:id/download", append(account, h.DownloadExport)...)
+	auth.Get("/2fa", append(account, h.GetTwoFactor)...)
+	auth.Post("/2fa/setup", append(account, h.SetupTwoFactor)...)
+	auth.Post("/2fa/verify", append(account, h.VerifyTwoFactor)...)
+	auth.Post("/2fa/disable", append(account, h.DisableTwoFactor)...)
+	auth.Post("/2fa/backup-codes", append(account, h.RegenerateBackupCodes)...)
 }
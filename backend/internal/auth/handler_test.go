@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPresentedRefreshTokenPrefersBody(t *testing.T) {
+	tests := []struct {
+		name, body, cookie    string
+		wantToken, wantCookie string
+	}{
+		{"body only", `{"refresh_token":"fresh"}`, "", "fresh", ""},
+		{"cookie only", `{}`, "cookie", "cookie", "cookie"},
+		{"both agree", `{"refresh_token":"same"}`, "same", "same", "same"},
+		{"stale cookie", `{"refresh_token":"fresh"}`, "stale", "fresh", "stale"},
+		{"empty body token", `{"refresh_token":""}`, "cookie", "cookie", "cookie"},
+		{"unparsable body", `not json`, "cookie", "cookie", "cookie"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var token, cookie string
+			app := fiber.New()
+			app.Post("/", func(c *fiber.Ctx) error {
+				token, cookie = presentedRefreshToken(c)
+				return nil
+			})
+
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tt.cookie})
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			if token != tt.wantToken || cookie != tt.wantCookie {
+				t.Errorf("presentedRefreshToken = %q, %q; want %q, %q", token, cookie, tt.wantToken, tt.wantCookie)
+			}
+		})
+	}
+}
+
+func TestRefreshWithMismatchedCookieIsRejected(t *testing.T) {
+	s := newTokenTestService()
+	h := NewHandler(s, s.config)
+	app := fiber.New()
+	app.Post("/refresh", h.RefreshTokens)
+
+	// Both tokens are valid; neither may be picked over the other
+	cookieToken, err := s.generateToken(tokenTestUser(), TokenTypeRefresh, refreshTokenTTL, nil)
+	if err != nil {
+		t.Fatalf("generate cookie token: %v", err)
+	}
+	bodyToken, err := s.generateToken(tokenTestUser(), TokenTypeRefresh, refreshTokenTTL, nil)
+	if err != nil {
+		t.Fatalf("generate body token: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/refresh", strings.NewReader(`{"refresh_token":"`+bodyToken+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: cookieToken})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+
+	cleared := false
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "refresh_token" && cookie.Value == "" && cookie.Expires.Before(time.Now()) {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("refresh cookie was not cleared")
+	}
+}
+
+func TestRefreshWithoutTokenIsRejected(t *testing.T) {
+	s := newTokenTestService()
+	app := fiber.New()
+	app.Post("/refresh", NewHandler(s, s.config).RefreshTokens)
+
+	req := httptest.NewRequest("POST", "/refresh", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+}
+
+func TestLogoutClearsAllAuthCookies(t *testing.T) {
+	s := newTokenTestService()
+	app := fiber.New()
+	app.Post("/logout", NewHandler(s, s.config).Logout)
+
+	req := httptest.NewRequest("POST", "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "logged_in", Value: "true"})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	cleared := map[string]bool{}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value == "" && !cookie.Expires.IsZero() && cookie.Expires.Before(time.Now()) {
+			cleared[cookie.Name] = true
+		}
+	}
+	for _, name := range []string{"access_token", "refresh_token", "logged_in"} {
+		if !cleared[name] {
+			t.Errorf("logout did not clear the %s cookie", name)
+		}
+	}
+}
+
+func TestLoggedInCookieExpiresWithAccessToken(t *testing.T) {
+	s := newTokenTestService()
+	h := NewHandler(s, s.config)
+	tokens := &TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 3600}
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		h.setAuthCookies(c, tokens)
+		return nil
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	maxAge := map[string]int{}
+	for _, cookie := range resp.Cookies() {
+		maxAge[cookie.Name] = cookie.MaxAge
+	}
+	if maxAge["logged_in"] != tokens.ExpiresIn || maxAge["access_token"] != tokens.ExpiresIn {
+		t.Errorf("logged_in max age %d, access_token %d; want both %d", maxAge["logged_in"], maxAge["access_token"], tokens.ExpiresIn)
+	}
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is a key with the ID put in the "kid" header of tokens it signs.
+// HMAC keys sign and verify with the same secret; RSA keys sign with the
+// private key (nil for retired keys) and verify with the public one.
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// keyRing holds the key new tokens are signed with, plus previous keys that
+// are still accepted so a rotation doesn't end every session at once
+type keyRing struct {
+	current  signingKey
+	accepted map[string]signingKey
+}
+
+// newKeyRing builds an HS256 key ring from the current secret and any previous ones
+func newKeyRing(current string, previous []string) *keyRing {
+	ring := &keyRing{
+		current:  newSigningKey(current),
+		accepted: make(map[string]signingKey),
+	}
+	ring.accepted[ring.current.id] = ring.current
+	for _, secret := range previous {
+		if secret == "" {
+			continue
+		}
+		key := newSigningKey(secret)
+		ring.accepted[key.id] = key
+	}
+	return ring
+}
+
+// newSigningKey derives a key ID from a secret; the ID reveals nothing useful about it
+func newSigningKey(secret string) signingKey {
+	sum := sha256.Sum256([]byte("sysdes-jwt-kid:" + secret))
+	return signingKey{
+		id:     hex.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// newRSAKey identifies an RSA key by a hash of its public half
+func newRSAKey(private *rsa.PrivateKey, public *rsa.PublicKey) (signingKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+
+	key := signingKey{
+		id:     hex.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodRS256,
+		verify: public,
+	}
+	if private != nil {
+		key.sign = private
+	}
+	return key, nil
+}
+
+// RSAKeys configures RS256 signing
+type RSAKeys struct {
+	// PrivateKey is a PEM private key (PKCS#1 or PKCS#8); PrivateKeyPath is
+	// read instead when it's empty
+	PrivateKey     string
+	PrivateKeyPath string
+	// PreviousPublicKeyPaths are PEM public keys of retired private keys,
+	// still accepted until the tokens they signed expire
+	PreviousPublicKeyPaths []string
+	// AcceptHS256 keeps accepting tokens signed with the JWT secrets, e.g. while
+	// switching a deployment from HS256
+	AcceptHS256 bool
+}
+
+// UseRSAKeys signs new tokens with RS256, so other services can verify them
+// with the public key (see JWKS) instead of sharing the JWT secret
+func (s *Service) UseRSAKeys(cfg RSAKeys) error {
+	pemData := strings.ReplaceAll(cfg.PrivateKey, `\n`, "\n")
+	if pemData == "" {
+		if cfg.PrivateKeyPath == "" {
+			return errors.New("RS256 needs JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_PATH")
+		}
+		data, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		pemData = string(data)
+	}
+
+	private, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(pemData))
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+	current, err := newRSAKey(private, &private.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	accepted := make(map[string]signingKey)
+	if cfg.AcceptHS256 {
+		for id, key := range s.keys.accepted {
+			accepted[id] = key
+		}
+	}
+	accepted[current.id] = current
+
+	for _, path := range cfg.PreviousPublicKeyPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read public key %s: %w", path, err)
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		key, err := newRSAKey(nil, public)
+		if err != nil {
+			return err
+		}
+		accepted[key.id] = key
+	}
+
+	s.keys = &keyRing{current: current, accepted: accepted}
+	return nil
+}
+
+// verificationKey picks the key for a token by its "kid" header, and checks
+// the token is signed with that key's algorithm so an RSA public key can never
+// be used as an HMAC secret. Tokens issued before key IDs existed are HS256
+// and tried against every accepted secret.
+func (r *keyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		keys := make([]jwt.VerificationKey, 0, len(r.accepted))
+		for _, key := range r.accepted {
+			if key.method == jwt.SigningMethodHS256 {
+				keys = append(keys, key.verify)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}
+
+	key, ok := r.accepted[kid]
+	if !ok || token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return key.verify, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JWKS returns the RSA public keys tokens may be signed with, for other
+// services to verify them. It's empty with HS256, whose secrets stay private.
+func (s *Service) JWKS() []JWK {
+	keys := []JWK{}
+	for _, key := range s.keys.accepted {
+		public, ok := key.verify.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		keys = append(keys, JWK{
+			KeyType:   "RSA",
+			KeyID:     key.id,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+			Modulus:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+	return keys
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/metrics"
 )
 
 // Middleware provides authentication middleware
@@ -18,9 +19,9 @@ func NewMiddleware(service *Service) *Middleware {
 	return &Middleware{service: service}
 }
 
-// RequireAuth is middleware that requires a valid JWT token
-// It checks both the Authorization header and cookies for the token
-// On success, it sets userID and userEmail in c.Locals()
+// RequireAuth is middleware that requires a valid JWT token or API key
+// It checks the Authorization header, then the X-API-Key header, then cookies
+// On success, it sets userID, userEmail and userRole in c.Locals()
 func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 	var token string
 
@@ -33,6 +34,24 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 		}
 	}
 
+	// Then an API key, as scripts send
+	if token == "" {
+		if key := c.Get(APIKeyHeader); key != "" {
+			claims, err := m.service.ValidateAPIKey(c.Context(), key)
+			metrics.AuthAttempt("api_key", err)
+			if err != nil {
+				logger.For(c).Debug().Err(err).Str("path", c.Path()).Msg("Invalid API key")
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error":   true,
+					"message": "Invalid API key",
+				})
+			}
+
+			setAPIKeyLocals(c, claims)
+			return c.Next()
+		}
+	}
+
 	// If no header, try cookie
 	if token == "" {
 		token = c.Cookies("access_token")
@@ -40,7 +59,7 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 
 	// No token found anywhere
 	if token == "" {
-		logger.Debug().Str("path", c.Path()).Msg("No auth token provided")
+		logger.For(c).Debug().Str("path", c.Path()).Msg("No auth token provided")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   true,
 			"message": "Authentication required",
@@ -48,9 +67,10 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 	}
 
 	// Validate the token
-	claims, err := m.service.ValidateToken(token)
+	claims, err := m.service.ValidateAccessToken(c.Context(), token)
+	metrics.AuthAttempt("token", err)
 	if err != nil {
-		logger.Debug().Err(err).Str("path", c.Path()).Msg("Invalid auth token")
+		logger.For(c).Debug().Err(err).Str("path", c.Path()).Msg("Invalid auth token")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid or expired token",
@@ -60,10 +80,56 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 	// Store user info in context for handlers to use
 	c.Locals("userID", claims.UserID)
 	c.Locals("userEmail", claims.Email)
+	c.Locals("userRole", claims.Role)
+	c.Locals("sessionID", claims.FamilyID)
 
 	return c.Next()
 }
 
+// RequireRole is middleware that only lets users with the given role through.
+// It must run after RequireAuth, which identifies the user:
+//
+//	api.Group("/ops", m.RequireAuth, m.RequireRole(RoleAdmin))
+//
+// The role is read from the database rather than the token, so a demotion
+// takes effect at once instead of when the user's access token expires.
+func (m *Middleware) RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsAuthenticated(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   true,
+				"message": "Authentication required",
+			})
+		}
+
+		user, err := m.service.GetUserByID(c.Context(), GetUserID(c))
+		if err != nil {
+			logger.FailureFor(c, err).Str("user_id", GetUserID(c)).Msg("Failed to look up user role")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "Failed to check permissions",
+			})
+		}
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   true,
+				"message": "Authentication required",
+			})
+		}
+		c.Locals("userRole", user.Role)
+
+		if user.Role != role {
+			logger.For(c).Debug().Str("path", c.Path()).Str("role", user.Role).Str("required", role).Msg("Insufficient role")
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   true,
+				"message": "Insufficient permissions",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
 // OptionalAuth is middleware that extracts user info if token is present
 // but doesn't require authentication - useful for public routes that
 // can show additional info for logged-in users
@@ -84,12 +150,24 @@ func (m *Middleware) OptionalAuth(c *fiber.Ctx) error {
 		token = c.Cookies("access_token")
 	}
 
+	// Try an API key
+	if token == "" {
+		if key := c.Get(APIKeyHeader); key != "" {
+			if claims, err := m.service.ValidateAPIKey(c.Context(), key); err == nil {
+				setAPIKeyLocals(c, claims)
+			}
+			return c.Next()
+		}
+	}
+
 	// If token found, try to validate it
 	if token != "" {
-		claims, err := m.service.ValidateToken(token)
+		claims, err := m.service.ValidateAccessToken(c.Context(), token)
 		if err == nil {
 			c.Locals("userID", claims.UserID)
 			c.Locals("userEmail", claims.Email)
+			c.Locals("userRole", claims.Role)
+			c.Locals("sessionID", claims.FamilyID)
 		}
 		// Don't return error if invalid - just continue without auth
 	}
@@ -97,22 +175,60 @@ func (m *Middleware) OptionalAuth(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// RequireInteractiveSession rejects requests made with an API key. It goes
+// after RequireAuth on account and security routes, which need a signed-in user.
+func RequireInteractiveSession(c *fiber.Ctx) error {
+	if GetAPIKeyID(c) != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": ErrAPIKeyNotAllowed.Error(),
+		})
+	}
+	return c.Next()
+}
+
+// setAPIKeyLocals stores the user an API key authenticated as. There's no
+// session; apiKeyID marks the request as made with a key.
+func setAPIKeyLocals(c *fiber.Ctx, claims *JWTClaims) {
+	c.Locals("userID", claims.UserID)
+	c.Locals("userEmail", claims.Email)
+	c.Locals("userRole", claims.Role)
+	c.Locals("apiKeyID", claims.ID)
+}
+
 // GetUserID extracts the user ID from context (set by middleware)
 // Returns empty string if not authenticated
 func GetUserID(c *fiber.Ctx) string {
-	if userID := c.Locals("userID"); userID != nil {
-		return userID.(string)
-	}
-	return ""
+	userID, _ := c.Locals("userID").(string)
+	return userID
 }
 
 // GetUserEmail extracts the user email from context (set by middleware)
 // Returns empty string if not authenticated
 func GetUserEmail(c *fiber.Ctx) string {
-	if email := c.Locals("userEmail"); email != nil {
-		return email.(string)
-	}
-	return ""
+	email, _ := c.Locals("userEmail").(string)
+	return email
+}
+
+// GetUserRole extracts the user's role from context (set by middleware)
+// Returns empty string if not authenticated
+func GetUserRole(c *fiber.Ctx) string {
+	role, _ := c.Locals("userRole").(string)
+	return role
+}
+
+// GetSessionID extracts the session of the access token from context (set by middleware)
+// Returns empty string for tokens issued before sessions were tracked
+func GetSessionID(c *fiber.Ctx) string {
+	sessionID, _ := c.Locals("sessionID").(string)
+	return sessionID
+}
+
+// GetAPIKeyID extracts the ID of the API key the request was made with
+// Returns empty string for requests authenticated with a token
+func GetAPIKeyID(c *fiber.Ctx) string {
+	keyID, _ := c.Locals("apiKeyID").(string)
+	return keyID
 }
 
 // IsAuthenticated checks if the request is authenticated
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireAuthRejectsNonAccessTokens(t *testing.T) {
+	s := newTokenTestService()
+	user := tokenTestUser()
+	m := NewMiddleware(s)
+
+	app := fiber.New()
+	app.Get("/", m.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	statuses := map[string]int{}
+	for _, tokenType := range []string{TokenTypeAccess, TokenTypeRefresh, TokenTypeTwoFactor} {
+		token, err := s.generateToken(user, tokenType, time.Hour, nil)
+		if err != nil {
+			t.Fatalf("generate %s token: %v", tokenType, err)
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request with %s token: %v", tokenType, err)
+		}
+		statuses[tokenType] = resp.StatusCode
+	}
+
+	want := map[string]int{
+		TokenTypeAccess:    fiber.StatusOK,
+		TokenTypeRefresh:   fiber.StatusUnauthorized,
+		TokenTypeTwoFactor: fiber.StatusUnauthorized,
+	}
+	for tokenType, status := range want {
+		if statuses[tokenType] != status {
+			t.Errorf("%s token: status %d, want %d", tokenType, statuses[tokenType], status)
+		}
+	}
+}
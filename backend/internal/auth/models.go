@@ -8,36 +8,85 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	AvatarURL string    `json:"avatar_url"`
-	GitHubID  *string   `json:"github_id,omitempty"`
-	GoogleID  *string   `json:"google_id,omitempty"`
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	AvatarURL   string    `json:"avatar_url"`
+	GitHubID    *string   `json:"github_id,omitempty"`
+	GoogleID    *string   `json:"google_id,omitempty"`
+	MicrosoftID *string   `json:"microsoft_id,omitempty"`
+	// TwoFactorEnabled is set once a TOTP enrollment is confirmed
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// Role is RoleUser or RoleAdmin
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// User roles. Admins can reach endpoints guarded by RequireRole(RoleAdmin).
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// OAuth providers a user can log in with
+const (
+	ProviderGitHub    = "github"
+	ProviderGoogle    = "google"
+	ProviderMicrosoft = "microsoft"
+)
+
+// LinkedProviders returns the OAuth providers linked to the user
+func (u *User) LinkedProviders() []string {
+	providers := []string{}
+	if u.GitHubID != nil {
+		providers = append(providers, ProviderGitHub)
+	}
+	if u.GoogleID != nil {
+		providers = append(providers, ProviderGoogle)
+	}
+	if u.MicrosoftID != nil {
+		providers = append(providers, ProviderMicrosoft)
+	}
+	return providers
+}
+
 // UserResponse is the public user data returned to clients
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	AvatarURL string    `json:"avatar_url"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               string    `json:"id"`
+	Email            string    `json:"email"`
+	Name             string    `json:"name"`
+	AvatarURL        string    `json:"avatar_url"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	Role             string    `json:"role"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID.String(),
-		Email:     u.Email,
-		Name:      u.Name,
-		AvatarURL: u.AvatarURL,
-		CreatedAt: u.CreatedAt,
+		ID:               u.ID.String(),
+		Email:            u.Email,
+		Name:             u.Name,
+		AvatarURL:        u.AvatarURL,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		Role:             u.Role,
+		CreatedAt:        u.CreatedAt,
 	}
 }
 
+// UpdateProfileRequest is the request body for updating the current user's profile.
+// Omitted or empty fields are left unchanged.
+type UpdateProfileRequest struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// DeleteAccountRequest is the request body for deleting the current user's account
+type DeleteAccountRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
 // GitHubUserInfo represents the user info from GitHub API
 type GitHubUserInfo struct {
 	ID        int64  `json:"id"`
@@ -63,6 +112,16 @@ type GoogleUserInfo struct {
 	Picture       string `json:"picture"`
 }
 
+// MicrosoftUserInfo represents the user info from the Microsoft Graph API
+type MicrosoftUserInfo struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	// Mail is the mailbox address, set for work accounts with Exchange
+	Mail string `json:"mail"`
+	// UserPrincipalName is the sign-in name, an email address for personal accounts
+	UserPrincipalName string `json:"userPrincipalName"`
+}
+
 // TokenPair holds access and refresh tokens
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
@@ -71,14 +130,36 @@ type TokenPair struct {
 	TokenType    string `json:"token_type"`
 }
 
-// AuthResponse is returned after successful authentication
+// AuthResponse is returned after successful authentication. Users with
+// two-factor authentication get a ChallengeToken instead of Tokens until they
+// enter a code.
 type AuthResponse struct {
-	User   *UserResponse `json:"user"`
-	Tokens *TokenPair    `json:"tokens"`
+	User           *UserResponse `json:"user"`
+	Tokens         *TokenPair    `json:"tokens,omitempty"`
+	ChallengeToken string        `json:"challenge_token,omitempty"`
 }
 
+// Token types carried in the "typ" claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeAPIKey marks claims of a request made with an API key rather than a token
+	TokenTypeAPIKey = "api_key"
+)
+
 // JWTClaims represents the claims in our JWT
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	TokenType string `json:"typ"`
+	// Role is the user's role when the token was issued (RoleUser for tokens
+	// from before roles existed). It may be stale; RequireRole checks the database.
+	Role string `json:"role"`
+	// ID is the token's "jti"; tokens issued before it existed have none.
+	// FamilyID is the session ("fam" claim), set on refresh tokens and on
+	// access tokens issued since sessions were listed.
+	ID        string    `json:"jti,omitempty"`
+	FamilyID  string    `json:"fam,omitempty"`
+	IssuedAt  time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
 }
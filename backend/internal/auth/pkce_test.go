@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+)
+
+// memoryVerifiers is an in-memory verifierStore
+type memoryVerifiers struct {
+	mu        sync.Mutex
+	verifiers map[string]string
+	saveErr   error
+}
+
+func (m *memoryVerifiers) Save(_ context.Context, state, verifier string) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.verifiers == nil {
+		m.verifiers = make(map[string]string)
+	}
+	m.verifiers[state] = verifier
+	return nil
+}
+
+func (m *memoryVerifiers) Take(_ context.Context, state string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	verifier := m.verifiers[state]
+	delete(m.verifiers, state)
+	return verifier, nil
+}
+
+func newPKCETestService(store verifierStore) *Service {
+	s := NewService(nil, &config.Config{
+		JWTSecret:         "test-secret",
+		GitHubClientID:    "client",
+		GitHubRedirectURL: "http://localhost/callback",
+	})
+	s.pkce = store
+	return s
+}
+
+func TestCodeChallengeMatchesRFC7636(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := codeChallenge(verifier); got != want {
+		t.Fatalf("codeChallenge(%q) = %q, want %q", verifier, got, want)
+	}
+}
+
+func TestNewCodeVerifier(t *testing.T) {
+	unreserved := regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+	first, err := newCodeVerifier()
+	if err != nil {
+		t.Fatalf("newCodeVerifier: %v", err)
+	}
+	second, err := newCodeVerifier()
+	if err != nil {
+		t.Fatalf("newCodeVerifier: %v", err)
+	}
+
+	for _, verifier := range []string{first, second} {
+		if !unreserved.MatchString(verifier) {
+			t.Errorf("verifier %q is not 43-128 unreserved characters", verifier)
+		}
+	}
+	if first == second {
+		t.Errorf("two verifiers are equal: %q", first)
+	}
+}
+
+func TestPKCERoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newPKCETestService(&memoryVerifiers{})
+
+	authURL, err := s.GetGitHubAuthURL(ctx, "state-1")
+	if err != nil {
+		t.Fatalf("GetGitHubAuthURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if got := query.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	challenge := query.Get("code_challenge")
+	if challenge == "" {
+		t.Fatal("auth URL has no code_challenge")
+	}
+
+	verifier, err := s.pkceVerifier(ctx, "state-1")
+	if err != nil {
+		t.Fatalf("pkceVerifier: %v", err)
+	}
+	if got := codeChallenge(verifier); got != challenge {
+		t.Errorf("challenge of saved verifier = %q, want %q", got, challenge)
+	}
+
+	// Each verifier can only be used once
+	if _, err := s.pkceVerifier(ctx, "state-1"); !errors.Is(err, ErrPKCEVerifierMissing) {
+		t.Errorf("second pkceVerifier error = %v, want ErrPKCEVerifierMissing", err)
+	}
+}
+
+func TestPKCEVerifierMissingRejectsCallback(t *testing.T) {
+	ctx := context.Background()
+	s := newPKCETestService(&memoryVerifiers{})
+
+	for _, state := range []string{"", "never-started"} {
+		verifier, err := s.pkceVerifier(ctx, state)
+		if !errors.Is(err, ErrPKCEVerifierMissing) {
+			t.Errorf("pkceVerifier(%q) = %q, %v; want ErrPKCEVerifierMissing", state, verifier, err)
+		}
+	}
+}
+
+func TestPKCEDisabled(t *testing.T) {
+	ctx := context.Background()
+	s := newPKCETestService(nil)
+
+	authURL, err := s.GetGitHubAuthURL(ctx, "state-1")
+	if err != nil {
+		t.Fatalf("GetGitHubAuthURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+	if parsed.Query().Has("code_challenge") {
+		t.Errorf("auth URL %q has a code_challenge with PKCE off", authURL)
+	}
+
+	verifier, err := s.pkceVerifier(ctx, "state-1")
+	if err != nil || verifier != "" {
+		t.Errorf("pkceVerifier = %q, %v; want no verifier and no error", verifier, err)
+	}
+}
+
+func TestPKCESaveFailureFailsLogin(t *testing.T) {
+	saveErr := errors.New("redis down")
+	s := newPKCETestService(&memoryVerifiers{saveErr: saveErr})
+
+	if _, err := s.GetGitHubAuthURL(context.Background(), "state-1"); !errors.Is(err, saveErr) {
+		t.Errorf("GetGitHubAuthURL error = %v, want %v", err, saveErr)
+	}
+}
@@ -4,10 +4,39 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+)
+
+// Common errors
+var (
+	// ErrProviderAlreadyLinked is returned when an OAuth account is already linked to another user
+	ErrProviderAlreadyLinked = errors.New("provider account is already linked to another user")
+	// ErrRefreshTokenReused is returned when a refresh token that was already exchanged is presented again
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+	// ErrRefreshTokenInvalid is returned for refresh tokens that are unknown, expired or revoked
+	ErrRefreshTokenInvalid = errors.New("refresh token is not valid")
+	// ErrLastProvider is returned when unlinking would leave a user with no way to log in
+	ErrLastProvider = errors.New("cannot unlink the only login provider; link another provider first")
+	// ErrProviderNotLinked is returned when unlinking a provider the user hasn't linked
+	ErrProviderNotLinked = errors.New("provider is not linked to this account")
+	// ErrUserNotFound is returned when deleting a user that doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrSignupDisabled is returned when a login would create a new account while signups are closed
+	ErrSignupDisabled = errors.New("signups are disabled")
+	// ErrInvalidName is returned for profile names that are blank or too long
+	ErrInvalidName = errors.New("name must be between 1 and 255 characters")
+	// ErrInvalidAvatarURL is returned for avatar URLs that aren't absolute http(s) URLs
+	ErrInvalidAvatarURL = errors.New("avatar_url must be an http or https URL")
+	// ErrTokenRevoked is returned for tokens revoked by logging out
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationUnavailable is returned when access tokens can't be revoked because Redis isn't configured
+	ErrRevocationUnavailable = errors.New("token revocation is unavailable")
 )
 
 // Repository handles database operations for auth
@@ -23,7 +52,7 @@ func NewRepository(db *pgxpool.Pool) *Repository {
 // FindByID finds a user by their ID
 func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, email, name, avatar_url, github_id, google_id, created_at, updated_at
+		SELECT id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -36,6 +65,9 @@ func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*User, error)
 		&user.AvatarURL,
 		&user.GitHubID,
 		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -53,7 +85,7 @@ func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*User, error)
 // FindByEmail finds a user by their email
 func (r *Repository) FindByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, name, avatar_url, github_id, google_id, created_at, updated_at
+		SELECT id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -66,6 +98,9 @@ func (r *Repository) FindByEmail(ctx context.Context, email string) (*User, erro
 		&user.AvatarURL,
 		&user.GitHubID,
 		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -83,7 +118,7 @@ func (r *Repository) FindByEmail(ctx context.Context, email string) (*User, erro
 // FindByGitHubID finds a user by their GitHub ID
 func (r *Repository) FindByGitHubID(ctx context.Context, githubID string) (*User, error) {
 	query := `
-		SELECT id, email, name, avatar_url, github_id, google_id, created_at, updated_at
+		SELECT id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
 		FROM users
 		WHERE github_id = $1
 	`
@@ -96,6 +131,9 @@ func (r *Repository) FindByGitHubID(ctx context.Context, githubID string) (*User
 		&user.AvatarURL,
 		&user.GitHubID,
 		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -113,7 +151,7 @@ func (r *Repository) FindByGitHubID(ctx context.Context, githubID string) (*User
 // FindByGoogleID finds a user by their Google ID
 func (r *Repository) FindByGoogleID(ctx context.Context, googleID string) (*User, error) {
 	query := `
-		SELECT id, email, name, avatar_url, github_id, google_id, created_at, updated_at
+		SELECT id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
 		FROM users
 		WHERE google_id = $1
 	`
@@ -126,6 +164,9 @@ func (r *Repository) FindByGoogleID(ctx context.Context, googleID string) (*User
 		&user.AvatarURL,
 		&user.GitHubID,
 		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -140,22 +181,58 @@ func (r *Repository) FindByGoogleID(ctx context.Context, googleID string) (*User
 	return &user, nil
 }
 
+// FindByMicrosoftID finds a user by their Microsoft ID
+func (r *Repository) FindByMicrosoftID(ctx context.Context, microsoftID string) (*User, error) {
+	query := `
+		SELECT id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
+		FROM users
+		WHERE microsoft_id = $1
+	`
+
+	var user User
+	err := r.db.QueryRow(ctx, query, microsoftID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.AvatarURL,
+		&user.GitHubID,
+		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by microsoft id: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Create creates a new user
-func (r *Repository) Create(ctx context.Context, email, name, avatarURL string, githubID, googleID *string) (*User, error) {
+func (r *Repository) Create(ctx context.Context, email, name, avatarURL string, githubID, googleID, microsoftID *string) (*User, error) {
 	query := `
-		INSERT INTO users (email, name, avatar_url, github_id, google_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, email, name, avatar_url, github_id, google_id, created_at, updated_at
+		INSERT INTO users (email, name, avatar_url, github_id, google_id, microsoft_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, email, name, avatar_url, github_id, google_id, microsoft_id, is_2fa_enabled, role, created_at, updated_at
 	`
 
 	var user User
-	err := r.db.QueryRow(ctx, query, email, name, avatarURL, githubID, googleID).Scan(
+	err := r.db.QueryRow(ctx, query, email, name, avatarURL, githubID, googleID, microsoftID).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
 		&user.AvatarURL,
 		&user.GitHubID,
 		&user.GoogleID,
+		&user.MicrosoftID,
+		&user.TwoFactorEnabled,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -176,11 +253,7 @@ func (r *Repository) UpdateGitHubID(ctx context.Context, userID uuid.UUID, githu
 	`
 
 	_, err := r.db.Exec(ctx, query, githubID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to update github id: %w", err)
-	}
-
-	return nil
+	return linkError(err, "github")
 }
 
 // UpdateGoogleID updates a user's Google ID
@@ -192,18 +265,100 @@ func (r *Repository) UpdateGoogleID(ctx context.Context, userID uuid.UUID, googl
 	`
 
 	_, err := r.db.Exec(ctx, query, googleID, userID)
+	return linkError(err, "google")
+}
+
+// UpdateMicrosoftID updates a user's Microsoft ID
+func (r *Repository) UpdateMicrosoftID(ctx context.Context, userID uuid.UUID, microsoftID string) error {
+	query := `
+		UPDATE users
+		SET microsoft_id = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, microsoftID, userID)
+	return linkError(err, "microsoft")
+}
+
+// linkError maps the error from setting a provider ID. The provider ID
+// columns are the only unique ones such an update touches, so any unique
+// violation means the account is already linked to someone else.
+func linkError(err error, provider string) error {
+	if database.IsUniqueViolation(err, "") {
+		return ErrProviderAlreadyLinked
+	}
 	if err != nil {
-		return fmt.Errorf("failed to update google id: %w", err)
+		return fmt.Errorf("failed to update %s id: %w", provider, err)
+	}
+	return nil
+}
+
+// UnlinkGitHub removes a user's GitHub ID. The update only applies while another
+// provider is still linked, so concurrent unlinks can't remove them all.
+func (r *Repository) UnlinkGitHub(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET github_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND (google_id IS NOT NULL OR microsoft_id IS NOT NULL)
+	`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink github: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLastProvider
 	}
 
 	return nil
 }
 
-// UpdateProfile updates a user's profile information
+// UnlinkGoogle removes a user's Google ID, only while another provider is still linked
+func (r *Repository) UnlinkGoogle(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET google_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND (github_id IS NOT NULL OR microsoft_id IS NOT NULL)
+	`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink google: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLastProvider
+	}
+
+	return nil
+}
+
+// UnlinkMicrosoft removes a user's Microsoft ID, only while another provider is still linked
+func (r *Repository) UnlinkMicrosoft(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET microsoft_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND (github_id IS NOT NULL OR google_id IS NOT NULL)
+	`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink microsoft: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLastProvider
+	}
+
+	return nil
+}
+
+// UpdateProfile updates a user's profile information. Empty values leave the
+// column unchanged.
 func (r *Repository) UpdateProfile(ctx context.Context, userID uuid.UUID, name, avatarURL string) error {
 	query := `
 		UPDATE users
-		SET name = $1, avatar_url = $2, updated_at = NOW()
+		SET name = COALESCE(NULLIF($1, ''), name),
+			avatar_url = COALESCE(NULLIF($2, ''), avatar_url),
+			updated_at = NOW()
 		WHERE id = $3
 	`
 
@@ -214,3 +369,126 @@ func (r *Repository) UpdateProfile(ctx context.Context, userID uuid.UUID, name,
 
 	return nil
 }
+
+// DeleteUser deletes a user and everything they own in one transaction and
+// returns the IDs of the deleted projects. Rows are removed children first
+// (whiteboards, then projects, then the user) so nothing relies on cascades
+// that older databases may lack; collaborations, assets and refresh tokens
+// cascade from the user row.
+func (r *Repository) DeleteUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM whiteboards WHERE project_id IN (SELECT id FROM projects WHERE user_id = $1)`
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete user whiteboards: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM projects WHERE user_id = $1 RETURNING id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user projects: %w", err)
+	}
+	var projectIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted project: %w", err)
+		}
+		projectIDs = append(projectIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete user projects: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+
+	return projectIDs, nil
+}
+
+// StoreRefreshToken records a newly issued refresh token and the client it was issued to
+func (r *Repository) StoreRefreshToken(ctx context.Context, jti, userID, familyID uuid.UUID, expiresAt time.Time, client sessionClient) error {
+	query := `
+		INSERT INTO refresh_tokens (jti, user_id, family_id, expires_at, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+	`
+
+	if _, err := r.db.Exec(ctx, query, jti, userID, familyID, expiresAt, client.userAgent, client.ip); err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshToken marks a refresh token as used and returns its family.
+// A token that was already consumed returns ErrRefreshTokenReused along with
+// its family, so the caller can revoke it; unknown, expired or revoked tokens
+// return ErrRefreshTokenInvalid.
+func (r *Repository) ConsumeRefreshToken(ctx context.Context, jti uuid.UUID) (uuid.UUID, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET consumed_at = NOW()
+		WHERE jti = $1 AND consumed_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING family_id
+	`
+
+	var familyID uuid.UUID
+	err := r.db.QueryRow(ctx, query, jti).Scan(&familyID)
+	if err == nil {
+		return familyID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+
+	// Work out why it couldn't be consumed
+	var consumed bool
+	err = r.db.QueryRow(ctx, `SELECT family_id, consumed_at IS NOT NULL FROM refresh_tokens WHERE jti = $1`, jti).Scan(&familyID, &consumed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	if consumed {
+		return familyID, ErrRefreshTokenReused
+	}
+
+	return uuid.Nil, ErrRefreshTokenInvalid
+}
+
+// RevokeUserRefreshTokens revokes every refresh token issued to a user
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes every outstanding refresh token descended from one login
+func (r *Repository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(ctx, query, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
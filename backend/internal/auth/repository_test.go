@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newDBTestRepository connects to the migrated database in TEST_DATABASE_URL,
+// skipping the test when it isn't set
+func newDBTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return NewRepository(pool)
+}
+
+func TestLinkErrorReportsAlreadyLinked(t *testing.T) {
+	for _, constraint := range []string{"users_github_id_key", "idx_users_microsoft_id_unique"} {
+		err := fmt.Errorf("exec: %w", &pgconn.PgError{Code: "23505", ConstraintName: constraint})
+		if got := linkError(err, "github"); !errors.Is(got, ErrProviderAlreadyLinked) {
+			t.Errorf("linkError(unique violation on %s) = %v, want ErrProviderAlreadyLinked", constraint, got)
+		}
+	}
+}
+
+func TestLinkErrorKeepsOtherErrors(t *testing.T) {
+	if err := linkError(nil, "google"); err != nil {
+		t.Errorf("linkError(nil) = %v, want nil", err)
+	}
+
+	cause := &pgconn.PgError{Code: "23503"}
+	err := linkError(cause, "google")
+	if errors.Is(err, ErrProviderAlreadyLinked) {
+		t.Errorf("foreign key violation reported as ErrProviderAlreadyLinked")
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("linkError(%v) = %v, want it wrapped", cause, err)
+	}
+}
+
+func TestConcurrentLinksOfOneProviderIDConflict(t *testing.T) {
+	r := newDBTestRepository(t)
+	ctx := context.Background()
+
+	links := map[string]func(userID uuid.UUID, providerID string) error{
+		"github": func(userID uuid.UUID, providerID string) error { return r.UpdateGitHubID(ctx, userID, providerID) },
+		"google": func(userID uuid.UUID, providerID string) error { return r.UpdateGoogleID(ctx, userID, providerID) },
+	}
+
+	for provider, link := range links {
+		t.Run(provider, func(t *testing.T) {
+			var users []uuid.UUID
+			for range 2 {
+				user, err := r.Create(ctx, uuid.NewString()+"@link-test.invalid", "Link Test", "", nil, nil, nil)
+				if err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+				users = append(users, user.ID)
+				t.Cleanup(func() {
+					_, _ = r.db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, user.ID)
+				})
+			}
+
+			providerID := uuid.NewString()
+			errs := make([]error, len(users))
+			var wg sync.WaitGroup
+			for i, userID := range users {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[i] = link(userID, providerID)
+				}()
+			}
+			wg.Wait()
+
+			linked, conflicts := 0, 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					linked++
+				case errors.Is(err, ErrProviderAlreadyLinked):
+					conflicts++
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if linked != 1 || conflicts != 1 {
+				t.Errorf("%d linked and %d conflicts, want exactly one of each", linked, conflicts)
+			}
+		})
+	}
+}
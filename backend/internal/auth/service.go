@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -20,8 +22,16 @@ import (
 
 // Service handles authentication business logic
 type Service struct {
-	repo   *Repository
-	config *config.Config
+	repo      *Repository
+	config    *config.Config
+	keys      *keyRing
+	blacklist *Blacklist
+	pkce      verifierStore
+	attempts  attemptCounter
+	// onProjectDelete hooks run for each project removed with a deleted account
+	onProjectDelete []func(ctx context.Context, projectID uuid.UUID)
+	// exportWake tells the ExportWorker a data export was queued
+	exportWake chan struct{}
 }
 
 // NewService creates a new auth service
@@ -29,21 +39,111 @@ func NewService(repo *Repository, cfg *config.Config) *Service {
 	return &Service{
 		repo:   repo,
 		config: cfg,
+		keys:   newKeyRing(cfg.JWTSecret, cfg.JWTPreviousSecrets),
+
+		attempts:   newMemoryAttempts(),
+		exportWake: make(chan struct{}, 1),
 	}
 }
 
+// UseBlacklist enables revoking tokens before they expire. Without it, logging
+// out only ends refresh token families; access tokens stay valid until expiry.
+func (s *Service) UseBlacklist(blacklist *Blacklist) {
+	s.blacklist = blacklist
+}
+
+// UseChallengeAttempts counts wrong 2FA codes in Redis, so a challenge's
+// attempts are shared by every instance. Without it, each counts its own.
+func (s *Service) UseChallengeAttempts(attempts *ChallengeAttempts) {
+	s.attempts = attempts
+}
+
+// UsePKCE adds PKCE challenges to OAuth logins. Without it, logins rely on
+// the client secret and state cookie alone.
+func (s *Service) UsePKCE(store *PKCEStore) {
+	s.pkce = store
+}
+
+// pkceChallenge creates and saves a code verifier for a login, returning the
+// authorize URL parameters for its challenge, or none if PKCE is off. A
+// verifier that can't be saved fails the login, as its callback would be rejected.
+func (s *Service) pkceChallenge(ctx context.Context, state string) (url.Values, error) {
+	if s.pkce == nil {
+		return nil, nil
+	}
+
+	verifier, err := newCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pkce.Save(ctx, state, verifier); err != nil {
+		return nil, err
+	}
+
+	return url.Values{
+		"code_challenge":        {codeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}, nil
+}
+
+// pkceVerifier returns the code verifier saved for a login's state, or "" if
+// PKCE is off. With PKCE on, every login saved one, so a state without a
+// verifier returns ErrPKCEVerifierMissing instead of skipping the check.
+func (s *Service) pkceVerifier(ctx context.Context, state string) (string, error) {
+	if s.pkce == nil {
+		return "", nil
+	}
+	if state == "" {
+		return "", ErrPKCEVerifierMissing
+	}
+
+	verifier, err := s.pkce.Take(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	if verifier == "" {
+		return "", ErrPKCEVerifierMissing
+	}
+	return verifier, nil
+}
+
+// OnProjectDelete registers a hook that runs for each project deleted along
+// with an account; it is the account-level counterpart of project.Service.OnDelete
+func (s *Service) OnProjectDelete(fn func(ctx context.Context, projectID uuid.UUID)) {
+	s.onProjectDelete = append(s.onProjectDelete, fn)
+}
+
 // ==================== JWT Methods ====================
 
-// GenerateTokenPair generates access and refresh tokens for a user
-func (s *Service) GenerateTokenPair(user *User) (*TokenPair, error) {
-	// Access token - short lived
-	accessToken, err := s.generateToken(user, time.Duration(s.config.JWTExpiryHours)*time.Hour)
+// refreshTokenTTL is how long a refresh token stays valid
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// GenerateTokenPair generates access and refresh tokens for a user, starting a new refresh token family
+func (s *Service) GenerateTokenPair(ctx context.Context, user *User) (*TokenPair, error) {
+	return s.generateTokenPair(ctx, user, uuid.New())
+}
+
+// generateTokenPair generates a token pair whose refresh token belongs to familyID.
+// The refresh token's ID is stored so it can only be exchanged once, along
+// with the client from ctx (see withClient).
+func (s *Service) generateTokenPair(ctx context.Context, user *User, familyID uuid.UUID) (*TokenPair, error) {
+	// Access token - short lived, carrying its session
+	accessToken, err := s.generateToken(user, TokenTypeAccess, time.Duration(s.config.JWTExpiryHours)*time.Hour, jwt.MapClaims{
+		"fam": familyID.String(),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	// Refresh token - long lived (30 days)
-	refreshToken, err := s.generateToken(user, 30*24*time.Hour)
+	// Refresh token - long lived (30 days), single use
+	jti := uuid.New()
+	if err := s.repo.StoreRefreshToken(ctx, jti, user.ID, familyID, time.Now().Add(refreshTokenTTL), clientFrom(ctx)); err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.generateToken(user, TokenTypeRefresh, refreshTokenTTL, jwt.MapClaims{
+		"jti": jti.String(),
+		"fam": familyID.String(),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -56,61 +156,233 @@ func (s *Service) GenerateTokenPair(user *User) (*TokenPair, error) {
 	}, nil
 }
 
-// generateToken creates a JWT token for a user
-func (s *Service) generateToken(user *User, expiry time.Duration) (string, error) {
+// generateToken creates a JWT token of the given type for a user, with any extra claims
+func (s *Service) generateToken(user *User, tokenType string, expiry time.Duration, extra jwt.MapClaims) (string, error) {
 	claims := jwt.MapClaims{
+		"jti":   uuid.New().String(),
 		"sub":   user.ID.String(),
 		"email": user.Email,
 		"name":  user.Name,
+		"role":  user.Role,
+		"typ":   tokenType,
 		"iat":   time.Now().Unix(),
 		"exp":   time.Now().Add(expiry).Unix(),
 	}
+	for k, v := range extra {
+		claims[k] = v
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWTSecret))
+	token := jwt.NewWithClaims(s.keys.current.method, claims)
+	token.Header["kid"] = s.keys.current.id
+	return token.SignedString(s.keys.current.sign)
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func (s *Service) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+// ValidateToken validates a JWT token and returns the claims.
+// Tokens signed with the current or any previous key are accepted, picked by
+// their "kid" header; revoked tokens and tokens without a "typ" claim are
+// rejected, so an untyped token can't pass as either kind.
+func (s *Service) ValidateToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keys.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}))
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return &JWTClaims{
-			UserID: claims["sub"].(string),
-			Email:  claims["email"].(string),
-		}, nil
+		tokenType, _ := claims["typ"].(string)
+		if tokenType == "" {
+			return nil, fmt.Errorf("invalid token: missing token type")
+		}
+		jti, _ := claims["jti"].(string)
+		familyID, _ := claims["fam"].(string)
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = RoleUser
+		}
+		result := &JWTClaims{
+			UserID:    claims["sub"].(string),
+			Email:     claims["email"].(string),
+			TokenType: tokenType,
+			Role:      role,
+			ID:        jti,
+			FamilyID:  familyID,
+		}
+		if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+			result.IssuedAt = iat.Time
+		}
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			result.ExpiresAt = exp.Time
+		}
+
+		if err := s.checkRevoked(ctx, result); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
 	return nil, fmt.Errorf("invalid token claims")
 }
 
+// checkRevoked rejects blacklisted tokens. If the blacklist can't be reached
+// the token is let through, so a Redis outage doesn't sign everyone out.
+func (s *Service) checkRevoked(ctx context.Context, claims *JWTClaims) error {
+	if s.blacklist == nil {
+		return nil
+	}
+
+	revoked, err := s.blacklist.IsRevoked(ctx, claims)
+	if err != nil {
+		logger.Ctx(ctx).Warn().Err(err).Msg("Token blacklist unavailable, skipping revocation check")
+		return nil
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// Logout revokes the presented tokens. Either may be empty; tokens that are
+// already invalid need no revoking.
+func (s *Service) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	if refreshToken != "" {
+		if claims, err := s.ValidateRefreshToken(ctx, refreshToken); err == nil {
+			// End the refresh family even without Redis
+			if familyID, err := uuid.Parse(claims.FamilyID); err == nil {
+				if err := s.repo.RevokeFamily(ctx, familyID); err != nil {
+					return err
+				}
+				if err := s.revokeFamily(ctx, familyID); err != nil {
+					return err
+				}
+			}
+			if err := s.revoke(ctx, claims); err != nil {
+				return err
+			}
+		}
+	}
+
+	if accessToken != "" {
+		if claims, err := s.ValidateAccessToken(ctx, accessToken); err == nil {
+			if err := s.revoke(ctx, claims); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every token issued to a user, on all devices, and their
+// API keys. Refresh tokens and API keys are always revoked; access tokens only
+// when the blacklist is enabled, otherwise ErrRevocationUnavailable is returned.
+func (s *Service) LogoutAll(ctx context.Context, userID string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if err := s.repo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUserAPIKeys(ctx, id); err != nil {
+		return err
+	}
+
+	if s.blacklist == nil {
+		return ErrRevocationUnavailable
+	}
+
+	return s.blacklist.RevokeUser(ctx, userID, s.maxTokenTTL())
+}
+
+// revoke blacklists one token, if the blacklist is enabled
+func (s *Service) revoke(ctx context.Context, claims *JWTClaims) error {
+	if s.blacklist == nil {
+		return nil
+	}
+	return s.blacklist.Revoke(ctx, claims.ID, claims.ExpiresAt)
+}
+
+// revokeFamily blacklists the access tokens of a session whose refresh tokens
+// were revoked, until the last of them would have expired
+func (s *Service) revokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if s.blacklist == nil {
+		return nil
+	}
+	return s.blacklist.RevokeFamily(ctx, familyID.String(), time.Duration(s.config.JWTExpiryHours)*time.Hour)
+}
+
+// maxTokenTTL is the longest lifetime of any token this service issues
+func (s *Service) maxTokenTTL() time.Duration {
+	accessTTL := time.Duration(s.config.JWTExpiryHours) * time.Hour
+	if accessTTL > refreshTokenTTL {
+		return accessTTL
+	}
+	return refreshTokenTTL
+}
+
+// ValidateAccessToken validates a JWT and rejects anything that isn't an access
+// token, such as refresh tokens presented as access tokens
+func (s *Service) ValidateAccessToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims, err := s.ValidateToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeAccess {
+		return nil, fmt.Errorf("invalid token type: %s", claims.TokenType)
+	}
+
+	return claims, nil
+}
+
+// ValidateRefreshToken validates a JWT and rejects anything that isn't a refresh token
+func (s *Service) ValidateRefreshToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims, err := s.ValidateToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, fmt.Errorf("invalid token type: expected refresh token")
+	}
+
+	return claims, nil
+}
+
 // ==================== GitHub OAuth ====================
 
 // GetGitHubAuthURL returns the GitHub OAuth authorization URL
-func (s *Service) GetGitHubAuthURL(state string) string {
+func (s *Service) GetGitHubAuthURL(ctx context.Context, state string) (string, error) {
 	params := url.Values{
 		"client_id":    {s.config.GitHubClientID},
 		"redirect_uri": {s.config.GitHubRedirectURL},
 		"scope":        {"read:user user:email"},
 		"state":        {state},
 	}
+	challenge, err := s.pkceChallenge(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range challenge {
+		params[k] = v
+	}
 
-	return fmt.Sprintf("https://github.com/login/oauth/authorize?%s", params.Encode())
+	return fmt.Sprintf("https://github.com/login/oauth/authorize?%s", params.Encode()), nil
 }
 
-// ExchangeGitHubCode exchanges a GitHub authorization code for tokens and user info
-func (s *Service) ExchangeGitHubCode(ctx context.Context, code string) (*AuthResponse, error) {
+// ExchangeGitHubCode exchanges a GitHub authorization code for tokens and user info.
+// state is the login's OAuth state, which finds its PKCE verifier.
+func (s *Service) ExchangeGitHubCode(ctx context.Context, code, state string) (*AuthResponse, error) {
+	verifier, err := s.pkceVerifier(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
 	// Exchange code for access token
-	accessToken, err := s.getGitHubAccessToken(code)
+	accessToken, err := s.getGitHubAccessToken(code, verifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange github code: %w", err)
 	}
@@ -125,7 +397,7 @@ func (s *Service) ExchangeGitHubCode(ctx context.Context, code string) (*AuthRes
 	if githubUser.Email == "" {
 		email, err := s.getGitHubUserEmail(accessToken)
 		if err != nil {
-			logger.Warn().Err(err).Msg("Failed to get GitHub user email")
+			logger.Ctx(ctx).Warn().Err(err).Msg("Failed to get GitHub user email")
 		} else {
 			githubUser.Email = email
 		}
@@ -141,25 +413,20 @@ func (s *Service) ExchangeGitHubCode(ctx context.Context, code string) (*AuthRes
 		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
 
-	// Generate tokens
-	tokens, err := s.GenerateTokenPair(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
-	}
-
-	return &AuthResponse{
-		User:   user.ToResponse(),
-		Tokens: tokens,
-	}, nil
+	// Generate tokens, or a challenge if the user has 2FA
+	return s.startSession(ctx, user)
 }
 
-func (s *Service) getGitHubAccessToken(code string) (string, error) {
+func (s *Service) getGitHubAccessToken(code, verifier string) (string, error) {
 	data := url.Values{
 		"client_id":     {s.config.GitHubClientID},
 		"client_secret": {s.config.GitHubClientSecret},
 		"code":          {code},
 		"redirect_uri":  {s.config.GitHubRedirectURL},
 	}
+	if verifier != "" {
+		data.Set("code_verifier", verifier)
+	}
 
 	req, err := http.NewRequest("POST", "https://github.com/login/oauth/access_token", strings.NewReader(data.Encode()))
 	if err != nil {
@@ -289,18 +556,21 @@ func (s *Service) findOrCreateGitHubUser(ctx context.Context, githubUser *GitHub
 	}
 
 	// Create new user
+	if err := s.checkSignup(githubUser.Email); err != nil {
+		return nil, err
+	}
 	name := githubUser.Name
 	if name == "" {
 		name = githubUser.Login
 	}
 
-	return s.repo.Create(ctx, githubUser.Email, name, githubUser.AvatarURL, &githubID, nil)
+	return s.repo.Create(ctx, githubUser.Email, name, githubUser.AvatarURL, &githubID, nil, nil)
 }
 
 // ==================== Google OAuth ====================
 
 // GetGoogleAuthURL returns the Google OAuth authorization URL
-func (s *Service) GetGoogleAuthURL(state string) string {
+func (s *Service) GetGoogleAuthURL(ctx context.Context, state string) (string, error) {
 	params := url.Values{
 		"client_id":     {s.config.GoogleClientID},
 		"redirect_uri":  {s.config.GoogleRedirectURL},
@@ -309,14 +579,27 @@ func (s *Service) GetGoogleAuthURL(state string) string {
 		"state":         {state},
 		"access_type":   {"offline"},
 	}
+	challenge, err := s.pkceChallenge(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range challenge {
+		params[k] = v
+	}
 
-	return fmt.Sprintf("https://accounts.google.com/o/oauth2/v2/auth?%s", params.Encode())
+	return fmt.Sprintf("https://accounts.google.com/o/oauth2/v2/auth?%s", params.Encode()), nil
 }
 
-// ExchangeGoogleCode exchanges a Google authorization code for tokens and user info
-func (s *Service) ExchangeGoogleCode(ctx context.Context, code string) (*AuthResponse, error) {
+// ExchangeGoogleCode exchanges a Google authorization code for tokens and user info.
+// state is the login's OAuth state, which finds its PKCE verifier.
+func (s *Service) ExchangeGoogleCode(ctx context.Context, code, state string) (*AuthResponse, error) {
+	verifier, err := s.pkceVerifier(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
 	// Exchange code for access token
-	accessToken, err := s.getGoogleAccessToken(code)
+	accessToken, err := s.getGoogleAccessToken(code, verifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange google code: %w", err)
 	}
@@ -337,19 +620,11 @@ func (s *Service) ExchangeGoogleCode(ctx context.Context, code string) (*AuthRes
 		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
 
-	// Generate tokens
-	tokens, err := s.GenerateTokenPair(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
-	}
-
-	return &AuthResponse{
-		User:   user.ToResponse(),
-		Tokens: tokens,
-	}, nil
+	// Generate tokens, or a challenge if the user has 2FA
+	return s.startSession(ctx, user)
 }
 
-func (s *Service) getGoogleAccessToken(code string) (string, error) {
+func (s *Service) getGoogleAccessToken(code, verifier string) (string, error) {
 	data := url.Values{
 		"client_id":     {s.config.GoogleClientID},
 		"client_secret": {s.config.GoogleClientSecret},
@@ -357,6 +632,9 @@ func (s *Service) getGoogleAccessToken(code string) (string, error) {
 		"redirect_uri":  {s.config.GoogleRedirectURL},
 		"grant_type":    {"authorization_code"},
 	}
+	if verifier != "" {
+		data.Set("code_verifier", verifier)
+	}
 
 	resp, err := http.PostForm("https://oauth2.googleapis.com/token", data)
 	if err != nil {
@@ -433,7 +711,187 @@ func (s *Service) findOrCreateGoogleUser(ctx context.Context, googleUser *Google
 	}
 
 	// Create new user
-	return s.repo.Create(ctx, googleUser.Email, googleUser.Name, googleUser.Picture, nil, &googleUser.ID)
+	if err := s.checkSignup(googleUser.Email); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, googleUser.Email, googleUser.Name, googleUser.Picture, nil, &googleUser.ID, nil)
+}
+
+// ==================== Microsoft OAuth ====================
+
+// GetMicrosoftAuthURL returns the Microsoft identity platform authorization URL
+func (s *Service) GetMicrosoftAuthURL(ctx context.Context, state string) (string, error) {
+	params := url.Values{
+		"client_id":     {s.config.MicrosoftClientID},
+		"redirect_uri":  {s.config.MicrosoftRedirectURL},
+		"response_type": {"code"},
+		"response_mode": {"query"},
+		"scope":         {"openid email profile User.Read"},
+		"state":         {state},
+	}
+	challenge, err := s.pkceChallenge(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range challenge {
+		params[k] = v
+	}
+
+	return fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/authorize?%s", url.PathEscape(s.config.MicrosoftTenant), params.Encode()), nil
+}
+
+// ExchangeMicrosoftCode exchanges a Microsoft authorization code for tokens and user info.
+// state is the login's OAuth state, which finds its PKCE verifier.
+func (s *Service) ExchangeMicrosoftCode(ctx context.Context, code, state string) (*AuthResponse, error) {
+	verifier, err := s.pkceVerifier(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exchange code for access token
+	accessToken, err := s.getMicrosoftAccessToken(code, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange microsoft code: %w", err)
+	}
+
+	// Get user info from Microsoft Graph
+	microsoftUser, err := s.getMicrosoftUserInfo(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get microsoft user info: %w", err)
+	}
+
+	if microsoftUser.Mail == "" {
+		return nil, fmt.Errorf("microsoft account does not have an email")
+	}
+
+	// Find or create user
+	user, err := s.findOrCreateMicrosoftUser(ctx, microsoftUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find or create user: %w", err)
+	}
+
+	// Generate tokens, or a challenge if the user has 2FA
+	return s.startSession(ctx, user)
+}
+
+func (s *Service) getMicrosoftAccessToken(code, verifier string) (string, error) {
+	data := url.Values{
+		"client_id":     {s.config.MicrosoftClientID},
+		"client_secret": {s.config.MicrosoftClientSecret},
+		"code":          {code},
+		"redirect_uri":  {s.config.MicrosoftRedirectURL},
+		"grant_type":    {"authorization_code"},
+		"scope":         {"openid email profile User.Read"},
+	}
+	if verifier != "" {
+		data.Set("code_verifier", verifier)
+	}
+
+	tokenURL := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(s.config.MicrosoftTenant))
+	resp, err := http.PostForm(tokenURL, data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		ErrorDesc   string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	if result.Error != "" {
+		return "", fmt.Errorf("%s: %s", result.Error, result.ErrorDesc)
+	}
+
+	return result.AccessToken, nil
+}
+
+func (s *Service) getMicrosoftUserInfo(accessToken string) (*MicrosoftUserInfo, error) {
+	req, err := http.NewRequest("GET", "https://graph.microsoft.com/v1.0/me?$select=id,displayName,mail,userPrincipalName", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("microsoft graph error: %s", string(body))
+	}
+
+	var user MicrosoftUserInfo
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	// Personal accounts have no mailbox address; their sign-in name is their email
+	if user.Mail == "" && strings.Contains(user.UserPrincipalName, "@") && !strings.Contains(user.UserPrincipalName, "#EXT#") {
+		user.Mail = user.UserPrincipalName
+	}
+
+	return &user, nil
+}
+
+func (s *Service) findOrCreateMicrosoftUser(ctx context.Context, microsoftUser *MicrosoftUserInfo) (*User, error) {
+	// Try to find by Microsoft ID
+	user, err := s.repo.FindByMicrosoftID(ctx, microsoftUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	// Try to find by email and link Microsoft account
+	user, err = s.repo.FindByEmail(ctx, microsoftUser.Mail)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// Link Microsoft account to existing user
+		if err := s.repo.UpdateMicrosoftID(ctx, user.ID, microsoftUser.ID); err != nil {
+			return nil, err
+		}
+		user.MicrosoftID = &microsoftUser.ID
+		return user, nil
+	}
+
+	// Create new user
+	if err := s.checkSignup(microsoftUser.Mail); err != nil {
+		return nil, err
+	}
+	name := microsoftUser.DisplayName
+	if name == "" {
+		name = microsoftUser.Mail
+	}
+
+	// Graph only serves profile photos as binary data, so there's no avatar URL
+	return s.repo.Create(ctx, microsoftUser.Mail, name, "", nil, nil, &microsoftUser.ID)
+}
+
+// checkSignup rejects creating an account for email while signups are
+// disabled, unless the email has been invited
+func (s *Service) checkSignup(email string) error {
+	if s.config.SignupEnabled {
+		return nil
+	}
+	for _, invited := range s.config.SignupAllowedEmails {
+		if strings.EqualFold(strings.TrimSpace(invited), email) {
+			return nil
+		}
+	}
+	return ErrSignupDisabled
 }
 
 // ==================== User Methods ====================
@@ -448,13 +906,166 @@ func (s *Service) GetUserByID(ctx context.Context, userID string) (*User, error)
 	return s.repo.FindByID(ctx, id)
 }
 
+// GetLinkedProviders returns the OAuth providers linked to a user, or nil if the user doesn't exist
+func (s *Service) GetLinkedProviders(ctx context.Context, userID string) ([]string, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return user.LinkedProviders(), nil
+}
+
+// UnlinkProvider removes an OAuth provider from a user's account. Users have
+// no password, so the last remaining provider can't be unlinked.
+func (s *Service) UnlinkProvider(ctx context.Context, userID, provider string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+
+	providers, err := s.GetLinkedProviders(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if providers == nil {
+		return ErrUserNotFound
+	}
+
+	linked := false
+	for _, p := range providers {
+		linked = linked || p == provider
+	}
+	if !linked {
+		return ErrProviderNotLinked
+	}
+	if len(providers) == 1 {
+		return ErrLastProvider
+	}
+
+	if provider == ProviderGitHub {
+		return s.repo.UnlinkGitHub(ctx, id)
+	}
+	if provider == ProviderMicrosoft {
+		return s.repo.UnlinkMicrosoft(ctx, id)
+	}
+	return s.repo.UnlinkGoogle(ctx, id)
+}
+
+// maxNameLength is the longest display name the users table holds
+const maxNameLength = 255
+
+// UpdateProfile updates the current user's display name and avatar and returns the updated user
+func (s *Service) UpdateProfile(ctx context.Context, userID string, req *UpdateProfileRequest) (*User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	// A name made only of whitespace is rejected rather than treated as omitted
+	name := strings.TrimSpace(req.Name)
+	if req.Name != "" && (name == "" || utf8.RuneCountInString(name) > maxNameLength) {
+		return nil, ErrInvalidName
+	}
+
+	avatarURL := strings.TrimSpace(req.AvatarURL)
+	if avatarURL != "" {
+		parsed, err := url.Parse(avatarURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, ErrInvalidAvatarURL
+		}
+	}
+
+	if err := s.repo.UpdateProfile(ctx, id, name, avatarURL); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindByID(ctx, id)
+}
+
+// DeleteAccount permanently deletes a user with their projects and whiteboards,
+// then revokes their outstanding tokens
+func (s *Service) DeleteAccount(ctx context.Context, userID string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+
+	projectIDs, err := s.repo.DeleteUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, projectID := range projectIDs {
+		for _, fn := range s.onProjectDelete {
+			fn(ctx, projectID)
+		}
+	}
+
+	// Refresh tokens went with the user row; access tokens need the blacklist
+	if s.blacklist != nil {
+		if err := s.blacklist.RevokeUser(ctx, userID, s.maxTokenTTL()); err != nil {
+			logger.Ctx(ctx).Warn().Err(err).Str("user_id", userID).Msg("Failed to revoke tokens of deleted account")
+		}
+	}
+
+	return nil
+}
+
+// ExportUserData assembles everything stored about a user
+func (s *Service) ExportUserData(ctx context.Context, userID string) (*DataExport, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	return s.repo.ExportUserData(ctx, id)
+}
+
+// consumeRefreshToken uses up a refresh token and returns the family its
+// replacement belongs to. Presenting an already-used token revokes the family,
+// since either the legitimate client or an attacker holds a copy.
+func (s *Service) consumeRefreshToken(ctx context.Context, claims *JWTClaims) (uuid.UUID, error) {
+	// Tokens issued before rotation existed carry no ID; they can be exchanged
+	// (until they expire) and start a new family
+	if claims.ID == "" {
+		return uuid.New(), nil
+	}
+
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, ErrRefreshTokenInvalid
+	}
+
+	familyID, err := s.repo.ConsumeRefreshToken(ctx, jti)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		logger.Ctx(ctx).Warn().Str("user_id", claims.UserID).Str("family_id", familyID.String()).Msg("Refresh token reuse detected, revoking family")
+		if revokeErr := s.repo.RevokeFamily(ctx, familyID); revokeErr != nil {
+			return uuid.Nil, revokeErr
+		}
+		if revokeErr := s.revokeFamily(ctx, familyID); revokeErr != nil {
+			return uuid.Nil, revokeErr
+		}
+		return uuid.Nil, err
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return familyID, nil
+}
+
 // RefreshTokens generates new tokens from a valid refresh token
 func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*AuthResponse, error) {
-	claims, err := s.ValidateToken(refreshToken)
+	claims, err := s.ValidateRefreshToken(ctx, refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
+	familyID, err := s.consumeRefreshToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.GetUserByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -463,7 +1074,7 @@ func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*Auth
 		return nil, fmt.Errorf("user not found")
 	}
 
-	tokens, err := s.GenerateTokenPair(user)
+	tokens, err := s.generateTokenPair(ctx, user, familyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
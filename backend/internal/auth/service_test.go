@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+)
+
+func newTokenTestService() *Service {
+	return NewService(nil, &config.Config{JWTSecret: "test-secret", JWTExpiryHours: 1})
+}
+
+func tokenTestUser() *User {
+	return &User{ID: uuid.New(), Email: "ada@example.com", Name: "Ada", Role: RoleUser}
+}
+
+// untypedToken signs a token the way tokens were issued before the "typ" claim
+func untypedToken(t *testing.T, s *Service, user *User) string {
+	t.Helper()
+	token := jwt.NewWithClaims(s.keys.current.method, jwt.MapClaims{
+		"sub":   user.ID.String(),
+		"email": user.Email,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = s.keys.current.id
+	signed, err := token.SignedString(s.keys.current.sign)
+	if err != nil {
+		t.Fatalf("sign untyped token: %v", err)
+	}
+	return signed
+}
+
+func TestTokenTypesCantBeCrossUsed(t *testing.T) {
+	ctx := context.Background()
+	s := newTokenTestService()
+	user := tokenTestUser()
+
+	family := jwt.MapClaims{"fam": uuid.New().String()}
+	accessToken, err := s.generateToken(user, TokenTypeAccess, time.Hour, family)
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	refreshToken, err := s.generateToken(user, TokenTypeRefresh, time.Hour, family)
+	if err != nil {
+		t.Fatalf("generate refresh token: %v", err)
+	}
+	challenge, err := s.generateToken(user, TokenTypeTwoFactor, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("generate challenge token: %v", err)
+	}
+
+	if _, err := s.ValidateAccessToken(ctx, accessToken); err != nil {
+		t.Errorf("access token rejected as access token: %v", err)
+	}
+	if _, err := s.ValidateRefreshToken(ctx, refreshToken); err != nil {
+		t.Errorf("refresh token rejected as refresh token: %v", err)
+	}
+
+	if _, err := s.ValidateRefreshToken(ctx, accessToken); err == nil {
+		t.Error("access token accepted as refresh token")
+	}
+	if _, err := s.ValidateAccessToken(ctx, refreshToken); err == nil {
+		t.Error("refresh token accepted as access token")
+	}
+	if _, err := s.ValidateAccessToken(ctx, challenge); err == nil {
+		t.Error("2fa challenge token accepted as access token")
+	}
+	if _, err := s.ValidateRefreshToken(ctx, challenge); err == nil {
+		t.Error("2fa challenge token accepted as refresh token")
+	}
+}
+
+func TestUntypedTokensAreRejected(t *testing.T) {
+	ctx := context.Background()
+	s := newTokenTestService()
+	token := untypedToken(t, s, tokenTestUser())
+
+	if _, err := s.ValidateToken(ctx, token); err == nil {
+		t.Error("untyped token accepted by ValidateToken")
+	}
+	if _, err := s.ValidateAccessToken(ctx, token); err == nil {
+		t.Error("untyped token accepted as access token")
+	}
+	if _, err := s.ValidateRefreshToken(ctx, token); err == nil {
+		t.Error("untyped token accepted as refresh token")
+	}
+}
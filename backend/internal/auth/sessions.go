@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// A session is one login: the family of refresh tokens rotated from it. Its
+// ID is the family ID, which access tokens carry too so the session making a
+// request can be recognized.
+
+// ErrSessionNotFound is returned for sessions that don't exist, have ended
+// or belong to someone else
+var ErrSessionNotFound = errors.New("session not found")
+
+// maxUserAgentLength caps the stored User-Agent header
+const maxUserAgentLength = 512
+
+// Session is an active login of the current user
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Current is set for the session the request was made with
+	Current bool `json:"current"`
+}
+
+// sessionClient is the client a refresh token is issued to
+type sessionClient struct {
+	userAgent string
+	ip        string
+}
+
+type sessionClientKey struct{}
+
+// withClient returns the request context carrying its client details, for
+// the refresh tokens issued while handling it
+func withClient(c *fiber.Ctx) context.Context {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return context.WithValue(c.Context(), sessionClientKey{}, sessionClient{
+		userAgent: userAgent,
+		ip:        c.IP(),
+	})
+}
+
+// clientFrom returns the client details attached by withClient, if any
+func clientFrom(ctx context.Context) sessionClient {
+	client, _ := ctx.Value(sessionClientKey{}).(sessionClient)
+	return client
+}
+
+// ==================== Repository ====================
+
+// ListSessions returns a user's sessions that still have a usable refresh
+// token, most recently used first
+func (r *Repository) ListSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT family_id,
+			COALESCE((array_agg(user_agent ORDER BY created_at DESC))[1], ''),
+			COALESCE((array_agg(ip_address ORDER BY created_at DESC))[1], ''),
+			MIN(created_at),
+			MAX(created_at)
+		FROM refresh_tokens
+		WHERE user_id = $1
+		GROUP BY family_id
+		HAVING bool_or(consumed_at IS NULL AND revoked_at IS NULL AND expires_at > NOW())
+		ORDER BY MAX(created_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		var familyID uuid.UUID
+		if err := rows.Scan(&familyID, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.ID = familyID.String()
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes the usable refresh token of one of a user's sessions.
+// It returns ErrSessionNotFound if the user has no such active session.
+func (r *Repository) RevokeSession(ctx context.Context, userID, familyID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND user_id = $2
+			AND consumed_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+	`, familyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// ==================== Service ====================
+
+// ListSessions returns the user's active sessions, flagging currentID
+func (s *Service) ListSessions(ctx context.Context, userID, currentID string) ([]Session, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	sessions, err := s.repo.ListSessions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions: it can no longer be
+// refreshed, and its access tokens are rejected when Redis is configured
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	familyID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := s.repo.RevokeSession(ctx, id, familyID); err != nil {
+		return err
+	}
+
+	return s.revokeFamily(ctx, familyID)
+}
@@ -0,0 +1,526 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Two-factor authentication adds a TOTP code (or a single-use backup code)
+// to logins. Logins are OAuth only, so it's the provider login that gets the
+// second step: the callback hands out a short-lived challenge token instead
+// of a session, and the client trades it plus a code for the token pair.
+// Unlike email verification, which waits for password signup because
+// providers already verify emails, a second factor still protects OAuth
+// accounts whose provider login is compromised.
+
+// TokenTypeTwoFactor is the "typ" of challenge tokens. They are rejected as
+// access and refresh tokens.
+const TokenTypeTwoFactor = "2fa_challenge"
+
+// twoFactorChallengeTTL is how long a login has to submit its code
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// totpIssuer names the account in authenticator apps
+const totpIssuer = "SysDes"
+
+// Errors returned for two-factor authentication
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp    = errors.New("start two-factor setup first")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("login challenge is invalid or has expired")
+)
+
+// TwoFactorCodeRequest is the request body for actions confirmed with a code
+type TwoFactorCodeRequest struct {
+	// Code is a code from the authenticator app or, where accepted, a backup code
+	Code string `json:"code"`
+}
+
+// TwoFactorLoginRequest is the request body for finishing a login with 2FA
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorSetupResponse is the secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// OTPAuthURI is the otpauth:// URI to show as a QR code
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus reports whether 2FA is on and how many backup codes are left
+type TwoFactorStatus struct {
+	Enabled              bool `json:"enabled"`
+	BackupCodesRemaining int  `json:"backup_codes_remaining"`
+}
+
+// twoFactorState is a user's stored 2FA data
+type twoFactorState struct {
+	secret   *string
+	enabled  bool
+	lastStep int64
+}
+
+// ==================== Repository ====================
+
+// findTwoFactor returns a user's 2FA data, or nil if the user doesn't exist
+func (r *Repository) findTwoFactor(ctx context.Context, userID uuid.UUID) (*twoFactorState, error) {
+	var state twoFactorState
+	err := r.db.QueryRow(ctx, `
+		SELECT totp_secret, is_2fa_enabled, totp_last_step
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&state.secret, &state.enabled, &state.lastStep)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find two-factor state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// SetTOTPSecret stores the secret of a pending enrollment, replacing any
+// earlier one. It fails with ErrTwoFactorEnabled once 2FA is on.
+func (r *Repository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = $1, totp_last_step = 0, updated_at = NOW()
+		WHERE id = $2 AND NOT is_2fa_enabled
+	`, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// EnableTwoFactor turns 2FA on with a fresh set of backup codes. step is the
+// time step of the code that confirmed the enrollment.
+func (r *Repository) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET is_2fa_enabled = TRUE, totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1 AND totp_secret IS NOT NULL AND NOT is_2fa_enabled
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DisableTwoFactor turns 2FA off and deletes the secret and backup codes
+func (r *Repository) DisableTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = NULL, is_2fa_enabled = FALSE, totp_last_step = 0, updated_at = NOW()
+		WHERE id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete backup codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AdvanceTOTPStep records step as the last accepted one. It returns false if
+// a code from this or a later step was already accepted, i.e. a replay.
+func (r *Repository) AdvanceTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND totp_last_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UseBackupCode marks an unused backup code as used, returning false if the
+// user has no such code
+func (r *Repository) UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_backup_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use backup code: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ReplaceBackupCodes swaps a user's backup codes for new ones
+func (r *Repository) ReplaceBackupCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CountBackupCodes returns how many of a user's backup codes are unused
+func (r *Repository) CountBackupCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_backup_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count backup codes: %w", err)
+	}
+
+	return count, nil
+}
+
+func replaceBackupCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete backup codes: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_backup_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`, userID, codeHashes); err != nil {
+		return fmt.Errorf("failed to store backup codes: %w", err)
+	}
+
+	return nil
+}
+
+// ==================== Service ====================
+
+// GetTwoFactorStatus reports a user's 2FA status
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	id, state, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{Enabled: state.enabled}
+	if state.enabled {
+		status.BackupCodesRemaining, err = s.repo.CountBackupCodes(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// SetupTwoFactor starts enrolling a user: it creates a secret for their
+// authenticator app, which takes effect once EnableTwoFactor confirms a code
+func (s *Service) SetupTwoFactor(ctx context.Context, userID string) (*TwoFactorSetupResponse, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTOTPSecret(ctx, id, secret); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totpURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor confirms an enrollment with a code from the authenticator
+// app and returns the user's backup codes, which are only shown this once
+func (s *Service) EnableTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	id, state, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if state.secret == nil {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	step, ok := verifyTOTP(*state.secret, code, time.Now(), state.lastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTwoFactor(ctx, id, step, hashBackupCodes(codes)); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off, confirmed with an authenticator or backup code
+func (s *Service) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	id, state, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !state.enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.checkSecondFactor(ctx, id, state, code, true); err != nil {
+		return err
+	}
+
+	return s.repo.DisableTwoFactor(ctx, id)
+}
+
+// RegenerateBackupCodes replaces a user's backup codes, confirmed with an
+// authenticator code (not a backup code, which would be replaced anyway)
+func (s *Service) RegenerateBackupCodes(ctx context.Context, userID, code string) ([]string, error) {
+	id, state, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !state.enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if err := s.checkSecondFactor(ctx, id, state, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceBackupCodes(ctx, id, hashBackupCodes(codes)); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CompleteTwoFactorLogin finishes a login that was challenged for a second
+// factor. Each challenge can only be completed once, and allows
+// maxChallengeAttempts wrong codes before the login has to start again.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string) (*AuthResponse, error) {
+	claims, err := s.ValidateToken(ctx, challengeToken)
+	if err != nil || claims.TokenType != TokenTypeTwoFactor {
+		return nil, ErrInvalidChallenge
+	}
+
+	failures, err := s.attempts.Failures(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if failures >= maxChallengeAttempts {
+		return nil, ErrTooManyAttempts
+	}
+
+	id, state, err := s.twoFactorState(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	// 2FA may have been turned off since the challenge was issued
+	if state.enabled {
+		if err := s.checkSecondFactor(ctx, id, state, code, true); err != nil {
+			if errors.Is(err, ErrInvalidTwoFactorCode) {
+				return nil, s.challengeFailed(ctx, claims)
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.revoke(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	tokens, err := s.GenerateTokenPair(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	return &AuthResponse{
+		User:   user.ToResponse(),
+		Tokens: tokens,
+	}, nil
+}
+
+// challengeFailed records a wrong code for a challenge. The last allowed one
+// also revokes the challenge and returns ErrTooManyAttempts.
+func (s *Service) challengeFailed(ctx context.Context, claims *JWTClaims) error {
+	failures, err := s.attempts.AddFailure(ctx, claims.ID, claims.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if failures < maxChallengeAttempts {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.revoke(ctx, claims); err != nil {
+		return err
+	}
+	return ErrTooManyAttempts
+}
+
+// startSession finishes a provider login: users with 2FA get a challenge
+// token to complete with a code, everyone else gets their token pair
+func (s *Service) startSession(ctx context.Context, user *User) (*AuthResponse, error) {
+	if user.TwoFactorEnabled {
+		challenge, err := s.generateToken(user, TokenTypeTwoFactor, twoFactorChallengeTTL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate login challenge: %w", err)
+		}
+		return &AuthResponse{
+			User:           user.ToResponse(),
+			ChallengeToken: challenge,
+		}, nil
+	}
+
+	tokens, err := s.GenerateTokenPair(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	return &AuthResponse{
+		User:   user.ToResponse(),
+		Tokens: tokens,
+	}, nil
+}
+
+// checkSecondFactor accepts an authenticator code not used before or, if
+// allowBackup, an unused backup code, which is used up
+func (s *Service) checkSecondFactor(ctx context.Context, userID uuid.UUID, state *twoFactorState, code string, allowBackup bool) error {
+	if state.secret != nil {
+		if step, ok := verifyTOTP(*state.secret, code, time.Now(), state.lastStep); ok {
+			advanced, err := s.repo.AdvanceTOTPStep(ctx, userID, step)
+			if err != nil {
+				return err
+			}
+			if !advanced {
+				return ErrInvalidTwoFactorCode
+			}
+			return nil
+		}
+	}
+
+	if allowBackup && code != "" {
+		used, err := s.repo.UseBackupCode(ctx, userID, hashBackupCode(code))
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// twoFactorState loads a user's 2FA data
+func (s *Service) twoFactorState(ctx context.Context, userID string) (uuid.UUID, *twoFactorState, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	state, err := s.repo.findTwoFactor(ctx, id)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	if state == nil {
+		return uuid.Nil, nil, ErrUserNotFound
+	}
+
+	return id, state, nil
+}
+
+func hashBackupCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashBackupCode(code)
+	}
+	return hashes
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChallengeAllowsLimitedAttempts(t *testing.T) {
+	ctx := context.Background()
+	s := newTokenTestService()
+
+	challenge, err := s.generateToken(tokenTestUser(), TokenTypeTwoFactor, twoFactorChallengeTTL, nil)
+	if err != nil {
+		t.Fatalf("generate challenge token: %v", err)
+	}
+	claims, err := s.ValidateToken(ctx, challenge)
+	if err != nil {
+		t.Fatalf("validate challenge token: %v", err)
+	}
+
+	for i := 1; i < maxChallengeAttempts; i++ {
+		if err := s.challengeFailed(ctx, claims); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("wrong code %d: error = %v, want ErrInvalidTwoFactorCode", i, err)
+		}
+	}
+	if err := s.challengeFailed(ctx, claims); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("wrong code %d: error = %v, want ErrTooManyAttempts", maxChallengeAttempts, err)
+	}
+
+	// A spent challenge is refused before its code is checked
+	if _, err := s.CompleteTwoFactorLogin(ctx, challenge, "123456"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("CompleteTwoFactorLogin with a spent challenge: error = %v, want ErrTooManyAttempts", err)
+	}
+}
+
+func TestChallengeAttemptsAreCountedPerChallenge(t *testing.T) {
+	ctx := context.Background()
+	attempts := newMemoryAttempts()
+	expiresAt := time.Now().Add(time.Minute)
+
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if _, err := attempts.AddFailure(ctx, "first", expiresAt); err != nil {
+			t.Fatalf("AddFailure: %v", err)
+		}
+	}
+
+	if got, _ := attempts.Failures(ctx, "first"); got != maxChallengeAttempts {
+		t.Errorf("Failures(first) = %d, want %d", got, maxChallengeAttempts)
+	}
+	if got, _ := attempts.Failures(ctx, "second"); got != 0 {
+		t.Errorf("Failures(second) = %d, want 0", got)
+	}
+}
+
+func TestChallengeAttemptsExpire(t *testing.T) {
+	ctx := context.Background()
+	attempts := newMemoryAttempts()
+
+	if _, err := attempts.AddFailure(ctx, "expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("AddFailure: %v", err)
+	}
+	if got, _ := attempts.Failures(ctx, "expired"); got != 0 {
+		t.Errorf("Failures after expiry = %d, want 0", got)
+	}
+}
@@ -0,0 +1,106 @@
+package preview
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+)
+
+// Handler handles HTTP requests for public project previews
+type Handler struct {
+	service *Service
+	config  *config.Config
+}
+
+// NewHandler creates a new preview handler
+func NewHandler(service *Service, cfg *config.Config) *Handler {
+	return &Handler{
+		service: service,
+		config:  cfg,
+	}
+}
+
+// RegisterRoutes registers the preview routes (all public).
+// renderLimit caps concurrent image renders per client.
+func (h *Handler) RegisterRoutes(api fiber.Router, renderLimit fiber.Handler) {
+	api.Get("/public/projects/:slug/og-image.png", renderLimit, h.Image)
+	api.Get("/public/projects/:slug/og", h.Meta)
+}
+
+// Image handles GET /api/v1/public/projects/:slug/og-image.png
+// @Summary Get the OpenGraph preview image for a public project
+// @Tags preview
+// @Param slug path string true "Project slug"
+// @Success 200 {file} png
+// @Router /public/projects/{slug}/og-image.png [get]
+func (h *Handler) Image(c *fiber.Ctx) error {
+	card, err := h.service.GetCard(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get project",
+		})
+	}
+
+	etag := fmt.Sprintf(`"%x"`, card.UpdatedAt.UnixNano())
+	c.Set(fiber.HeaderCacheControl, "public, max-age=86400, stale-while-revalidate=604800")
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	png, err := h.service.RenderImage(c.Context(), card)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to render preview",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(png)
+}
+
+// Meta handles GET /api/v1/public/projects/:slug/og
+// @Summary Get OpenGraph meta tags for a public project
+// @Tags preview
+// @Param slug path string true "Project slug"
+// @Success 200 {object} MetaResponse
+// @Router /public/projects/{slug}/og [get]
+func (h *Handler) Meta(c *fiber.Ctx) error {
+	card, err := h.service.GetCard(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get project",
+		})
+	}
+
+	description := card.Description
+	if description == "" {
+		description = "A system design by " + card.OwnerName + " on SysDes"
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.JSON(MetaResponse{
+		Title:       card.Name,
+		Description: description,
+		Image:       c.BaseURL() + "/api/v1/public/projects/" + card.Slug + "/og-image.png",
+		ImageWidth:  render.CardWidth,
+		ImageHeight: render.CardHeight,
+		URL:         h.config.FrontendURL + "/public/" + card.Slug,
+		SiteName:    "SysDes",
+		Type:        "website",
+	})
+}
@@ -0,0 +1,32 @@
+package preview
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Card is the data needed to render a public project's preview card. The
+// default whiteboard's canvas is loaded separately, only when the card image
+// isn't cached.
+type Card struct {
+	Slug        string
+	Name        string
+	Description string
+	OwnerName   string
+	// DefaultWhiteboardID is nil for projects without whiteboards
+	DefaultWhiteboardID *uuid.UUID
+	UpdatedAt           time.Time
+}
+
+// MetaResponse carries the OpenGraph tags a frontend should emit for a public project
+type MetaResponse struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"image_width"`
+	ImageHeight int    `json:"image_height"`
+	URL         string `json:"url"`
+	SiteName    string `json:"site_name"`
+	Type        string `json:"type"`
+}
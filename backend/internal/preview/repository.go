@@ -0,0 +1,82 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+)
+
+// Repository handles database operations for preview cards
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new preview repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// FindCardBySlug loads a public project with its owner and default whiteboard,
+// without the whiteboard's canvas
+func (r *Repository) FindCardBySlug(ctx context.Context, slug string) (*Card, error) {
+	query := `
+		SELECT p.public_slug, p.name, COALESCE(p.description, ''), u.name,
+			w.id, GREATEST(p.updated_at, COALESCE(w.updated_at, p.updated_at))
+		FROM projects p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN LATERAL (
+			SELECT id, updated_at
+			FROM whiteboards
+			WHERE project_id = p.id AND deleted_at IS NULL
+			ORDER BY created_at ASC
+			LIMIT 1
+		) w ON true
+		WHERE p.public_slug = $1 AND p.is_public = true AND p.archived_at IS NULL
+	`
+
+	var card Card
+	err := r.db.QueryRow(ctx, query, slug).Scan(
+		&card.Slug,
+		&card.Name,
+		&card.Description,
+		&card.OwnerName,
+		&card.DefaultWhiteboardID,
+		&card.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find preview card by slug: %w", err)
+	}
+
+	return &card, nil
+}
+
+// FindCanvas loads a whiteboard's canvas data, or nil if the whiteboard is gone
+func (r *Repository) FindCanvas(ctx context.Context, whiteboardID uuid.UUID) (json.RawMessage, error) {
+	query := `SELECT data, data_compressed, data_encoding FROM whiteboards WHERE id = $1 AND deleted_at IS NULL`
+
+	var stored compress.Stored
+	err := r.db.QueryRow(ctx, query, whiteboardID).Scan(&stored.Plain, &stored.Compressed, &stored.Encoding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find preview canvas: %w", err)
+	}
+
+	data, err := stored.Unpack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preview canvas: %w", err)
+	}
+	return data, nil
+}
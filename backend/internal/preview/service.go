@@ -0,0 +1,88 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/lru"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+)
+
+// ErrProjectNotFound is returned when no public project has the requested slug
+var ErrProjectNotFound = errors.New("project not found")
+
+// maxCachedCards bounds the in-memory image cache; the least recently used
+// cards are evicted first
+const maxCachedCards = 512
+
+// cardCacheTTL drops cards that stay cached but unrequested. Changed projects
+// are re-rendered regardless, since entries are checked against UpdatedAt.
+const cardCacheTTL = 24 * time.Hour
+
+type cachedImage struct {
+	updatedAt time.Time
+	png       []byte
+}
+
+// Service renders and caches preview cards for public projects
+type Service struct {
+	repo  *Repository
+	cache *lru.Cache[string, cachedImage]
+}
+
+// NewService creates a new preview service
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo:  repo,
+		cache: lru.New[string, cachedImage](maxCachedCards, cardCacheTTL),
+	}
+}
+
+// GetCard returns the card data for a public project
+func (s *Service) GetCard(ctx context.Context, slug string) (*Card, error) {
+	card, err := s.repo.FindCardBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preview card: %w", err)
+	}
+	if card == nil {
+		return nil, ErrProjectNotFound
+	}
+	return card, nil
+}
+
+// RenderImage returns the PNG preview card for a public project.
+// Images are cached until the project or its default whiteboard changes.
+func (s *Service) RenderImage(ctx context.Context, card *Card) ([]byte, error) {
+	if cached, ok := s.cache.Get(card.Slug); ok && cached.updatedAt.Equal(card.UpdatedAt) {
+		return cached.png, nil
+	}
+
+	var canvas struct {
+		Shapes []map[string]interface{} `json:"shapes"`
+	}
+	if card.DefaultWhiteboardID != nil {
+		data, err := s.repo.FindCanvas(ctx, *card.DefaultWhiteboardID)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			// A corrupt canvas still gets a card, just without a thumbnail
+			_ = json.Unmarshal(data, &canvas)
+		}
+	}
+
+	thumbnail := render.Canvas(canvas.Shapes, 536, 282)
+	img := render.Card(card.Name, "by "+card.OwnerName, thumbnail)
+
+	png, err := render.EncodePNG(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode preview card: %w", err)
+	}
+
+	s.cache.Add(card.Slug, cachedImage{updatedAt: card.UpdatedAt, png: png})
+
+	return png, nil
+}
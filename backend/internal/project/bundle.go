@@ -0,0 +1,242 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+)
+
+// BundleVersion is the project bundle format written by ExportProject.
+// Imports accept this version only.
+const BundleVersion = 1
+
+// maxBundleWhiteboards bounds the whiteboards a single import may create
+const maxBundleWhiteboards = 500
+
+// ProjectBundle is a portable copy of a project and its whiteboards. It
+// carries no IDs, slugs or sharing settings; importing always creates a new
+// private project.
+type ProjectBundle struct {
+	Version     int                 `json:"version"`
+	ExportedAt  time.Time           `json:"exported_at"`
+	Project     BundleProject       `json:"project"`
+	Whiteboards []*BundleWhiteboard `json:"whiteboards"`
+}
+
+// BundleProject is the project part of a bundle
+type BundleProject struct {
+	Name                  string `json:"name"`
+	Description           string `json:"description"`
+	UniqueWhiteboardNames bool   `json:"unique_whiteboard_names"`
+}
+
+// BundleWhiteboard is a whiteboard with its canvas data, in the project's order
+type BundleWhiteboard struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// CanvasPreparer validates and normalizes a new whiteboard's canvas the way a
+// save does, returning the data to store and its content hash
+type CanvasPreparer func(data json.RawMessage) (json.RawMessage, string, error)
+
+// errNoCanvasPreparer is returned by imports when no CanvasPreparer is set
+var errNoCanvasPreparer = errors.New("imported canvases can't be validated")
+
+// BundleError is returned for bundles that can't be imported
+type BundleError struct {
+	Reason string
+}
+
+func (e *BundleError) Error() string {
+	return "invalid project bundle: " + e.Reason
+}
+
+// ==================== Repository ====================
+
+// FindBundleWhiteboards returns a project's whiteboards in tab order, leaving out its trash
+func (r *Repository) FindBundleWhiteboards(ctx context.Context, projectID uuid.UUID) ([]*BundleWhiteboard, error) {
+	query := `
+		SELECT name, data, data_compressed, data_encoding
+		FROM whiteboards
+		WHERE project_id = $1 AND deleted_at IS NULL
+		ORDER BY position ASC, created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboards: %w", err)
+	}
+	defer rows.Close()
+
+	whiteboards := []*BundleWhiteboard{}
+	for rows.Next() {
+		var wb BundleWhiteboard
+		var stored compress.Stored
+		if err := rows.Scan(&wb.Name, &stored.Plain, &stored.Compressed, &stored.Encoding); err != nil {
+			return nil, fmt.Errorf("failed to scan whiteboard: %w", err)
+		}
+		if wb.Data, err = stored.Unpack(); err != nil {
+			return nil, fmt.Errorf("failed to read whiteboard %q: %w", wb.Name, err)
+		}
+		whiteboards = append(whiteboards, &wb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find whiteboards: %w", err)
+	}
+
+	return whiteboards, nil
+}
+
+// Import creates a project for userID with the bundle's whiteboards in one
+// bulk transaction. hashes holds the content hash of each whiteboard.
+func (r *Repository) Import(ctx context.Context, userID uuid.UUID, bundle *ProjectBundle, hashes []string) (*Project, error) {
+	var projectID uuid.UUID
+	err := r.bulk.RunInTx(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO projects (user_id, name, description, unique_whiteboard_names)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`
+
+		p := bundle.Project
+		if err := tx.QueryRow(ctx, query, userID, p.Name, p.Description, p.UniqueWhiteboardNames).Scan(&projectID); err != nil {
+			return fmt.Errorf("failed to create project: %w", err)
+		}
+
+		// The bundle's order becomes the tab order; created_at is spaced out too
+		// so the default (earliest) whiteboard is the first one
+		now := time.Now()
+		rows := make([][]interface{}, len(bundle.Whiteboards))
+		for i, wb := range bundle.Whiteboards {
+			rows[i] = []interface{}{projectID, wb.Name, []byte(wb.Data), hashes[i], p.UniqueWhiteboardNames, i, now.Add(time.Duration(i) * time.Microsecond)}
+		}
+		_, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"whiteboards"},
+			[]string{"project_id", "name", "data", "content_hash", "enforce_unique_name", "position", "created_at"},
+			pgx.CopyFromRows(rows),
+		)
+		if database.IsUniqueViolation(err, "idx_whiteboards_unique_name") {
+			return &BundleError{Reason: "whiteboard names must be unique when unique_whiteboard_names is set"}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import whiteboards: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, projectID)
+}
+
+// ==================== Service ====================
+
+// ExportProject bundles a project the user can read, for ImportProject.
+// Collaborators of either role can export, as they can read every whiteboard.
+func (s *Service) ExportProject(ctx context.Context, projectID, userID uuid.UUID) (*ProjectBundle, error) {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID && !project.IsPublic {
+		role, err := s.repo.CollaboratorRole(ctx, projectID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, s.forbidden(project)
+		}
+	}
+
+	whiteboards, err := s.repo.FindBundleWhiteboards(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectBundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Project: BundleProject{
+			Name:                  project.Name,
+			Description:           project.Description,
+			UniqueWhiteboardNames: project.UniqueWhiteboardNames,
+		},
+		Whiteboards: whiteboards,
+	}, nil
+}
+
+// ImportProject recreates a bundled project as a new private project of the
+// user, with fresh IDs. Every canvas is validated and normalized like a save;
+// bundles that fail validation return a BundleError and nothing is imported.
+func (s *Service) ImportProject(ctx context.Context, userID uuid.UUID, bundle *ProjectBundle) (*ProjectResponse, error) {
+	if s.prepareCanvas == nil {
+		return nil, errNoCanvasPreparer
+	}
+
+	hashes, err := validateBundle(bundle, s.prepareCanvas)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.repo.Import(ctx, userID, bundle, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	return s.toResponse(project, nil), nil
+}
+
+// validateBundle checks a bundle can be imported, replaces each whiteboard's
+// canvas with its prepared form and returns the content hash of each
+func validateBundle(bundle *ProjectBundle, prepare CanvasPreparer) ([]string, error) {
+	if bundle.Version != BundleVersion {
+		return nil, &BundleError{Reason: fmt.Sprintf("unsupported version %d (supported: %d)", bundle.Version, BundleVersion)}
+	}
+	if bundle.Project.Name == "" || len(bundle.Project.Name) > 255 {
+		return nil, &BundleError{Reason: "project name must be 1-255 characters"}
+	}
+	if len(bundle.Project.Description) > 1000 {
+		return nil, &BundleError{Reason: "project description must be at most 1000 characters"}
+	}
+	if len(bundle.Whiteboards) > maxBundleWhiteboards {
+		return nil, &BundleError{Reason: fmt.Sprintf("at most %d whiteboards can be imported", maxBundleWhiteboards)}
+	}
+
+	hashes := make([]string, len(bundle.Whiteboards))
+	for i, wb := range bundle.Whiteboards {
+		if wb == nil {
+			return nil, &BundleError{Reason: fmt.Sprintf("whiteboard %d is empty", i)}
+		}
+		if wb.Name == "" || len(wb.Name) > 255 {
+			return nil, &BundleError{Reason: fmt.Sprintf("whiteboard %d: name must be 1-255 characters", i)}
+		}
+		if len(wb.Data) == 0 || string(wb.Data) == "null" {
+			wb.Data = json.RawMessage(`{}`)
+		}
+
+		data, hash, err := prepare(wb.Data)
+		if err != nil {
+			return nil, &BundleError{Reason: fmt.Sprintf("whiteboard %d: %v", i, err)}
+		}
+		wb.Data, hashes[i] = data, hash
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,254 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+)
+
+// Collaborator roles
+const (
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Errors returned when adding collaborators
+var (
+	ErrUserNotFound         = errors.New("no user with that email")
+	ErrInvalidRole          = errors.New("role must be \"editor\" or \"viewer\"")
+	ErrAlreadyCollaborator  = errors.New("user is already a collaborator on this project")
+	ErrOwnerNotCollaborator = errors.New("the project owner can't be added as a collaborator")
+)
+
+// CollaboratorQuotaError is returned when a project already has the maximum number of collaborators
+type CollaboratorQuotaError struct {
+	Count int
+	Limit int
+}
+
+func (e *CollaboratorQuotaError) Error() string {
+	return fmt.Sprintf("project already has %d of %d allowed collaborators", e.Count, e.Limit)
+}
+
+// AddCollaboratorRequest is the request body for adding a collaborator
+type AddCollaboratorRequest struct {
+	Email string `json:"email" validate:"required,max=255"`
+	// Role defaults to editor
+	Role string `json:"role,omitempty"`
+}
+
+// CollaboratorResponse is a project collaborator returned to clients
+type CollaboratorResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CollaboratorsListResponse is the response for listing a project's collaborators
+type CollaboratorsListResponse struct {
+	Collaborators []*CollaboratorResponse `json:"collaborators"`
+	Total         int                     `json:"total"`
+}
+
+// AddCollaborator adds a user to a project's collaborators. The project row is
+// locked while counting, so concurrent adds can't overshoot max (0 is unlimited).
+func (r *Repository) AddCollaborator(ctx context.Context, projectID, userID uuid.UUID, role string, max int) (time.Time, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM projects WHERE id = $1 FOR UPDATE`, projectID); err != nil {
+		return time.Time{}, fmt.Errorf("failed to lock project: %w", err)
+	}
+
+	if max > 0 {
+		var count int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM project_collaborators WHERE project_id = $1`, projectID).Scan(&count); err != nil {
+			return time.Time{}, fmt.Errorf("failed to count collaborators: %w", err)
+		}
+		if count >= max {
+			return time.Time{}, &CollaboratorQuotaError{Count: count, Limit: max}
+		}
+	}
+
+	query := `
+		INSERT INTO project_collaborators (project_id, user_id, role)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+
+	var createdAt time.Time
+	err = tx.QueryRow(ctx, query, projectID, userID, role).Scan(&createdAt)
+	if database.IsUniqueViolation(err, "") {
+		return time.Time{}, ErrAlreadyCollaborator
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to add collaborator: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return time.Time{}, fmt.Errorf("failed to commit collaborator: %w", err)
+	}
+
+	return createdAt, nil
+}
+
+// ListCollaborators returns a project's collaborators, oldest first
+func (r *Repository) ListCollaborators(ctx context.Context, projectID uuid.UUID) ([]*CollaboratorResponse, error) {
+	query := `
+		SELECT c.user_id, u.email, u.name, c.role, c.created_at
+		FROM project_collaborators c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.project_id = $1
+		ORDER BY c.created_at ASC, c.user_id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collaborators: %w", err)
+	}
+	defer rows.Close()
+
+	collaborators := []*CollaboratorResponse{}
+	for rows.Next() {
+		var id uuid.UUID
+		var c CollaboratorResponse
+		if err := rows.Scan(&id, &c.Email, &c.Name, &c.Role, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan collaborator: %w", err)
+		}
+		c.UserID = id.String()
+		collaborators = append(collaborators, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate collaborators: %w", err)
+	}
+
+	return collaborators, nil
+}
+
+// FindUserByEmail finds a user's ID and name by email (case-insensitive).
+// Returns uuid.Nil if there is none.
+func (r *Repository) FindUserByEmail(ctx context.Context, email string) (uuid.UUID, string, error) {
+	query := `SELECT id, name FROM users WHERE LOWER(email) = LOWER($1)`
+
+	var id uuid.UUID
+	var name string
+	err := r.db.QueryRow(ctx, query, email).Scan(&id, &name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, "", nil
+	}
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to find user by email: %w", err)
+	}
+
+	return id, name, nil
+}
+
+// AddCollaborator gives another user access to a project. Only the owner can
+// add collaborators, and each project holds at most the configured number.
+func (s *Service) AddCollaborator(ctx context.Context, projectID, userID uuid.UUID, req *AddCollaboratorRequest) (*CollaboratorResponse, error) {
+	role := req.Role
+	if role == "" {
+		role = RoleEditor
+	}
+	if role != RoleEditor && role != RoleViewer {
+		return nil, ErrInvalidRole
+	}
+
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		return nil, s.forbidden(project)
+	}
+
+	email := strings.TrimSpace(req.Email)
+	collaboratorID, name, err := s.repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if collaboratorID == uuid.Nil {
+		return nil, ErrUserNotFound
+	}
+	if collaboratorID == project.UserID {
+		return nil, ErrOwnerNotCollaborator
+	}
+
+	createdAt, err := s.repo.AddCollaborator(ctx, projectID, collaboratorID, role, s.maxCollaborators)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CollaboratorResponse{
+		UserID:    collaboratorID.String(),
+		Email:     email,
+		Name:      name,
+		Role:      role,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// ListCollaborators lists a project's collaborators. The owner and the
+// collaborators themselves can see who else has access.
+func (s *Service) ListCollaborators(ctx context.Context, projectID, userID uuid.UUID) ([]*CollaboratorResponse, error) {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		role, err := s.repo.CollaboratorRole(ctx, projectID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, s.forbidden(project)
+		}
+	}
+
+	return s.repo.ListCollaborators(ctx, projectID)
+}
+
+// RemoveCollaborator revokes another user's access to a project. Only the
+// owner can remove collaborators; collaborators leave with LeaveProject.
+func (s *Service) RemoveCollaborator(ctx context.Context, projectID, userID, collaboratorID uuid.UUID) error {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		return s.forbidden(project)
+	}
+
+	removed, err := s.repo.RemoveCollaborator(ctx, projectID, collaboratorID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotCollaborator
+	}
+
+	s.accessChanged(ctx, projectID)
+	return nil
+}
@@ -0,0 +1,78 @@
+package project
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// favoriteColumn selects whether the user in $1 has starred each project, so
+// lists get the flag in the same query
+const favoriteColumn = `EXISTS (SELECT 1 FROM project_favorites f WHERE f.project_id = projects.id AND f.user_id = $1)`
+
+// ==================== Repository ====================
+
+// AddFavorite stars a project for a user; starring it again is a no-op
+func (r *Repository) AddFavorite(ctx context.Context, projectID, userID uuid.UUID) error {
+	query := `
+		INSERT INTO project_favorites (user_id, project_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, project_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, projectID); err != nil {
+		return fmt.Errorf("failed to add favorite: %w", err)
+	}
+	return nil
+}
+
+// RemoveFavorite unstars a project for a user; unstarring one that isn't starred is a no-op
+func (r *Repository) RemoveFavorite(ctx context.Context, projectID, userID uuid.UUID) error {
+	query := `DELETE FROM project_favorites WHERE user_id = $1 AND project_id = $2`
+
+	if _, err := r.db.Exec(ctx, query, userID, projectID); err != nil {
+		return fmt.Errorf("failed to remove favorite: %w", err)
+	}
+	return nil
+}
+
+// ==================== Service ====================
+
+// FavoriteProject stars a project the user can see: their own, one they
+// collaborate on, or a public one
+func (s *Service) FavoriteProject(ctx context.Context, projectID, userID uuid.UUID) error {
+	if err := s.checkCanView(ctx, projectID, userID); err != nil {
+		return err
+	}
+	return s.repo.AddFavorite(ctx, projectID, userID)
+}
+
+// UnfavoriteProject unstars a project. Access isn't checked, so users can
+// always clear stars on projects they've since lost access to.
+func (s *Service) UnfavoriteProject(ctx context.Context, projectID, userID uuid.UUID) error {
+	return s.repo.RemoveFavorite(ctx, projectID, userID)
+}
+
+// checkCanView checks that the user owns, collaborates on, or can publicly see a project
+func (s *Service) checkCanView(ctx context.Context, projectID, userID uuid.UUID) error {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return ErrProjectNotFound
+	}
+	if project.UserID == userID || project.IsPublic {
+		return nil
+	}
+
+	role, err := s.repo.CollaboratorRole(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return s.forbidden(project)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultPublicPageSize and maxPublicPageSize bound pages of the public gallery
+const (
+	defaultPublicPageSize = 20
+	maxPublicPageSize     = 100
+)
+
+// PublicProject is a public project with its owner's display details
+type PublicProject struct {
+	ID                  uuid.UUID
+	Name                string
+	Description         string
+	PublicSlug          string
+	DefaultWhiteboardID *uuid.UUID
+	ThumbnailURL        *string
+	OwnerName           string
+	OwnerAvatarURL      *string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// PublicProjectResponse is a project as listed in the public gallery. It
+// leaves out owner IDs and settings only the owner needs.
+type PublicProjectResponse struct {
+	ID                  string    `json:"id"`
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	PublicSlug          string    `json:"public_slug"`
+	URL                 string    `json:"url"`
+	DefaultWhiteboardID *string   `json:"default_whiteboard_id"`
+	ThumbnailURL        *string   `json:"thumbnail_url,omitempty"`
+	OwnerName           string    `json:"owner_name"`
+	OwnerAvatarURL      *string   `json:"owner_avatar_url,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// PublicProjectListResponse is one page of the public gallery
+type PublicProjectListResponse struct {
+	Projects []*PublicProjectResponse `json:"projects"`
+	Total    int                      `json:"total"`
+	Limit    int                      `json:"limit"`
+	Offset   int                      `json:"offset"`
+}
+
+// ==================== Repository ====================
+
+// FindPublic returns one page of public, unarchived projects, most recently
+// updated first, whose name or description contains q (case-insensitive; an
+// empty q matches all). It also returns the number of matches across pages.
+func (r *Repository) FindPublic(ctx context.Context, q string, limit, offset int) ([]*PublicProject, int, error) {
+	conditions := "p.is_public = true AND p.archived_at IS NULL AND p.public_slug IS NOT NULL"
+	args := []interface{}{}
+	if q != "" {
+		args = append(args, "%"+escapeLike(q)+"%")
+		conditions += " AND (p.name ILIKE $1 OR COALESCE(p.description, '') ILIKE $1)"
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM projects p WHERE ` + conditions
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count public projects: %w", err)
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, COALESCE(p.description, ''), p.public_slug,
+			(SELECT w.id FROM whiteboards w WHERE w.project_id = p.id AND w.deleted_at IS NULL ORDER BY w.created_at ASC LIMIT 1),
+			p.thumbnail_url, u.name, u.avatar_url, p.created_at, p.updated_at
+		FROM projects p
+		JOIN users u ON u.id = p.user_id
+		WHERE %s
+		ORDER BY p.updated_at DESC, p.id DESC
+		LIMIT $%d OFFSET $%d
+	`, conditions, len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find public projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []*PublicProject{}
+	for rows.Next() {
+		var p PublicProject
+		err := rows.Scan(
+			&p.ID,
+			&p.Name,
+			&p.Description,
+			&p.PublicSlug,
+			&p.DefaultWhiteboardID,
+			&p.ThumbnailURL,
+			&p.OwnerName,
+			&p.OwnerAvatarURL,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan public project: %w", err)
+		}
+		projects = append(projects, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate public projects: %w", err)
+	}
+
+	return projects, total, nil
+}
+
+// ==================== Service ====================
+
+// ListPublicProjects lists one page of the public gallery. A limit of 0 means
+// the default page size; larger limits are capped at maxPublicPageSize.
+func (s *Service) ListPublicProjects(ctx context.Context, q string, limit, offset int) (*PublicProjectListResponse, error) {
+	if limit <= 0 {
+		limit = defaultPublicPageSize
+	}
+	if limit > maxPublicPageSize {
+		limit = maxPublicPageSize
+	}
+
+	projects, total, err := s.repo.FindPublic(ctx, strings.TrimSpace(q), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*PublicProjectResponse, len(projects))
+	for i, p := range projects {
+		responses[i] = &PublicProjectResponse{
+			ID:                  p.ID.String(),
+			Name:                p.Name,
+			Description:         p.Description,
+			PublicSlug:          p.PublicSlug,
+			URL:                 s.frontendURL + "/public/" + p.PublicSlug,
+			DefaultWhiteboardID: uuidString(p.DefaultWhiteboardID),
+			OwnerName:           p.OwnerName,
+			OwnerAvatarURL:      p.OwnerAvatarURL,
+			CreatedAt:           p.CreatedAt,
+			UpdatedAt:           p.UpdatedAt,
+		}
+	}
+
+	return &PublicProjectListResponse{
+		Projects: responses,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}, nil
+}
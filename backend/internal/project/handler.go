@@ -1,10 +1,16 @@
 package project
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/prefer"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/validate"
 )
 
 // Handler handles HTTP requests for projects
@@ -17,19 +23,34 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
-// RegisterRoutes registers the project routes
-func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth fiber.Handler) {
+// RegisterRoutes registers the project routes.
+// bulkLimit caps the size of bulk requests, like imports.
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth, bulkLimit fiber.Handler) {
 	projects := api.Group("/projects")
 
 	// Protected routes
 	projects.Use(requireAuth)
 	projects.Get("/", h.List)
 	projects.Post("/", h.Create)
+	projects.Post("/import", bulkLimit, h.Import)
 	projects.Get("/:id", h.Get)
 	projects.Put("/:id", h.Update)
 	projects.Delete("/:id", h.Delete)
-
-	// Public route for shared projects (no auth required)
+	projects.Post("/:id/archive", h.Archive)
+	projects.Post("/:id/unarchive", h.Unarchive)
+	projects.Post("/:id/touch", h.Touch)
+	projects.Post("/:id/favorite", h.Favorite)
+	projects.Delete("/:id/favorite", h.Unfavorite)
+	projects.Get("/:id/public-url", h.PublicURL)
+	projects.Get("/:id/export", h.Export)
+	projects.Patch("/:id/slug", h.UpdateSlug)
+	projects.Get("/:id/collaborators", h.ListCollaborators)
+	projects.Post("/:id/collaborators", h.AddCollaborator)
+	projects.Delete("/:id/collaborators/me", h.Leave)
+	projects.Delete("/:id/collaborators/:userId", h.RemoveCollaborator)
+
+	// Public routes for shared projects (no auth required)
+	api.Get("/public/projects", h.ListPublic)
 	api.Get("/public/projects/:slug", h.GetPublic)
 }
 
@@ -37,6 +58,10 @@ func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth fiber.Handler) {
 // @Summary List user's projects
 // @Tags projects
 // @Security BearerAuth
+// @Param q query string false "Case-insensitive search in name and description"
+// @Param is_public query bool false "Only public (true) or private (false) projects"
+// @Param include_archived query bool false "Include archived projects"
+// @Param favorites_only query bool false "Only projects the user has starred"
 // @Success 200 {object} ProjectListResponse
 // @Router /projects [get]
 func (h *Handler) List(c *fiber.Ctx) error {
@@ -47,7 +72,32 @@ func (h *Handler) List(c *fiber.Ctx) error {
 		})
 	}
 
-	projects, err := h.service.GetUserProjects(c.Context(), userID)
+	var isPublic *bool
+	if raw := c.Query("is_public"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "is_public must be true or false",
+			})
+		}
+		isPublic = &value
+	}
+
+	includeArchived, err := strconv.ParseBool(c.Query("include_archived", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "include_archived must be true or false",
+		})
+	}
+
+	favoritesOnly, err := strconv.ParseBool(c.Query("favorites_only", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "favorites_only must be true or false",
+		})
+	}
+
+	projects, err := h.service.SearchProjects(c.Context(), userID, c.Query("q"), isPublic, includeArchived, favoritesOnly)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get projects",
@@ -102,6 +152,43 @@ func (h *Handler) Get(c *fiber.Ctx) error {
 	return c.JSON(project)
 }
 
+// ListPublic handles GET /api/v1/public/projects
+// @Summary Browse public projects
+// @Description Public projects of every user, most recently updated first, for the community gallery.
+// @Tags projects
+// @Param q query string false "Case-insensitive search in name and description"
+// @Param limit query int false "Page size (default 20, at most 100)"
+// @Param offset query int false "Projects to skip"
+// @Success 200 {object} PublicProjectListResponse
+// @Router /public/projects [get]
+func (h *Handler) ListPublic(c *fiber.Ctx) error {
+	var err error
+	limit, offset := 0, 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit must be a positive integer",
+			})
+		}
+	}
+	if raw := c.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "offset must be a non-negative integer",
+			})
+		}
+	}
+
+	page, err := h.service.ListPublicProjects(c.Context(), c.Query("q"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list public projects",
+		})
+	}
+
+	return c.JSON(page)
+}
+
 // GetPublic handles GET /api/v1/public/projects/:slug
 // @Summary Get a public project by slug
 // @Tags projects
@@ -136,7 +223,9 @@ func (h *Handler) GetPublic(c *fiber.Ctx) error {
 // @Tags projects
 // @Security BearerAuth
 // @Param body body CreateProjectRequest true "Project data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
 // @Success 201 {object} ProjectResponse
+// @Success 204
 // @Router /projects [post]
 func (h *Handler) Create(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
@@ -152,22 +241,112 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 			"error": "invalid request body",
 		})
 	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	project, err := h.service.CreateProject(c.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnknownRegion) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create project",
+		})
+	}
+
+	return writeProject(c, fiber.StatusCreated, project)
+}
 
-	// Validate
-	if req.Name == "" {
+// Import handles POST /api/v1/projects/import
+// @Summary Create a project from an exported bundle
+// @Description IDs and slugs are regenerated; the new project is private. Canvases are validated and
+// @Description normalized like saves. 422 for invalid bundles, and nothing is imported
+// @Tags projects
+// @Security BearerAuth
+// @Param body body ProjectBundle true "Bundle from GET /projects/{id}/export"
+// @Success 201 {object} ProjectResponse
+// @Router /projects/import [post]
+func (h *Handler) Import(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var bundle ProjectBundle
+	if err := json.Unmarshal(c.Body(), &bundle); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": (&BundleError{Reason: err.Error()}).Error(),
+		})
+	}
+
+	project, err := h.service.ImportProject(c.Context(), userID, &bundle)
+	if err != nil {
+		var bundleErr *BundleError
+		if errors.As(err, &bundleErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": bundleErr.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to import project",
+		})
+	}
+
+	return writeProject(c, fiber.StatusCreated, project)
+}
+
+// Export handles GET /api/v1/projects/:id/export
+// @Summary Export a project and its whiteboards as a bundle for import
+// @Description The owner, collaborators (viewers included) and, for public projects, anyone signed in can export.
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} ProjectBundle
+// @Router /projects/{id}/export [get]
+func (h *Handler) Export(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "name is required",
+			"error": "invalid project id",
 		})
 	}
 
-	project, err := h.service.CreateProject(c.Context(), userID, &req)
+	bundle, err := h.service.ExportProject(c.Context(), projectID, userID)
 	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create project",
+			"error": "failed to export project",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(project)
+	c.Attachment("sysdes-project-" + projectID.String() + ".json")
+	return c.JSON(bundle)
 }
 
 // Update handles PUT /api/v1/projects/:id
@@ -176,7 +355,9 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path string true "Project ID"
 // @Param body body UpdateProjectRequest true "Project data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
 // @Success 200 {object} ProjectResponse
+// @Success 204
 // @Router /projects/{id} [put]
 func (h *Handler) Update(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
@@ -199,6 +380,9 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 			"error": "invalid request body",
 		})
 	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
 
 	project, err := h.service.UpdateProject(c.Context(), projectID, userID, &req)
 	if err != nil {
@@ -212,19 +396,38 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 				"error": "access denied",
 			})
 		}
+		if errors.Is(err, ErrUnknownRegion) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var rejected *ContentRejectedError
+		if errors.As(err, &rejected) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": rejected.Error(),
+				"field": rejected.Field,
+			})
+		}
+		if errors.Is(err, ErrDuplicateWhiteboardNames) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update project",
 		})
 	}
 
-	return c.JSON(project)
+	return writeProject(c, fiber.StatusOK, project)
 }
 
 // Delete handles DELETE /api/v1/projects/:id
 // @Summary Delete a project
+// @Description Only archived projects can be deleted, unless permanent is set
 // @Tags projects
 // @Security BearerAuth
 // @Param id path string true "Project ID"
+// @Param permanent query bool false "Delete even if the project isn't archived"
 // @Success 204
 // @Router /projects/{id} [delete]
 func (h *Handler) Delete(c *fiber.Ctx) error {
@@ -242,7 +445,14 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.service.DeleteProject(c.Context(), projectID, userID)
+	permanent, err := strconv.ParseBool(c.Query("permanent", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "permanent must be true or false",
+		})
+	}
+
+	err = h.service.DeleteProject(c.Context(), projectID, userID, permanent)
 	if err != nil {
 		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -254,6 +464,11 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 				"error": "access denied",
 			})
 		}
+		if errors.Is(err, ErrProjectNotArchived) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to delete project",
 		})
@@ -262,6 +477,506 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// Archive handles POST /api/v1/projects/:id/archive
+// @Summary Archive a project
+// @Description Hides the project from the list and its public link until it's unarchived
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} ProjectResponse
+// @Router /projects/{id}/archive [post]
+func (h *Handler) Archive(c *fiber.Ctx) error {
+	return h.setArchived(c, true)
+}
+
+// Unarchive handles POST /api/v1/projects/:id/unarchive
+// @Summary Restore an archived project
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} ProjectResponse
+// @Router /projects/{id}/unarchive [post]
+func (h *Handler) Unarchive(c *fiber.Ctx) error {
+	return h.setArchived(c, false)
+}
+
+func (h *Handler) setArchived(c *fiber.Ctx, archived bool) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	project, err := h.service.ArchiveProject(c.Context(), projectID, userID, archived)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to archive project",
+		})
+	}
+
+	return c.JSON(project)
+}
+
+// Touch handles POST /api/v1/projects/:id/touch
+// @Summary Mark a project as recently used
+// @Description Bumps updated_at (at most once a minute) so the project sorts first in the list
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 204
+// @Router /projects/{id}/touch [post]
+func (h *Handler) Touch(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	err = h.service.TouchProject(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to touch project",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Favorite handles POST /api/v1/projects/:id/favorite
+// @Summary Star a project
+// @Description Pins a project the user can see; starring it again is a no-op
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 204
+// @Router /projects/{id}/favorite [post]
+func (h *Handler) Favorite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	err = h.service.FavoriteProject(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to favorite project",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Unfavorite handles DELETE /api/v1/projects/:id/favorite
+// @Summary Unstar a project
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 204
+// @Router /projects/{id}/favorite [delete]
+func (h *Handler) Unfavorite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	if err := h.service.UnfavoriteProject(c.Context(), projectID, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to unfavorite project",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PublicURL handles GET /api/v1/projects/:id/public-url
+// @Summary Get or preview a project's shareable URL
+// @Description Returns the published URL, or the one publishing would generate without reserving it
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} PublicURLResponse
+// @Router /projects/{id}/public-url [get]
+func (h *Handler) PublicURL(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	url, err := h.service.PreviewPublicURL(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get public url",
+		})
+	}
+
+	return c.JSON(url)
+}
+
+// UpdateSlug handles PATCH /api/v1/projects/:id/slug
+// @Summary Choose a public project's slug
+// @Description Replaces the generated slug; the project must be public. 409 if the slug is taken
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Param request body UpdateSlugRequest true "New slug"
+// @Success 200 {object} ProjectResponse
+// @Router /projects/{id}/slug [patch]
+func (h *Handler) UpdateSlug(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	var req UpdateSlugRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	project, err := h.service.UpdatePublicSlug(c.Context(), projectID, userID, req.Slug)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSlug) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		if errors.Is(err, ErrSlugTaken) || errors.Is(err, ErrProjectNotPublic) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update slug",
+		})
+	}
+
+	return c.JSON(project)
+}
+
+// ListCollaborators handles GET /api/v1/projects/:id/collaborators
+// @Summary List a project's collaborators
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 200 {object} CollaboratorsListResponse
+// @Router /projects/{id}/collaborators [get]
+func (h *Handler) ListCollaborators(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	collaborators, err := h.service.ListCollaborators(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list collaborators",
+		})
+	}
+
+	return c.JSON(CollaboratorsListResponse{
+		Collaborators: collaborators,
+		Total:         len(collaborators),
+	})
+}
+
+// AddCollaborator handles POST /api/v1/projects/:id/collaborators
+// @Summary Add a collaborator to a project
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Param body body AddCollaboratorRequest true "Collaborator email and role"
+// @Success 201 {object} CollaboratorResponse
+// @Router /projects/{id}/collaborators [post]
+func (h *Handler) AddCollaborator(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	var req AddCollaboratorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	collaborator, err := h.service.AddCollaborator(c.Context(), projectID, userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		if errors.Is(err, ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrInvalidRole) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrAlreadyCollaborator) || errors.Is(err, ErrOwnerNotCollaborator) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var quota *CollaboratorQuotaError
+		if errors.As(err, &quota) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "quota_exceeded",
+				"message": quota.Error(),
+				"count":   quota.Count,
+				"limit":   quota.Limit,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to add collaborator",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(collaborator)
+}
+
+// Leave handles DELETE /api/v1/projects/:id/collaborators/me
+// @Summary Leave a project as a collaborator
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Success 204
+// @Router /projects/{id}/collaborators/me [delete]
+func (h *Handler) Leave(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	err = h.service.LeaveProject(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrNotCollaborator) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrOwnerCannotLeave) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to leave project",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveCollaborator handles DELETE /api/v1/projects/:id/collaborators/:userId
+// @Summary Remove a collaborator from a project
+// @Tags projects
+// @Security BearerAuth
+// @Param id path string true "Project ID"
+// @Param userId path string true "Collaborator user ID"
+// @Success 204
+// @Router /projects/{id}/collaborators/{userId} [delete]
+func (h *Handler) RemoveCollaborator(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	collaboratorID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	err = h.service.RemoveCollaborator(c.Context(), projectID, userID, collaboratorID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrNotCollaborator) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to remove collaborator",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// writeProject sends a written project, or only its Location and ETag
+// (derived from updated_at) when the client prefers return=minimal
+func writeProject(c *fiber.Ctx, status int, p *ProjectResponse) error {
+	etag := fmt.Sprintf(`"%x"`, p.UpdatedAt.UnixNano())
+	return prefer.Write(c, status, "/api/v1/projects/"+p.ID, etag, p)
+}
+
 // getUserID extracts the user ID from the Fiber context (set by auth middleware)
 func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	userIDStr, ok := c.Locals("userID").(string)
@@ -8,37 +8,70 @@ import (
 
 // Project represents a system design project
 type Project struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	IsPublic    bool      `json:"is_public"`
-	PublicSlug  *string   `json:"public_slug,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                    uuid.UUID `json:"id"`
+	UserID                uuid.UUID `json:"user_id"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	IsPublic              bool      `json:"is_public"`
+	PublicSlug            *string   `json:"public_slug,omitempty"`
+	UniqueWhiteboardNames bool      `json:"unique_whiteboard_names"`
+	StorageRegion         *string   `json:"storage_region,omitempty"`
+	// DefaultWhiteboardID is the project's earliest whiteboard, nil if it has none
+	DefaultWhiteboardID *uuid.UUID `json:"default_whiteboard_id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// ArchivedAt is set while the project is archived
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	// ThumbnailURL is where the project's thumbnail is served, nil until one is set
+	ThumbnailURL *string `json:"thumbnail_url,omitempty"`
+	// IsFavorite is whether the listing user starred the project, loaded by list queries
+	IsFavorite *bool `json:"is_favorite,omitempty"`
+	// Author is the owner's public profile, loaded by single-project lookups
+	Author *ProjectAuthor `json:"author,omitempty"`
+}
+
+// ProjectAuthor is who created a project, as shown to other users
+type ProjectAuthor struct {
+	Name      string  `json:"name"`
+	AvatarURL *string `json:"avatar_url,omitempty"`
 }
 
 // ProjectResponse is the public project data returned to clients
 type ProjectResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	IsPublic    bool      `json:"is_public"`
-	PublicSlug  *string   `json:"public_slug,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                    string     `json:"id"`
+	Name                  string     `json:"name"`
+	Description           string     `json:"description"`
+	IsPublic              bool       `json:"is_public"`
+	PublicSlug            *string    `json:"public_slug,omitempty"`
+	UniqueWhiteboardNames bool       `json:"unique_whiteboard_names"`
+	StorageRegion         string     `json:"storage_region"`
+	DefaultWhiteboardID   *string    `json:"default_whiteboard_id"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	ArchivedAt            *time.Time `json:"archived_at,omitempty"`
+	ThumbnailURL          *string    `json:"thumbnail_url,omitempty"`
+	// IsFavorite is only set on the user's project list
+	IsFavorite *bool `json:"is_favorite,omitempty"`
+	// Author is only set on responses for users other than the owner
+	Author *ProjectAuthor `json:"author,omitempty"`
 }
 
 // ToResponse converts Project to ProjectResponse
 func (p *Project) ToResponse() *ProjectResponse {
 	return &ProjectResponse{
-		ID:          p.ID.String(),
-		Name:        p.Name,
-		Description: p.Description,
-		IsPublic:    p.IsPublic,
-		PublicSlug:  p.PublicSlug,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:                    p.ID.String(),
+		Name:                  p.Name,
+		Description:           p.Description,
+		IsPublic:              p.IsPublic,
+		PublicSlug:            p.PublicSlug,
+		UniqueWhiteboardNames: p.UniqueWhiteboardNames,
+		StorageRegion:         stringValue(p.StorageRegion),
+		DefaultWhiteboardID:   uuidString(p.DefaultWhiteboardID),
+		CreatedAt:             p.CreatedAt,
+		UpdatedAt:             p.UpdatedAt,
+		ArchivedAt:            p.ArchivedAt,
+		ThumbnailURL:          p.ThumbnailURL,
+		IsFavorite:            p.IsFavorite,
 	}
 }
 
@@ -46,13 +79,46 @@ func (p *Project) ToResponse() *ProjectResponse {
 type CreateProjectRequest struct {
 	Name        string `json:"name" validate:"required,min=1,max=255"`
 	Description string `json:"description" validate:"max=1000"`
+	// StorageRegion pins where the project's uploaded assets and thumbnails are
+	// stored; empty uses the default. Canvas data stays in the primary database.
+	StorageRegion *string `json:"storage_region,omitempty"`
+	// TemplateProjectID clones the whiteboards of one of the user's projects (or a public one)
+	TemplateProjectID *uuid.UUID `json:"template_project_id,omitempty"`
 }
 
 // UpdateProjectRequest is the request body for updating a project
 type UpdateProjectRequest struct {
-	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
-	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
-	IsPublic    *bool   `json:"is_public,omitempty"`
+	Name                  *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Description           *string `json:"description,omitempty" validate:"omitempty,max=1000"`
+	IsPublic              *bool   `json:"is_public,omitempty"`
+	UniqueWhiteboardNames *bool   `json:"unique_whiteboard_names,omitempty"`
+	StorageRegion         *string `json:"storage_region,omitempty"`
+}
+
+// fields names the fields the request changes, for the activity log
+func (r *UpdateProjectRequest) fields() []string {
+	fields := []string{}
+	if r.Name != nil {
+		fields = append(fields, "name")
+	}
+	if r.Description != nil {
+		fields = append(fields, "description")
+	}
+	if r.IsPublic != nil {
+		fields = append(fields, "is_public")
+	}
+	if r.UniqueWhiteboardNames != nil {
+		fields = append(fields, "unique_whiteboard_names")
+	}
+	if r.StorageRegion != nil {
+		fields = append(fields, "storage_region")
+	}
+	return fields
+}
+
+// UpdateSlugRequest is the request body for choosing a public project's slug
+type UpdateSlugRequest struct {
+	Slug string `json:"slug"`
 }
 
 // ProjectsListResponse is the response for listing projects
@@ -60,3 +126,29 @@ type ProjectsListResponse struct {
 	Projects []*ProjectResponse `json:"projects"`
 	Total    int                `json:"total"`
 }
+
+// stringValue dereferences an optional string, returning "" for nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// uuidString formats an optional UUID, returning nil for nil
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
+
+// PublicURLResponse is a project's shareable URL. For unpublished projects it
+// previews the slug publishing would generate; nothing is reserved, so the
+// suffix can differ if another project takes the slug first.
+type PublicURLResponse struct {
+	Slug      string `json:"slug"`
+	URL       string `json:"url"`
+	Published bool   `json:"published"`
+}
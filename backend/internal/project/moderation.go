@@ -0,0 +1,76 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContentRejectedError is returned when a public project's name or description
+// fails the public content filter
+type ContentRejectedError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ContentRejectedError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// contentFilter screens names and descriptions shown on public pages.
+// Private projects are never screened.
+type contentFilter struct {
+	blocked        []string
+	maxName        int
+	maxDescription int
+}
+
+func newContentFilter(blocklist []string, maxName, maxDescription int) *contentFilter {
+	f := &contentFilter{maxName: maxName, maxDescription: maxDescription}
+	for _, term := range blocklist {
+		if term = normalizeWords(term); term != "" {
+			f.blocked = append(f.blocked, term)
+		}
+	}
+	return f
+}
+
+// check screens the name and description a project will have once public
+func (f *contentFilter) check(name, description string) error {
+	if f.maxName > 0 && utf8.RuneCountInString(name) > f.maxName {
+		return &ContentRejectedError{Field: "name", Reason: fmt.Sprintf("must be at most %d characters for public projects", f.maxName)}
+	}
+	if f.maxDescription > 0 && utf8.RuneCountInString(description) > f.maxDescription {
+		return &ContentRejectedError{Field: "description", Reason: fmt.Sprintf("must be at most %d characters for public projects", f.maxDescription)}
+	}
+	if f.blocks(name) {
+		return &ContentRejectedError{Field: "name", Reason: "contains language that isn't allowed on public projects"}
+	}
+	if f.blocks(description) {
+		return &ContentRejectedError{Field: "description", Reason: "contains language that isn't allowed on public projects"}
+	}
+	return nil
+}
+
+// blocks reports whether text contains a blocked term as whole words, so
+// "class" isn't caught by a blocked "ass"
+func (f *contentFilter) blocks(text string) bool {
+	if len(f.blocked) == 0 {
+		return false
+	}
+	padded := " " + normalizeWords(text) + " "
+	for _, term := range f.blocked {
+		if strings.Contains(padded, " "+term+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWords lowercases s and reduces it to single-space-separated words
+func normalizeWords(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
@@ -4,31 +4,57 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/bulk"
 )
 
 // Repository handles database operations for projects
 type Repository struct {
 	db *pgxpool.Pool
+	// bulk bounds the transactions of bulk writes, like imports
+	bulk *bulk.Guard
 }
 
 // NewRepository creates a new project repository
-func NewRepository(db *pgxpool.Pool) *Repository {
-	return &Repository{db: db}
+func NewRepository(db *pgxpool.Pool, bulkGuard *bulk.Guard) *Repository {
+	return &Repository{db: db, bulk: bulkGuard}
+}
+
+// defaultWhiteboardColumn selects the ID of a project's default (earliest) whiteboard,
+// or NULL if it has none. Reading it never creates a whiteboard.
+const defaultWhiteboardColumn = `(SELECT w.id FROM whiteboards w WHERE w.project_id = projects.id AND w.deleted_at IS NULL ORDER BY w.created_at ASC LIMIT 1)`
+
+// authorJoin joins a project's owner as "author", for the Author of single-project lookups
+const authorJoin = `LEFT JOIN users author ON author.id = projects.user_id`
+
+// newAuthor builds a project's Author from the joined owner columns, nil if
+// the owner row is missing
+func newAuthor(name, avatarURL *string) *ProjectAuthor {
+	if name == nil {
+		return nil
+	}
+	return &ProjectAuthor{Name: *name, AvatarURL: avatarURL}
 }
 
-// FindByID finds a project by its ID
+// FindByID finds a project by its ID, with its owner as Author
 func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Project, error) {
 	query := `
-		SELECT id, user_id, name, description, is_public, public_slug, created_at, updated_at
+		SELECT projects.id, projects.user_id, projects.name, projects.description, projects.is_public, projects.public_slug, projects.unique_whiteboard_names,
+			projects.storage_region, projects.created_at, projects.updated_at, projects.archived_at, projects.thumbnail_url, ` + defaultWhiteboardColumn + `,
+			author.name, author.avatar_url
 		FROM projects
-		WHERE id = $1
+		` + authorJoin + `
+		WHERE projects.id = $1
 	`
 
 	var project Project
+	var authorName, authorAvatarURL *string
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&project.ID,
 		&project.UserID,
@@ -36,8 +62,15 @@ func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Project, erro
 		&project.Description,
 		&project.IsPublic,
 		&project.PublicSlug,
+		&project.UniqueWhiteboardNames,
+		&project.StorageRegion,
 		&project.CreatedAt,
 		&project.UpdatedAt,
+		&project.ArchivedAt,
+		&project.ThumbnailURL,
+		&project.DefaultWhiteboardID,
+		&authorName,
+		&authorAvatarURL,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -47,19 +80,20 @@ func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Project, erro
 		return nil, fmt.Errorf("failed to find project by id: %w", err)
 	}
 
+	project.Author = newAuthor(authorName, authorAvatarURL)
 	return &project, nil
 }
 
-// FindByUserID finds all projects for a user
-func (r *Repository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*Project, error) {
+// FindByUserID finds all projects for a user, leaving out archived ones unless includeArchived
+func (r *Repository) FindByUserID(ctx context.Context, userID uuid.UUID, includeArchived bool) ([]*Project, error) {
 	query := `
-		SELECT id, user_id, name, description, is_public, public_slug, created_at, updated_at
+		SELECT id, user_id, name, description, is_public, public_slug, unique_whiteboard_names, storage_region, created_at, updated_at, archived_at, thumbnail_url, ` + defaultWhiteboardColumn + `, ` + favoriteColumn + `
 		FROM projects
-		WHERE user_id = $1
-		ORDER BY updated_at DESC
+		WHERE user_id = $1 AND ($2 OR archived_at IS NULL)
+		ORDER BY updated_at DESC, id DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query, userID, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find projects by user id: %w", err)
 	}
@@ -75,8 +109,81 @@ func (r *Repository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*Pro
 			&project.Description,
 			&project.IsPublic,
 			&project.PublicSlug,
+			&project.UniqueWhiteboardNames,
+			&project.StorageRegion,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+			&project.ArchivedAt,
+			&project.ThumbnailURL,
+			&project.DefaultWhiteboardID,
+			&project.IsFavorite,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, &project)
+	}
+
+	return projects, nil
+}
+
+// SearchByUserID finds a user's projects whose name or description contains q
+// (case-insensitive), optionally filtered by visibility and to those the user
+// has starred. An empty q, nil isPublic and false favoritesOnly return the same
+// list as FindByUserID. Filters are only ever passed as parameters, never
+// spliced into the SQL.
+func (r *Repository) SearchByUserID(ctx context.Context, userID uuid.UUID, q string, isPublic *bool, includeArchived, favoritesOnly bool) ([]*Project, error) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{userID}
+
+	if !includeArchived {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+
+	if q != "" {
+		args = append(args, "%"+escapeLike(q)+"%")
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR COALESCE(description, '') ILIKE $%d)", n, n))
+	}
+	if isPublic != nil {
+		args = append(args, *isPublic)
+		conditions = append(conditions, fmt.Sprintf("is_public = $%d", len(args)))
+	}
+	if favoritesOnly {
+		conditions = append(conditions, favoriteColumn)
+	}
+
+	query := `
+		SELECT id, user_id, name, description, is_public, public_slug, unique_whiteboard_names, storage_region, created_at, updated_at, archived_at, thumbnail_url, ` + defaultWhiteboardColumn + `, ` + favoriteColumn + `
+		FROM projects
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY updated_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		var project Project
+		err := rows.Scan(
+			&project.ID,
+			&project.UserID,
+			&project.Name,
+			&project.Description,
+			&project.IsPublic,
+			&project.PublicSlug,
+			&project.UniqueWhiteboardNames,
+			&project.StorageRegion,
 			&project.CreatedAt,
 			&project.UpdatedAt,
+			&project.ArchivedAt,
+			&project.ThumbnailURL,
+			&project.DefaultWhiteboardID,
+			&project.IsFavorite,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan project: %w", err)
@@ -87,15 +194,25 @@ func (r *Repository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*Pro
 	return projects, nil
 }
 
-// FindBySlug finds a public project by its slug
+// escapeLike escapes LIKE wildcards so user input only matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// FindBySlug finds a public project by its slug, with its owner as Author.
+// Archived projects aren't shared.
 func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Project, error) {
 	query := `
-		SELECT id, user_id, name, description, is_public, public_slug, created_at, updated_at
+		SELECT projects.id, projects.user_id, projects.name, projects.description, projects.is_public, projects.public_slug, projects.unique_whiteboard_names,
+			projects.storage_region, projects.created_at, projects.updated_at, projects.archived_at, projects.thumbnail_url, ` + defaultWhiteboardColumn + `,
+			author.name, author.avatar_url
 		FROM projects
-		WHERE public_slug = $1 AND is_public = true
+		` + authorJoin + `
+		WHERE projects.public_slug = $1 AND projects.is_public = true AND projects.archived_at IS NULL
 	`
 
 	var project Project
+	var authorName, authorAvatarURL *string
 	err := r.db.QueryRow(ctx, query, slug).Scan(
 		&project.ID,
 		&project.UserID,
@@ -103,8 +220,15 @@ func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Project, err
 		&project.Description,
 		&project.IsPublic,
 		&project.PublicSlug,
+		&project.UniqueWhiteboardNames,
+		&project.StorageRegion,
 		&project.CreatedAt,
 		&project.UpdatedAt,
+		&project.ArchivedAt,
+		&project.ThumbnailURL,
+		&project.DefaultWhiteboardID,
+		&authorName,
+		&authorAvatarURL,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -114,38 +238,94 @@ func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Project, err
 		return nil, fmt.Errorf("failed to find project by slug: %w", err)
 	}
 
+	project.Author = newAuthor(authorName, authorAvatarURL)
 	return &project, nil
 }
 
-// Create creates a new project
-func (r *Repository) Create(ctx context.Context, userID uuid.UUID, name, description string) (*Project, error) {
+// Create creates a new project. If templateID is set, the template project's
+// whiteboards are cloned into the new project in the same transaction.
+func (r *Repository) Create(ctx context.Context, userID uuid.UUID, name, description string, storageRegion *string, templateID *uuid.UUID) (*Project, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO projects (user_id, name, description)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, name, description, is_public, public_slug, created_at, updated_at
+		INSERT INTO projects (user_id, name, description, storage_region)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, name, description, is_public, public_slug, unique_whiteboard_names, storage_region, created_at, updated_at, archived_at, thumbnail_url, ` + defaultWhiteboardColumn + `
 	`
 
 	var project Project
-	err := r.db.QueryRow(ctx, query, userID, name, description).Scan(
+	err = tx.QueryRow(ctx, query, userID, name, description, storageRegion).Scan(
 		&project.ID,
 		&project.UserID,
 		&project.Name,
 		&project.Description,
 		&project.IsPublic,
 		&project.PublicSlug,
+		&project.UniqueWhiteboardNames,
+		&project.StorageRegion,
 		&project.CreatedAt,
 		&project.UpdatedAt,
+		&project.ArchivedAt,
+		&project.ThumbnailURL,
+		&project.DefaultWhiteboardID,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
 	}
 
+	if templateID != nil {
+		if err := cloneWhiteboards(ctx, tx, *templateID, project.ID); err != nil {
+			return nil, err
+		}
+
+		// The RETURNING clause ran before the clones existed
+		query := `SELECT ` + defaultWhiteboardColumn + ` FROM projects WHERE id = $1`
+		if err := tx.QueryRow(ctx, query, project.ID).Scan(&project.DefaultWhiteboardID); err != nil {
+			return nil, fmt.Errorf("failed to find default whiteboard: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit project creation: %w", err)
+	}
+
 	return &project, nil
 }
 
-// Update updates a project
-func (r *Repository) Update(ctx context.Context, id uuid.UUID, name, description *string, isPublic *bool) (*Project, error) {
+// cloneWhiteboards copies every whiteboard of one project, except those in
+// its trash, into another.
+// Thumbnails aren't copied; the clones get fresh ones once rendered.
+func cloneWhiteboards(ctx context.Context, tx pgx.Tx, fromProjectID, toProjectID uuid.UUID) error {
+	query := `
+		INSERT INTO whiteboards (project_id, name, data, data_compressed, data_encoding, content_hash, enforce_unique_name, position)
+		SELECT $2, name, data, data_compressed, data_encoding, content_hash,
+			(SELECT unique_whiteboard_names FROM projects WHERE id = $2), position
+		FROM whiteboards
+		WHERE project_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC
+	`
+
+	if _, err := tx.Exec(ctx, query, fromProjectID, toProjectID); err != nil {
+		return fmt.Errorf("failed to clone whiteboards: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates a project. Changing uniqueWhiteboardNames also re-flags the
+// project's whiteboards, which fails with a unique violation if duplicates exist.
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, name, description *string, isPublic, uniqueWhiteboardNames *bool, storageRegion *string) (*Project, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	// Build dynamic update query
 	query := `
 		UPDATE projects
@@ -153,21 +333,28 @@ func (r *Repository) Update(ctx context.Context, id uuid.UUID, name, description
 			name = COALESCE($2, name),
 			description = COALESCE($3, description),
 			is_public = COALESCE($4, is_public),
+			unique_whiteboard_names = COALESCE($5, unique_whiteboard_names),
+			storage_region = COALESCE($6, storage_region),
 			updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, user_id, name, description, is_public, public_slug, created_at, updated_at
+		RETURNING id, user_id, name, description, is_public, public_slug, unique_whiteboard_names, storage_region, created_at, updated_at, archived_at, thumbnail_url, ` + defaultWhiteboardColumn + `
 	`
 
 	var project Project
-	err := r.db.QueryRow(ctx, query, id, name, description, isPublic).Scan(
+	err = tx.QueryRow(ctx, query, id, name, description, isPublic, uniqueWhiteboardNames, storageRegion).Scan(
 		&project.ID,
 		&project.UserID,
 		&project.Name,
 		&project.Description,
 		&project.IsPublic,
 		&project.PublicSlug,
+		&project.UniqueWhiteboardNames,
+		&project.StorageRegion,
 		&project.CreatedAt,
 		&project.UpdatedAt,
+		&project.ArchivedAt,
+		&project.ThumbnailURL,
+		&project.DefaultWhiteboardID,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -177,6 +364,17 @@ func (r *Repository) Update(ctx context.Context, id uuid.UUID, name, description
 		return nil, fmt.Errorf("failed to update project: %w", err)
 	}
 
+	if uniqueWhiteboardNames != nil {
+		_, err = tx.Exec(ctx, `UPDATE whiteboards SET enforce_unique_name = $2 WHERE project_id = $1`, id, *uniqueWhiteboardNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update whiteboard name enforcement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit project update: %w", err)
+	}
+
 	return &project, nil
 }
 
@@ -196,11 +394,90 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// GenerateUniqueSlug generates a unique public slug for a project
-func (r *Repository) GenerateUniqueSlug(ctx context.Context, baseName string) (string, error) {
-	// Create a slug from the base name
-	slug := generateSlug(baseName)
+// SetArchived archives or restores a project. Archiving an archived project
+// keeps its original archived_at.
+func (r *Repository) SetArchived(ctx context.Context, id uuid.UUID, archived bool) (*Project, error) {
+	query := `
+		UPDATE projects
+		SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, NOW()) ELSE NULL END
+		WHERE id = $1
+		RETURNING id, user_id, name, description, is_public, public_slug, unique_whiteboard_names, storage_region, created_at, updated_at, archived_at, thumbnail_url, ` + defaultWhiteboardColumn + `
+	`
+
+	var project Project
+	err := r.db.QueryRow(ctx, query, id, archived).Scan(
+		&project.ID,
+		&project.UserID,
+		&project.Name,
+		&project.Description,
+		&project.IsPublic,
+		&project.PublicSlug,
+		&project.UniqueWhiteboardNames,
+		&project.StorageRegion,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&project.ArchivedAt,
+		&project.ThumbnailURL,
+		&project.DefaultWhiteboardID,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
 
+	return &project, nil
+}
+
+// RemoveCollaborator removes a user from a project's collaborators
+// Returns false if the user was not a collaborator
+func (r *Repository) RemoveCollaborator(ctx context.Context, projectID, userID uuid.UUID) (bool, error) {
+	query := `DELETE FROM project_collaborators WHERE project_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, projectID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove collaborator: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CollaboratorRole returns a user's collaborator role on a project, or "" if they aren't one
+func (r *Repository) CollaboratorRole(ctx context.Context, projectID, userID uuid.UUID) (string, error) {
+	query := `SELECT role FROM project_collaborators WHERE project_id = $1 AND user_id = $2`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, projectID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get collaborator role: %w", err)
+	}
+
+	return role, nil
+}
+
+// Touch bumps a project's updated_at, unless it was already bumped within
+// minInterval, so frequent touches don't each cost a write
+func (r *Repository) Touch(ctx context.Context, id uuid.UUID, minInterval time.Duration) error {
+	query := `
+		UPDATE projects
+		SET updated_at = NOW()
+		WHERE id = $1 AND updated_at < NOW() - $2 * INTERVAL '1 second'
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, int64(minInterval.Seconds())); err != nil {
+		return fmt.Errorf("failed to touch project: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateUniqueSlug returns slug, or slug with a random suffix if it is already taken
+func (r *Repository) GenerateUniqueSlug(ctx context.Context, slug string) (string, error) {
 	// Check if it exists
 	exists, err := r.slugExists(ctx, slug)
 	if err != nil {
@@ -239,19 +516,3 @@ func (r *Repository) UpdateSlug(ctx context.Context, id uuid.UUID, slug *string)
 	_, err := r.db.Exec(ctx, query, id, slug)
 	return err
 }
-
-// Helper function to generate a URL-friendly slug
-func generateSlug(name string) string {
-	// Simple slug generation - lowercase, replace spaces with dashes
-	slug := ""
-	for _, c := range name {
-		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' {
-			slug += string(c)
-		} else if c >= 'A' && c <= 'Z' {
-			slug += string(c + 32) // lowercase
-		} else if c == ' ' {
-			slug += "-"
-		}
-	}
-	return slug
-}
@@ -4,42 +4,121 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/activity"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
 )
 
 // Common errors
 var (
-	ErrProjectNotFound = errors.New("project not found")
-	ErrUnauthorized    = errors.New("unauthorized to access this project")
+	ErrProjectNotFound          = errors.New("project not found")
+	ErrUnauthorized             = errors.New("unauthorized to access this project")
+	ErrNotCollaborator          = errors.New("user is not a collaborator on this project")
+	ErrOwnerCannotLeave         = errors.New("project owner cannot leave; transfer ownership instead")
+	ErrUnknownRegion            = errors.New("unknown storage region")
+	ErrTemplateNotFound         = errors.New("template project not found")
+	ErrDuplicateWhiteboardNames = errors.New("project has whiteboards with duplicate names; rename them before enforcing unique names")
+	ErrProjectNotArchived       = errors.New("archive the project before deleting it permanently")
+	ErrProjectNotPublic         = errors.New("project is not public")
+	ErrInvalidSlug              = errors.New("slug must be 3-50 lowercase letters, digits and dashes, without leading, trailing or repeated dashes")
+	ErrSlugTaken                = errors.New("slug is already taken")
 )
 
 // Service handles business logic for projects
 type Service struct {
-	repo *Repository
+	repo             *Repository
+	regions          map[string]string
+	defaultRegion    string
+	hideForbidden    bool
+	slugMaxLength    int
+	slugPrefix       string
+	frontendURL      string
+	maxCollaborators int
+	filter           *contentFilter
+	onDelete         []func(ctx context.Context, projectID uuid.UUID)
+	onAccess         []func(ctx context.Context, projectID uuid.UUID)
+	activity         *activity.Recorder
+	prepareCanvas    CanvasPreparer
 }
 
 // NewService creates a new project service
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, cfg *config.Config) *Service {
+	return &Service{
+		repo:             repo,
+		regions:          cfg.StorageRegions(),
+		defaultRegion:    cfg.BlobDefaultRegion,
+		hideForbidden:    cfg.HideForbidden,
+		slugMaxLength:    cfg.PublicSlugMaxLength,
+		slugPrefix:       cfg.PublicSlugFallbackPrefix,
+		frontendURL:      cfg.FrontendURL,
+		maxCollaborators: cfg.MaxCollaboratorsPerProject,
+		filter:           newContentFilter(cfg.PublicContentBlocklist, cfg.PublicNameMaxLength, cfg.PublicDescriptionMaxLength),
+	}
+}
+
+// OnDelete registers a hook that runs after a project is deleted, so other
+// domains can clean up data the database cascade doesn't reach (e.g. stored blobs)
+func (s *Service) OnDelete(fn func(ctx context.Context, projectID uuid.UUID)) {
+	s.onDelete = append(s.onDelete, fn)
+}
+
+// OnAccessChange registers a hook that runs after a change to who may access a
+// project (its visibility, owner or collaborators), so caches of access data
+// can be dropped and open live sessions re-checked
+func (s *Service) OnAccessChange(fn func(ctx context.Context, projectID uuid.UUID)) {
+	s.onAccess = append(s.onAccess, fn)
 }
 
-// GetUserProjects gets all projects for a user
-func (s *Service) GetUserProjects(ctx context.Context, userID uuid.UUID) ([]*ProjectResponse, error) {
-	projects, err := s.repo.FindByUserID(ctx, userID)
+// SetCanvasPreparer sets how imported canvases are validated and normalized;
+// imports fail until it is set
+func (s *Service) SetCanvasPreparer(prepare CanvasPreparer) {
+	s.prepareCanvas = prepare
+}
+
+// SetActivityRecorder makes the service log project changes to recorder. Permanent
+// deletes aren't logged, since a project's activity is deleted with it.
+func (s *Service) SetActivityRecorder(recorder *activity.Recorder) {
+	s.activity = recorder
+}
+
+// GetUserProjects gets all projects for a user, archived ones only if includeArchived
+func (s *Service) GetUserProjects(ctx context.Context, userID uuid.UUID, includeArchived bool) ([]*ProjectResponse, error) {
+	projects, err := s.repo.FindByUserID(ctx, userID, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user projects: %w", err)
 	}
 
 	responses := make([]*ProjectResponse, len(projects))
 	for i, p := range projects {
-		responses[i] = s.toResponse(p)
+		responses[i] = s.toResponse(p, nil)
 	}
 
 	return responses, nil
 }
 
-// GetProject gets a project by ID, checking ownership
+// SearchProjects gets a user's projects matching q in name or description and,
+// if isPublic is set, with that visibility. Archived projects are only
+// included if includeArchived, and only starred ones if favoritesOnly.
+func (s *Service) SearchProjects(ctx context.Context, userID uuid.UUID, q string, isPublic *bool, includeArchived, favoritesOnly bool) ([]*ProjectResponse, error) {
+	projects, err := s.repo.SearchByUserID(ctx, userID, strings.TrimSpace(q), isPublic, includeArchived, favoritesOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search projects: %w", err)
+	}
+
+	responses := make([]*ProjectResponse, len(projects))
+	for i, p := range projects {
+		responses[i] = s.toResponse(p, nil)
+	}
+
+	return responses, nil
+}
+
+// GetProject gets a project by ID if the user owns or collaborates on it, or it is public
 func (s *Service) GetProject(ctx context.Context, projectID, userID uuid.UUID) (*ProjectResponse, error) {
 	project, err := s.repo.FindByID(ctx, projectID)
 	if err != nil {
@@ -49,12 +128,24 @@ func (s *Service) GetProject(ctx context.Context, projectID, userID uuid.UUID) (
 		return nil, ErrProjectNotFound
 	}
 
-	// Check access - either owner or public project
+	// Check access - owner, collaborator or public project
 	if project.UserID != userID && !project.IsPublic {
-		return nil, ErrUnauthorized
+		role, err := s.repo.CollaboratorRole(ctx, projectID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, s.forbidden(project)
+		}
 	}
 
-	return s.toResponse(project), nil
+	// Owners know who they are; everyone else sees who made the project
+	var author *ProjectAuthor
+	if project.UserID != userID {
+		author = project.Author
+	}
+
+	return s.toResponse(project, author), nil
 }
 
 // GetPublicProject gets a public project by slug
@@ -67,17 +158,36 @@ func (s *Service) GetPublicProject(ctx context.Context, slug string) (*ProjectRe
 		return nil, ErrProjectNotFound
 	}
 
-	return s.toResponse(project), nil
+	return s.toResponse(project, project.Author), nil
 }
 
 // CreateProject creates a new project
 func (s *Service) CreateProject(ctx context.Context, userID uuid.UUID, req *CreateProjectRequest) (*ProjectResponse, error) {
-	project, err := s.repo.Create(ctx, userID, req.Name, req.Description)
+	if err := s.checkRegion(req.StorageRegion); err != nil {
+		return nil, err
+	}
+
+	if req.TemplateProjectID != nil {
+		template, err := s.repo.FindByID(ctx, *req.TemplateProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find template project: %w", err)
+		}
+		// Private projects of other users are reported as missing, not forbidden
+		if template == nil || (template.UserID != userID && !template.IsPublic) {
+			return nil, ErrTemplateNotFound
+		}
+	}
+
+	project, err := s.repo.Create(ctx, userID, req.Name, req.Description, req.StorageRegion, req.TemplateProjectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
 	}
 
-	return s.toResponse(project), nil
+	s.activity.Record(ctx, project.ID, userID, activity.ProjectCreated, map[string]interface{}{
+		"name": project.Name,
+	})
+
+	return s.toResponse(project, nil), nil
 }
 
 // UpdateProject updates a project
@@ -91,13 +201,34 @@ func (s *Service) UpdateProject(ctx context.Context, projectID, userID uuid.UUID
 		return nil, ErrProjectNotFound
 	}
 	if existing.UserID != userID {
-		return nil, ErrUnauthorized
+		return nil, s.forbidden(existing)
+	}
+	if err := s.checkRegion(req.StorageRegion); err != nil {
+		return nil, err
+	}
+
+	// Screen what will be shown publicly, on publish and on edits while public
+	willBePublic := existing.IsPublic
+	if req.IsPublic != nil {
+		willBePublic = *req.IsPublic
+	}
+	if willBePublic {
+		name, description := existing.Name, existing.Description
+		if req.Name != nil {
+			name = *req.Name
+		}
+		if req.Description != nil {
+			description = *req.Description
+		}
+		if err := s.filter.check(name, description); err != nil {
+			return nil, err
+		}
 	}
 
 	// Handle public slug generation when making public
 	if req.IsPublic != nil && *req.IsPublic && !existing.IsPublic {
 		// Generate a unique slug when making project public
-		slug, err := s.repo.GenerateUniqueSlug(ctx, existing.Name)
+		slug, err := s.uniqueSlug(ctx, existing.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate slug: %w", err)
 		}
@@ -114,16 +245,158 @@ func (s *Service) UpdateProject(ctx context.Context, projectID, userID uuid.UUID
 	}
 
 	// Update the project
-	project, err := s.repo.Update(ctx, projectID, req.Name, req.Description, req.IsPublic)
+	project, err := s.repo.Update(ctx, projectID, req.Name, req.Description, req.IsPublic, req.UniqueWhiteboardNames, req.StorageRegion)
+	if database.IsUniqueViolation(err, "idx_whiteboards_unique_name") {
+		return nil, ErrDuplicateWhiteboardNames
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update project: %w", err)
 	}
 
-	return s.toResponse(project), nil
+	if req.IsPublic != nil && *req.IsPublic != existing.IsPublic {
+		s.accessChanged(ctx, projectID)
+	}
+
+	s.activity.Record(ctx, projectID, userID, activity.ProjectUpdated, map[string]interface{}{
+		"fields": req.fields(),
+	})
+
+	return s.toResponse(project, nil), nil
+}
+
+// PreviewPublicURL returns the owner the URL a project is shared at, or the
+// one publishing it would produce. It never publishes or reserves a slug.
+func (s *Service) PreviewPublicURL(ctx context.Context, projectID, userID uuid.UUID) (*PublicURLResponse, error) {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		return nil, s.forbidden(project)
+	}
+
+	if project.IsPublic && project.PublicSlug != nil {
+		return s.publicURL(*project.PublicSlug, true), nil
+	}
+
+	slug, err := s.uniqueSlug(ctx, project.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate slug: %w", err)
+	}
+
+	return s.publicURL(slug, false), nil
 }
 
-// DeleteProject deletes a project
-func (s *Service) DeleteProject(ctx context.Context, projectID, userID uuid.UUID) error {
+// UpdatePublicSlug replaces the generated slug of a public project with one
+// the owner picked. The old URL stops working.
+func (s *Service) UpdatePublicSlug(ctx context.Context, projectID, userID uuid.UUID, slug string) (*ProjectResponse, error) {
+	if !validCustomSlug(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		return nil, s.forbidden(project)
+	}
+	if !project.IsPublic {
+		return nil, ErrProjectNotPublic
+	}
+	if project.PublicSlug != nil && *project.PublicSlug == slug {
+		return s.toResponse(project, nil), nil
+	}
+
+	taken, err := s.repo.slugExists(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check slug: %w", err)
+	}
+	if taken {
+		return nil, ErrSlugTaken
+	}
+
+	// The unique constraint catches a slug claimed since the check
+	err = s.repo.UpdateSlug(ctx, projectID, &slug)
+	if database.IsUniqueViolation(err, "") {
+		return nil, ErrSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update slug: %w", err)
+	}
+
+	project.PublicSlug = &slug
+	s.activity.Record(ctx, projectID, userID, activity.ProjectUpdated, map[string]interface{}{
+		"fields": []string{"public_slug"},
+	})
+	return s.toResponse(project, nil), nil
+}
+
+// uniqueSlug generates a slug for a project name that no project uses yet.
+// It only reads, so calling it doesn't claim the slug.
+func (s *Service) uniqueSlug(ctx context.Context, name string) (string, error) {
+	// Leave room for the suffix added when the slug is already taken
+	base := generateSlug(name, s.slugMaxLength-slugSuffixLength, s.slugPrefix)
+	return s.repo.GenerateUniqueSlug(ctx, base)
+}
+
+// publicURL builds the response for a project's shareable URL
+func (s *Service) publicURL(slug string, published bool) *PublicURLResponse {
+	return &PublicURLResponse{
+		Slug:      slug,
+		URL:       s.frontendURL + "/public/" + slug,
+		Published: published,
+	}
+}
+
+// ArchiveProject archives or restores a project. Archived projects drop out
+// of the project list and their public link stops working, but nothing is
+// deleted.
+func (s *Service) ArchiveProject(ctx context.Context, projectID, userID uuid.UUID, archived bool) (*ProjectResponse, error) {
+	existing, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrProjectNotFound
+	}
+	if existing.UserID != userID {
+		return nil, s.forbidden(existing)
+	}
+
+	project, err := s.repo.SetArchived(ctx, projectID, archived)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	// The public link comes and goes with the archive
+	if project.IsPublic && (existing.ArchivedAt != nil) != archived {
+		s.accessChanged(ctx, projectID)
+	}
+
+	if (existing.ArchivedAt != nil) != archived {
+		action := activity.ProjectRestored
+		if archived {
+			action = activity.ProjectArchived
+		}
+		s.activity.Record(ctx, projectID, userID, action, nil)
+	}
+
+	return s.toResponse(project, nil), nil
+}
+
+// DeleteProject permanently deletes a project. Only archived projects can be
+// deleted, unless permanent is set to skip the archive.
+func (s *Service) DeleteProject(ctx context.Context, projectID, userID uuid.UUID, permanent bool) error {
 	// First check ownership
 	existing, err := s.repo.FindByID(ctx, projectID)
 	if err != nil {
@@ -133,21 +406,131 @@ func (s *Service) DeleteProject(ctx context.Context, projectID, userID uuid.UUID
 		return ErrProjectNotFound
 	}
 	if existing.UserID != userID {
-		return ErrUnauthorized
+		return s.forbidden(existing)
+	}
+	if existing.ArchivedAt == nil && !permanent {
+		return ErrProjectNotArchived
+	}
+
+	if err := s.repo.Delete(ctx, projectID); err != nil {
+		return err
+	}
+
+	for _, fn := range s.onDelete {
+		fn(ctx, projectID)
+	}
+
+	return nil
+}
+
+// touchInterval is the minimum time between updated_at bumps from TouchProject
+const touchInterval = time.Minute
+
+// TouchProject marks a project as recently used so it sorts to the top of the
+// dashboard. Only the owner and editors can touch a project; viewers opening a
+// shared project don't reorder the owner's list.
+func (s *Service) TouchProject(ctx context.Context, projectID, userID uuid.UUID) error {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return ErrProjectNotFound
+	}
+
+	if project.UserID != userID {
+		role, err := s.repo.CollaboratorRole(ctx, projectID, userID)
+		if err != nil {
+			return err
+		}
+		if role != "editor" {
+			if role == "" {
+				return s.forbidden(project)
+			}
+			return ErrUnauthorized
+		}
+	}
+
+	return s.repo.Touch(ctx, projectID, touchInterval)
+}
+
+// LeaveProject removes the calling user from a project's collaborators
+func (s *Service) LeaveProject(ctx context.Context, projectID, userID uuid.UUID) error {
+	project, err := s.repo.FindByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to find project: %w", err)
+	}
+	if project == nil {
+		return ErrProjectNotFound
+	}
+	if project.UserID == userID {
+		return ErrOwnerCannotLeave
+	}
+
+	removed, err := s.repo.RemoveCollaborator(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotCollaborator
+	}
+
+	s.accessChanged(ctx, projectID)
+	return nil
+}
+
+// accessChanged runs the OnAccessChange hooks for a project
+func (s *Service) accessChanged(ctx context.Context, projectID uuid.UUID) {
+	for _, fn := range s.onAccess {
+		fn(ctx, projectID)
 	}
+}
+
+// forbidden returns the error for a user acting on a project they don't own.
+// With HIDE_FORBIDDEN, private projects are reported as missing so their IDs
+// can't be probed; public projects are already visible, so they keep 403.
+func (s *Service) forbidden(p *Project) error {
+	if s.hideForbidden && !p.IsPublic {
+		return ErrProjectNotFound
+	}
+	return ErrUnauthorized
+}
 
-	return s.repo.Delete(ctx, projectID)
+// checkRegion rejects storage regions that have no configured backend
+func (s *Service) checkRegion(region *string) error {
+	if region == nil || *region == "" {
+		return nil
+	}
+	if _, ok := s.regions[*region]; !ok {
+		return ErrUnknownRegion
+	}
+	return nil
+}
+
+// regionOf returns the effective storage region of a project
+func (s *Service) regionOf(p *Project) string {
+	if p.StorageRegion == nil || *p.StorageRegion == "" {
+		return s.defaultRegion
+	}
+	return *p.StorageRegion
 }
 
-// toResponse converts a Project to a ProjectResponse
-func (s *Service) toResponse(p *Project) *ProjectResponse {
+// toResponse converts a Project to a ProjectResponse, showing author if set
+func (s *Service) toResponse(p *Project, author *ProjectAuthor) *ProjectResponse {
 	return &ProjectResponse{
-		ID:          p.ID.String(),
-		Name:        p.Name,
-		Description: p.Description,
-		IsPublic:    p.IsPublic,
-		PublicSlug:  p.PublicSlug,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:                    p.ID.String(),
+		Name:                  p.Name,
+		Description:           p.Description,
+		IsPublic:              p.IsPublic,
+		PublicSlug:            p.PublicSlug,
+		UniqueWhiteboardNames: p.UniqueWhiteboardNames,
+		StorageRegion:         s.regionOf(p),
+		DefaultWhiteboardID:   uuidString(p.DefaultWhiteboardID),
+		CreatedAt:             p.CreatedAt,
+		UpdatedAt:             p.UpdatedAt,
+		ArchivedAt:            p.ArchivedAt,
+		ThumbnailURL:          p.ThumbnailURL,
+		IsFavorite:            p.IsFavorite,
+		Author:                author,
 	}
 }
@@ -0,0 +1,99 @@
+package project
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugSuffixLength is the room GenerateUniqueSlug needs for a collision suffix ("-" + 8 hex chars)
+const slugSuffixLength = 9
+
+// minSlugLength keeps a misconfigured maximum from producing useless slugs
+const minSlugLength = 16
+
+// Bounds for slugs chosen by users
+const (
+	customSlugMinLength = 3
+	customSlugMaxLength = 50
+)
+
+// customSlugPattern is lowercase letters and digits in dash-separated groups
+var customSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validCustomSlug reports whether a user-chosen slug is acceptable
+func validCustomSlug(slug string) bool {
+	return len(slug) >= customSlugMinLength && len(slug) <= customSlugMaxLength && customSlugPattern.MatchString(slug)
+}
+
+// transliterations covers Latin letters that don't decompose into an ASCII base
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "ae", 'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o", 'đ': "d", 'Đ': "d", 'ð': "d", 'Ð': "d",
+	'ł': "l", 'Ł': "l", 'þ': "th", 'Þ': "th", 'ı': "i",
+}
+
+// generateSlug builds a URL-friendly slug of at most maxLen characters.
+// Accents are stripped and full-width forms folded to ASCII, and runs of
+// spaces and dashes become a single dash with none at either end. Names with
+// no usable characters left (e.g. CJK or emoji only) get fallbackPrefix plus
+// a random suffix, so the result is never empty.
+func generateSlug(name string, maxLen int, fallbackPrefix string) string {
+	if maxLen < minSlugLength {
+		maxLen = minSlugLength
+	}
+
+	slug := asciiSlug(name)
+	if slug == "" {
+		prefix := asciiSlug(fallbackPrefix)
+		if prefix == "" {
+			prefix = "project"
+		}
+		if len(prefix) > maxLen-slugSuffixLength {
+			prefix = strings.TrimRight(prefix[:maxLen-slugSuffixLength], "-")
+		}
+		return prefix + "-" + uuid.New().String()[:8]
+	}
+
+	if len(slug) > maxLen {
+		slug = strings.TrimRight(slug[:maxLen], "-")
+	}
+	return slug
+}
+
+// asciiSlug lowercases s and keeps only ASCII letters, digits and dashes.
+// Whitespace turns into dashes, and dashes are collapsed and trimmed, so
+// "  A -- B " becomes "a-b".
+func asciiSlug(s string) string {
+	var b strings.Builder
+	dash := false // a dash is due before the next letter or digit
+	for _, c := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, c) {
+			continue // combining accent left over from decomposition
+		}
+		var word string
+		switch t, ok := transliterations[c]; {
+		case ok:
+			word = t
+		case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'):
+			word = string(c)
+		case c >= 'A' && c <= 'Z':
+			word = string(c + 32) // lowercase
+		case c == '-' || unicode.IsSpace(c):
+			dash = true
+			continue
+		default:
+			continue
+		}
+
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(word)
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package project
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSlugTransliterates(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Crème Brûlée", "creme-brulee"},
+		{"Straße", "strasse"},
+		{"Ærø Øst", "aero-ost"},
+		{"Łódź", "lodz"},
+		{"ＡＢＣ１２３", "abc123"}, // full-width forms
+		{"Ünïcödé", "unicode"},
+	}
+
+	for _, tt := range tests {
+		if got := generateSlug(tt.name, 64, "project"); got != tt.want {
+			t.Errorf("generateSlug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSlugFallsBack(t *testing.T) {
+	random := regexp.MustCompile(`^([a-z0-9-]+)-[0-9a-f]{8}$`)
+
+	tests := []struct {
+		name, prefix, wantPrefix string
+	}{
+		{"系统设计", "project", "project"},
+		{"🚀🚀", "Board", "board"},
+		{"", "", "project"},
+		{"日本語", "日本", "project"}, // an unusable prefix falls back too
+	}
+
+	for _, tt := range tests {
+		got := generateSlug(tt.name, 64, tt.prefix)
+		m := random.FindStringSubmatch(got)
+		if m == nil || m[1] != tt.wantPrefix {
+			t.Errorf("generateSlug(%q, prefix %q) = %q, want %q plus a random suffix", tt.name, tt.prefix, got, tt.wantPrefix)
+		}
+	}
+
+	if a, b := generateSlug("系统", 64, "project"), generateSlug("系统", 64, "project"); a == b {
+		t.Errorf("two fallback slugs are equal: %q", a)
+	}
+}
+
+func TestGenerateSlugBoundsLength(t *testing.T) {
+	long := strings.Repeat("word ", 20)
+
+	if got := generateSlug(long, 24, "project"); len(got) > 24 || strings.HasSuffix(got, "-") {
+		t.Errorf("generateSlug(long, 24) = %q, want at most 24 characters without a trailing dash", got)
+	}
+
+	// A maximum below minSlugLength is raised to it
+	if got := generateSlug(strings.Repeat("a", 40), 4, "project"); len(got) != minSlugLength {
+		t.Errorf("generateSlug with max 4 = %q (%d characters), want %d", got, len(got), minSlugLength)
+	}
+
+	// The fallback prefix is shortened to leave room for the suffix
+	got := generateSlug("系统", minSlugLength, strings.Repeat("p", 40))
+	if len(got) != minSlugLength {
+		t.Errorf("fallback slug %q has %d characters, want %d", got, len(got), minSlugLength)
+	}
+}
+
+func TestGenerateSlugCollapsesDashes(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"A  B", "a-b"},
+		{"  A -- B ", "a-b"},
+		{" Hi ", "hi"},
+		{"--leading and trailing--", "leading-and-trailing"},
+		{"tabs\tand\nnewlines", "tabs-and-newlines"},
+		{"a - - b", "a-b"},
+		{"C++ & Go", "c-go"},
+		{"¡Hola!", "hola"},
+	}
+
+	for _, tt := range tests {
+		if got := generateSlug(tt.name, 64, "project"); got != tt.want {
+			t.Errorf("generateSlug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSlugTrimsDashAfterTruncation(t *testing.T) {
+	// Cut right after "abcdefghijklmno", leaving a dash at the end
+	if got := generateSlug("abcdefghijklmno pqrs", minSlugLength, "project"); got != "abcdefghijklmno" {
+		t.Errorf("generateSlug = %q, want %q", got, "abcdefghijklmno")
+	}
+}
+
+func TestGenerateSlugDashesOnlyFallsBack(t *testing.T) {
+	random := regexp.MustCompile(`^project-[0-9a-f]{8}$`)
+
+	for _, name := range []string{"---", " - ", "\t"} {
+		if got := generateSlug(name, 64, "--"); !random.MatchString(got) {
+			t.Errorf("generateSlug(%q) = %q, want project plus a random suffix", name, got)
+		}
+	}
+}
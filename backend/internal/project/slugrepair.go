@@ -0,0 +1,203 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/activity"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Slug regeneration batch sizes
+const (
+	defaultSlugRepairBatchSize = 100
+	maxSlugRepairBatchSize     = 1000
+	// slugRepairAttempts bounds retries when a freshly generated slug is
+	// claimed by another project before it's stored
+	slugRepairAttempts = 3
+)
+
+// ErrInvalidSlugRepairCursor is returned when after isn't a project ID
+var ErrInvalidSlugRepairCursor = errors.New("after must be a project id")
+
+// slugCandidate is a public project whose slug may need regenerating
+type slugCandidate struct {
+	ID         uuid.UUID
+	Name       string
+	PublicSlug *string
+}
+
+// RegenerateSlugsRequest is the request body for regenerating public slugs
+type RegenerateSlugsRequest struct {
+	// After resumes an interrupted run after the project ID it last reported
+	After     string `json:"after,omitempty"`
+	BatchSize int    `json:"batch_size,omitempty" validate:"omitempty,min=1,max=1000"`
+}
+
+// RegenerateSlugsResponse reports the outcome of a slug regeneration run
+type RegenerateSlugsResponse struct {
+	Scanned int `json:"scanned"`
+	Updated int `json:"updated"`
+	// Skipped counts projects whose names still don't make a valid slug
+	Skipped int `json:"skipped"`
+	// LastProjectID is the last project checked; pass it as after to resume
+	LastProjectID string `json:"last_project_id,omitempty"`
+}
+
+// ==================== Repository ====================
+
+// FindPublicSlugBatch returns up to limit public projects with IDs after
+// cursor, in ID order
+func (r *Repository) FindPublicSlugBatch(ctx context.Context, cursor uuid.UUID, limit int) ([]*slugCandidate, error) {
+	query := `
+		SELECT id, name, public_slug
+		FROM projects
+		WHERE is_public = true AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find public projects: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*slugCandidate
+	for rows.Next() {
+		var c slugCandidate
+		if err := rows.Scan(&c.ID, &c.Name, &c.PublicSlug); err != nil {
+			return nil, fmt.Errorf("failed to scan public project: %w", err)
+		}
+		candidates = append(candidates, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate public projects: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// ReplaceSlug sets a public project's slug unless it changed since it was read
+// as previous. updated_at is left alone: a repair isn't an edit. Reports
+// whether the slug was replaced.
+func (r *Repository) ReplaceSlug(ctx context.Context, id uuid.UUID, previous *string, slug string) (bool, error) {
+	query := `
+		UPDATE projects
+		SET public_slug = $3
+		WHERE id = $1 AND is_public = true AND public_slug IS NOT DISTINCT FROM $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, previous, slug)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ==================== Service ====================
+
+// RegenerateSlugs gives every public project whose slug is missing or no
+// longer valid a fresh unique one, working through projects in batches by ID.
+// Changes are logged to each project's activity as made by adminID. On
+// failure the partial result is returned with the error; its LastProjectID
+// can be passed back as after to resume.
+func (s *Service) RegenerateSlugs(ctx context.Context, adminID uuid.UUID, req *RegenerateSlugsRequest) (*RegenerateSlugsResponse, error) {
+	cursor := uuid.Nil
+	if req.After != "" {
+		id, err := uuid.Parse(req.After)
+		if err != nil {
+			return nil, ErrInvalidSlugRepairCursor
+		}
+		cursor = id
+	}
+
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultSlugRepairBatchSize
+	}
+	if batchSize > maxSlugRepairBatchSize {
+		batchSize = maxSlugRepairBatchSize
+	}
+
+	result := &RegenerateSlugsResponse{}
+	for {
+		batch, err := s.repo.FindPublicSlugBatch(ctx, cursor, batchSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, p := range batch {
+			if err := s.repairSlug(ctx, adminID, p, result); err != nil {
+				return result, fmt.Errorf("failed to regenerate slug of project %s: %w", p.ID, err)
+			}
+			result.Scanned++
+			result.LastProjectID = p.ID.String()
+			cursor = p.ID
+		}
+
+		logger.Ctx(ctx).Info().Str("user_id", adminID.String()).Int("scanned", result.Scanned).Int("updated", result.Updated).Str("last_project_id", result.LastProjectID).Msg("Regenerated slug batch")
+
+		if len(batch) < batchSize {
+			return result, nil
+		}
+	}
+}
+
+// repairSlug stores a new unique slug for one project if its slug is missing
+// or invalid, counting the outcome in result. Projects unpublished or given a
+// slug by their owner meanwhile are left alone.
+func (s *Service) repairSlug(ctx context.Context, adminID uuid.UUID, p *slugCandidate, result *RegenerateSlugsResponse) error {
+	if p.PublicSlug != nil && s.validStoredSlug(*p.PublicSlug) {
+		return nil
+	}
+
+	// Replacing one invalid slug with another would churn on every run
+	base := generateSlug(p.Name, s.slugMaxLength-slugSuffixLength, s.slugPrefix)
+	if !s.validStoredSlug(base) {
+		result.Skipped++
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		slug, err := s.repo.GenerateUniqueSlug(ctx, base)
+		if err != nil {
+			return err
+		}
+
+		// Another project can claim the slug between the check and the write
+		updated, err := s.repo.ReplaceSlug(ctx, p.ID, p.PublicSlug, slug)
+		if database.IsUniqueViolation(err, "") && attempt < slugRepairAttempts-1 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if updated {
+			result.Updated++
+			s.activity.Record(ctx, p.ID, adminID, activity.ProjectUpdated, map[string]interface{}{
+				"fields":   []string{"public_slug"},
+				"previous": p.PublicSlug,
+				"source":   "admin",
+			})
+		}
+		return nil
+	}
+}
+
+// validStoredSlug reports whether a stored slug is one the current rules
+// could have produced: dash-separated lowercase letters and digits, no longer
+// than a generated or custom slug may be
+func (s *Service) validStoredSlug(slug string) bool {
+	maxLen := s.slugMaxLength
+	if maxLen < customSlugMaxLength {
+		maxLen = customSlugMaxLength
+	}
+	return len(slug) <= maxLen && customSlugPattern.MatchString(slug)
+}
@@ -0,0 +1,60 @@
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownRegion is returned for a storage region with no configured backend
+var ErrUnknownRegion = errors.New("unknown storage region")
+
+// Router picks a Store by data-residency region, so projects pinned to a region
+// keep their blobs (uploaded assets and thumbnails) in that region's backend.
+// Canvas data isn't a blob and stays in the primary database.
+type Router struct {
+	stores        map[string]Store
+	defaultRegion string
+}
+
+// NewRouter creates a router over region stores. defaultRegion is used when a
+// project has no region set and must be one of the configured regions.
+func NewRouter(defaultRegion string, stores map[string]Store) (*Router, error) {
+	if _, ok := stores[defaultRegion]; !ok {
+		return nil, fmt.Errorf("%w: default region %q has no store", ErrUnknownRegion, defaultRegion)
+	}
+	return &Router{
+		stores:        stores,
+		defaultRegion: defaultRegion,
+	}, nil
+}
+
+// Resolve maps an empty region to the default and rejects unknown ones
+func (r *Router) Resolve(region string) (string, error) {
+	if region == "" {
+		return r.defaultRegion, nil
+	}
+	if _, ok := r.stores[region]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownRegion, region)
+	}
+	return region, nil
+}
+
+// Store returns the store for a region ("" means the default region)
+func (r *Router) Store(region string) (Store, error) {
+	region, err := r.Resolve(region)
+	if err != nil {
+		return nil, err
+	}
+	return r.stores[region], nil
+}
+
+// Regions returns the configured region names, sorted
+func (r *Router) Regions() []string {
+	regions := make([]string, 0, len(r.stores))
+	for region := range r.stores {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
@@ -0,0 +1,115 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Guard is a shared safety net for bulk endpoints: it caps the number of items
+// a single request may touch and bounds the transaction the work runs in
+type Guard struct {
+	maxItems         int
+	statementTimeout time.Duration
+}
+
+// NewGuard creates a new bulk guard
+func NewGuard(maxItems int, statementTimeout time.Duration) *Guard {
+	return &Guard{
+		maxItems:         maxItems,
+		statementTimeout: statementTimeout,
+	}
+}
+
+// Middleware rejects requests whose JSON body is an array, or contains a
+// top-level array or object field, with more items than the cap. It runs before the handler so
+// oversized requests never reach the database.
+func (g *Guard) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body := c.Body()
+		if len(body) == 0 {
+			return c.Next()
+		}
+
+		if n := largestCollection(body); n > g.maxItems {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":     "too many items",
+				"count":     n,
+				"max_items": g.maxItems,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RunInTx runs fn inside a transaction whose statements are bounded by the
+// guard's statement timeout. The transaction is committed if fn returns nil.
+func (g *Guard) RunInTx(ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin bulk transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// SET LOCAL doesn't accept parameters, set_config(..., true) is the equivalent
+	timeout := fmt.Sprintf("%dms", g.statementTimeout.Milliseconds())
+	if _, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`, timeout); err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit bulk transaction: %w", err)
+	}
+
+	return nil
+}
+
+// largestCollection returns the length of the body if it is a JSON array, or
+// the item count of its largest top-level array or object field if it is an
+// object (so maps keyed by ID, like layout positions, are capped too)
+func largestCollection(body []byte) int {
+	var list []json.RawMessage
+	if err := json.Unmarshal(body, &list); err == nil {
+		return len(list)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return 0
+	}
+
+	largest := 0
+	for _, raw := range fields {
+		if n := collectionLen(raw); n > largest {
+			largest = n
+		}
+	}
+
+	return largest
+}
+
+// collectionLen returns the number of items in a JSON array or object, or 0
+// for any other value
+func collectionLen(raw json.RawMessage) int {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return len(items)
+	}
+
+	var keyed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keyed); err == nil {
+		return len(keyed)
+	}
+
+	return 0
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+var Client *redis.Client
+
+// Connect connects to Redis. redisURL may be a bare "host:port" address or a
+// redis:// / rediss:// URL with credentials and database number.
+func Connect(redisURL string) (*redis.Client, error) {
+	opts := &redis.Options{Addr: redisURL}
+	if strings.Contains(redisURL, "://") {
+		parsed, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		opts = parsed
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Test the connection
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	Client = client
+	logger.Info().Msg("✅ Connected to Redis")
+
+	return client, nil
+}
+
+// Close closes the Redis connection
+func Close() {
+	if Client != nil {
+		conns := Client.PoolStats().TotalConns
+		_ = Client.Close()
+		logger.Info().Uint32("connections", conns).Msg("🔌 Disconnected from Redis")
+	}
+}
+
+// Health checks if the Redis connection is healthy
+func Health() error {
+	if Client == nil {
+		return errors.New("redis not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return Client.Ping(ctx).Err()
+}
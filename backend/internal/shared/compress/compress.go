@@ -0,0 +1,66 @@
+// Package compress stores large JSON documents gzip-compressed. A stored
+// document is a column triple: the plain JSON, the compressed bytes and the
+// encoding naming how they were compressed (NULL for plain documents).
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Gzip is the encoding of gzip-compressed documents
+const Gzip = "gzip"
+
+// Stored is a JSON document as written to or read from its columns. Exactly
+// one of Plain and Compressed is set, except for a NULL document.
+type Stored struct {
+	Plain      json.RawMessage
+	Compressed []byte
+	Encoding   *string
+}
+
+// Pack prepares a document for storage. Documents of at least threshold bytes
+// are gzip-compressed; smaller ones, or all of them when threshold is 0 or
+// less, are stored as plain JSON.
+func Pack(data json.RawMessage, threshold int) (*Stored, error) {
+	if threshold <= 0 || len(data) < threshold {
+		return &Stored{Plain: data}, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress document: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress document: %w", err)
+	}
+
+	encoding := Gzip
+	return &Stored{Compressed: buf.Bytes(), Encoding: &encoding}, nil
+}
+
+// Unpack returns the plain JSON of a stored document, decompressing it if needed
+func (s *Stored) Unpack() (json.RawMessage, error) {
+	if s.Encoding == nil {
+		return s.Plain, nil
+	}
+	if *s.Encoding != Gzip {
+		return nil, fmt.Errorf("unknown document encoding %q", *s.Encoding)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(s.Compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress document: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress document: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,81 @@
+package concurrency
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Limiter caps the number of in-flight requests per key (usually a user ID).
+// Unlike a rate limiter it doesn't care how often requests arrive, only how
+// many are running at once, so one client can't tie up every expensive worker.
+type Limiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	max      int
+}
+
+// New creates a limiter allowing max concurrent requests per key.
+// A max of zero or less disables the limit.
+func New(max int) *Limiter {
+	return &Limiter{
+		inFlight: make(map[string]int),
+		max:      max,
+	}
+}
+
+// Acquire takes a slot for key, returning false if key is already at the limit
+func (l *Limiter) Acquire(key string) bool {
+	if l.max <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[key] >= l.max {
+		return false
+	}
+	l.inFlight[key]++
+	return true
+}
+
+// Release frees a slot taken by Acquire
+func (l *Limiter) Release(key string) {
+	if l.max <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[key] <= 1 {
+		// Drop idle keys so the map doesn't grow with every user ever seen
+		delete(l.inFlight, key)
+		return
+	}
+	l.inFlight[key]--
+}
+
+// Middleware returns a handler that rejects requests with 429 while the caller
+// already has max requests in flight. Authenticated requests are keyed by user ID,
+// anonymous ones by client IP.
+func (l *Limiter) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.IP()
+		if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+			key = "user:" + userID
+		}
+
+		if !l.Acquire(key) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":         "too_many_concurrent",
+				"message":       "too many requests in progress, wait for earlier ones to finish",
+				"max_in_flight": l.max,
+			})
+		}
+		defer l.Release(key)
+
+		return c.Next()
+	}
+}
@@ -44,11 +44,13 @@ func Connect(databaseURL string) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-// Close closes the database connection pool
+// Close closes the database connection pool, waiting for acquired connections
+// to be released
 func Close() {
 	if Pool != nil {
+		conns := Pool.Stat().TotalConns()
 		Pool.Close()
-		logger.Info().Msg("🔌 Disconnected from PostgreSQL")
+		logger.Info().Int32("connections", conns).Msg("🔌 Disconnected from PostgreSQL")
 	}
 }
 
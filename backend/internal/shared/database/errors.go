@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeUniqueViolation     = "23505"
+	codeForeignKeyViolation = "23503"
+)
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+// If constraint is non-empty, the violated constraint (or index) must match it.
+func IsUniqueViolation(err error, constraint string) bool {
+	return isConstraintError(err, codeUniqueViolation, constraint)
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+// If constraint is non-empty, the violated constraint must match it.
+func IsForeignKeyViolation(err error, constraint string) bool {
+	return isConstraintError(err, codeForeignKeyViolation, constraint)
+}
+
+func isConstraintError(err error, code, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != code {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
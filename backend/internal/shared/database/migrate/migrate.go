@@ -0,0 +1,216 @@
+// Package migrate applies ordered, up-only SQL migrations and records them in
+// the schema_migrations table
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// lockID is the advisory lock held while migrating, so instances starting
+// together apply each migration once
+const lockID = 7_281_943_001
+
+// Migration is one schema change, read from a file named NNN_description.sql
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Load reads the migrations in the top directory of fsys, ordered by version.
+// Files other than .sql are ignored; two files with one version are an error.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := []Migration{}
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies the migrations in fsys newer than the database's version, each in
+// its own transaction, and returns how many were applied. A failed migration
+// is rolled back and stops the run.
+func Up(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS) (int, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	// The advisory lock belongs to a session, so hold one connection throughout
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return 0, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := createTable(ctx, conn); err != nil {
+		return 0, err
+	}
+
+	var current int
+	if err := conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return applied, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		// Without arguments Exec uses the simple protocol, which allows several statements
+		if _, err := tx.Exec(ctx, m.SQL); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("migration %s failed: %w", m.Name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return applied, fmt.Errorf("failed to commit migration %s: %w", m.Name, err)
+		}
+
+		logger.Info().Int("version", m.Version).Str("name", m.Name).Msg("Applied migration")
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Baseline adopts a database whose schema predates migration tracking: when
+// no migration has been recorded yet, it records those in fsys up to version
+// as applied without running them, and returns how many it recorded. On a
+// database with migration history it does nothing.
+func Baseline(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS, version int) (int, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return 0, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := createTable(ctx, conn); err != nil {
+		return 0, err
+	}
+
+	var recorded bool
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations)`).Scan(&recorded); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if recorded {
+		return 0, nil
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	marked := 0
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return 0, fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+		}
+		marked++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit baseline: %w", err)
+	}
+
+	logger.Info().Int("version", version).Int("marked", marked).Msg("Recorded baseline migrations as applied")
+	return marked, nil
+}
+
+// createTable creates the schema_migrations table if it doesn't exist
+func createTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// Version returns the newest applied migration, 0 if none has been applied
+func Version(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	if err := pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
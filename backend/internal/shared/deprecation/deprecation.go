@@ -0,0 +1,135 @@
+// Package deprecation announces API routes that are going away, using the
+// Deprecation, Sunset (RFC 8594) and Link headers, so clients get notice well
+// before a route or the whole API version is removed.
+package deprecation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// dateLayout is the format of configured sunset dates
+const dateLayout = "2006-01-02"
+
+// Notice describes one deprecation. A zero Sunset means no removal date is set yet.
+type Notice struct {
+	Sunset time.Time
+}
+
+// Policy holds which routes are deprecated. Routes are keyed by their
+// registered pattern (e.g. "/api/v1/projects/:id/touch"), optionally prefixed
+// with a method ("POST /api/v1/projects/:id/touch").
+type Policy struct {
+	version *Notice
+	routes  map[string]Notice
+	link    string
+}
+
+// New builds a policy. versionDeprecated (or a versionSunset date) marks every
+// route in the group; routes maps individual routes to a sunset date
+// (YYYY-MM-DD, or "" for none). link, if set, points clients to migration docs.
+func New(versionDeprecated bool, versionSunset string, routes map[string]string, link string) (*Policy, error) {
+	p := &Policy{routes: make(map[string]Notice, len(routes)), link: link}
+
+	if versionDeprecated || versionSunset != "" {
+		sunset, err := parseDate(versionSunset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API version sunset: %w", err)
+		}
+		p.version = &Notice{Sunset: sunset}
+	}
+
+	for route, date := range routes {
+		sunset, err := parseDate(date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sunset for %s: %w", route, err)
+		}
+		p.routes[route] = Notice{Sunset: sunset}
+	}
+
+	return p, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, value)
+}
+
+// Middleware marks responses from deprecated routes. It runs after the route
+// handler so it can match on the route's pattern rather than the raw path.
+// Deprecated routes also get a "warning" field added to JSON object bodies;
+// a deprecated version only sets headers.
+func (p *Policy) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if p.version == nil && len(p.routes) == 0 {
+			return err
+		}
+
+		path := c.Route().Path
+		notice, ok := p.routes[c.Method()+" "+path]
+		if !ok {
+			notice, ok = p.routes[path]
+		}
+		routeDeprecated := ok
+		if !ok {
+			if p.version == nil {
+				return err
+			}
+			notice = *p.version
+		}
+
+		c.Set("Deprecation", "true")
+		if !notice.Sunset.IsZero() {
+			c.Set("Sunset", notice.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if p.link != "" {
+			c.Append(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="deprecation"`, p.link))
+		}
+
+		if routeDeprecated && err == nil {
+			addWarning(c, notice)
+		}
+		return err
+	}
+}
+
+// addWarning inserts a "warning" field at the start of a JSON object body,
+// leaving the rest of the body byte-for-byte as the handler wrote it
+func addWarning(c *fiber.Ctx, notice Notice) {
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+
+	body := bytes.TrimSpace(c.Response().Body())
+	if len(body) < 2 || body[0] != '{' {
+		return
+	}
+
+	message := "This endpoint is deprecated"
+	if !notice.Sunset.IsZero() {
+		message += " and will be removed on " + notice.Sunset.Format(dateLayout)
+	}
+	warning, _ := json.Marshal(message)
+
+	rest := bytes.TrimSpace(body[1:])
+	separator := ","
+	if len(rest) > 0 && rest[0] == '}' {
+		separator = ""
+	}
+
+	out := make([]byte, 0, len(body)+len(warning)+12)
+	out = append(out, `{"warning":`...)
+	out = append(out, warning...)
+	out = append(out, separator...)
+	out = append(out, rest...)
+	c.Response().SetBodyRaw(out)
+}
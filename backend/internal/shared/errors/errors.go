@@ -1,8 +1,12 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 // AppError represents an application error
@@ -10,6 +14,8 @@ type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	// Fields maps invalid request fields to what's wrong with them
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 func (e *AppError) Error() string {
@@ -83,3 +89,29 @@ func Validation(details string) *AppError {
 		Details: details,
 	}
 }
+
+// StatusClientClosedRequest is the non-standard status (nginx's 499) recorded
+// for requests whose client went away before the response was ready
+const StatusClientClosedRequest = 499
+
+// IsCanceled reports whether err comes from a cancelled or timed-out context,
+// i.e. the request was abandoned rather than something failing
+func IsCanceled(err error) bool {
+	return stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded)
+}
+
+// ClientAborted returns middleware that turns 5xx responses of abandoned
+// requests into 499, so they aren't logged or counted as server errors
+func ClientAborted() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		aborted := c.Context().Err() != nil || c.UserContext().Err() != nil
+		if aborted && (IsCanceled(err) || c.Response().StatusCode() >= fiber.StatusInternalServerError) {
+			c.Status(StatusClientClosedRequest)
+			return nil
+		}
+
+		return err
+	}
+}
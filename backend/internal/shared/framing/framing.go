@@ -0,0 +1,33 @@
+package framing
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+const allowKey = "framing.ancestors"
+
+// Middleware forbids other sites from framing responses (clickjacking
+// protection), except those a handler opened up with Allow
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		if ancestors, ok := c.Locals(allowKey).(string); ok {
+			c.Set(fiber.HeaderContentSecurityPolicy, "frame-ancestors "+ancestors)
+			return err
+		}
+
+		c.Set(fiber.HeaderXFrameOptions, "DENY")
+		c.Set(fiber.HeaderContentSecurityPolicy, "frame-ancestors 'none'")
+		return err
+	}
+}
+
+// Allow lets the current response be framed by ancestors, a CSP
+// frame-ancestors source list such as "*" or "https://blog.example.com"
+func Allow(c *fiber.Ctx, ancestors string) {
+	if ancestors == "" {
+		ancestors = "'none'"
+	}
+	c.Locals(allowKey, ancestors)
+}
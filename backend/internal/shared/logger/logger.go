@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
+
+	apperrors "github.com/AnupamSingh2004/SysDes/backend/internal/shared/errors"
 )
 
 var Log zerolog.Logger
@@ -23,8 +25,10 @@ func Init(env string) {
 	}
 
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	revealPII = false
 	if env == "development" {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		revealPII = true
 	}
 }
 
@@ -48,6 +52,19 @@ func Error() *zerolog.Event {
 	return Log.Error()
 }
 
+// Failure logs err at error level, or at debug level if it only means the
+// request was cancelled (client went away, deadline passed)
+func Failure(err error) *zerolog.Event {
+	return failure(&Log, err)
+}
+
+func failure(l *zerolog.Logger, err error) *zerolog.Event {
+	if apperrors.IsCanceled(err) {
+		return l.Debug().Err(err).Bool("canceled", true)
+	}
+	return l.Error().Err(err)
+}
+
 // Fatal logs a fatal message and exits
 func Fatal() *zerolog.Event {
 	return Log.Fatal()
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// RequestConfig configures the request logging middleware
+type RequestConfig struct {
+	// SkipPaths are exact paths that are never logged (health checks, metrics, probes)
+	SkipPaths []string
+
+	// RouteLevels maps path prefixes to the level their requests are logged at,
+	// e.g. "/api/v1/public" => "debug". The longest matching prefix wins;
+	// unmatched requests log at info.
+	RouteLevels map[string]string
+
+	// SlowThreshold promotes requests that take longer than this to warn level.
+	// Zero disables slow-request highlighting.
+	SlowThreshold time.Duration
+}
+
+// RequestLogger returns middleware that logs each request through zerolog,
+// tagged with its request_id when RequestID runs first
+func RequestLogger(cfg RequestConfig) fiber.Handler {
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+
+	levels := make(map[string]zerolog.Level, len(cfg.RouteLevels))
+	for prefix, name := range cfg.RouteLevels {
+		level, err := zerolog.ParseLevel(strings.ToLower(name))
+		if err != nil || level == zerolog.NoLevel {
+			Warn().Str("prefix", prefix).Str("level", name).Msg("Ignoring invalid route log level")
+			continue
+		}
+		levels[prefix] = level
+	}
+
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if skip[path] {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+		latency := time.Since(start)
+
+		// Let the error handler write the response first so the logged status is accurate
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		level := routeLevel(levels, path)
+		slow := cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold
+		if slow && level < zerolog.WarnLevel {
+			level = zerolog.WarnLevel
+		}
+		if status >= fiber.StatusInternalServerError {
+			level = zerolog.ErrorLevel
+		}
+
+		event := For(c).WithLevel(level).
+			Int("status", status).
+			Str("method", c.Method()).
+			Str("path", Path(c)).
+			Dur("latency", latency).
+			Str("ip", c.IP())
+		if slow {
+			event = event.Bool("slow", true)
+		}
+		event.Msg("Request")
+
+		return nil
+	}
+}
+
+// Path returns the request path for logging, with secrets carried in it
+// (e.g. /api/v1/embed/:token) redacted
+func Path(c *fiber.Ctx) string {
+	path := c.Path()
+	for _, param := range c.Route().Params {
+		if param == "token" {
+			if value := c.Params(param); value != "" {
+				path = strings.Replace(path, value, Secret(value), 1)
+			}
+		}
+	}
+	return path
+}
+
+// routeLevel returns the level for the longest prefix matching path
+func routeLevel(levels map[string]zerolog.Level, path string) zerolog.Level {
+	level := zerolog.InfoLevel
+	longest := -1
+	for prefix, l := range levels {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			level = l
+			longest = len(prefix)
+		}
+	}
+	return level
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"strings"
+)
+
+// revealPII is set in development, where logs stay on the developer's machine
+// and run at debug level; everywhere else personal data and secrets are
+// masked before they reach log aggregation
+var revealPII bool
+
+// redacted replaces secrets in logs
+const redacted = "[redacted]"
+
+// Email masks an email address for logging, keeping the first letter and the
+// domain: "anupam@example.com" becomes "a***@example.com"
+func Email(email string) string {
+	if revealPII || email == "" {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Secret redacts a token, code or other secret for logging. Empty values are
+// kept, so logs still show whether one was sent.
+func Secret(value string) string {
+	if revealPII || value == "" {
+		return value
+	}
+	return redacted
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// RequestIDHeader carries a request's correlation ID. A proxy or client may
+// set it; the response always echoes the ID used.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps incoming IDs, which end up in every log line
+const maxRequestIDLength = 128
+
+// Keys of the request ID and request logger in c.Locals, which also makes them
+// reachable from c.Context() in services
+type (
+	requestIDKey     struct{}
+	requestLoggerKey struct{}
+)
+
+// RequestID returns middleware that tags each request with an ID, taken from
+// X-Request-ID when it's sane and generated otherwise, and gives the request a
+// logger that adds it to every line as request_id
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		requestLogger := Log.With().Str("request_id", id).Logger()
+		c.Locals(requestIDKey{}, id)
+		c.Locals(requestLoggerKey{}, &requestLogger)
+		c.Set(RequestIDHeader, id)
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the request, or "" outside RequestID
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey{}).(string)
+	return id
+}
+
+// For returns the logger for a request, which tags lines with its request_id
+func For(c *fiber.Ctx) *zerolog.Logger {
+	return Ctx(c.Context())
+}
+
+// Ctx returns the request logger carried by ctx (a request's c.Context() or a
+// context derived from it), or the global logger for other contexts
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(requestLoggerKey{}).(*zerolog.Logger); ok {
+		return l
+	}
+	return &Log
+}
+
+// FailureFor is Failure for the logger of a request
+func FailureFor(c *fiber.Ctx, err error) *zerolog.Event {
+	return failure(For(c), err)
+}
+
+// FailureCtx is Failure for the logger carried by ctx
+func FailureCtx(ctx context.Context, err error) *zerolog.Event {
+	return failure(Ctx(ctx), err)
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so a client
+// can't break up or forge log lines through the header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// DefaultMessage is returned to clients while writes are blocked
+const DefaultMessage = "SysDes is undergoing maintenance; changes are temporarily disabled"
+
+const (
+	// redisKey holds the shared state once an admin has set it
+	redisKey = "maintenance:enabled"
+	// cacheTTL is how long an instance trusts its last read of redisKey, so
+	// a change reaches every instance within this long
+	cacheTTL = 2 * time.Second
+	// readTimeout bounds the Redis read made on the request path
+	readTimeout = 500 * time.Millisecond
+)
+
+// Mode is a read-only switch that can be flipped at runtime. With Redis the
+// state is shared by every instance; without it, it is local to the process.
+type Mode struct {
+	client  *redis.Client
+	enabled atomic.Bool
+
+	// refresh lets one request at a time re-read Redis; checkedAt is when it last did
+	refresh   sync.Mutex
+	checkedAt atomic.Int64
+}
+
+// New creates a maintenance mode switch with the given initial state. With a
+// nil client (Redis unavailable) the switch only affects this process.
+// Instances share the initial state until an admin sets one.
+func New(client *redis.Client, enabled bool) *Mode {
+	m := &Mode{client: client}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled reports whether writes are currently blocked. If Redis can't be
+// read, the last known state is kept.
+func (m *Mode) Enabled(ctx context.Context) bool {
+	if m.client == nil {
+		return m.enabled.Load()
+	}
+
+	// Requests arriving while another re-reads Redis use the cached state
+	if m.fresh() || !m.refresh.TryLock() {
+		return m.enabled.Load()
+	}
+	defer m.refresh.Unlock()
+	if m.fresh() {
+		return m.enabled.Load()
+	}
+	m.checkedAt.Store(time.Now().UnixNano())
+
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	value, err := m.client.Get(ctx, redisKey).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		// Never set; keep the configured initial state
+	case err != nil:
+		logger.Ctx(ctx).Warn().Err(err).Msg("Failed to read maintenance mode, keeping last known state")
+	default:
+		m.enabled.Store(value == "1")
+	}
+
+	return m.enabled.Load()
+}
+
+// Set turns maintenance mode on or off, on every instance when Redis is configured
+func (m *Mode) Set(ctx context.Context, enabled bool) error {
+	if m.client != nil {
+		value := "0"
+		if enabled {
+			value = "1"
+		}
+		if err := m.client.Set(ctx, redisKey, value, 0).Err(); err != nil {
+			return fmt.Errorf("failed to set maintenance mode: %w", err)
+		}
+	}
+
+	m.enabled.Store(enabled)
+	m.checkedAt.Store(time.Now().UnixNano())
+
+	return nil
+}
+
+// fresh reports whether the cached state was read from Redis within cacheTTL
+func (m *Mode) fresh() bool {
+	return time.Since(time.Unix(0, m.checkedAt.Load())) < cacheTTL
+}
+
+// Middleware rejects non-read requests with 503 while maintenance mode is on.
+// Requests whose path starts with one of exemptPrefixes always pass, so sessions
+// can still be refreshed and admins can turn maintenance off again.
+func (m *Mode) Middleware(exemptPrefixes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.Enabled(c.Context()) {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		path := c.Path()
+		for _, prefix := range exemptPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return c.Next()
+			}
+		}
+
+		c.Set(fiber.HeaderRetryAfter, "120")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":       "maintenance",
+			"message":     DefaultMessage,
+			"maintenance": true,
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sysdes"
+
+// unmatchedRoute labels requests no route matched, so unknown paths can't
+// blow up label cardinality
+const unmatchedRoute = "unmatched"
+
+// registry holds the server's metrics, along with Go runtime and process stats
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	authAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Logins, token refreshes and token checks by method and result.",
+	}, []string{"method", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		authAttempts,
+	)
+}
+
+// Middleware returns middleware that counts and times each request by its
+// route pattern (e.g. /api/v1/projects/:id) and final status. Errors go
+// through the app's error handler first, as in the request logger, so they
+// are counted with the status the client gets.
+func Middleware() fiber.Handler {
+	// Handler routes (not middleware) by "METHOD path", collected on the first
+	// request once every route is registered
+	var (
+		routesOnce sync.Once
+		routes     map[string]bool
+	)
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		routesOnce.Do(func() {
+			routes = make(map[string]bool)
+			for _, r := range c.App().GetRoutes(true) {
+				routes[r.Method+" "+r.Path] = true
+			}
+		})
+
+		// When no handler matched, the current route is the last middleware run
+		route := c.Route().Path
+		if !routes[c.Route().Method+" "+route] {
+			route = unmatchedRoute
+		}
+
+		status := strconv.Itoa(c.Response().StatusCode())
+		// Labels outlive the request, so copy the method out of fasthttp's buffer
+		method := strings.Clone(c.Method())
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
+
+// AuthAttempt counts an authentication attempt: method names the flow
+// ("github", "refresh", "2fa", ...) and err is its outcome
+func AuthAttempt(method string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	authAttempts.WithLabelValues(method, result).Inc()
+}
+
+// Handler serves the metrics in the Prometheus text format. With a token set,
+// scrapers must send it as a bearer token.
+func Handler(token string) fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	return func(c *fiber.Ctx) error {
+		if token != "" {
+			provided, _ := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "invalid metrics token",
+				})
+			}
+		}
+
+		return serve(c)
+	}
+}
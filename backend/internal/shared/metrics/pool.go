@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reports database pool stats, read from pgxpool.Stat() on
+// every scrape
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	newConns          *prometheus.Desc
+}
+
+// RegisterDBPool adds the stats of a database pool to the metrics
+func RegisterDBPool(pool *pgxpool.Pool) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	registry.MustRegister(&poolCollector{
+		pool:              pool,
+		acquiredConns:     desc("acquired_connections", "Connections currently checked out of the pool."),
+		idleConns:         desc("idle_connections", "Idle connections in the pool."),
+		constructingConns: desc("constructing_connections", "Connections being opened."),
+		totalConns:        desc("total_connections", "Connections in the pool, of any state."),
+		maxConns:          desc("max_connections", "Maximum size of the pool."),
+		acquires:          desc("acquires_total", "Connections acquired from the pool."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires canceled before getting a connection."),
+		newConns:          desc("new_connections_total", "Connections opened by the pool."),
+	})
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.acquiredConns
+	ch <- p.idleConns
+	ch <- p.constructingConns
+	ch <- p.totalConns
+	ch <- p.maxConns
+	ch <- p.acquires
+	ch <- p.acquireDuration
+	ch <- p.emptyAcquires
+	ch <- p.canceledAcquires
+	ch <- p.newConns
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value int32) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(p.acquiredConns, stat.AcquiredConns())
+	gauge(p.idleConns, stat.IdleConns())
+	gauge(p.constructingConns, stat.ConstructingConns())
+	gauge(p.totalConns, stat.TotalConns())
+	gauge(p.maxConns, stat.MaxConns())
+	counter(p.acquires, float64(stat.AcquireCount()))
+	counter(p.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(p.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(p.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(p.newConns, float64(stat.NewConnsCount()))
+}
@@ -0,0 +1,205 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+const (
+	// batchSize is how many events one poll claims
+	batchSize = 50
+	// retryBaseDelay is the backoff before the first retry; it doubles per attempt
+	retryBaseDelay = 5 * time.Second
+	// maxRetryDelay caps the backoff
+	maxRetryDelay = 10 * time.Minute
+	// retention is how long delivered events are kept before being purged
+	retention = 7 * 24 * time.Hour
+)
+
+// Handler delivers one event. Returning an error schedules a retry, so
+// handlers must tolerate seeing the same event more than once.
+type Handler func(ctx context.Context, msg Message) error
+
+type route struct {
+	prefix  string
+	handler Handler
+}
+
+// Dispatcher delivers outbox events to the handlers registered for their topic
+type Dispatcher struct {
+	db          *pgxpool.Pool
+	interval    time.Duration
+	maxAttempts int
+	routes      []route
+}
+
+// NewDispatcher creates a dispatcher that polls every interval. Events that
+// fail maxAttempts times are marked failed and no longer retried.
+func NewDispatcher(db *pgxpool.Pool, interval time.Duration, maxAttempts int) *Dispatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		db:          db,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Handle registers a handler for every topic starting with prefix.
+// Register all handlers before calling Run.
+func (d *Dispatcher) Handle(prefix string, handler Handler) {
+	d.routes = append(d.routes, route{prefix: prefix, handler: handler})
+}
+
+// Run delivers events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+	logger.Info().Dur("interval", d.interval).Msg("📬 Outbox dispatcher started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep draining while full batches come back
+		for ctx.Err() == nil {
+			n, err := d.dispatchBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn().Err(err).Msg("Failed to dispatch outbox events")
+				}
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) > time.Hour {
+			if err := d.purge(ctx); err != nil && ctx.Err() == nil {
+				logger.Warn().Err(err).Msg("Failed to purge delivered outbox events")
+			}
+			lastPurge = time.Now()
+		}
+	}
+}
+
+// dispatchBatch claims due events and delivers them. Rows stay locked until
+// the batch commits, so several dispatchers can run against one database
+// without delivering the same event concurrently.
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, topic, aggregate_id, payload, attempts, created_at
+		FROM outbox
+		WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	messages, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Message, error) {
+		var m Message
+		err := row.Scan(&m.ID, &m.Topic, &m.AggregateID, &m.Payload, &m.Attempts, &m.CreatedAt)
+		return m, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox events: %w", err)
+	}
+
+	for _, msg := range messages {
+		if err := d.deliver(ctx, msg); err != nil {
+			if err := d.fail(ctx, tx, msg, err); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE outbox SET sent_at = NOW() WHERE id = $1`, msg.ID); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox event sent: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+	}
+
+	return len(messages), nil
+}
+
+// deliver runs every handler whose prefix matches the event's topic.
+// Events no handler is interested in count as delivered.
+func (d *Dispatcher) deliver(ctx context.Context, msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("outbox handler panicked: %v", r)
+		}
+	}()
+
+	for _, r := range d.routes {
+		if !strings.HasPrefix(msg.Topic, r.prefix) {
+			continue
+		}
+		if err := r.handler(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail schedules a retry with exponential backoff, or gives up on the event
+func (d *Dispatcher) fail(ctx context.Context, tx pgx.Tx, msg Message, cause error) error {
+	attempts := msg.Attempts + 1
+	log := logger.Warn().Err(cause).Int64("event_id", msg.ID).Str("topic", msg.Topic).Int("attempt", attempts)
+
+	if attempts >= d.maxAttempts {
+		log.Msg("Outbox event failed permanently")
+		_, err := tx.Exec(ctx, `UPDATE outbox SET attempts = $2, last_error = $3, failed_at = NOW() WHERE id = $1`,
+			msg.ID, attempts, cause.Error())
+		if err != nil {
+			return fmt.Errorf("failed to mark outbox event failed: %w", err)
+		}
+		return nil
+	}
+
+	delay := retryBaseDelay << (attempts - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	log.Dur("retry_in", delay).Msg("Outbox event delivery failed, retrying")
+
+	_, err := tx.Exec(ctx, `UPDATE outbox SET attempts = $2, last_error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 millisecond' WHERE id = $1`,
+		msg.ID, attempts, cause.Error(), delay.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to schedule outbox retry: %w", err)
+	}
+	return nil
+}
+
+// purge deletes delivered events past the retention period
+func (d *Dispatcher) purge(ctx context.Context) error {
+	_, err := d.db.Exec(ctx, `DELETE FROM outbox WHERE sent_at < NOW() - $1 * INTERVAL '1 second'`, int64(retention.Seconds()))
+	return err
+}
@@ -0,0 +1,45 @@
+// Package outbox implements a transactional outbox: events are stored in the
+// same transaction as the state change that caused them, and a Dispatcher
+// delivers them afterwards, retrying until handlers succeed.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Execer is satisfied by pgx.Tx (and *pgxpool.Pool, for changes that are a single statement)
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Message is a stored event
+type Message struct {
+	ID          int64
+	Topic       string
+	AggregateID uuid.UUID
+	Payload     json.RawMessage
+	Attempts    int
+	CreatedAt   time.Time
+}
+
+// Insert records an event. Call it with the transaction that makes the change,
+// so the event exists if and only if the change is committed.
+func Insert(ctx context.Context, db Execer, topic string, aggregateID uuid.UUID, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+
+	query := `INSERT INTO outbox (topic, aggregate_id, payload) VALUES ($1, $2, $3)`
+	if _, err := db.Exec(ctx, query, topic, aggregateID, body); err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+
+	return nil
+}
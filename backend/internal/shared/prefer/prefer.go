@@ -0,0 +1,48 @@
+// Package prefer honors the Prefer request header (RFC 7240) on write endpoints,
+// letting clients such as autosave skip the response body they don't need.
+package prefer
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReturnMinimal reports whether the request asked for Prefer: return=minimal
+func ReturnMinimal(c *fiber.Ctx) bool {
+	for _, pref := range strings.Split(c.Get("Prefer"), ",") {
+		// Preference parameters after ';' don't change the preference itself
+		token, _, _ := strings.Cut(pref, ";")
+		name, value, ok := strings.Cut(strings.TrimSpace(token), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "return") {
+			continue
+		}
+		if strings.EqualFold(strings.Trim(strings.TrimSpace(value), `"`), "minimal") {
+			return true
+		}
+	}
+	return false
+}
+
+// Write sends the result of a create or update. The ETag is always set when
+// known, and Location on creates; with return=minimal the body is dropped and
+// 204 No Content is sent with those headers instead of status.
+func Write(c *fiber.Ctx, status int, location, etag string, body interface{}) error {
+	c.Vary("Prefer")
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+
+	if ReturnMinimal(c) {
+		if location != "" {
+			c.Location(location)
+		}
+		c.Set("Preference-Applied", "return=minimal")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	if location != "" && status == fiber.StatusCreated {
+		c.Location(location)
+	}
+	return c.Status(status).JSON(body)
+}
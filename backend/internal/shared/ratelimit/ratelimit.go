@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	apperrors "github.com/AnupamSingh2004/SysDes/backend/internal/shared/errors"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+const keyPrefix = "ratelimit:"
+
+// slidingWindow counts requests in the last window milliseconds with a sorted
+// set of request timestamps, adding this one if it's under the limit. It
+// returns {allowed, remaining, retry after in ms}.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return {1, limit - count - 1, 0}
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+// Options configures one rate limit
+type Options struct {
+	// Name separates this limit's counters from other limits'
+	Name string
+	// Max is the number of requests allowed per Window; 0 or less disables the limit
+	Max    int
+	Window time.Duration
+	// Next, if set, skips the limit for requests it returns true for
+	Next func(c *fiber.Ctx) bool
+}
+
+// Limiter enforces sliding-window rate limits shared by every instance
+// through Redis. Requests are counted per user when authenticated (userID
+// set by the auth middleware) and per IP otherwise.
+type Limiter struct {
+	client *redis.Client
+}
+
+// New creates a limiter backed by Redis. With a nil client (Redis
+// unavailable) its middleware lets every request through.
+func New(client *redis.Client) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Middleware returns a handler that rejects requests over the limit with 429
+// and a Retry-After header. If Redis fails, requests are let through rather
+// than the API going down with it.
+func (l *Limiter) Middleware(opts Options) fiber.Handler {
+	if l.client == nil || opts.Max <= 0 || opts.Window <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	window := opts.Window.Milliseconds()
+	return func(c *fiber.Ctx) error {
+		if opts.Next != nil && opts.Next(c) {
+			return c.Next()
+		}
+
+		key := keyPrefix + opts.Name + ":ip:" + c.IP()
+		if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+			key = keyPrefix + opts.Name + ":user:" + userID
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), time.Second)
+		defer cancel()
+
+		now := time.Now().UnixMilli()
+		result, err := slidingWindow.Run(ctx, l.client, []string{key}, now, window, opts.Max, uuid.NewString()).Int64Slice()
+		if err != nil || len(result) != 3 {
+			logger.For(c).Warn().Err(err).Str("limit", opts.Name).Msg("Rate limit check failed, allowing request")
+			return c.Next()
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(opts.Max))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(result[1], 10))
+		if result[0] == 1 {
+			return c.Next()
+		}
+
+		// Round up, so clients that wait as told aren't rejected again
+		retryAfter := (result[2] + 999) / 1000
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+		return c.Status(fiber.StatusTooManyRequests).JSON(apperrors.ErrTooManyRequests)
+	}
+}
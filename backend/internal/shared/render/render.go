@@ -0,0 +1,308 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Card dimensions recommended for OpenGraph images
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+var (
+	background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	brand      = color.RGBA{R: 0x4f, G: 0x46, B: 0xe5, A: 0xff}
+	ink        = color.RGBA{R: 0x1e, G: 0x1e, B: 0x1e, A: 0xff}
+	muted      = color.RGBA{R: 0x6b, G: 0x72, B: 0x80, A: 0xff}
+	panel      = color.RGBA{R: 0xf8, G: 0xf9, B: 0xfb, A: 0xff}
+)
+
+// Canvas renders whiteboard shapes (as stored in canvas JSON) into a width x height
+// image, scaled to fit with padding. Only outlines are drawn, which is enough for
+// thumbnails and previews.
+func Canvas(shapes []map[string]interface{}, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	minX, minY, maxX, maxY, ok := bounds(shapes)
+	if !ok {
+		return img
+	}
+
+	const padding = 16.0
+	scale := math.Min(
+		(float64(width)-2*padding)/math.Max(maxX-minX, 1),
+		(float64(height)-2*padding)/math.Max(maxY-minY, 1),
+	)
+	scale = math.Min(scale, 4)
+
+	project := func(x, y float64) (int, int) {
+		return int(padding + (x-minX)*scale), int(padding + (y-minY)*scale)
+	}
+
+	for _, shape := range shapes {
+		stroke := parseColor(str(shape, "strokeColor"), ink)
+		x, y := num(shape, "x"), num(shape, "y")
+		w, h := num(shape, "width"), num(shape, "height")
+
+		switch str(shape, "type") {
+		case "rectangle":
+			x0, y0 := project(x, y)
+			x1, y1 := project(x+w, y+h)
+			strokeRect(img, x0, y0, x1, y1, stroke)
+		case "ellipse":
+			cx, cy := project(x+w/2, y+h/2)
+			strokeEllipse(img, cx, cy, int(math.Abs(w)*scale/2), int(math.Abs(h)*scale/2), stroke)
+		case "line", "arrow", "freedraw":
+			points := pointsOf(shape)
+			for i := 1; i < len(points); i++ {
+				x0, y0 := project(x+points[i-1][0], y+points[i-1][1])
+				x1, y1 := project(x+points[i][0], y+points[i][1])
+				line(img, x0, y0, x1, y1, stroke)
+			}
+		case "text":
+			tx, ty := project(x, y)
+			drawText(img, firstLine(str(shape, "text")), tx, ty+13, 1, stroke)
+		}
+	}
+
+	return img
+}
+
+// Card renders a branded OpenGraph card with a title, subtitle and optional thumbnail
+func Card(title, subtitle string, thumbnail image.Image) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, CardWidth, CardHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: panel}, image.Point{}, draw.Src)
+
+	// Brand bar
+	draw.Draw(img, image.Rect(0, 0, CardWidth, 24), &image.Uniform{C: brand}, image.Point{}, draw.Src)
+	drawText(img, "SysDes", 64, 96, 3, brand)
+
+	drawText(img, truncate(title, 28), 64, 200, 4, ink)
+	if subtitle != "" {
+		drawText(img, truncate(subtitle, 40), 64, 260, 2, muted)
+	}
+
+	if thumbnail != nil {
+		area := image.Rect(600, 300, CardWidth-64, CardHeight-48)
+		draw.Draw(img, area, &image.Uniform{C: background}, image.Point{}, draw.Src)
+		draw.Draw(img, area, thumbnail, thumbnail.Bounds().Min, draw.Src)
+		strokeRect(img, area.Min.X, area.Min.Y, area.Max.X-1, area.Max.Y-1, muted)
+	}
+
+	return img
+}
+
+// EncodePNG encodes an image as PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ==================== Helpers ====================
+
+// drawText draws text with the built-in bitmap font, upscaled by an integer factor
+func drawText(dst *image.RGBA, text string, x, y, scale int, c color.Color) {
+	if text == "" {
+		return
+	}
+
+	face := basicfont.Face7x13
+	// Clip to what fits across dst first: the scratch image is as wide as the
+	// text, so a long text shape would otherwise allocate for every character
+	text = truncate(text, max(dst.Bounds().Dx()/(face.Advance*scale), 4))
+	width := font.MeasureString(face, text).Ceil()
+	height := face.Height
+
+	// Render at native size, then scale up with nearest-neighbour so large text stays crisp
+	glyphs := image.NewRGBA(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{
+		Dst:  glyphs,
+		Src:  &image.Uniform{C: c},
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(text)
+
+	top := y - face.Ascent*scale
+	for gy := 0; gy < height; gy++ {
+		for gx := 0; gx < width; gx++ {
+			px := glyphs.RGBAAt(gx, gy)
+			if px.A == 0 {
+				continue
+			}
+			for sy := 0; sy < scale; sy++ {
+				for sx := 0; sx < scale; sx++ {
+					setPixel(dst, x+gx*scale+sx, top+gy*scale+sy, px)
+				}
+			}
+		}
+	}
+}
+
+func setPixel(img *image.RGBA, x, y int, c color.Color) {
+	if image.Pt(x, y).In(img.Bounds()) {
+		img.Set(x, y, c)
+	}
+}
+
+// line draws a line using Bresenham's algorithm
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		setPixel(img, x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func strokeRect(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	line(img, x0, y0, x1, y0, c)
+	line(img, x1, y0, x1, y1, c)
+	line(img, x1, y1, x0, y1, c)
+	line(img, x0, y1, x0, y0, c)
+}
+
+func strokeEllipse(img *image.RGBA, cx, cy, rx, ry int, c color.Color) {
+	steps := 4 * (rx + ry + 1)
+	px, py := cx+rx, cy
+	for i := 1; i <= steps; i++ {
+		t := 2 * math.Pi * float64(i) / float64(steps)
+		x := cx + int(float64(rx)*math.Cos(t))
+		y := cy + int(float64(ry)*math.Sin(t))
+		line(img, px, py, x, y, c)
+		px, py = x, y
+	}
+}
+
+// Extent returns the width and height of the area shapes cover, in canvas
+// units, or false if there are no shapes
+func Extent(shapes []map[string]interface{}) (width, height float64, ok bool) {
+	minX, minY, maxX, maxY, ok := bounds(shapes)
+	if !ok {
+		return 0, 0, false
+	}
+	return maxX - minX, maxY - minY, true
+}
+
+// bounds returns the bounding box of all shapes in canvas coordinates
+func bounds(shapes []map[string]interface{}) (minX, minY, maxX, maxY float64, ok bool) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+
+	extend := func(x, y float64) {
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		ok = true
+	}
+
+	for _, shape := range shapes {
+		x, y := num(shape, "x"), num(shape, "y")
+		extend(x, y)
+		extend(x+num(shape, "width"), y+num(shape, "height"))
+		for _, p := range pointsOf(shape) {
+			extend(x+p[0], y+p[1])
+		}
+	}
+
+	return minX, minY, maxX, maxY, ok
+}
+
+func pointsOf(shape map[string]interface{}) [][2]float64 {
+	raw, _ := shape["points"].([]interface{})
+	points := make([][2]float64, 0, len(raw))
+	for _, p := range raw {
+		if m, ok := p.(map[string]interface{}); ok {
+			points = append(points, [2]float64{num(m, "x"), num(m, "y")})
+		}
+	}
+	return points
+}
+
+func num(m map[string]interface{}, key string) float64 {
+	if v, ok := m[key].(float64); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return 0
+}
+
+func str(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+// parseColor parses a #rrggbb color, falling back to def
+func parseColor(s string, def color.RGBA) color.RGBA {
+	if len(s) != 7 || s[0] != '#' {
+		return def
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return def
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// truncate shortens s to at most n runes, adding an ellipsis; the bitmap font only covers ASCII
+func truncate(s string, n int) string {
+	// Stops at rune n+1, so very long strings aren't scanned to the end
+	cut, count := 0, 0
+	for i := range s {
+		if count == n-3 {
+			cut = i
+		}
+		if count == n {
+			return s[:cut] + "..."
+		}
+		count++
+	}
+	return s
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
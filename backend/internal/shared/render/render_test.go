@@ -0,0 +1,37 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is too long", 10, "this is..."},
+		{"ééééééé", 5, "éé..."},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCanvasClipsLongText(t *testing.T) {
+	shapes := []map[string]interface{}{{
+		"type": "text", "x": 0.0, "y": 0.0, "width": 100.0, "height": 20.0,
+		"text": strings.Repeat("x", 5_000_000),
+	}}
+
+	// Would allocate gigabytes if the scratch image covered the whole text
+	allocs := testing.AllocsPerRun(1, func() { Canvas(shapes, 320, 180) })
+	if allocs > 1000 {
+		t.Errorf("rendering long text made %.0f allocations", allocs)
+	}
+}
@@ -0,0 +1,117 @@
+// Package validate enforces the `validate:"..."` tags of request structs
+// (go-playground/validator rules) and reports failures per JSON field
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	apperrors "github.com/AnupamSingh2004/SysDes/backend/internal/shared/errors"
+)
+
+// validate is safe for concurrent use and caches struct metadata, so it's shared
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by the name clients send them under
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
+
+// Struct checks a request struct against its validate tags. It returns nil if
+// the struct is valid, or a 422 validation error with a message per invalid
+// field. Nil pointer fields tagged omitempty are skipped, so partial updates
+// only validate the fields they set.
+func Struct(s interface{}) *apperrors.AppError {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		// Not a struct, or a tag the validator can't parse: a bug in the caller
+		panic(fmt.Sprintf("validate: %v", err))
+	}
+
+	fields := make(map[string]string, len(fieldErrs))
+	details := make([]string, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		field := fieldPath(fe)
+		if _, ok := fields[field]; ok {
+			continue
+		}
+		fields[field] = message(fe)
+		details = append(details, field+" "+fields[field])
+	}
+
+	verr := apperrors.Validation(strings.Join(details, "; "))
+	verr.Fields = fields
+	return verr
+}
+
+// fieldPath is the field's JSON path below the top-level struct, e.g. "name"
+// or "positions[a].x"
+func fieldPath(fe validator.FieldError) string {
+	_, path, _ := strings.Cut(fe.Namespace(), ".")
+	return path
+}
+
+// message describes a failed rule in words for clients
+func message(fe validator.FieldError) string {
+	unit := "characters"
+	switch fe.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		unit = ""
+	}
+
+	if fe.Param() == "1" {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if unit == "" {
+			return "must be at least " + fe.Param()
+		}
+		return fmt.Sprintf("must have at least %s %s", fe.Param(), unit)
+	case "max", "lte":
+		if unit == "" {
+			return "must be at most " + fe.Param()
+		}
+		return fmt.Sprintf("must have at most %s %s", fe.Param(), unit)
+	case "len":
+		if unit == "" {
+			return "must be " + fe.Param()
+		}
+		return fmt.Sprintf("must have exactly %s %s", fe.Param(), unit)
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
@@ -0,0 +1,30 @@
+package thumbnail
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Thumbnail dimensions
+const (
+	Width  = 400
+	Height = 300
+)
+
+// Job is a queued request to (re)render a whiteboard thumbnail
+type Job struct {
+	WhiteboardID uuid.UUID `json:"whiteboard_id"`
+	Attempt      int       `json:"attempt"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// Source is the whiteboard data a thumbnail is rendered from
+type Source struct {
+	WhiteboardID  uuid.UUID
+	ProjectID     uuid.UUID
+	Data          json.RawMessage
+	ContentHash   string
+	ThumbnailHash string
+	StorageRegion string
+}
@@ -0,0 +1,131 @@
+package thumbnail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys used by the queue
+const (
+	queueKey   = "thumbnails:queue"
+	pendingKey = "thumbnails:pending"
+	retryKey   = "thumbnails:retry"
+	deadKey    = "thumbnails:dead"
+)
+
+// Queue is a Redis-backed thumbnail job queue.
+// Jobs live in a list; a set of pending whiteboard IDs collapses repeated saves
+// into one job, and a sorted set holds retries until their backoff expires.
+type Queue struct {
+	client *redis.Client
+}
+
+// NewQueue creates a thumbnail queue on a Redis client
+func NewQueue(client *redis.Client) *Queue {
+	return &Queue{client: client}
+}
+
+// Enqueue queues a render for a whiteboard unless one is already pending
+func (q *Queue) Enqueue(ctx context.Context, whiteboardID uuid.UUID) error {
+	added, err := q.client.SAdd(ctx, pendingKey, whiteboardID.String()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to enqueue thumbnail: %w", err)
+	}
+	if added == 0 {
+		return nil
+	}
+
+	return q.push(ctx, Job{WhiteboardID: whiteboardID})
+}
+
+// Dequeue waits up to timeout for a job. Returns nil if none arrived.
+func (q *Queue) Dequeue(ctx context.Context, timeout time.Duration) (*Job, error) {
+	result, err := q.client.BRPop(ctx, timeout, queueKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue thumbnail: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail job: %w", err)
+	}
+
+	// Saves made from here on need a fresh render, so let them enqueue again
+	if err := q.client.SRem(ctx, pendingKey, job.WhiteboardID.String()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to clear pending thumbnail: %w", err)
+	}
+
+	return &job, nil
+}
+
+// Retry schedules a failed job to run again after delay
+func (q *Queue) Retry(ctx context.Context, job Job, delay time.Duration) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	due := float64(time.Now().Add(delay).Unix())
+	if err := q.client.ZAdd(ctx, retryKey, redis.Z{Score: due, Member: payload}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule thumbnail retry: %w", err)
+	}
+	return nil
+}
+
+// PromoteDue moves retries whose backoff has expired back onto the queue
+func (q *Queue) PromoteDue(ctx context.Context) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	due, err := q.client.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read thumbnail retries: %w", err)
+	}
+
+	for _, payload := range due {
+		// Only the worker that removes the entry requeues it
+		removed, err := q.client.ZRem(ctx, retryKey, payload).Result()
+		if err != nil {
+			return fmt.Errorf("failed to promote thumbnail retry: %w", err)
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := q.client.LPush(ctx, queueKey, payload).Err(); err != nil {
+			return fmt.Errorf("failed to promote thumbnail retry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeadLetter records a job that exhausted its retries, for inspection
+func (q *Queue) DeadLetter(ctx context.Context, job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := q.client.LPush(ctx, deadKey, payload).Err(); err != nil {
+		return fmt.Errorf("failed to dead-letter thumbnail job: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) push(ctx context.Context, job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := q.client.LPush(ctx, queueKey, payload).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue thumbnail: %w", err)
+	}
+	return nil
+}
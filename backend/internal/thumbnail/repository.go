@@ -0,0 +1,124 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+)
+
+// Repository handles database operations for thumbnails
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new thumbnail repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// FindSource loads the data needed to render a whiteboard's thumbnail
+func (r *Repository) FindSource(ctx context.Context, whiteboardID uuid.UUID) (*Source, error) {
+	query := `
+		SELECT w.id, w.project_id, w.data, w.data_compressed, w.data_encoding, COALESCE(w.content_hash, ''),
+			COALESCE(w.thumbnail_hash, ''), COALESCE(p.storage_region, '')
+		FROM whiteboards w
+		JOIN projects p ON p.id = w.project_id
+		WHERE w.id = $1
+	`
+
+	var source Source
+	var stored compress.Stored
+	err := r.db.QueryRow(ctx, query, whiteboardID).Scan(
+		&source.WhiteboardID,
+		&source.ProjectID,
+		&stored.Plain,
+		&stored.Compressed,
+		&stored.Encoding,
+		&source.ContentHash,
+		&source.ThumbnailHash,
+		&source.StorageRegion,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find thumbnail source: %w", err)
+	}
+
+	if source.Data, err = stored.Unpack(); err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail source: %w", err)
+	}
+	return &source, nil
+}
+
+// SetThumbnail records a rendered thumbnail for a whiteboard
+func (r *Repository) SetThumbnail(ctx context.Context, whiteboardID uuid.UUID, key, contentHash string) error {
+	query := `
+		UPDATE whiteboards
+		SET thumbnail_key = $2, thumbnail_hash = $3, thumbnail_updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, whiteboardID, key, contentHash)
+	if err != nil {
+		return fmt.Errorf("failed to set thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+// IsStale reports whether a whiteboard has no thumbnail, or one rendered
+// from different content than it holds now
+func (r *Repository) IsStale(ctx context.Context, whiteboardID uuid.UUID) (bool, error) {
+	query := `
+		SELECT thumbnail_key IS NULL OR thumbnail_hash IS DISTINCT FROM content_hash
+		FROM whiteboards
+		WHERE id = $1
+	`
+
+	var stale bool
+	if err := r.db.QueryRow(ctx, query, whiteboardID).Scan(&stale); err != nil {
+		return false, fmt.Errorf("failed to check thumbnail: %w", err)
+	}
+
+	return stale, nil
+}
+
+// FindStale returns the IDs of whiteboards outside the trash with no thumbnail,
+// or whose thumbnail was rendered from different content than they hold now
+func (r *Repository) FindStale(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM whiteboards
+		WHERE deleted_at IS NULL
+			AND (thumbnail_key IS NULL OR thumbnail_hash IS DISTINCT FROM content_hash)
+		ORDER BY updated_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stale thumbnails: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan whiteboard id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find stale thumbnails: %w", err)
+	}
+
+	return ids, nil
+}
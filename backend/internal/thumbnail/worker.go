@@ -0,0 +1,183 @@
+package thumbnail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/blobstore"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+)
+
+// retryBaseDelay is the backoff before the first retry; it doubles per attempt
+const retryBaseDelay = 10 * time.Second
+
+// Worker renders queued thumbnails in the background
+type Worker struct {
+	queue       *Queue
+	repo        *Repository
+	stores      *blobstore.Router
+	concurrency int
+	maxAttempts int
+}
+
+// NewWorker creates a thumbnail worker pool with the given concurrency.
+// Jobs that fail maxAttempts times are dead-lettered.
+func NewWorker(queue *Queue, repo *Repository, stores *blobstore.Router, concurrency, maxAttempts int) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Worker{
+		queue:       queue,
+		repo:        repo,
+		stores:      stores,
+		concurrency: concurrency,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Run processes jobs until ctx is cancelled, then waits for in-flight renders
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	// Retry promoter
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.queue.PromoteDue(ctx); err != nil && ctx.Err() == nil {
+					logger.Warn().Err(err).Msg("Failed to promote thumbnail retries")
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	logger.Info().Int("workers", w.concurrency).Msg("🖼️ Thumbnail workers started")
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.queue.Dequeue(ctx, 5*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn().Err(err).Msg("Failed to dequeue thumbnail job")
+			time.Sleep(time.Second)
+			continue
+		}
+		if job == nil {
+			continue
+		}
+
+		// Renders use their own context so shutdown doesn't abort a half-written thumbnail
+		renderCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = w.process(renderCtx, job.WhiteboardID)
+		cancel()
+
+		if err != nil {
+			w.fail(*job, err)
+		}
+	}
+}
+
+// fail retries a job with exponential backoff, or dead-letters it
+func (w *Worker) fail(job Job, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job.Attempt++
+	job.LastError = err.Error()
+	log := logger.Warn().Err(err).Str("whiteboard_id", job.WhiteboardID.String()).Int("attempt", job.Attempt)
+
+	if job.Attempt >= w.maxAttempts {
+		log.Msg("Thumbnail render failed permanently, dead-lettering")
+		if err := w.queue.DeadLetter(ctx, job); err != nil {
+			logger.Error().Err(err).Msg("Failed to dead-letter thumbnail job")
+		}
+		return
+	}
+
+	log.Msg("Thumbnail render failed, retrying")
+	delay := retryBaseDelay << (job.Attempt - 1)
+	if err := w.queue.Retry(ctx, job, delay); err != nil {
+		logger.Error().Err(err).Msg("Failed to schedule thumbnail retry")
+	}
+}
+
+// process renders and stores one whiteboard's thumbnail
+func (w *Worker) process(ctx context.Context, whiteboardID uuid.UUID) (err error) {
+	// A malformed canvas must not take the worker down with it
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("render panicked: %v", r)
+		}
+	}()
+
+	source, err := w.repo.FindSource(ctx, whiteboardID)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		// Deleted since it was queued
+		return nil
+	}
+	if source.ContentHash != "" && source.ContentHash == source.ThumbnailHash {
+		// Already up to date
+		return nil
+	}
+
+	var canvas struct {
+		Shapes []map[string]interface{} `json:"shapes"`
+	}
+	if len(source.Data) > 0 {
+		if err := json.Unmarshal(source.Data, &canvas); err != nil {
+			return fmt.Errorf("failed to parse canvas: %w", err)
+		}
+	}
+
+	png, err := render.EncodePNG(render.Canvas(canvas.Shapes, Width, Height))
+	if err != nil {
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	store, err := w.stores.Store(source.StorageRegion)
+	if err != nil {
+		return err
+	}
+
+	key := Key(source.ProjectID, source.WhiteboardID)
+	if err := store.Put(ctx, key, png); err != nil {
+		return err
+	}
+
+	return w.repo.SetThumbnail(ctx, source.WhiteboardID, key, source.ContentHash)
+}
+
+// Key returns the blob key of a whiteboard's thumbnail
+func Key(projectID, whiteboardID uuid.UUID) string {
+	return "projects/" + projectID.String() + "/thumbnails/" + whiteboardID.String() + ".png"
+}
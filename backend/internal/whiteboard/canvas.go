@@ -0,0 +1,202 @@
+package whiteboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Canvas schema versions the server understands. Older versions are upgraded
+// by migrateCanvas; newer ones come from a frontend the server doesn't know yet.
+const (
+	MinCanvasVersion     = 0
+	CurrentCanvasVersion = 1
+)
+
+// VersionError is returned in strict mode for canvases with an unsupported schema version
+type VersionError struct {
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("canvas version %d is not supported (supported: %d-%d)", e.Version, MinCanvasVersion, CurrentCanvasVersion)
+}
+
+// ParseCanvasData parses stored canvas JSON and upgrades it to the current schema version
+func ParseCanvasData(data json.RawMessage) (*CanvasData, error) {
+	var canvas CanvasData
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &canvas); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+		}
+	}
+
+	migrateCanvas(&canvas)
+	return &canvas, nil
+}
+
+// normalizeCanvasVersion checks the schema version of incoming canvas data.
+// Older versions are migrated to the current one. Versions newer than the server
+// supports are rejected with a VersionError in strict mode, and stored untouched
+// otherwise so a newer frontend doesn't lose data.
+func normalizeCanvasVersion(data json.RawMessage, strict bool) (json.RawMessage, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+	}
+
+	switch {
+	case header.Version > CurrentCanvasVersion:
+		if strict {
+			return nil, &VersionError{Version: header.Version}
+		}
+		logger.Warn().Int("version", header.Version).Msg("Storing canvas with unknown future version")
+		return data, nil
+	case header.Version < CurrentCanvasVersion:
+		canvas, err := ParseCanvasData(data)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(canvas)
+	default:
+		return data, nil
+	}
+}
+
+// stampCanvasTimes replaces the client-supplied createdAt and updatedAt (Unix
+// milliseconds) with server time, so a client with a wrong clock can't move them.
+// updatedAt always moves past the value in previous, the stored canvas, and
+// createdAt is kept from it; previous is nil for a new whiteboard.
+func stampCanvasTimes(data, previous json.RawMessage, now time.Time) (json.RawMessage, error) {
+	var canvas map[string]json.RawMessage
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+	}
+
+	// Stored canvases were written by this function, so bad values there just restart the clock
+	var stored struct {
+		CreatedAt int64 `json:"createdAt"`
+		UpdatedAt int64 `json:"updatedAt"`
+	}
+	if len(previous) > 0 {
+		_ = json.Unmarshal(previous, &stored)
+	}
+
+	updatedAt := now.UnixMilli()
+	if updatedAt <= stored.UpdatedAt {
+		updatedAt = stored.UpdatedAt + 1
+	}
+	createdAt := stored.CreatedAt
+	if createdAt <= 0 || createdAt > updatedAt {
+		createdAt = updatedAt
+	}
+
+	canvas["createdAt"], _ = json.Marshal(createdAt)
+	canvas["updatedAt"], _ = json.Marshal(updatedAt)
+	return json.Marshal(canvas)
+}
+
+// migrateCanvas upgrades canvas data in place, one schema version at a time
+func migrateCanvas(canvas *CanvasData) {
+	// Version 0 is the empty "{}" document stored for new whiteboards
+	if canvas.Version < 1 {
+		if canvas.Viewport.Zoom == 0 {
+			canvas.Viewport.Zoom = 1
+		}
+		canvas.Version = 1
+	}
+
+	if canvas.Shapes == nil {
+		canvas.Shapes = []Shape{}
+	}
+}
+
+// MarshalCanonical serializes canvas data with a stable key order.
+// Struct fields keep their declared order and shape maps are sorted by key,
+// so equal canvases always produce identical bytes.
+func MarshalCanonical(canvas *CanvasData, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(canvas, "", "  ")
+	}
+	return json.Marshal(canvas)
+}
+
+// marshalExport serializes an export document, optionally indented
+func marshalExport(v interface{}, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
+}
+
+// ContentHash returns a SHA-256 of the canonicalized canvas data. Shapes are sorted
+// by ID first, so canvases that differ only in shape order hash equally.
+func ContentHash(data json.RawMessage) (string, error) {
+	canvas, err := ParseCanvasData(data)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := make([][]byte, len(canvas.Shapes))
+	for i, shape := range canvas.Shapes {
+		if encoded[i], err = json.Marshal(shape); err != nil {
+			return "", fmt.Errorf("failed to encode shape: %w", err)
+		}
+	}
+
+	// Sort by ID, falling back to the encoded shape so the order is total
+	order := make([]int, len(canvas.Shapes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		idA, _ := canvas.Shapes[order[a]]["id"].(string)
+		idB, _ := canvas.Shapes[order[b]]["id"].(string)
+		if idA != idB {
+			return idA < idB
+		}
+		return bytes.Compare(encoded[order[a]], encoded[order[b]]) < 0
+	})
+
+	sorted := make([]Shape, len(order))
+	for i, idx := range order {
+		sorted[i] = canvas.Shapes[idx]
+	}
+	canvas.Shapes = sorted
+
+	body, err := MarshalCanonical(canvas, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode canvas: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// exportFilename builds a download filename from a whiteboard name
+func exportFilename(name, ext string) string {
+	var b strings.Builder
+	for _, c := range strings.TrimSpace(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			b.WriteRune(c)
+		case c == ' ':
+			b.WriteRune('-')
+		}
+	}
+
+	base := b.String()
+	if base == "" {
+		base = "whiteboard"
+	}
+	return base + "." + ext
+}
@@ -0,0 +1,90 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func canvasTimes(t *testing.T, data json.RawMessage) (createdAt, updatedAt int64) {
+	t.Helper()
+	var canvas CanvasData
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		t.Fatalf("stamped data is not a canvas: %v", err)
+	}
+	return canvas.CreatedAt, canvas.UpdatedAt
+}
+
+func TestStampCanvasTimesIgnoresBackwardsClientTime(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	previous := json.RawMessage(`{"createdAt":1600000000000,"updatedAt":1690000000000}`)
+	// The client's clock is years behind
+	data := json.RawMessage(`{"version":1,"shapes":[],"createdAt":1000,"updatedAt":2000}`)
+
+	stamped, err := stampCanvasTimes(data, previous, now)
+	if err != nil {
+		t.Fatalf("stampCanvasTimes: %v", err)
+	}
+
+	createdAt, updatedAt := canvasTimes(t, stamped)
+	if updatedAt != now.UnixMilli() {
+		t.Errorf("updatedAt = %d, want server time %d", updatedAt, now.UnixMilli())
+	}
+	if createdAt != 1_600_000_000_000 {
+		t.Errorf("createdAt = %d, want the stored 1600000000000", createdAt)
+	}
+}
+
+func TestStampCanvasTimesIsMonotonic(t *testing.T) {
+	// The server clock went back, or two saves landed in the same millisecond
+	previous := json.RawMessage(`{"createdAt":1600000000000,"updatedAt":1700000000500}`)
+	data := json.RawMessage(`{"version":1,"shapes":[]}`)
+
+	stamped, err := stampCanvasTimes(data, previous, time.UnixMilli(1_700_000_000_000))
+	if err != nil {
+		t.Fatalf("stampCanvasTimes: %v", err)
+	}
+	if _, updatedAt := canvasTimes(t, stamped); updatedAt != 1_700_000_000_501 {
+		t.Errorf("updatedAt = %d, want one past the stored 1700000000500", updatedAt)
+	}
+}
+
+func TestStampCanvasTimesNewWhiteboard(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	// Client times in the future are ignored too
+	data := json.RawMessage(`{"version":1,"shapes":[],"createdAt":9999999999999,"updatedAt":9999999999999}`)
+
+	stamped, err := stampCanvasTimes(data, nil, now)
+	if err != nil {
+		t.Fatalf("stampCanvasTimes: %v", err)
+	}
+	createdAt, updatedAt := canvasTimes(t, stamped)
+	if createdAt != now.UnixMilli() || updatedAt != now.UnixMilli() {
+		t.Errorf("createdAt, updatedAt = %d, %d; want both %d", createdAt, updatedAt, now.UnixMilli())
+	}
+}
+
+func TestStampCanvasTimesKeepsOtherFields(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"text"}],"viewport":{"zoom":1.5}}`)
+
+	stamped, err := stampCanvasTimes(data, json.RawMessage(`{}`), time.Now())
+	if err != nil {
+		t.Fatalf("stampCanvasTimes: %v", err)
+	}
+	var canvas CanvasData
+	if err := json.Unmarshal(stamped, &canvas); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if canvas.Version != 1 || len(canvas.Shapes) != 1 || canvas.Viewport.Zoom != 1.5 {
+		t.Errorf("canvas = %+v, want the other fields unchanged", canvas)
+	}
+}
+
+func TestStampCanvasTimesRejectsNonObjects(t *testing.T) {
+	for _, data := range []string{`[]`, `"canvas"`, `{`} {
+		if _, err := stampCanvasTimes(json.RawMessage(data), nil, time.Now()); !errors.Is(err, ErrInvalidCanvas) {
+			t.Errorf("stampCanvasTimes(%s) error = %v, want ErrInvalidCanvas", data, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Embeds put a read-only rendering of a whiteboard on another site, in an
+// iframe. Only whiteboards that are already visible without an account can be
+// embedded: those in public projects, and those shared with a live link.
+
+// embedType is the token type of embed URLs
+const embedType = "embed"
+
+// How an embed was authorized, kept in its token
+const (
+	embedViaPublic   = "public"
+	embedViaLiveLink = "live_link"
+)
+
+// Recommended embed dimensions, in pixels
+const (
+	embedPadding       = 32
+	embedMinWidth      = 320
+	embedMaxWidth      = 1200
+	embedMinHeight     = 200
+	embedMaxHeight     = 900
+	embedDefaultWidth  = 800
+	embedDefaultHeight = 450
+)
+
+// Errors returned for embeds
+var (
+	ErrEmbedNotAllowed = errors.New("only whiteboards in public projects or shared with a live link can be embedded")
+	ErrInvalidEmbed    = errors.New("embed is invalid or has expired")
+)
+
+// EmbedResponse is an iframe-ready embed of a whiteboard
+type EmbedResponse struct {
+	URL       string    `json:"url"`
+	HTML      string    `json:"html"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Embed is a signed embed of a whiteboard, before it's turned into a URL
+type Embed struct {
+	Token      string
+	Whiteboard *Whiteboard
+	Width      int
+	Height     int
+	ExpiresAt  time.Time
+}
+
+// CreateEmbed signs an embed for a whiteboard in a public project or, with
+// liveToken, one shared with a live link. Embeds made from a live link expire
+// with it; public ones last EMBED_TOKEN_TTL_HOURS but stop working if the
+// project is made private.
+func (s *Service) CreateEmbed(ctx context.Context, whiteboardID uuid.UUID, liveToken string) (*Embed, error) {
+	via := embedViaPublic
+	expiresAt := time.Now().Add(s.embedTTL)
+	if liveToken != "" {
+		linkExpiresAt, err := s.LiveLinkAccess(ctx, liveToken, whiteboardID)
+		if err != nil {
+			return nil, err
+		}
+		via = embedViaLiveLink
+		expiresAt = linkExpiresAt
+	}
+
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+	if via == embedViaPublic {
+		if err := s.checkPublic(ctx, whiteboard.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	shapes, err := embedShapes(whiteboard)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.signLink(embedType, whiteboardID, expiresAt, jwt.MapClaims{"via": via})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign embed: %w", err)
+	}
+
+	width, height := EmbedSize(shapes)
+	return &Embed{
+		Token:      token,
+		Whiteboard: whiteboard,
+		Width:      width,
+		Height:     height,
+		ExpiresAt:  expiresAt.UTC(),
+	}, nil
+}
+
+// EmbedView checks an embed token and returns its whiteboard and shapes
+func (s *Service) EmbedView(ctx context.Context, token string) (*Whiteboard, []map[string]interface{}, error) {
+	whiteboardID, _, claims, err := s.parseLink(embedType, token)
+	if err != nil {
+		return nil, nil, ErrInvalidEmbed
+	}
+
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, nil, ErrWhiteboardNotFound
+	}
+
+	// Public embeds follow the project's visibility; live link ones are
+	// covered by the link until it expires
+	if via, _ := claims["via"].(string); via != embedViaLiveLink {
+		if err := s.checkPublic(ctx, whiteboard.ProjectID); err != nil {
+			if errors.Is(err, ErrEmbedNotAllowed) {
+				return nil, nil, ErrInvalidEmbed
+			}
+			return nil, nil, err
+		}
+	}
+
+	shapes, err := embedShapes(whiteboard)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return whiteboard, shapes, nil
+}
+
+// checkPublic checks that anyone, signed in or not, can view a project
+func (s *Service) checkPublic(ctx context.Context, projectID uuid.UUID) error {
+	role, err := s.projectRole(ctx, projectID, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	if role < roleViewer {
+		return ErrEmbedNotAllowed
+	}
+	return nil
+}
+
+// EmbedSize recommends iframe dimensions for shapes: their bounding box plus
+// padding, scaled to fit embed limits while keeping the aspect ratio
+func EmbedSize(shapes []map[string]interface{}) (int, int) {
+	w, h, ok := render.Extent(shapes)
+	if !ok {
+		return embedDefaultWidth, embedDefaultHeight
+	}
+	w, h = math.Max(w, 1)+2*embedPadding, math.Max(h, 1)+2*embedPadding
+
+	scale := math.Min(1, math.Min(embedMaxWidth/w, embedMaxHeight/h))
+	if w*scale < embedMinWidth {
+		scale = embedMinWidth / w
+	}
+
+	width := math.Min(math.Max(w*scale, embedMinWidth), embedMaxWidth)
+	height := math.Min(math.Max(h*scale, embedMinHeight), embedMaxHeight)
+	return int(math.Round(width)), int(math.Round(height))
+}
+
+func embedShapes(whiteboard *Whiteboard) ([]map[string]interface{}, error) {
+	canvas, err := ParseCanvasData(whiteboard.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	shapes := make([]map[string]interface{}, len(canvas.Shapes))
+	for i, shape := range canvas.Shapes {
+		shapes[i] = shape
+	}
+	return shapes, nil
+}
@@ -0,0 +1,93 @@
+package whiteboard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published on a project's event stream
+const (
+	EventWhiteboardCreated = "whiteboard.created"
+	EventWhiteboardUpdated = "whiteboard.updated"
+	EventWhiteboardDeleted = "whiteboard.deleted"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it
+const eventBuffer = 16
+
+// Event is a lightweight notification about a whiteboard in a project.
+// It carries no canvas data; clients refetch what they need.
+type Event struct {
+	Type         string    `json:"type"`
+	ProjectID    string    `json:"project_id"`
+	WhiteboardID string    `json:"whiteboard_id"`
+	Name         string    `json:"name,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// newEvent describes a change to a whiteboard
+func newEvent(eventType string, w *Whiteboard) Event {
+	return Event{
+		Type:         eventType,
+		ProjectID:    w.ProjectID.String(),
+		WhiteboardID: w.ID.String(),
+		Name:         w.Name,
+		At:           time.Now().UTC(),
+	}
+}
+
+// Broker fans whiteboard events out to this instance's subscribers of a
+// project. Events reach it from the outbox dispatcher, through the Redis relay
+// when one is configured so that every instance's broker gets every event.
+type Broker struct {
+	mu   sync.RWMutex
+	subs map[uuid.UUID]map[chan Event]struct{}
+}
+
+// NewBroker creates an empty event broker
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[uuid.UUID]map[chan Event]struct{})}
+}
+
+// Subscribe registers for a project's events. The returned function
+// unsubscribes and closes the channel.
+func (b *Broker) Subscribe(projectID uuid.UUID) (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+
+	b.mu.Lock()
+	if b.subs[projectID] == nil {
+		b.subs[projectID] = make(map[chan Event]struct{})
+	}
+	b.subs[projectID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[projectID], ch)
+			if len(b.subs[projectID]) == 0 {
+				delete(b.subs, projectID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to every subscriber of its project without blocking;
+// subscribers whose buffer is full miss the event
+func (b *Broker) Publish(projectID uuid.UUID, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs[projectID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
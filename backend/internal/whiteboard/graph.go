@@ -0,0 +1,173 @@
+package whiteboard
+
+import "math"
+
+// connectorTypes are shapes exported as edges rather than nodes
+var connectorTypes = map[string]bool{
+	"arrow": true,
+	"line":  true,
+}
+
+// edgeSnapDistance is how far an unbound connector end may sit outside a
+// shape's bounds and still count as attached to it
+const edgeSnapDistance = 8.0
+
+// GraphNode is a shape in the structural export
+type GraphNode struct {
+	ID    string  `json:"id"`
+	Type  string  `json:"type"`
+	Label string  `json:"label,omitempty"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// GraphEdge is a connection between two nodes in the structural export
+type GraphEdge struct {
+	ID     string `json:"id,omitempty"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Label  string `json:"label,omitempty"`
+}
+
+// Graph is the structure of a canvas without styling or viewport
+type Graph struct {
+	Version int         `json:"version"`
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+}
+
+// ToGraph projects canvas data onto nodes and edges. Arrows and lines become
+// edges, using their bindings when present and otherwise the shapes their
+// endpoints touch; connectors that don't join two nodes are dropped, as are
+// freehand strokes.
+func ToGraph(canvas *CanvasData) *Graph {
+	graph := &Graph{
+		Version: canvas.Version,
+		Nodes:   []GraphNode{},
+		Edges:   []GraphEdge{},
+	}
+
+	for _, shape := range canvas.Shapes {
+		shapeType := shapeString(shape, "type")
+		if connectorTypes[shapeType] || shapeType == "freedraw" {
+			continue
+		}
+		id := shapeString(shape, "id")
+		if id == "" {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:    id,
+			Type:  shapeType,
+			Label: shapeLabel(shape),
+			X:     shapeNumber(shape, "x"),
+			Y:     shapeNumber(shape, "y"),
+		})
+
+		// Plain connection lists from older canvases
+		for _, target := range connectionTargets(shape) {
+			graph.Edges = append(graph.Edges, GraphEdge{Source: id, Target: target})
+		}
+	}
+
+	for _, shape := range canvas.Shapes {
+		if !connectorTypes[shapeString(shape, "type")] {
+			continue
+		}
+
+		source, target := bindingIDs(shape)
+		start, end, ok := connectorEnds(shape)
+		if source == "" && ok {
+			source = nodeAt(canvas.Shapes, start)
+		}
+		if target == "" && ok {
+			target = nodeAt(canvas.Shapes, end)
+		}
+		if source == "" || target == "" {
+			continue
+		}
+
+		graph.Edges = append(graph.Edges, GraphEdge{
+			ID:     shapeString(shape, "id"),
+			Source: source,
+			Target: target,
+			Label:  shapeLabel(shape),
+		})
+	}
+
+	return graph
+}
+
+// bindingIDs returns the shapes a connector is explicitly bound to, if any
+func bindingIDs(shape Shape) (source, target string) {
+	bound := func(key string) string {
+		switch b := shape[key].(type) {
+		case string:
+			return b
+		case map[string]interface{}:
+			id, _ := b["elementId"].(string)
+			return id
+		}
+		return ""
+	}
+	return bound("startBinding"), bound("endBinding")
+}
+
+// connectorEnds returns the absolute first and last points of a connector
+func connectorEnds(shape Shape) (start, end [2]float64, ok bool) {
+	points, _ := shape["points"].([]interface{})
+	if len(points) < 2 {
+		return start, end, false
+	}
+
+	x, y := shapeNumber(shape, "x"), shapeNumber(shape, "y")
+	at := func(p interface{}) [2]float64 {
+		m, _ := p.(map[string]interface{})
+		return [2]float64{x + shapeNumber(m, "x"), y + shapeNumber(m, "y")}
+	}
+	return at(points[0]), at(points[len(points)-1]), true
+}
+
+// nodeAt returns the smallest node whose bounds contain p (within the snap distance)
+func nodeAt(shapes []Shape, p [2]float64) string {
+	best, bestArea := "", math.Inf(1)
+	for _, shape := range shapes {
+		shapeType := shapeString(shape, "type")
+		if connectorTypes[shapeType] || shapeType == "freedraw" {
+			continue
+		}
+
+		x, y := shapeNumber(shape, "x"), shapeNumber(shape, "y")
+		w, h := shapeNumber(shape, "width"), shapeNumber(shape, "height")
+		minX, maxX := math.Min(x, x+w), math.Max(x, x+w)
+		minY, maxY := math.Min(y, y+h), math.Max(y, y+h)
+
+		if p[0] < minX-edgeSnapDistance || p[0] > maxX+edgeSnapDistance ||
+			p[1] < minY-edgeSnapDistance || p[1] > maxY+edgeSnapDistance {
+			continue
+		}
+		if area := math.Abs(w * h); area < bestArea {
+			best, bestArea = shapeString(shape, "id"), area
+		}
+	}
+	return best
+}
+
+func shapeLabel(shape Shape) string {
+	if label := shapeString(shape, "label"); label != "" {
+		return label
+	}
+	return shapeString(shape, "text")
+}
+
+func shapeString(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+func shapeNumber(m map[string]interface{}, key string) float64 {
+	if v, ok := m[key].(float64); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return 0
+}
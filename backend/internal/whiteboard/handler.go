@@ -1,47 +1,101 @@
 package whiteboard
 
 import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/framing"
 	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/prefer"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/render"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/validate"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 // Handler handles HTTP requests for whiteboards
 type Handler struct {
-	service *Service
+	service        *Service
+	hub            *Hub
+	embedAncestors string
+	liveOrigins    map[string]bool
 }
 
-// NewHandler creates a new whiteboard handler
-func NewHandler(service *Service) *Handler {
-	return &Handler{service: service}
+// NewHandler creates a new whiteboard handler. embedAncestors lists the
+// sites (as CSP frame-ancestors sources) that may frame embeds; liveOrigins
+// (comma-separated, like the CORS origins) are the sites signed-in browsers
+// may open live sessions from.
+func NewHandler(service *Service, hub *Hub, embedAncestors, liveOrigins string) *Handler {
+	origins := make(map[string]bool)
+	for _, origin := range strings.Split(liveOrigins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins[origin] = true
+		}
+	}
+	return &Handler{service: service, hub: hub, embedAncestors: embedAncestors, liveOrigins: origins}
 }
 
-// RegisterRoutes registers the whiteboard routes
-func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth fiber.Handler) {
+// RegisterRoutes registers the whiteboard routes.
+// exportLimit caps concurrent exports per user; bulkLimit caps the size of batch requests
+// (batch-get, reorder and layout);
+// renderLimit caps concurrent embed renders.
+func (h *Handler) RegisterRoutes(api fiber.Router, requireAuth, exportLimit, bulkLimit, renderLimit fiber.Handler) {
 	// Project-scoped whiteboard routes (protected)
 	projects := api.Group("/projects/:projectId/whiteboards")
 	projects.Use(requireAuth)
 	projects.Get("/", h.ListByProject)
+	projects.Get("/summary", h.Summary)
 	projects.Get("/default", h.GetDefault)
 	projects.Post("/", h.Create)
 	projects.Put("/default/canvas", h.SaveCanvasByProject)
+	projects.Put("/reorder", bulkLimit, h.Reorder)
+	projects.Get("/trash", h.Trash)
+
+	// Live whiteboard changes for a project (server-sent events)
+	api.Get("/projects/:projectId/events", requireAuth, h.Events)
+
+	// Live collaboration. Registered ahead of the group's requireAuth so live
+	// link viewers, who have no account, can connect with just the link's token.
+	api.Get("/whiteboards/:id/ws", h.liveAuth(requireAuth), h.LiveUpgrade, websocket.New(h.Live))
+
+	// Embeds, for public whiteboards and live link holders (no account needed)
+	api.Get("/whiteboards/:id/embed", h.CreateEmbed)
+	api.Get("/embed/:token", renderLimit, h.EmbedView)
 
 	// Direct whiteboard routes (protected)
 	whiteboards := api.Group("/whiteboards")
 	whiteboards.Use(requireAuth)
+	whiteboards.Post("/validate", h.Validate)
+	whiteboards.Post("/batch-get", bulkLimit, h.BatchGet)
 	whiteboards.Get("/:id", h.Get)
 	whiteboards.Put("/:id", h.Update)
 	whiteboards.Put("/:id/canvas", h.SaveCanvas)
+	whiteboards.Post("/:id/duplicate", h.Duplicate)
+	whiteboards.Post("/:id/canvas/layout", bulkLimit, h.Layout)
+	whiteboards.Get("/:id/export", exportLimit, h.Export)
+	whiteboards.Post("/:id/live-links", h.CreateLiveLink)
+	whiteboards.Get("/:id/versions", h.Versions)
+	whiteboards.Get("/:id/versions/:versionId", h.Version)
+	whiteboards.Post("/:id/restore/:versionId", h.Restore)
+	whiteboards.Post("/:id/restore", h.Undelete)
 	whiteboards.Delete("/:id", h.Delete)
 }
 
 // ListByProject handles GET /api/v1/projects/:projectId/whiteboards
 // @Summary List whiteboards for a project
+// @Description Whiteboard metadata in tab order; data is null unless include=data asks for every canvas too
 // @Tags whiteboards
 // @Security BearerAuth
 // @Param projectId path string true "Project ID"
+// @Param include query string false "Comma-separated extras to include: data"
 // @Success 200 {object} WhiteboardListResponse
 // @Router /projects/{projectId}/whiteboards [get]
 func (h *Handler) ListByProject(c *fiber.Ctx) error {
@@ -59,7 +113,21 @@ func (h *Handler) ListByProject(c *fiber.Ctx) error {
 		})
 	}
 
-	whiteboards, err := h.service.GetProjectWhiteboards(c.Context(), projectID, userID)
+	withData := false
+	if include := c.Query("include"); include != "" {
+		for _, field := range strings.Split(include, ",") {
+			switch strings.TrimSpace(field) {
+			case "data":
+				withData = true
+			default:
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "include must be a comma-separated list of: data",
+				})
+			}
+		}
+	}
+
+	whiteboards, skipped, err := h.service.GetProjectWhiteboards(c.Context(), projectID, userID, withData)
 	if err != nil {
 		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -76,20 +144,27 @@ func (h *Handler) ListByProject(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(WhiteboardListResponse{
+	response := WhiteboardListResponse{
 		Whiteboards: whiteboards,
 		Total:       len(whiteboards),
-	})
+	}
+	if skipped > 0 {
+		response.Warnings = []string{fmt.Sprintf("%d whiteboard(s) could not be loaded and were skipped", skipped)}
+	}
+
+	return c.JSON(response)
 }
 
-// GetDefault handles GET /api/v1/projects/:projectId/whiteboards/default
-// @Summary Get default whiteboard for a project (creates one if none exists)
+// Summary handles GET /api/v1/projects/:projectId/whiteboards/summary
+// @Summary Get content hashes for a project's whiteboards
+// @Description Per-whiteboard content hashes in tab order, with no canvas data, plus one hash covering the whole project. Answers 304 when If-None-Match names the current project hash.
 // @Tags whiteboards
 // @Security BearerAuth
 // @Param projectId path string true "Project ID"
-// @Success 200 {object} WhiteboardResponse
-// @Router /projects/{projectId}/whiteboards/default [get]
-func (h *Handler) GetDefault(c *fiber.Ctx) error {
+// @Success 200 {object} SummaryResponse
+// @Success 304 "Not modified"
+// @Router /projects/{projectId}/whiteboards/summary [get]
+func (h *Handler) Summary(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -104,7 +179,7 @@ func (h *Handler) GetDefault(c *fiber.Ctx) error {
 		})
 	}
 
-	whiteboard, err := h.service.GetDefaultWhiteboard(c.Context(), projectID, userID)
+	summary, err := h.service.GetProjectSummary(c.Context(), projectID, userID)
 	if err != nil {
 		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -116,24 +191,29 @@ func (h *Handler) GetDefault(c *fiber.Ctx) error {
 				"error": "access denied",
 			})
 		}
-		logger.Error().Err(err).Str("projectID", projectID.String()).Str("userID", userID.String()).Msg("Failed to get default whiteboard")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "failed to get default whiteboard",
-			"details": err.Error(),
+			"error": "failed to get whiteboard summary",
 		})
 	}
 
-	return c.JSON(whiteboard)
+	etag := `W/"` + summary.ContentHash + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(summary)
 }
 
-// Get handles GET /api/v1/whiteboards/:id
-// @Summary Get a whiteboard by ID
+// Trash handles GET /api/v1/projects/:projectId/whiteboards/trash
+// @Summary List a project's deleted whiteboards
+// @Description Whiteboards that can still be restored, most recently deleted first. Project owner only.
 // @Tags whiteboards
 // @Security BearerAuth
-// @Param id path string true "Whiteboard ID"
-// @Success 200 {object} WhiteboardResponse
-// @Router /whiteboards/{id} [get]
-func (h *Handler) Get(c *fiber.Ctx) error {
+// @Param projectId path string true "Project ID"
+// @Success 200 {object} TrashListResponse
+// @Router /projects/{projectId}/whiteboards/trash [get]
+func (h *Handler) Trash(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -141,18 +221,18 @@ func (h *Handler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	whiteboardID, err := uuid.Parse(c.Params("id"))
+	projectID, err := uuid.Parse(c.Params("projectId"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid whiteboard id",
+			"error": "invalid project id",
 		})
 	}
 
-	whiteboard, err := h.service.GetWhiteboard(c.Context(), whiteboardID, userID)
+	trash, err := h.service.ListTrash(c.Context(), projectID, userID)
 	if err != nil {
-		if errors.Is(err, ErrWhiteboardNotFound) {
+		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "whiteboard not found",
+				"error": "project not found",
 			})
 		}
 		if errors.Is(err, ErrUnauthorized) {
@@ -161,22 +241,22 @@ func (h *Handler) Get(c *fiber.Ctx) error {
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to get whiteboard",
+			"error": "failed to get deleted whiteboards",
 		})
 	}
 
-	return c.JSON(whiteboard)
+	return c.JSON(trash)
 }
 
-// Create handles POST /api/v1/projects/:projectId/whiteboards
-// @Summary Create a new whiteboard
+// Reorder handles PUT /api/v1/projects/:projectId/whiteboards/reorder
+// @Summary Set the tab order of a project's whiteboards
 // @Tags whiteboards
 // @Security BearerAuth
 // @Param projectId path string true "Project ID"
-// @Param body body CreateWhiteboardRequest true "Whiteboard data"
-// @Success 201 {object} WhiteboardResponse
-// @Router /projects/{projectId}/whiteboards [post]
-func (h *Handler) Create(c *fiber.Ctx) error {
+// @Param body body ReorderRequest true "Whiteboard IDs in their new order"
+// @Success 200 {object} WhiteboardListResponse
+// @Router /projects/{projectId}/whiteboards/reorder [put]
+func (h *Handler) Reorder(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -191,15 +271,20 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 		})
 	}
 
-	var req CreateWhiteboardRequest
+	var req ReorderRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
 
-	whiteboard, err := h.service.CreateWhiteboard(c.Context(), projectID, userID, &req)
+	whiteboards, err := h.service.ReorderWhiteboards(c.Context(), projectID, userID, &req)
 	if err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "project not found",
@@ -211,22 +296,26 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create whiteboard",
+			"error": "failed to reorder whiteboards",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(whiteboard)
+	return c.JSON(WhiteboardListResponse{
+		Whiteboards: whiteboards,
+		Total:       len(whiteboards),
+	})
 }
 
-// Update handles PUT /api/v1/whiteboards/:id
-// @Summary Update a whiteboard
+// GetDefault handles GET /api/v1/projects/:projectId/whiteboards/default
+// @Summary Get default whiteboard for a project (creates one if none exists)
 // @Tags whiteboards
 // @Security BearerAuth
-// @Param id path string true "Whiteboard ID"
-// @Param body body UpdateWhiteboardRequest true "Whiteboard data"
+// @Param projectId path string true "Project ID"
+// @Param If-None-Match header string false "ETag from an earlier response; 304 if the whiteboard is unchanged"
 // @Success 200 {object} WhiteboardResponse
-// @Router /whiteboards/{id} [put]
-func (h *Handler) Update(c *fiber.Ctx) error {
+// @Success 304
+// @Router /projects/{projectId}/whiteboards/default [get]
+func (h *Handler) GetDefault(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -234,25 +323,18 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 		})
 	}
 
-	whiteboardID, err := uuid.Parse(c.Params("id"))
+	projectID, err := uuid.Parse(c.Params("projectId"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid whiteboard id",
-		})
-	}
-
-	var req UpdateWhiteboardRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": "invalid project id",
 		})
 	}
 
-	whiteboard, err := h.service.UpdateWhiteboard(c.Context(), whiteboardID, userID, &req)
+	whiteboard, err := h.service.GetDefaultWhiteboard(c.Context(), projectID, userID)
 	if err != nil {
-		if errors.Is(err, ErrWhiteboardNotFound) {
+		if errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "whiteboard not found",
+				"error": "project not found",
 			})
 		}
 		if errors.Is(err, ErrUnauthorized) {
@@ -260,23 +342,30 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 				"error": "access denied",
 			})
 		}
+		logger.FailureFor(c, err).Str("project_id", projectID.String()).Str("user_id", userID.String()).Msg("Failed to get default whiteboard")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to update whiteboard",
+			"error":   "failed to get default whiteboard",
+			"details": err.Error(),
 		})
 	}
 
-	return c.JSON(whiteboard)
+	return sendWhiteboard(c, whiteboard)
 }
 
-// SaveCanvas handles PUT /api/v1/whiteboards/:id/canvas
-// @Summary Save canvas data for a whiteboard
+// eventKeepAlive is how often an idle event stream sends a comment line, so
+// proxies don't close it and dead clients are noticed
+const eventKeepAlive = 25 * time.Second
+
+// Events handles GET /api/v1/projects/:projectId/events
+// @Summary Stream whiteboard changes in a project
+// @Description Server-sent events: whiteboard.created, whiteboard.updated, whiteboard.deleted
 // @Tags whiteboards
 // @Security BearerAuth
-// @Param id path string true "Whiteboard ID"
-// @Param body body SaveCanvasRequest true "Canvas data"
-// @Success 200 {object} WhiteboardResponse
-// @Router /whiteboards/{id}/canvas [put]
-func (h *Handler) SaveCanvas(c *fiber.Ctx) error {
+// @Param projectId path string true "Project ID"
+// @Produce text/event-stream
+// @Success 200
+// @Router /projects/{projectId}/events [get]
+func (h *Handler) Events(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -284,27 +373,142 @@ func (h *Handler) SaveCanvas(c *fiber.Ctx) error {
 		})
 	}
 
-	whiteboardID, err := uuid.Parse(c.Params("id"))
+	projectID, err := uuid.Parse(c.Params("projectId"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid whiteboard id",
+			"error": "invalid project id",
 		})
 	}
 
-	var req SaveCanvasRequest
+	events, cancel, err := h.service.SubscribeProjectEvents(c.Context(), projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to subscribe to project events",
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// The server's write timeout would cut the stream off; instead each write
+	// gets its own deadline, a little longer than the keep-alive interval
+	conn := c.Context().Conn()
+	extendDeadline := func() {
+		_ = conn.SetWriteDeadline(time.Now().Add(2 * eventKeepAlive))
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		extendDeadline()
+
+		ticker := time.NewTicker(eventKeepAlive)
+		defer ticker.Stop()
+
+		// Tell the client the stream is live before the first event
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			extendDeadline()
+
+			// A failed flush means the client went away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// BatchGet handles POST /api/v1/whiteboards/batch-get
+// @Summary Fetch several whiteboards by ID
+// @Description Returns the whiteboards the user can read, plus a status (ok, not_found, forbidden, invalid_id) per requested ID
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param body body BatchGetRequest true "Whiteboard IDs"
+// @Success 200 {object} BatchGetResponse
+// @Router /whiteboards/batch-get [post]
+func (h *Handler) BatchGet(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req BatchGetRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	response, err := h.service.BatchGetWhiteboards(c.Context(), req.IDs, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get whiteboards",
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// Get handles GET /api/v1/whiteboards/:id
+// @Summary Get a whiteboard by ID
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param If-None-Match header string false "ETag from an earlier response; 304 if the whiteboard is unchanged"
+// @Success 200 {object} WhiteboardResponse
+// @Success 304
+// @Router /whiteboards/{id} [get]
+func (h *Handler) Get(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
 
-	if len(req.Data) == 0 {
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "data is required",
+			"error": "invalid whiteboard id",
 		})
 	}
 
-	whiteboard, err := h.service.SaveCanvasData(c.Context(), whiteboardID, userID, req.Data)
+	whiteboard, err := h.service.GetWhiteboard(c.Context(), whiteboardID, userID)
 	if err != nil {
 		if errors.Is(err, ErrWhiteboardNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -317,22 +521,24 @@ func (h *Handler) SaveCanvas(c *fiber.Ctx) error {
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to save canvas data",
+			"error": "failed to get whiteboard",
 		})
 	}
 
-	return c.JSON(whiteboard)
+	return sendWhiteboard(c, whiteboard)
 }
 
-// SaveCanvasByProject handles PUT /api/v1/projects/:projectId/whiteboards/default/canvas
-// @Summary Save canvas data for a project's default whiteboard
+// Create handles POST /api/v1/projects/:projectId/whiteboards
+// @Summary Create a new whiteboard
 // @Tags whiteboards
 // @Security BearerAuth
 // @Param projectId path string true "Project ID"
-// @Param body body SaveCanvasRequest true "Canvas data"
-// @Success 200 {object} WhiteboardResponse
-// @Router /projects/{projectId}/whiteboards/default/canvas [put]
-func (h *Handler) SaveCanvasByProject(c *fiber.Ctx) error {
+// @Param body body CreateWhiteboardRequest true "Whiteboard data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 201 {object} WhiteboardResponse
+// @Success 204
+// @Router /projects/{projectId}/whiteboards [post]
+func (h *Handler) Create(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -347,24 +553,84 @@ func (h *Handler) SaveCanvasByProject(c *fiber.Ctx) error {
 		})
 	}
 
-	var req SaveCanvasRequest
+	var req CreateWhiteboardRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	whiteboard, err := h.service.CreateWhiteboard(c.Context(), projectID, userID, &req)
+	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		if handled, resp := nameConflictResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrCreateForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": ErrCreateForbidden.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create whiteboard",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusCreated, whiteboard)
+}
+
+// Duplicate handles POST /api/v1/whiteboards/:id/duplicate
+// @Summary Copy a whiteboard into a new one in the same project
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 201 {object} WhiteboardResponse
+// @Success 204
+// @Router /whiteboards/{id}/duplicate [post]
+func (h *Handler) Duplicate(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
 
-	if len(req.Data) == 0 {
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "data is required",
+			"error": "invalid whiteboard id",
 		})
 	}
 
-	whiteboard, err := h.service.SaveCanvasDataByProject(c.Context(), projectID, userID, req.Data)
+	whiteboard, err := h.service.DuplicateWhiteboard(c.Context(), whiteboardID, userID)
 	if err != nil {
-		if errors.Is(err, ErrProjectNotFound) {
+		if handled, resp := nameConflictResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "project not found",
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrCreateForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": ErrCreateForbidden.Error(),
 			})
 		}
 		if errors.Is(err, ErrUnauthorized) {
@@ -373,21 +639,24 @@ func (h *Handler) SaveCanvasByProject(c *fiber.Ctx) error {
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to save canvas data",
+			"error": "failed to duplicate whiteboard",
 		})
 	}
 
-	return c.JSON(whiteboard)
+	return writeWhiteboard(c, fiber.StatusCreated, whiteboard)
 }
 
-// Delete handles DELETE /api/v1/whiteboards/:id
-// @Summary Delete a whiteboard
+// Update handles PUT /api/v1/whiteboards/:id
+// @Summary Update a whiteboard
 // @Tags whiteboards
 // @Security BearerAuth
 // @Param id path string true "Whiteboard ID"
+// @Param body body UpdateWhiteboardRequest true "Whiteboard data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 200 {object} WhiteboardResponse
 // @Success 204
-// @Router /whiteboards/{id} [delete]
-func (h *Handler) Delete(c *fiber.Ctx) error {
+// @Router /whiteboards/{id} [put]
+func (h *Handler) Update(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -402,8 +671,24 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.service.DeleteWhiteboard(c.Context(), whiteboardID, userID)
+	var req UpdateWhiteboardRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	whiteboard, err := h.service.UpdateWhiteboard(c.Context(), whiteboardID, userID, &req)
 	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		if handled, resp := nameConflictResponse(c, err); handled {
+			return resp
+		}
 		if errors.Is(err, ErrWhiteboardNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "whiteboard not found",
@@ -415,13 +700,944 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to delete whiteboard",
+			"error": "failed to update whiteboard",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// SaveCanvas handles PUT /api/v1/whiteboards/:id/canvas
+// @Summary Save canvas data for a whiteboard
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param body body SaveCanvasRequest true "Canvas data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 200 {object} WhiteboardResponse
+// @Success 204
+// @Router /whiteboards/{id}/canvas [put]
+func (h *Handler) SaveCanvas(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	var req SaveCanvasRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	whiteboard, err := h.service.SaveCanvasData(c.Context(), whiteboardID, userID, req.Data)
+	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save canvas data",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// Layout handles POST /api/v1/whiteboards/:id/canvas/layout
+// @Summary Move shapes on a whiteboard's canvas
+// @Description Updates only the positions of the given shapes. If-Match must carry the
+// @Description whiteboard's ETag; the request fails with 412 if the canvas changed since.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param If-Match header string true "ETag of the canvas the layout was computed on"
+// @Param body body LayoutRequest true "New positions by shape ID"
+// @Success 200 {object} WhiteboardResponse
+// @Router /whiteboards/{id}/canvas/layout [post]
+func (h *Handler) Layout(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"error": "If-Match header is required",
+		})
+	}
+
+	var req LayoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	hash := etagContentHash(ifMatch)
+
+	whiteboard, err := h.service.ApplyLayout(c.Context(), whiteboardID, userID, hash, req.Positions)
+	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		var unknown *UnknownShapesError
+		if errors.As(err, &unknown) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "unknown_shapes",
+				"message": unknown.Error(),
+				"missing": unknown.IDs,
+			})
+		}
+		if errors.Is(err, ErrCanvasChanged) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save layout",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// SaveCanvasByProject handles PUT /api/v1/projects/:projectId/whiteboards/default/canvas
+// @Summary Save canvas data for a project's default whiteboard
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param projectId path string true "Project ID"
+// @Param body body SaveCanvasRequest true "Canvas data"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 200 {object} WhiteboardResponse
+// @Success 204
+// @Router /projects/{projectId}/whiteboards/default/canvas [put]
+func (h *Handler) SaveCanvasByProject(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid project id",
+		})
+	}
+
+	var req SaveCanvasRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	whiteboard, err := h.service.SaveCanvasDataByProject(c.Context(), projectID, userID, req.Data)
+	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "project not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save canvas data",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// Export handles GET /api/v1/whiteboards/:id/export
+// @Summary Export a whiteboard's canvas as normalized JSON or as a node/edge graph
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param format query string false "Export format (json, graph)"
+// @Param pretty query bool false "Pretty-print the output"
+// @Success 200 {object} CanvasData
+// @Router /whiteboards/{id}/export [get]
+func (h *Handler) Export(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "graph" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "unsupported export format",
+		})
+	}
+
+	whiteboard, canvas, err := h.service.ExportWhiteboard(c.Context(), whiteboardID, userID)
+	if err != nil {
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to export whiteboard",
+		})
+	}
+
+	var body []byte
+	ext := "json"
+	if format == "graph" {
+		body, err = marshalExport(ToGraph(canvas), c.QueryBool("pretty"))
+		ext = "graph.json"
+	} else {
+		body, err = MarshalCanonical(canvas, c.QueryBool("pretty"))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to export whiteboard",
+		})
+	}
+
+	c.Attachment(exportFilename(whiteboard.Name, ext))
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(body)
+}
+
+// Versions handles GET /api/v1/whiteboards/:id/versions
+// @Summary List a whiteboard's saved versions
+// @Tags whiteboards
+// @Security BearerAuth
+// @Description Metadata only, newest first; fetch a version by ID for its canvas data.
+// @Param id path string true "Whiteboard ID"
+// @Param limit query int false "Page size (default 20, capped by WHITEBOARD_VERSION_PAGE_MAX)"
+// @Param offset query int false "Versions to skip"
+// @Success 200 {object} VersionListResponse
+// @Router /whiteboards/{id}/versions [get]
+func (h *Handler) Versions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	limit, offset := 0, 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit must be a positive integer",
+			})
+		}
+	}
+	if raw := c.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "offset must be a non-negative integer",
+			})
+		}
+	}
+
+	page, err := h.service.ListVersions(c.Context(), whiteboardID, userID, limit, offset)
+	if err != nil {
+		if errors.Is(err, ErrWhiteboardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list versions",
+		})
+	}
+
+	return c.JSON(page)
+}
+
+// Version handles GET /api/v1/whiteboards/:id/versions/:versionId
+// @Summary Get a saved version of a whiteboard
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param versionId path string true "Version ID"
+// @Success 200 {object} VersionResponse
+// @Router /whiteboards/{id}/versions/{versionId} [get]
+func (h *Handler) Version(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	versionID, err := uuid.Parse(c.Params("versionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid version id",
+		})
+	}
+
+	version, err := h.service.GetVersion(c.Context(), whiteboardID, versionID, userID)
+	if err != nil {
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrVersionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get version",
+		})
+	}
+
+	return c.JSON(version)
+}
+
+// Restore handles POST /api/v1/whiteboards/:id/restore/:versionId
+// @Summary Restore a saved version of a whiteboard
+// @Description Copies the version's canvas back into the whiteboard, saving it as a new version.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param versionId path string true "Version ID"
+// @Param Prefer header string false "return=minimal to get 204 with Location and ETag only"
+// @Success 200 {object} WhiteboardResponse
+// @Success 204
+// @Router /whiteboards/{id}/restore/{versionId} [post]
+func (h *Handler) Restore(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	versionID, err := uuid.Parse(c.Params("versionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid version id",
+		})
+	}
+
+	whiteboard, err := h.service.RestoreVersion(c.Context(), whiteboardID, versionID, userID)
+	if err != nil {
+		if handled, resp := canvasErrorResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrVersionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to restore version",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// liveAuth skips requireAuth for requests carrying a live link token, which
+// LiveUpgrade checks instead
+func (h *Handler) liveAuth(requireAuth fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Query("live_token") != "" {
+			return c.Next()
+		}
+		return requireAuth(c)
+	}
+}
+
+// LiveUpgrade handles GET /api/v1/whiteboards/:id/ws before the WebSocket
+// upgrade, rejecting requests that aren't upgrades or lack access
+// @Summary Collaborate on a whiteboard live
+// @Description WebSocket. Clients send shapes.upsert and shapes.delete deltas (editors only)
+// @Description and any other typed message; the server relays them to the room with user_id
+// @Description and saves the canvas periodically. Authenticate with the access_token cookie,
+// @Description or watch read-only with a live link's live_token until it expires. Cookie sessions
+// @Description must come from the frontend's origin. Access is re-checked while connected; users
+// @Description who lose it get an error message and are disconnected.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param live_token query string false "Live link token, instead of signing in"
+// @Success 101
+// @Router /whiteboards/{id}/ws [get]
+func (h *Handler) LiveUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"error": "websocket upgrade required",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	if token := c.Query("live_token"); token != "" {
+		expiresAt, err := h.service.LiveLinkAccess(c.Context(), token, whiteboardID)
+		if err != nil {
+			if errors.Is(err, ErrInvalidLiveLink) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			if errors.Is(err, ErrWhiteboardNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "whiteboard not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to open whiteboard",
+			})
+		}
+
+		// Live link viewers are anonymous and read-only
+		c.Locals("liveWhiteboardID", whiteboardID)
+		c.Locals("liveUserID", uuid.Nil)
+		c.Locals("liveCanEdit", false)
+		c.Locals("liveExpiresAt", expiresAt)
+		return c.Next()
+	}
+
+	// Browsers send the session cookie with upgrades from any site, so only
+	// the frontend may open signed-in sessions (live links carry their own token)
+	if origin := c.Get(fiber.HeaderOrigin); origin != "" && !h.liveOrigins[origin] {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "origin not allowed",
+		})
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	canEdit, err := h.service.LiveAccess(c.Context(), whiteboardID, userID)
+	if err != nil {
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to open whiteboard",
+		})
+	}
+
+	c.Locals("liveWhiteboardID", whiteboardID)
+	c.Locals("liveUserID", userID)
+	c.Locals("liveCanEdit", canEdit)
+	return c.Next()
+}
+
+// Live runs an upgraded connection checked by LiveUpgrade
+func (h *Handler) Live(conn *websocket.Conn) {
+	whiteboardID, _ := conn.Locals("liveWhiteboardID").(uuid.UUID)
+	userID, _ := conn.Locals("liveUserID").(uuid.UUID)
+	canEdit, _ := conn.Locals("liveCanEdit").(bool)
+
+	// Live links stop working when they expire, including open connections
+	if expiresAt, ok := conn.Locals("liveExpiresAt").(time.Time); ok {
+		timer := time.AfterFunc(time.Until(expiresAt), func() {
+			_ = conn.Close()
+		})
+		defer timer.Stop()
+	}
+
+	h.hub.Serve(conn, whiteboardID, userID, canEdit)
+}
+
+// CreateLiveLink handles POST /api/v1/whiteboards/:id/live-links
+// @Summary Create a read-only live link
+// @Description Anyone with the link can watch the whiteboard's live room, without signing in, until it expires.
+// @Description Links can't be revoked before then. Owner only.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Param request body CreateLiveLinkRequest false "Link lifetime"
+// @Success 201 {object} LiveLinkResponse
+// @Router /whiteboards/{id}/live-links [post]
+func (h *Handler) CreateLiveLink(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	var req CreateLiveLinkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+
+	link, err := h.service.CreateLiveLink(c.Context(), whiteboardID, userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrLiveLinkTTL) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create live link",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+// CreateEmbed handles GET /api/v1/whiteboards/:id/embed
+// @Summary Get an embeddable rendering of a whiteboard
+// @Description Returns a signed URL and iframe snippet, sized to the canvas, for whiteboards in public
+// @Description projects or, with live_token, shared with a live link. Only the embed URL may be framed.
+// @Tags whiteboards
+// @Param id path string true "Whiteboard ID"
+// @Param live_token query string false "Live link token, for whiteboards that aren't public"
+// @Success 200 {object} EmbedResponse
+// @Router /whiteboards/{id}/embed [get]
+func (h *Handler) CreateEmbed(c *fiber.Ctx) error {
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	embed, err := h.service.CreateEmbed(c.Context(), whiteboardID, c.Query("live_token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidLiveLink) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrEmbedNotAllowed) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create embed",
+		})
+	}
+
+	url := c.BaseURL() + "/api/v1/embed/" + embed.Token
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(EmbedResponse{
+		URL: url,
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" style="border:0" loading="lazy"></iframe>`,
+			html.EscapeString(url), embed.Width, embed.Height, html.EscapeString(embed.Whiteboard.Name)),
+		Width:     embed.Width,
+		Height:    embed.Height,
+		ExpiresAt: embed.ExpiresAt,
+	})
+}
+
+// EmbedView handles GET /api/v1/embed/:token
+// @Summary View an embedded whiteboard
+// @Description An HTML page with a rendering of the whiteboard, meant for an iframe. The only
+// @Description response that other sites may frame (see EMBED_FRAME_ANCESTORS).
+// @Tags whiteboards
+// @Produce html
+// @Param token path string true "Embed token"
+// @Success 200 {string} string
+// @Router /embed/{token} [get]
+func (h *Handler) EmbedView(c *fiber.Ctx) error {
+	whiteboard, shapes, err := h.service.EmbedView(c.Context(), c.Params("token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidEmbed) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to load embed",
+		})
+	}
+
+	width, height := EmbedSize(shapes)
+	png, err := render.EncodePNG(render.Canvas(shapes, width, height))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to render embed",
+		})
+	}
+
+	framing.Allow(c, h.embedAncestors)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	name := html.EscapeString(whiteboard.Name)
+	return c.SendString(`<!DOCTYPE html><html><head><meta charset="utf-8"><title>` + name + `</title>` +
+		`<style>html,body{margin:0;height:100%;background:#fff}img{display:block;width:100%;height:100%;object-fit:contain}</style>` +
+		`</head><body><img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `" alt="` + name + `"></body></html>`)
+}
+
+// Delete handles DELETE /api/v1/whiteboards/:id
+// @Summary Delete a whiteboard
+// @Description Moves the whiteboard to the project's trash, from which the owner can restore it until the retention period ends.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Success 204
+// @Router /whiteboards/{id} [delete]
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	err = h.service.DeleteWhiteboard(c.Context(), whiteboardID, userID)
+	if err != nil {
+		if errors.Is(err, ErrWhiteboardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete whiteboard",
 		})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// Undelete handles POST /api/v1/whiteboards/:id/restore
+// @Summary Restore a deleted whiteboard
+// @Description Takes a whiteboard out of the trash within the retention period. Project owner only.
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param id path string true "Whiteboard ID"
+// @Success 200 {object} WhiteboardResponse
+// @Router /whiteboards/{id}/restore [post]
+func (h *Handler) Undelete(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	whiteboardID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid whiteboard id",
+		})
+	}
+
+	whiteboard, err := h.service.RestoreWhiteboard(c.Context(), whiteboardID, userID)
+	if err != nil {
+		if handled, resp := nameConflictResponse(c, err); handled {
+			return resp
+		}
+		if errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "whiteboard not found in trash",
+			})
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to restore whiteboard",
+		})
+	}
+
+	return writeWhiteboard(c, fiber.StatusOK, whiteboard)
+}
+
+// canvasErrorResponse writes the response for canvas data errors
+// Returns false if err is not a canvas data error
+func canvasErrorResponse(c *fiber.Ctx, err error) (bool, error) {
+	var versionErr *VersionError
+	if errors.As(err, &versionErr) {
+		return true, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "client_outdated",
+			"message": versionErr.Error(),
+			"version": versionErr.Version,
+			"supported_versions": fiber.Map{
+				"min": MinCanvasVersion,
+				"max": CurrentCanvasVersion,
+			},
+		})
+	}
+	var typeErr *ShapeTypeError
+	if errors.As(err, &typeErr) {
+		return true, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "unsupported_shape_types",
+			"message":  typeErr.Error(),
+			"rejected": typeErr.Rejected,
+		})
+	}
+	var schemaErr *CanvasSchemaError
+	if errors.As(err, &schemaErr) {
+		return true, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "invalid_canvas_schema",
+			"message": schemaErr.Error(),
+			"issues":  schemaErr.Issues,
+		})
+	}
+	var sizeErr *CanvasTooLargeError
+	if errors.As(err, &sizeErr) {
+		return true, c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":     "canvas too large",
+			"size":      sizeErr.Size,
+			"max_bytes": sizeErr.Limit,
+		})
+	}
+	if errors.Is(err, ErrInvalidCanvas) {
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid canvas data",
+		})
+	}
+	return false, nil
+}
+
+// nameConflictResponse writes a 409 for duplicate whiteboard names
+// Returns false if err is not a name conflict
+func nameConflictResponse(c *fiber.Ctx, err error) (bool, error) {
+	var conflictErr *NameConflictError
+	if !errors.As(err, &conflictErr) {
+		return false, nil
+	}
+
+	body := fiber.Map{
+		"error":   "duplicate_name",
+		"message": conflictErr.Error(),
+	}
+	if conflictErr.ExistingID != uuid.Nil {
+		body["existing_whiteboard_id"] = conflictErr.ExistingID.String()
+	}
+	return true, c.Status(fiber.StatusConflict).JSON(body)
+}
+
+// Validate handles POST /api/v1/whiteboards/validate
+// @Summary Validate canvas data without saving it
+// @Tags whiteboards
+// @Security BearerAuth
+// @Param body body SaveCanvasRequest true "Canvas data"
+// @Success 200 {object} ValidateCanvasResponse
+// @Router /whiteboards/validate [post]
+func (h *Handler) Validate(c *fiber.Ctx) error {
+	var req SaveCanvasRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if verr := validate.Struct(&req); verr != nil {
+		return c.Status(verr.Code).JSON(verr)
+	}
+
+	return c.JSON(h.service.ValidateCanvas(req.Data))
+}
+
+// writeWhiteboard sends a written whiteboard, or only its Location and ETag
+// (the content hash) when the client prefers return=minimal
+func writeWhiteboard(c *fiber.Ctx, status int, w *WhiteboardResponse) error {
+	return prefer.Write(c, status, "/api/v1/whiteboards/"+w.ID, whiteboardETag(w), w)
+}
+
+// sendWhiteboard sends a whiteboard that was read, or 304 Not Modified if the
+// client's If-None-Match already names its current ETag. Browsers are told to
+// revalidate every time, so their polls cost a 304 until the whiteboard changes.
+func sendWhiteboard(c *fiber.Ctx, w *WhiteboardResponse) error {
+	etag := whiteboardETag(w)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(w)
+}
+
+// whiteboardETag is a weak ETag of the whiteboard's content hash and last
+// write time. The time makes it change on every save, renames included; the
+// hash lets If-Match on targeted writes such as layout check the canvas.
+func whiteboardETag(w *WhiteboardResponse) string {
+	return fmt.Sprintf(`W/"%s-%x"`, w.ContentHash, w.UpdatedAt.UnixNano())
+}
+
+// etagContentHash extracts the content hash from a whiteboard ETag sent back
+// by a client. Bare quoted hashes, as older responses sent, are accepted too.
+func etagContentHash(etag string) string {
+	tag := strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+	hash, _, _ := strings.Cut(tag, "-")
+	return hash
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly (RFC 9110 13.1.2): "*" or any listed tag equal apart from W/ matches
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
 // getUserID extracts the user ID from the context (set by auth middleware)
 func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	userIDStr, ok := c.Locals("userID").(string)
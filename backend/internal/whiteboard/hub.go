@@ -0,0 +1,774 @@
+package whiteboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Live message types. Clients send shape deltas (and any other message type,
+// e.g. cursors, which is relayed untouched); the server adds user_id to every
+// relayed message and sends the room's canvas as a snapshot on join, and again
+// whenever the canvas is changed outside the room.
+const (
+	LiveShapesUpsert = "shapes.upsert"
+	LiveShapesDelete = "shapes.delete"
+	LiveSnapshot     = "snapshot"
+	LiveUserJoined   = "user.joined"
+	LiveUserLeft     = "user.left"
+	LiveError        = "error"
+)
+
+// Live connection limits
+const (
+	liveMaxMessageBytes = 1 << 20
+	liveSendBuffer      = 64
+	liveWriteWait       = 10 * time.Second
+	livePongWait        = 60 * time.Second
+	livePingPeriod      = livePongWait * 9 / 10
+	// liveAccessCheckInterval is how often a room re-checks its users' access,
+	// besides when a project's collaborators change
+	liveAccessCheckInterval = 30 * time.Second
+	// liveSaveAttempts bounds how often a save is merged and retried when the
+	// stored canvas keeps changing underneath it
+	liveSaveAttempts = 3
+)
+
+// liveSaveKey marks the context of a room's own saves, so the hub doesn't
+// treat them as changes made outside the room
+type liveSaveKey struct{}
+
+// liveMessage is the part of a client message the hub understands
+type liveMessage struct {
+	Type   string                       `json:"type"`
+	Shapes []map[string]json.RawMessage `json:"shapes,omitempty"`
+	IDs    []string                     `json:"ids,omitempty"`
+}
+
+// Hub relays live edits between clients connected to the same whiteboard.
+// Each whiteboard with connected clients has a room holding the canvas as
+// edited so far, which is saved every persist interval and when the last
+// client leaves. A save only goes through if the stored canvas is still the
+// one the room last loaded or saved; otherwise (a REST save, a restored
+// version, a layout, or a room on another instance) the room reloads the
+// stored canvas, replays its unsaved edits on top and sends clients a new
+// snapshot. Rooms are in-process only: clients on other instances don't see
+// these edits until they're saved.
+//
+// A user may be connected several times (one per tab). They're announced as
+// joined on their first connection and as left after their last, and snapshots
+// list each user once.
+type Hub struct {
+	service  *Service
+	interval time.Duration
+	// maxPerUser caps each user's connections to a room; 0 means no cap
+	maxPerUser int
+
+	mu    sync.Mutex
+	rooms map[uuid.UUID]*room
+}
+
+// NewHub creates a hub that saves open rooms every persistInterval. A user
+// opening more than maxPerUser connections to a room has their oldest closed.
+func NewHub(service *Service, persistInterval time.Duration, maxPerUser int) *Hub {
+	if persistInterval <= 0 {
+		persistInterval = 5 * time.Second
+	}
+	h := &Hub{
+		service:    service,
+		interval:   persistInterval,
+		maxPerUser: maxPerUser,
+		rooms:      make(map[uuid.UUID]*room),
+	}
+	service.OnSave(h.canvasSaved)
+	return h
+}
+
+// room is the live state of one whiteboard
+type room struct {
+	id        uuid.UUID
+	projectID uuid.UUID
+	hub       *Hub
+	stop      chan struct{}
+	done      chan struct{}
+	// saving serializes saves and reloads, so an older canvas never overwrites a newer one
+	saving sync.Mutex
+	mu     sync.Mutex
+	conns  map[*liveClient]struct{}
+	// joins numbers clients in the order they joined
+	joins  uint64
+	canvas map[string]json.RawMessage
+	shapes []map[string]json.RawMessage
+	// hash is the content hash of the stored canvas the room is based on
+	hash string
+	// pending are the shape changes not saved yet, replayed if the stored canvas changes
+	pending []*liveMessage
+	dirty   bool
+	// closing is set, under the hub's lock, once the last client has left
+	closing bool
+	// editor is the last user to change the canvas; saves are made as them
+	editor uuid.UUID
+}
+
+// liveClient is one connection in a room
+type liveClient struct {
+	conn   *websocket.Conn
+	userID uuid.UUID
+	// joined orders the client among the room's connections
+	joined uint64
+	// canEdit is re-checked while the client is connected
+	canEdit atomic.Bool
+	send    chan []byte
+	written chan struct{}
+	// mu guards closed, so nothing is queued on send once it's closed
+	mu     sync.Mutex
+	closed bool
+}
+
+// close stops the client's writer; callers must have removed it from its room
+func (c *liveClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// Serve runs a live connection until the client disconnects. Access must
+// already have been checked; canEdit says whether the user may change shapes.
+// A uuid.Nil userID is an anonymous live link viewer: they only receive, and
+// aren't announced to the room.
+func (h *Hub) Serve(conn *websocket.Conn, whiteboardID, userID uuid.UUID, canEdit bool) {
+	client := &liveClient{
+		conn:    conn,
+		userID:  userID,
+		send:    make(chan []byte, liveSendBuffer),
+		written: make(chan struct{}),
+	}
+	client.canEdit.Store(canEdit)
+
+	r, err := h.join(whiteboardID, client)
+	if err != nil {
+		logger.Warn().Err(err).Str("whiteboard_id", whiteboardID.String()).Msg("Failed to open live whiteboard")
+		_ = conn.WriteMessage(websocket.TextMessage, liveErrorMessage("failed to open whiteboard"))
+		return
+	}
+
+	go client.writeLoop()
+	// The connection is released when Serve returns, so wait for the writer too
+	defer func() {
+		h.leave(r, client)
+		<-client.written
+	}()
+
+	conn.SetReadLimit(liveMaxMessageBytes)
+	_ = conn.SetReadDeadline(time.Now().Add(livePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(livePongWait))
+	})
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		r.handle(client, raw)
+	}
+}
+
+// writeLoop sends queued messages and keep-alive pings. It is the only writer
+// on the connection once the client has joined.
+func (c *liveClient) writeLoop() {
+	ticker := time.NewTicker(livePingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+		close(c.written)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, nil)
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// join adds a client to a whiteboard's room, opening the room if needed
+func (h *Hub) join(whiteboardID uuid.UUID, client *liveClient) (*room, error) {
+	for {
+		h.mu.Lock()
+		r, ok := h.rooms[whiteboardID]
+		if ok && !r.closing {
+			r.mu.Lock()
+			first := len(r.userConnsLocked(client.userID)) == 0
+			r.joins++
+			client.joined = r.joins
+			r.conns[client] = struct{}{}
+			var evicted *liveClient
+			if !client.anonymous() && h.maxPerUser > 0 {
+				if conns := r.userConnsLocked(client.userID); len(conns) > h.maxPerUser {
+					evicted = conns[0]
+				}
+			}
+			snapshot := r.snapshotLocked()
+			r.mu.Unlock()
+			h.mu.Unlock()
+
+			client.send <- snapshot
+			if evicted != nil {
+				r.kick(evicted, "closed because this whiteboard is open in too many of your tabs")
+			}
+			if first && !client.anonymous() {
+				r.broadcast(client, liveEvent(LiveUserJoined, client.userID))
+			}
+			return r, nil
+		}
+		h.mu.Unlock()
+
+		if ok {
+			// Wait for the closing room's final save so the new room loads it
+			<-r.done
+			continue
+		}
+
+		// Load outside the lock; if another client opened the room meanwhile, use theirs
+		opened, err := h.open(whiteboardID)
+		if err != nil {
+			return nil, err
+		}
+		h.mu.Lock()
+		if _, ok := h.rooms[whiteboardID]; !ok {
+			h.rooms[whiteboardID] = opened
+			go opened.run()
+		}
+		h.mu.Unlock()
+	}
+}
+
+// leave removes a client, closing the room when it empties. Clients the room
+// already dropped (see kick) were announced as gone then.
+func (h *Hub) leave(r *room, client *liveClient) {
+	h.mu.Lock()
+	r.mu.Lock()
+	_, present := r.conns[client]
+	delete(r.conns, client)
+	last := len(r.userConnsLocked(client.userID)) == 0
+	closeRoom := len(r.conns) == 0 && !r.closing
+	r.mu.Unlock()
+	if closeRoom {
+		r.closing = true
+	}
+	h.mu.Unlock()
+
+	client.close()
+
+	if closeRoom {
+		close(r.stop)
+		return
+	}
+	if present && last && !client.anonymous() {
+		r.broadcast(nil, liveEvent(LiveUserLeft, client.userID))
+	}
+}
+
+// canvasSaved is the service's OnSave hook: a canvas saved outside a room is
+// loaded into the room, if one is open
+func (h *Hub) canvasSaved(ctx context.Context, whiteboardID uuid.UUID) {
+	if ctx.Value(liveSaveKey{}) != nil {
+		return
+	}
+
+	h.mu.Lock()
+	r, ok := h.rooms[whiteboardID]
+	h.mu.Unlock()
+	if ok {
+		go r.sync()
+	}
+}
+
+// AccessChanged re-checks who may stay in the rooms of a project's
+// whiteboards. It is registered as a hook on project access changes.
+func (h *Hub) AccessChanged(_ context.Context, projectID uuid.UUID) {
+	h.mu.Lock()
+	var rooms []*room
+	for _, r := range h.rooms {
+		if r.projectID == projectID {
+			rooms = append(rooms, r)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, r := range rooms {
+		go r.recheck()
+	}
+}
+
+// Shutdown saves every open room and disconnects its clients
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	rooms := make([]*room, 0, len(h.rooms))
+	for _, r := range h.rooms {
+		rooms = append(rooms, r)
+	}
+	h.mu.Unlock()
+
+	for _, r := range rooms {
+		r.flush()
+		r.mu.Lock()
+		for c := range r.conns {
+			_ = c.conn.Close()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// open loads a whiteboard's stored canvas into a new room
+func (h *Hub) open(whiteboardID uuid.UUID) (*room, error) {
+	whiteboard, err := h.service.repo.FindByID(context.Background(), whiteboardID)
+	if err != nil {
+		return nil, err
+	}
+	if whiteboard == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	canvas, shapes, err := parseLiveCanvas(whiteboard.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &room{
+		id:        whiteboardID,
+		projectID: whiteboard.ProjectID,
+		hub:       h,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		conns:     make(map[*liveClient]struct{}),
+		canvas:    canvas,
+		shapes:    shapes,
+		hash:      whiteboard.ContentHash,
+	}, nil
+}
+
+// parseLiveCanvas splits stored canvas data into its shapes and everything else
+func parseLiveCanvas(data json.RawMessage) (map[string]json.RawMessage, []map[string]json.RawMessage, error) {
+	canvas := map[string]json.RawMessage{}
+	var shapes []map[string]json.RawMessage
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &canvas); err != nil {
+			return nil, nil, err
+		}
+	}
+	if raw, ok := canvas["shapes"]; ok {
+		if err := json.Unmarshal(raw, &shapes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return canvas, shapes, nil
+}
+
+// run saves the room every persist interval until it closes, then saves it
+// once more before removing it from the hub. It also re-checks access.
+func (r *room) run() {
+	defer func() {
+		r.hub.mu.Lock()
+		if r.hub.rooms[r.id] == r {
+			delete(r.hub.rooms, r.id)
+		}
+		r.hub.mu.Unlock()
+		close(r.done)
+	}()
+
+	ticker := time.NewTicker(r.hub.interval)
+	defer ticker.Stop()
+	accessTicker := time.NewTicker(liveAccessCheckInterval)
+	defer accessTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-accessTicker.C:
+			r.recheck()
+		case <-r.stop:
+			r.flush()
+			return
+		}
+	}
+}
+
+// flush saves the room's canvas if it changed since the last save. If the
+// stored canvas changed meanwhile, the room's edits are merged onto it first.
+// Edits that fail to save stay pending and are retried on the next flush.
+func (r *room) flush() {
+	r.saving.Lock()
+	defer r.saving.Unlock()
+
+	ctx := context.WithValue(context.Background(), liveSaveKey{}, true)
+	for attempt := 0; attempt < liveSaveAttempts; attempt++ {
+		r.mu.Lock()
+		if !r.dirty {
+			r.mu.Unlock()
+			return
+		}
+		data, err := r.marshalLocked()
+		editor, base, saving := r.editor, r.hash, len(r.pending)
+		r.mu.Unlock()
+
+		var whiteboard *Whiteboard
+		if err == nil {
+			whiteboard, err = r.hub.service.saveLiveCanvas(ctx, r.id, editor, data, base)
+		}
+		if errors.Is(err, ErrCanvasChanged) {
+			if err := r.reload(); err != nil {
+				logger.Warn().Err(err).Str("whiteboard_id", r.id.String()).Msg("Failed to reload live whiteboard")
+				return
+			}
+			continue
+		}
+		if err != nil {
+			logger.Warn().Err(err).Str("whiteboard_id", r.id.String()).Msg("Failed to save live whiteboard")
+			return
+		}
+
+		r.mu.Lock()
+		r.hash = whiteboard.ContentHash
+		r.pending = r.pending[saving:]
+		r.dirty = len(r.pending) > 0
+		r.mu.Unlock()
+		return
+	}
+
+	logger.Warn().Str("whiteboard_id", r.id.String()).Msg("Live whiteboard keeps changing underneath its save; retrying on the next flush")
+}
+
+// sync loads a canvas saved outside the room, unless the room is already based on it
+func (r *room) sync() {
+	r.saving.Lock()
+	defer r.saving.Unlock()
+
+	if err := r.reload(); err != nil {
+		logger.Warn().Err(err).Str("whiteboard_id", r.id.String()).Msg("Failed to reload live whiteboard")
+	}
+}
+
+// reload replaces the room's canvas with the stored one, replays the
+// unsaved edits on top and sends every client the result. Callers hold saving.
+func (r *room) reload() error {
+	whiteboard, err := r.hub.service.repo.FindByID(context.Background(), r.id)
+	if err != nil {
+		return err
+	}
+	if whiteboard == nil {
+		return ErrWhiteboardNotFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if whiteboard.ContentHash == r.hash {
+		return nil
+	}
+
+	canvas, shapes, err := parseLiveCanvas(whiteboard.Data)
+	if err != nil {
+		return err
+	}
+	r.canvas, r.shapes, r.hash = canvas, shapes, whiteboard.ContentHash
+	for _, msg := range r.pending {
+		r.applyLocked(msg)
+	}
+	r.dirty = len(r.pending) > 0
+
+	snapshot := r.snapshotLocked()
+	for c := range r.conns {
+		r.sendLocked(c, snapshot)
+	}
+	return nil
+}
+
+// recheck disconnects users who may no longer see the whiteboard and updates
+// whether the rest may edit it
+func (r *room) recheck() {
+	r.mu.Lock()
+	clients := make([]*liveClient, 0, len(r.conns))
+	for c := range r.conns {
+		clients = append(clients, c)
+	}
+	r.mu.Unlock()
+
+	ctx := context.Background()
+	for _, c := range clients {
+		if c.anonymous() {
+			// Live link viewers keep watching until the link expires, unless the whiteboard is gone
+			whiteboard, err := r.hub.service.repo.FindByID(ctx, r.id)
+			if err == nil && whiteboard == nil {
+				r.kick(c, "this whiteboard has been deleted")
+			}
+			continue
+		}
+
+		canEdit, err := r.hub.service.LiveAccess(ctx, r.id, c.userID)
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrWhiteboardNotFound) || errors.Is(err, ErrProjectNotFound) {
+			r.kick(c, "you no longer have access to this whiteboard")
+			continue
+		}
+		if err != nil {
+			logger.Warn().Err(err).Str("whiteboard_id", r.id.String()).Str("user_id", c.userID.String()).Msg("Failed to re-check live access")
+			continue
+		}
+		c.canEdit.Store(canEdit)
+	}
+}
+
+// kick drops a client from the room with a reason. The connection closes once
+// its reader stops, which ends Serve as if the client had left.
+func (r *room) kick(c *liveClient, reason string) {
+	c.canEdit.Store(false)
+
+	r.mu.Lock()
+	_, present := r.conns[c]
+	delete(r.conns, c)
+	last := len(r.userConnsLocked(c.userID)) == 0
+	r.mu.Unlock()
+	if !present {
+		return
+	}
+
+	c.reply(liveErrorMessage(reason))
+	_ = c.conn.SetReadDeadline(time.Now())
+	if last && !c.anonymous() {
+		r.broadcast(nil, liveEvent(LiveUserLeft, c.userID))
+	}
+}
+
+// handle applies and relays one message from a client
+func (r *room) handle(from *liveClient, raw []byte) {
+	if from.anonymous() {
+		from.reply(liveErrorMessage("live links are read-only"))
+		return
+	}
+
+	var msg liveMessage
+	if err := json.Unmarshal(raw, &msg); err != nil || msg.Type == "" {
+		from.reply(liveErrorMessage("messages must be JSON objects with a type"))
+		return
+	}
+
+	switch msg.Type {
+	case LiveShapesUpsert, LiveShapesDelete:
+		if !from.canEdit.Load() {
+			from.reply(liveErrorMessage("you can view this whiteboard but not edit it"))
+			return
+		}
+		if !r.apply(from.userID, &msg) {
+			from.reply(liveErrorMessage("every shape needs a string id"))
+			return
+		}
+	case LiveSnapshot, LiveUserJoined, LiveUserLeft, LiveError:
+		from.reply(liveErrorMessage("message type is reserved for the server"))
+		return
+	}
+
+	// Relay the client's message as sent, tagged with who sent it
+	var relayed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &relayed); err != nil {
+		return
+	}
+	relayed["user_id"], _ = json.Marshal(from.userID.String())
+	out, err := json.Marshal(relayed)
+	if err != nil {
+		return
+	}
+	r.broadcast(from, out)
+}
+
+// apply changes the room's shapes and keeps the change until it's saved.
+// Returns false if a shape has no id.
+func (r *room) apply(userID uuid.UUID, msg *liveMessage) bool {
+	if msg.Type == LiveShapesUpsert {
+		for _, shape := range msg.Shapes {
+			var id string
+			if json.Unmarshal(shape["id"], &id) != nil || id == "" {
+				return false
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.applyLocked(msg)
+	r.pending = append(r.pending, msg)
+	r.dirty = true
+	r.editor = userID
+	return true
+}
+
+// applyLocked applies a checked shape change to the room's shapes; callers hold mu
+func (r *room) applyLocked(msg *liveMessage) {
+	ids := msg.IDs
+	if msg.Type == LiveShapesUpsert {
+		ids = make([]string, len(msg.Shapes))
+		for i, shape := range msg.Shapes {
+			_ = json.Unmarshal(shape["id"], &ids[i])
+		}
+	}
+
+	index := make(map[string]int, len(r.shapes))
+	for i, shape := range r.shapes {
+		var id string
+		if json.Unmarshal(shape["id"], &id) == nil {
+			index[id] = i
+		}
+	}
+
+	switch msg.Type {
+	case LiveShapesUpsert:
+		for i, shape := range msg.Shapes {
+			if at, ok := index[ids[i]]; ok {
+				r.shapes[at] = shape
+			} else {
+				index[ids[i]] = len(r.shapes)
+				r.shapes = append(r.shapes, shape)
+			}
+		}
+	case LiveShapesDelete:
+		deleted := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			deleted[id] = true
+		}
+		kept := r.shapes[:0]
+		for _, shape := range r.shapes {
+			var id string
+			if json.Unmarshal(shape["id"], &id) == nil && deleted[id] {
+				continue
+			}
+			kept = append(kept, shape)
+		}
+		r.shapes = kept
+	}
+}
+
+// broadcast queues a message for every client but from. Clients too slow to
+// keep up are disconnected rather than silently missing edits.
+func (r *room) broadcast(from *liveClient, msg []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for c := range r.conns {
+		if c != from {
+			r.sendLocked(c, msg)
+		}
+	}
+}
+
+// sendLocked queues a message for a client in the room, disconnecting it if
+// it's too slow to keep up; callers hold mu
+func (r *room) sendLocked(c *liveClient, msg []byte) {
+	select {
+	case c.send <- msg:
+	default:
+		_ = c.conn.Close()
+	}
+}
+
+// marshalLocked encodes the room's canvas; callers hold mu
+func (r *room) marshalLocked() (json.RawMessage, error) {
+	shapes := r.shapes
+	if shapes == nil {
+		shapes = []map[string]json.RawMessage{}
+	}
+	raw, err := json.Marshal(shapes)
+	if err != nil {
+		return nil, err
+	}
+	r.canvas["shapes"] = raw
+	return json.Marshal(r.canvas)
+}
+
+// userConnsLocked returns a user's connections to the room, oldest first; callers hold mu
+func (r *room) userConnsLocked(userID uuid.UUID) []*liveClient {
+	var conns []*liveClient
+	for c := range r.conns {
+		if c.userID == userID {
+			conns = append(conns, c)
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].joined < conns[j].joined })
+	return conns
+}
+
+// snapshotLocked builds the snapshot message sent to joining clients; callers hold mu
+func (r *room) snapshotLocked() []byte {
+	data, _ := r.marshalLocked()
+	users := make(map[string]struct{}, len(r.conns))
+	for c := range r.conns {
+		if !c.anonymous() {
+			users[c.userID.String()] = struct{}{}
+		}
+	}
+	ids := make([]string, 0, len(users))
+	for id := range users {
+		ids = append(ids, id)
+	}
+
+	msg, _ := json.Marshal(struct {
+		Type  string          `json:"type"`
+		Data  json.RawMessage `json:"data"`
+		Users []string        `json:"users"`
+	}{LiveSnapshot, data, ids})
+	return msg
+}
+
+// anonymous reports whether the client joined through a live link
+func (c *liveClient) anonymous() bool {
+	return c.userID == uuid.Nil
+}
+
+// reply queues a message for this client only
+func (c *liveClient) reply(msg []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+	}
+}
+
+// liveErrorMessage encodes an error message for a client
+func liveErrorMessage(message string) []byte {
+	msg, _ := json.Marshal(map[string]string{"type": LiveError, "message": message})
+	return msg
+}
+
+// liveEvent encodes a server message about a user
+func liveEvent(eventType string, userID uuid.UUID) []byte {
+	msg, _ := json.Marshal(map[string]string{"type": eventType, "user_id": userID.String()})
+	return msg
+}
@@ -0,0 +1,104 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newTestRoom(h *Hub) *room {
+	r := &room{
+		id:     uuid.New(),
+		hub:    h,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		conns:  make(map[*liveClient]struct{}),
+		canvas: map[string]json.RawMessage{},
+	}
+	h.rooms[r.id] = r
+	return r
+}
+
+func newTestClient(userID uuid.UUID) *liveClient {
+	return &liveClient{userID: userID, send: make(chan []byte, liveSendBuffer)}
+}
+
+// presenceEvents drains a client's queue, returning the presence events in it
+func presenceEvents(c *liveClient) []string {
+	var events []string
+	for {
+		select {
+		case msg := <-c.send:
+			var event struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(msg, &event) == nil && (event.Type == LiveUserJoined || event.Type == LiveUserLeft) {
+				events = append(events, event.Type)
+			}
+		default:
+			return events
+		}
+	}
+}
+
+func TestPresenceIsAnnouncedOncePerUser(t *testing.T) {
+	h := &Hub{rooms: make(map[uuid.UUID]*room)}
+	r := newTestRoom(h)
+
+	observer := newTestClient(uuid.New())
+	if _, err := h.join(r.id, observer); err != nil {
+		t.Fatalf("join observer: %v", err)
+	}
+	presenceEvents(observer)
+
+	userID := uuid.New()
+	first, second := newTestClient(userID), newTestClient(userID)
+	for _, c := range []*liveClient{first, second} {
+		if _, err := h.join(r.id, c); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+	}
+	if events := presenceEvents(observer); len(events) != 1 || events[0] != LiveUserJoined {
+		t.Errorf("after two tabs joined, observer got %v; want one %s", events, LiveUserJoined)
+	}
+
+	h.leave(r, first)
+	if events := presenceEvents(observer); len(events) != 0 {
+		t.Errorf("after one of two tabs left, observer got %v; want nothing", events)
+	}
+	h.leave(r, second)
+	if events := presenceEvents(observer); len(events) != 1 || events[0] != LiveUserLeft {
+		t.Errorf("after the last tab left, observer got %v; want one %s", events, LiveUserLeft)
+	}
+}
+
+func TestUserConnsAreOldestFirst(t *testing.T) {
+	h := &Hub{rooms: make(map[uuid.UUID]*room)}
+	r := newTestRoom(h)
+	userID := uuid.New()
+
+	var joined []*liveClient
+	for range 3 {
+		c := newTestClient(userID)
+		if _, err := h.join(r.id, c); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+		joined = append(joined, c)
+	}
+	if _, err := h.join(r.id, newTestClient(uuid.New())); err != nil {
+		t.Fatalf("join other user: %v", err)
+	}
+
+	r.mu.Lock()
+	conns := r.userConnsLocked(userID)
+	r.mu.Unlock()
+	if len(conns) != len(joined) {
+		t.Fatalf("got %d connections, want %d", len(conns), len(joined))
+	}
+	for i := range joined {
+		if conns[i] != joined[i] {
+			t.Errorf("connection %d is out of join order", i)
+		}
+	}
+}
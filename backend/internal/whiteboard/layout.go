@@ -0,0 +1,166 @@
+package whiteboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCanvasChanged is returned when a layout was computed against a canvas
+// that has been saved since (its If-Match no longer matches the content hash)
+var ErrCanvasChanged = errors.New("canvas has changed since it was read")
+
+// ShapePosition is the new position of one shape
+type ShapePosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// LayoutRequest is the request body for repositioning shapes, keyed by shape ID
+type LayoutRequest struct {
+	Positions map[string]ShapePosition `json:"positions" validate:"min=1"`
+}
+
+// UnknownShapesError is returned when a layout references shapes that aren't on the canvas
+type UnknownShapesError struct {
+	IDs []string
+}
+
+func (e *UnknownShapesError) Error() string {
+	return fmt.Sprintf("shapes not found on canvas: %s", strings.Join(e.IDs, ", "))
+}
+
+// applyLayout moves shapes to new positions. Only each shape's x and y are
+// rewritten; every other field of the shapes and the canvas is kept as stored.
+func applyLayout(data json.RawMessage, positions map[string]ShapePosition) (json.RawMessage, error) {
+	var canvas map[string]json.RawMessage
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+	}
+
+	var shapes []map[string]json.RawMessage
+	if raw, ok := canvas["shapes"]; ok {
+		if err := json.Unmarshal(raw, &shapes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+		}
+	}
+
+	moved := make(map[string]bool, len(positions))
+	for _, shape := range shapes {
+		var id string
+		if err := json.Unmarshal(shape["id"], &id); err != nil {
+			continue
+		}
+		pos, ok := positions[id]
+		if !ok {
+			continue
+		}
+		shape["x"], _ = json.Marshal(pos.X)
+		shape["y"], _ = json.Marshal(pos.Y)
+		moved[id] = true
+	}
+
+	var missing []string
+	for id := range positions {
+		if !moved[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, &UnknownShapesError{IDs: missing}
+	}
+
+	rawShapes, err := json.Marshal(shapes)
+	if err != nil {
+		return nil, err
+	}
+	canvas["shapes"] = rawShapes
+	return json.Marshal(canvas)
+}
+
+// UpdateDataIfMatch updates a whiteboard's canvas data only if its content hash
+// is still expectedHash. Returns nil if the whiteboard is gone or has changed.
+func (r *Repository) UpdateDataIfMatch(ctx context.Context, id uuid.UUID, data json.RawMessage, contentHash, expectedHash string, authorID uuid.UUID) (*Whiteboard, error) {
+	stored, err := r.packData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE whiteboards
+		SET
+			data = $2,
+			data_compressed = $3,
+			data_encoding = $4,
+			content_hash = $5,
+			updated_at = NOW()
+		WHERE id = $1 AND COALESCE(content_hash, '') = $6 AND deleted_at IS NULL
+		RETURNING ` + whiteboardColumns + `
+	`
+
+	return r.recordChange(ctx, EventWhiteboardUpdated, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, id, stored.Plain, stored.Compressed, stored.Encoding, contentHash, expectedHash))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update whiteboard data: %w", err)
+		}
+		if err := insertVersion(ctx, tx, whiteboard, authorID); err != nil {
+			return nil, err
+		}
+		return whiteboard, nil
+	})
+}
+
+// ApplyLayout repositions shapes on a whiteboard's stored canvas. ifMatch is the
+// content hash the layout was computed against; the write is rejected with
+// ErrCanvasChanged if the canvas was saved in the meantime.
+func (s *Service) ApplyLayout(ctx context.Context, whiteboardID, userID uuid.UUID, ifMatch string, positions map[string]ShapePosition) (*WhiteboardResponse, error) {
+	existing, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	// Same rule as a full canvas save
+	if err := s.checkEditAccess(ctx, existing.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	if existing.ContentHash != ifMatch {
+		return nil, ErrCanvasChanged
+	}
+
+	data, err := applyLayout(existing.Data, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	data, hash, err := s.prepareCanvas(data, existing.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	whiteboard, err := s.repo.UpdateDataIfMatch(ctx, whiteboardID, data, hash, ifMatch, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save layout: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, ErrCanvasChanged
+	}
+
+	s.saved(ctx, whiteboardID)
+	s.canvasSaved(ctx, existing.ProjectID, whiteboardID, userID, "layout")
+
+	return whiteboard.ToResponse(), nil
+}
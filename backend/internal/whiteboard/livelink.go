@@ -0,0 +1,174 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Live links let someone without an account watch a whiteboard's live room.
+// The link carries a signed, expiring token scoped to one whiteboard; it is
+// stateless, so it can't be revoked early (short of rotating JWT_SECRET).
+// Links signed with a secret in JWT_PREVIOUS_SECRETS keep working until
+// it's dropped, like sessions do.
+
+// Live link lifetimes
+const (
+	defaultLiveLinkTTL = time.Hour
+	maxLiveLinkTTL     = 24 * time.Hour
+)
+
+// liveLinkType is the token type of live links. They are signed with a key
+// derived from the JWT secret, so they never validate as access tokens.
+const liveLinkType = "live_view"
+
+// Errors returned for live links
+var (
+	ErrInvalidLiveLink = errors.New("live link is invalid or has expired")
+	ErrLiveLinkTTL     = fmt.Errorf("expires_in_minutes must be between 1 and %d", int(maxLiveLinkTTL/time.Minute))
+
+	errInvalidLink = errors.New("invalid link token")
+)
+
+// CreateLiveLinkRequest is the request body for creating a live link
+type CreateLiveLinkRequest struct {
+	// ExpiresInMinutes defaults to 60
+	ExpiresInMinutes int `json:"expires_in_minutes,omitempty"`
+}
+
+// LiveLinkResponse is a read-only live link for a whiteboard
+type LiveLinkResponse struct {
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateLiveLink creates a link that lets anyone holding it watch a
+// whiteboard's live room, read-only, until it expires. Only the owner can
+// share a whiteboard this way.
+func (s *Service) CreateLiveLink(ctx context.Context, whiteboardID, userID uuid.UUID, req *CreateLiveLinkRequest) (*LiveLinkResponse, error) {
+	ttl := defaultLiveLinkTTL
+	if req.ExpiresInMinutes != 0 {
+		ttl = time.Duration(req.ExpiresInMinutes) * time.Minute
+		if ttl <= 0 || ttl > maxLiveLinkTTL {
+			return nil, ErrLiveLinkTTL
+		}
+	}
+
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+	if err := s.checkOwnership(ctx, whiteboard.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	signed, err := s.signLink(liveLinkType, whiteboardID, expiresAt, jwt.MapClaims{"by": userID.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign live link: %w", err)
+	}
+
+	return &LiveLinkResponse{
+		Token:     signed,
+		Path:      "/api/v1/whiteboards/" + whiteboardID.String() + "/ws?live_token=" + signed,
+		ExpiresAt: expiresAt.UTC(),
+	}, nil
+}
+
+// LiveLinkAccess checks that a live link token is for whiteboardID, and that
+// the whiteboard still exists, and returns when the link expires
+func (s *Service) LiveLinkAccess(ctx context.Context, tokenString string, whiteboardID uuid.UUID) (time.Time, error) {
+	subject, expiresAt, _, err := s.parseLink(liveLinkType, tokenString)
+	if err != nil || subject != whiteboardID {
+		return time.Time{}, ErrInvalidLiveLink
+	}
+
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return time.Time{}, ErrWhiteboardNotFound
+	}
+
+	return expiresAt, nil
+}
+
+// signLink signs a token of type typ scoped to whiteboardID. Live links and
+// embeds share the key; typ keeps one from being used as the other.
+func (s *Service) signLink(typ string, whiteboardID uuid.UUID, expiresAt time.Time, extra jwt.MapClaims) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": whiteboardID.String(),
+		"typ": typ,
+		"iat": time.Now().Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.liveLinkKeys[0])
+}
+
+// parseLink checks a token signed by signLink, with the current or a previous
+// key, and returns its whiteboard, expiry and claims
+func (s *Service) parseLink(typ, tokenString string) (uuid.UUID, time.Time, jwt.MapClaims, error) {
+	var token *jwt.Token
+	for _, key := range s.liveLinkKeys {
+		parsed, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return key, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+		if err == nil && parsed.Valid {
+			token = parsed
+			break
+		}
+	}
+	if token == nil {
+		return uuid.Nil, time.Time{}, nil, errInvalidLink
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, time.Time{}, nil, errInvalidLink
+	}
+	if tokenType, _ := claims["typ"].(string); tokenType != typ {
+		return uuid.Nil, time.Time{}, nil, errInvalidLink
+	}
+
+	subject, _ := claims["sub"].(string)
+	whiteboardID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, nil, errInvalidLink
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return uuid.Nil, time.Time{}, nil, errInvalidLink
+	}
+
+	return whiteboardID, exp.Time, claims, nil
+}
+
+// liveLinkKeys derives the link signing key from the JWT secret, followed by
+// the keys of previous secrets, which are only used to verify
+func liveLinkKeys(secret string, previous []string) [][]byte {
+	keys := [][]byte{liveLinkKey(secret)}
+	for _, p := range previous {
+		if p != "" {
+			keys = append(keys, liveLinkKey(p))
+		}
+	}
+	return keys
+}
+
+// liveLinkKey derives a link signing key from a JWT secret
+func liveLinkKey(secret string) []byte {
+	return []byte("live-link:" + secret)
+}
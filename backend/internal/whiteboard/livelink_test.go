@@ -0,0 +1,147 @@
+package whiteboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newLiveLinkTestService(secret string, previous ...string) *Service {
+	return &Service{liveLinkKeys: liveLinkKeys(secret, previous)}
+}
+
+func TestLiveLinkRoundTrip(t *testing.T) {
+	s := newLiveLinkTestService("secret")
+	whiteboardID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	token, err := s.signLink(liveLinkType, whiteboardID, expiresAt, nil)
+	if err != nil {
+		t.Fatalf("signLink: %v", err)
+	}
+	subject, gotExpiry, _, err := s.parseLink(liveLinkType, token)
+	if err != nil {
+		t.Fatalf("parseLink: %v", err)
+	}
+	if subject != whiteboardID || !gotExpiry.Equal(expiresAt) {
+		t.Errorf("parseLink = %s, %s; want %s, %s", subject, gotExpiry, whiteboardID, expiresAt)
+	}
+}
+
+func TestExpiredLiveLinksAreRejected(t *testing.T) {
+	s := newLiveLinkTestService("secret")
+	whiteboardID := uuid.New()
+
+	token, err := s.signLink(liveLinkType, whiteboardID, time.Now().Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatalf("signLink: %v", err)
+	}
+	if _, _, _, err := s.parseLink(liveLinkType, token); err == nil {
+		t.Error("expired live link accepted")
+	}
+	// Rejected before the whiteboard is looked up
+	if _, err := s.LiveLinkAccess(context.Background(), token, whiteboardID); !errors.Is(err, ErrInvalidLiveLink) {
+		t.Errorf("LiveLinkAccess error = %v, want ErrInvalidLiveLink", err)
+	}
+}
+
+func TestLiveLinksAreScopedToOneWhiteboard(t *testing.T) {
+	s := newLiveLinkTestService("secret")
+
+	token, err := s.signLink(liveLinkType, uuid.New(), time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("signLink: %v", err)
+	}
+	if _, err := s.LiveLinkAccess(context.Background(), token, uuid.New()); !errors.Is(err, ErrInvalidLiveLink) {
+		t.Errorf("LiveLinkAccess for another whiteboard: error = %v, want ErrInvalidLiveLink", err)
+	}
+}
+
+func TestLiveLinksRejectOtherTokens(t *testing.T) {
+	s := newLiveLinkTestService("secret")
+	whiteboardID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+
+	embed, err := s.signLink(embedType, whiteboardID, expiresAt, nil)
+	if err != nil {
+		t.Fatalf("sign embed token: %v", err)
+	}
+	// A session token signed with the JWT secret itself, as auth issues them
+	session, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": whiteboardID.String(),
+		"typ": liveLinkType,
+		"exp": expiresAt.Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign session token: %v", err)
+	}
+
+	for name, token := range map[string]string{"embed": embed, "session": session, "garbage": "not-a-token"} {
+		if _, _, _, err := s.parseLink(liveLinkType, token); err == nil {
+			t.Errorf("%s token accepted as a live link", name)
+		}
+	}
+}
+
+func TestLiveLinksSurviveSecretRotation(t *testing.T) {
+	old := newLiveLinkTestService("old-secret")
+	rotated := newLiveLinkTestService("new-secret", "old-secret")
+	unrelated := newLiveLinkTestService("new-secret")
+
+	token, err := old.signLink(liveLinkType, uuid.New(), time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("signLink: %v", err)
+	}
+	if _, _, _, err := rotated.parseLink(liveLinkType, token); err != nil {
+		t.Errorf("link signed with a previous secret rejected: %v", err)
+	}
+	if _, _, _, err := unrelated.parseLink(liveLinkType, token); err == nil {
+		t.Error("link accepted after its secret was dropped")
+	}
+}
+
+func TestCreateLiveLinkBoundsTTL(t *testing.T) {
+	s := newLiveLinkTestService("secret")
+	maxMinutes := int(maxLiveLinkTTL / time.Minute)
+
+	for _, minutes := range []int{-5, maxMinutes + 1} {
+		_, err := s.CreateLiveLink(context.Background(), uuid.New(), uuid.New(), &CreateLiveLinkRequest{ExpiresInMinutes: minutes})
+		if !errors.Is(err, ErrLiveLinkTTL) {
+			t.Errorf("expires_in_minutes %d: error = %v, want ErrLiveLinkTTL", minutes, err)
+		}
+	}
+}
+
+func TestLiveLinkViewersAreReadOnly(t *testing.T) {
+	r := &room{conns: make(map[*liveClient]struct{})}
+	upsert := []byte(`{"type":"` + LiveShapesUpsert + `","shapes":[{"id":"a","type":"rectangle"}]}`)
+
+	viewer := &liveClient{userID: uuid.Nil, send: make(chan []byte, 1)}
+	viewer.canEdit.Store(true) // ignored for anonymous viewers
+	member := &liveClient{userID: uuid.New(), send: make(chan []byte, 1)}
+
+	for name, c := range map[string]*liveClient{"live link viewer": viewer, "viewer member": member} {
+		r.handle(c, upsert)
+
+		select {
+		case msg := <-c.send:
+			var reply struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(msg, &reply); err != nil || reply.Type != LiveError {
+				t.Errorf("%s got %s, want an error message", name, msg)
+			}
+		default:
+			t.Errorf("%s got no reply", name)
+		}
+	}
+
+	if len(r.pending) != 0 || r.dirty || len(r.shapes) != 0 {
+		t.Errorf("read-only clients changed the room: %d pending, dirty %v", len(r.pending), r.dirty)
+	}
+}
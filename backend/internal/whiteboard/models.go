@@ -54,33 +54,45 @@ type CanvasData struct {
 
 // Whiteboard represents a whiteboard/canvas in the database
 type Whiteboard struct {
-	ID        uuid.UUID       `json:"id"`
-	ProjectID uuid.UUID       `json:"project_id"`
-	Name      string          `json:"name"`
-	Data      json.RawMessage `json:"data"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID          uuid.UUID       `json:"id"`
+	ProjectID   uuid.UUID       `json:"project_id"`
+	Name        string          `json:"name"`
+	Data        json.RawMessage `json:"data"`
+	ContentHash string          `json:"content_hash"`
+	// StorageRegion is the project's region, where its thumbnail and assets
+	// are stored ("" is the default region); Data itself is kept in Postgres
+	StorageRegion string `json:"storage_region"`
+	// Position is the whiteboard's place in its project's tab order
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // WhiteboardResponse is the public whiteboard data returned to clients
 type WhiteboardResponse struct {
-	ID        string          `json:"id"`
-	ProjectID string          `json:"project_id"`
-	Name      string          `json:"name"`
-	Data      json.RawMessage `json:"data"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID            string          `json:"id"`
+	ProjectID     string          `json:"project_id"`
+	Name          string          `json:"name"`
+	Data          json.RawMessage `json:"data"`
+	ContentHash   string          `json:"content_hash,omitempty"`
+	StorageRegion string          `json:"storage_region,omitempty"`
+	Position      int             `json:"position"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 // ToResponse converts Whiteboard to WhiteboardResponse
 func (w *Whiteboard) ToResponse() *WhiteboardResponse {
 	return &WhiteboardResponse{
-		ID:        w.ID.String(),
-		ProjectID: w.ProjectID.String(),
-		Name:      w.Name,
-		Data:      w.Data,
-		CreatedAt: w.CreatedAt,
-		UpdatedAt: w.UpdatedAt,
+		ID:            w.ID.String(),
+		ProjectID:     w.ProjectID.String(),
+		Name:          w.Name,
+		Data:          w.Data,
+		ContentHash:   w.ContentHash,
+		StorageRegion: w.StorageRegion,
+		Position:      w.Position,
+		CreatedAt:     w.CreatedAt,
+		UpdatedAt:     w.UpdatedAt,
 	}
 }
 
@@ -100,13 +112,57 @@ type UpdateWhiteboardRequest struct {
 	Data *json.RawMessage `json:"data,omitempty"`
 }
 
+// fields names the fields the request changes, for the activity log
+func (r *UpdateWhiteboardRequest) fields() []string {
+	fields := []string{}
+	if r.Name != nil {
+		fields = append(fields, "name")
+	}
+	if r.Data != nil {
+		fields = append(fields, "data")
+	}
+	return fields
+}
+
 // SaveCanvasRequest is a simplified request for saving canvas data
 type SaveCanvasRequest struct {
 	Data json.RawMessage `json:"data" validate:"required"`
 }
 
+// ValidateCanvasResponse is the response for validating canvas data without saving
+type ValidateCanvasResponse struct {
+	Valid  bool              `json:"valid"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
 // WhiteboardListResponse is the response for listing whiteboards
 type WhiteboardListResponse struct {
 	Whiteboards []*WhiteboardResponse `json:"whiteboards"`
 	Total       int                   `json:"total"`
+	Warnings    []string              `json:"warnings,omitempty"`
+}
+
+// Per-ID statuses in a batch get
+const (
+	BatchStatusOK        = "ok"
+	BatchStatusNotFound  = "not_found"
+	BatchStatusForbidden = "forbidden"
+	BatchStatusInvalidID = "invalid_id"
+)
+
+// BatchGetRequest is the request body for fetching several whiteboards at once
+type BatchGetRequest struct {
+	IDs []string `json:"ids" validate:"min=1"`
+}
+
+// BatchGetResult reports what happened to one requested ID
+type BatchGetResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// BatchGetResponse holds the accessible whiteboards and a status for every requested ID, in request order
+type BatchGetResponse struct {
+	Whiteboards []*WhiteboardResponse `json:"whiteboards"`
+	Results     []BatchGetResult      `json:"results"`
 }
@@ -0,0 +1,202 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+)
+
+// Whiteboards become orphaned when their project row is gone but the delete
+// didn't cascade (databases from before the foreign key, or a bug). Every
+// access check goes through the missing project, so users can neither open
+// nor delete them; only an operator can repair them.
+
+// Orphan repair actions
+const (
+	OrphanActionDelete   = "delete"
+	OrphanActionReassign = "reassign"
+)
+
+// orphanReportLimit caps how many orphans are listed in a report; the total is always exact
+const orphanReportLimit = 100
+
+// Errors returned when repairing orphans
+var (
+	ErrInvalidRepairAction = errors.New("action must be \"delete\" or \"reassign\"")
+	ErrOrphanCountChanged  = errors.New("number of orphaned whiteboards changed since it was confirmed; review the report again")
+	ErrOrphanNameConflict  = errors.New("target project requires unique whiteboard names and an orphan's name is already taken")
+)
+
+// OrphanedWhiteboard is a whiteboard whose project no longer exists
+type OrphanedWhiteboard struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrphanReport lists orphaned whiteboards, oldest first
+type OrphanReport struct {
+	Total       int                   `json:"total"`
+	Projects    int                   `json:"projects"`
+	Whiteboards []*OrphanedWhiteboard `json:"whiteboards"`
+}
+
+// RepairOrphansRequest is the request body for repairing orphaned whiteboards.
+// ConfirmCount must equal the total from the latest report.
+type RepairOrphansRequest struct {
+	Action          string `json:"action"`
+	TargetProjectID string `json:"target_project_id,omitempty"`
+	ConfirmCount    *int   `json:"confirm_count"`
+}
+
+// RepairOrphansResponse reports how many orphans were repaired
+type RepairOrphansResponse struct {
+	Action   string `json:"action"`
+	Repaired int    `json:"repaired"`
+}
+
+// orphanCondition matches whiteboards whose project row is missing
+const orphanCondition = `NOT EXISTS (SELECT 1 FROM projects p WHERE p.id = whiteboards.project_id)`
+
+// FindOrphans returns up to limit orphaned whiteboards, plus how many there are
+// in total and how many distinct missing projects they point to
+func (r *Repository) FindOrphans(ctx context.Context, limit int) ([]*OrphanedWhiteboard, int, int, error) {
+	var total, projects int
+	countQuery := `SELECT COUNT(*), COUNT(DISTINCT project_id) FROM whiteboards WHERE ` + orphanCondition
+	if err := r.db.QueryRow(ctx, countQuery).Scan(&total, &projects); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count orphaned whiteboards: %w", err)
+	}
+
+	query := `
+		SELECT id, project_id, name, updated_at
+		FROM whiteboards
+		WHERE ` + orphanCondition + `
+		ORDER BY updated_at ASC, id ASC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to find orphaned whiteboards: %w", err)
+	}
+	defer rows.Close()
+
+	orphans := []*OrphanedWhiteboard{}
+	for rows.Next() {
+		var id, projectID uuid.UUID
+		var orphan OrphanedWhiteboard
+		if err := rows.Scan(&id, &projectID, &orphan.Name, &orphan.UpdatedAt); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan orphaned whiteboard: %w", err)
+		}
+		orphan.ID = id.String()
+		orphan.ProjectID = projectID.String()
+		orphans = append(orphans, &orphan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to iterate orphaned whiteboards: %w", err)
+	}
+
+	return orphans, total, projects, nil
+}
+
+// RepairOrphans hard-deletes every orphaned whiteboard, or moves them into
+// targetProjectID when it is set. The change is rolled back unless exactly
+// expected whiteboards are affected, so nothing unreviewed is touched.
+func (r *Repository) RepairOrphans(ctx context.Context, targetProjectID *uuid.UUID, expected int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var affected int64
+	if targetProjectID == nil {
+		result, err := tx.Exec(ctx, `DELETE FROM whiteboards WHERE `+orphanCondition)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete orphaned whiteboards: %w", err)
+		}
+		affected = result.RowsAffected()
+	} else {
+		// Lock the target so it can't be deleted while orphans move in
+		var uniqueNames bool
+		err := tx.QueryRow(ctx, `SELECT unique_whiteboard_names FROM projects WHERE id = $1 FOR SHARE`, *targetProjectID).Scan(&uniqueNames)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrProjectNotFound
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to find target project: %w", err)
+		}
+
+		query := `
+			UPDATE whiteboards
+			SET project_id = $1, enforce_unique_name = $2, updated_at = NOW()
+			WHERE ` + orphanCondition
+		result, err := tx.Exec(ctx, query, *targetProjectID, uniqueNames)
+		if database.IsUniqueViolation(err, uniqueNameIndex) {
+			return 0, ErrOrphanNameConflict
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to reassign orphaned whiteboards: %w", err)
+		}
+		affected = result.RowsAffected()
+	}
+
+	if affected != int64(expected) {
+		return 0, ErrOrphanCountChanged
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit orphan repair: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+// FindOrphanedWhiteboards reports whiteboards whose project no longer exists
+func (s *Service) FindOrphanedWhiteboards(ctx context.Context) (*OrphanReport, error) {
+	orphans, total, projects, err := s.repo.FindOrphans(ctx, orphanReportLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrphanReport{
+		Total:       total,
+		Projects:    projects,
+		Whiteboards: orphans,
+	}, nil
+}
+
+// RepairOrphanedWhiteboards deletes or reassigns all orphaned whiteboards.
+// req.ConfirmCount must be set and match the current number of orphans.
+func (s *Service) RepairOrphanedWhiteboards(ctx context.Context, req *RepairOrphansRequest) (*RepairOrphansResponse, error) {
+	var target *uuid.UUID
+	switch req.Action {
+	case OrphanActionDelete:
+	case OrphanActionReassign:
+		id, err := uuid.Parse(req.TargetProjectID)
+		if err != nil {
+			return nil, ErrProjectNotFound
+		}
+		target = &id
+	default:
+		return nil, ErrInvalidRepairAction
+	}
+
+	repaired, err := s.repo.RepairOrphans(ctx, target, *req.ConfirmCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RepairOrphansResponse{
+		Action:   req.Action,
+		Repaired: repaired,
+	}, nil
+}
@@ -0,0 +1,505 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// Reindex job statuses
+const (
+	ReindexQueued    = "queued"
+	ReindexRunning   = "running"
+	ReindexCompleted = "completed"
+	ReindexFailed    = "failed"
+	ReindexCancelled = "cancelled"
+)
+
+// Reindex limits
+const (
+	maxReindexBatchSize = 1000
+	// reindexLease is how long a running job may go without progress before
+	// another worker treats it as abandoned (e.g. its server restarted) and resumes it
+	reindexLease = 2 * time.Minute
+	reindexPoll  = 10 * time.Second
+)
+
+// Errors returned for reindex jobs
+var (
+	ErrReindexNotFound     = errors.New("reindex job not found")
+	ErrUnknownDerivation   = errors.New("unknown derivation")
+	ErrReindexActive       = errors.New("a reindex of this derivation is already queued or running")
+	ErrReindexNotResumable = errors.New("only failed or cancelled reindex jobs can be resumed")
+	ErrReindexFinished     = errors.New("reindex job has already finished")
+	ErrInvalidReindexBatch = fmt.Errorf("batch_size must be between 1 and %d", maxReindexBatchSize)
+	errReindexStopped      = errors.New("reindex job was cancelled")
+)
+
+// Derivation recomputes one kind of derived data for a whiteboard. It reports
+// whether anything changed; returning an error counts the whiteboard as failed
+// without stopping the job.
+type Derivation func(ctx context.Context, w *Whiteboard) (bool, error)
+
+// StartReindexRequest is the request body for starting a reindex
+type StartReindexRequest struct {
+	Derivation string `json:"derivation"`
+	// BatchSize defaults to REINDEX_BATCH_SIZE
+	BatchSize int `json:"batch_size,omitempty"`
+}
+
+// ReindexJob is a reindex job and its progress
+type ReindexJob struct {
+	ID         string     `json:"id"`
+	Derivation string     `json:"derivation"`
+	Status     string     `json:"status"`
+	BatchSize  int        `json:"batch_size"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	cursor uuid.UUID
+}
+
+// ReindexListResponse lists the available derivations and the most recent jobs
+type ReindexListResponse struct {
+	Derivations []string      `json:"derivations"`
+	Jobs        []*ReindexJob `json:"jobs"`
+}
+
+const reindexJobColumns = `id, derivation, status, batch_size, COALESCE(cursor, '00000000-0000-0000-0000-000000000000'),
+	total, processed, updated, failed, COALESCE(last_error, ''), created_at, updated_at, finished_at`
+
+func scanReindexJob(row pgx.Row) (*ReindexJob, error) {
+	var job ReindexJob
+	var id uuid.UUID
+	err := row.Scan(&id, &job.Derivation, &job.Status, &job.BatchSize, &job.cursor,
+		&job.Total, &job.Processed, &job.Updated, &job.Failed, &job.LastError,
+		&job.CreatedAt, &job.UpdatedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = id.String()
+	return &job, nil
+}
+
+// CreateReindexJob queues a job, unless one for the same derivation is already active
+func (r *Repository) CreateReindexJob(ctx context.Context, derivation string, batchSize int) (*ReindexJob, error) {
+	query := `
+		INSERT INTO reindex_jobs (derivation, batch_size, total)
+		VALUES ($1, $2, (SELECT COUNT(*) FROM whiteboards))
+		RETURNING ` + reindexJobColumns
+
+	job, err := scanReindexJob(r.db.QueryRow(ctx, query, derivation, batchSize))
+	if database.IsUniqueViolation(err, "") {
+		return nil, ErrReindexActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reindex job: %w", err)
+	}
+
+	return job, nil
+}
+
+// FindReindexJob finds a reindex job. Returns nil if there is none.
+func (r *Repository) FindReindexJob(ctx context.Context, id uuid.UUID) (*ReindexJob, error) {
+	query := `SELECT ` + reindexJobColumns + ` FROM reindex_jobs WHERE id = $1`
+
+	job, err := scanReindexJob(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reindex job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListReindexJobs returns the most recent reindex jobs, newest first
+func (r *Repository) ListReindexJobs(ctx context.Context, limit int) ([]*ReindexJob, error) {
+	query := `SELECT ` + reindexJobColumns + ` FROM reindex_jobs ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reindex jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*ReindexJob{}
+	for rows.Next() {
+		job, err := scanReindexJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reindex job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reindex jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimReindexJob marks the oldest queued job, or a running job whose worker
+// stopped reporting progress, as running and returns it. Returns nil if there
+// is nothing to do.
+func (r *Repository) ClaimReindexJob(ctx context.Context, lease time.Duration) (*ReindexJob, error) {
+	query := `
+		UPDATE reindex_jobs
+		SET status = 'running', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM reindex_jobs
+			WHERE status = 'queued'
+				OR (status = 'running' AND updated_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + reindexJobColumns
+
+	job, err := scanReindexJob(r.db.QueryRow(ctx, query, lease.Seconds()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim reindex job: %w", err)
+	}
+
+	return job, nil
+}
+
+// SaveReindexProgress records a running job's progress, which also renews its
+// lease. Returns errReindexStopped if the job is no longer running.
+func (r *Repository) SaveReindexProgress(ctx context.Context, job *ReindexJob) error {
+	query := `
+		UPDATE reindex_jobs
+		SET cursor = $2, processed = $3, updated = $4, failed = $5,
+			last_error = NULLIF($6, ''), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	tag, err := r.db.Exec(ctx, query, uuid.MustParse(job.ID), job.cursor, job.Processed, job.Updated, job.Failed, job.LastError)
+	if err != nil {
+		return fmt.Errorf("failed to save reindex progress: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errReindexStopped
+	}
+
+	return nil
+}
+
+// SetReindexStatus moves a job from one of the given statuses to another.
+// Returns false if the job wasn't in any of them, and ErrReindexActive if
+// requeueing it would give its derivation two active jobs.
+func (r *Repository) SetReindexStatus(ctx context.Context, id uuid.UUID, status, lastError string, from ...string) (bool, error) {
+	query := `
+		UPDATE reindex_jobs
+		SET status = $2,
+			last_error = COALESCE(NULLIF($3, ''), last_error),
+			updated_at = NOW(),
+			finished_at = CASE WHEN $2 IN ('completed', 'failed', 'cancelled') THEN NOW() ELSE NULL END
+		WHERE id = $1 AND status = ANY($4)
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, status, lastError, from)
+	if database.IsUniqueViolation(err, "") {
+		return false, ErrReindexActive
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update reindex job: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// FindBatchAfter returns up to limit whiteboards with IDs after cursor, in ID order
+func (r *Repository) FindBatchAfter(ctx context.Context, cursor uuid.UUID, limit int) ([]*Whiteboard, error) {
+	query := `
+		SELECT ` + whiteboardColumns + `
+		FROM whiteboards
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard batch: %w", err)
+	}
+	defer rows.Close()
+
+	var whiteboards []*Whiteboard
+	for rows.Next() {
+		w, err := scanWhiteboard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan whiteboard: %w", err)
+		}
+		whiteboards = append(whiteboards, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate whiteboards: %w", err)
+	}
+
+	return whiteboards, nil
+}
+
+// SetContentHash stores a recomputed content hash, unless the whiteboard was
+// saved since it was read (that save stored a fresh hash already). updated_at
+// is left alone: a backfill isn't an edit.
+func (r *Repository) SetContentHash(ctx context.Context, id uuid.UUID, hash string, readAt time.Time) (bool, error) {
+	query := `UPDATE whiteboards SET content_hash = $2 WHERE id = $1 AND updated_at = $3`
+
+	tag, err := r.db.Exec(ctx, query, id, hash, readAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to set content hash: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RegisterDerivation makes a kind of derived data rebuildable by reindex jobs
+func (s *Service) RegisterDerivation(name string, fn Derivation) {
+	s.derivations[name] = fn
+}
+
+// deriveContentHash recomputes a whiteboard's content hash
+func (s *Service) deriveContentHash(ctx context.Context, w *Whiteboard) (bool, error) {
+	hash, err := ContentHash(w.Data)
+	if err != nil {
+		return false, err
+	}
+	if hash == w.ContentHash {
+		return false, nil
+	}
+	return s.repo.SetContentHash(ctx, w.ID, hash, w.UpdatedAt)
+}
+
+// Reindexer runs reindex jobs in the background, one at a time, pausing
+// between batches so a backfill doesn't crowd out regular traffic. Progress is
+// stored after every batch, so a job interrupted by a restart resumes from its
+// last batch once its lease runs out.
+type Reindexer struct {
+	service   *Service
+	batchSize int
+	interval  time.Duration
+	wake      chan struct{}
+}
+
+// NewReindexer creates a reindexer that by default processes batchSize
+// whiteboards per batch and waits interval between batches
+func NewReindexer(service *Service, batchSize int, interval time.Duration) *Reindexer {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Reindexer{
+		service:   service,
+		batchSize: min(batchSize, maxReindexBatchSize),
+		interval:  interval,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// List returns the registered derivations and the most recent jobs
+func (r *Reindexer) List(ctx context.Context) (*ReindexListResponse, error) {
+	jobs, err := r.service.repo.ListReindexJobs(ctx, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	derivations := make([]string, 0, len(r.service.derivations))
+	for name := range r.service.derivations {
+		derivations = append(derivations, name)
+	}
+	sort.Strings(derivations)
+
+	return &ReindexListResponse{Derivations: derivations, Jobs: jobs}, nil
+}
+
+// Start queues a job rebuilding one derivation across all whiteboards
+func (r *Reindexer) Start(ctx context.Context, req *StartReindexRequest) (*ReindexJob, error) {
+	if _, ok := r.service.derivations[req.Derivation]; !ok {
+		return nil, ErrUnknownDerivation
+	}
+
+	batchSize := req.BatchSize
+	if batchSize == 0 {
+		batchSize = r.batchSize
+	}
+	if batchSize < 1 || batchSize > maxReindexBatchSize {
+		return nil, ErrInvalidReindexBatch
+	}
+
+	job, err := r.service.repo.CreateReindexJob(ctx, req.Derivation, batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	r.notify()
+	return job, nil
+}
+
+// Get returns a job's progress
+func (r *Reindexer) Get(ctx context.Context, id uuid.UUID) (*ReindexJob, error) {
+	job, err := r.service.repo.FindReindexJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrReindexNotFound
+	}
+	return job, nil
+}
+
+// Cancel stops a queued or running job. A running job stops after its
+// current batch, whose progress is dropped and redone if the job is resumed.
+func (r *Reindexer) Cancel(ctx context.Context, id uuid.UUID) (*ReindexJob, error) {
+	return r.transition(ctx, id, ReindexCancelled, ErrReindexFinished, ReindexQueued, ReindexRunning)
+}
+
+// Resume requeues a failed or cancelled job; it continues after the last batch it finished
+func (r *Reindexer) Resume(ctx context.Context, id uuid.UUID) (*ReindexJob, error) {
+	job, err := r.transition(ctx, id, ReindexQueued, ErrReindexNotResumable, ReindexFailed, ReindexCancelled)
+	if err != nil {
+		return nil, err
+	}
+	r.notify()
+	return job, nil
+}
+
+// transition moves a job between statuses, returning conflict if it isn't in one of from
+func (r *Reindexer) transition(ctx context.Context, id uuid.UUID, status string, conflict error, from ...string) (*ReindexJob, error) {
+	changed, err := r.service.repo.SetReindexStatus(ctx, id, status, "", from...)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, conflict
+	}
+
+	return job, nil
+}
+
+func (r *Reindexer) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes jobs until ctx is cancelled. An interrupted job keeps its
+// lease, so it is resumed here or by another instance once the lease expires.
+func (r *Reindexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(reindexPoll)
+	defer ticker.Stop()
+
+	for {
+		for {
+			job, err := r.service.repo.ClaimReindexJob(ctx, reindexLease)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn().Err(err).Msg("Failed to claim reindex job")
+				}
+				break
+			}
+			if job == nil {
+				break
+			}
+			r.run(ctx, job)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run works through a claimed job batch by batch
+func (r *Reindexer) run(ctx context.Context, job *ReindexJob) {
+	jobID := uuid.MustParse(job.ID)
+	logger.Info().Str("job_id", job.ID).Str("derivation", job.Derivation).Int("processed", job.Processed).Msg("Reindex job started")
+
+	derive, ok := r.service.derivations[job.Derivation]
+	if !ok {
+		// Registered by a server build or configuration that this one lacks
+		r.finish(jobID, ReindexFailed, ErrUnknownDerivation.Error())
+		return
+	}
+
+	for {
+		batch, err := r.service.repo.FindBatchAfter(ctx, job.cursor, job.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.finish(jobID, ReindexFailed, err.Error())
+			}
+			return
+		}
+		if len(batch) == 0 {
+			r.finish(jobID, ReindexCompleted, "")
+			logger.Info().Str("job_id", job.ID).Int("updated", job.Updated).Int("failed", job.Failed).Msg("Reindex job completed")
+			return
+		}
+
+		for _, w := range batch {
+			changed, err := derive(ctx, w)
+			switch {
+			case err != nil:
+				job.Failed++
+				job.LastError = fmt.Sprintf("%s: %v", w.ID, err)
+			case changed:
+				job.Updated++
+			}
+			job.Processed++
+			job.cursor = w.ID
+		}
+
+		if err := r.service.repo.SaveReindexProgress(ctx, job); err != nil {
+			if !errors.Is(err, errReindexStopped) && ctx.Err() == nil {
+				logger.Warn().Err(err).Str("job_id", job.ID).Msg("Failed to save reindex progress")
+			}
+			// Cancelled, or the lease will let it resume later
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// finish records a job's final status. It uses its own context so a job that
+// fails as the server shuts down is still marked.
+func (r *Reindexer) finish(id uuid.UUID, status, lastError string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := r.service.repo.SetReindexStatus(ctx, id, status, lastError, ReindexRunning); err != nil {
+		logger.Warn().Err(err).Str("job_id", id.String()).Msg("Failed to finish reindex job")
+	}
+}
@@ -0,0 +1,82 @@
+package whiteboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// relayChannel is the Redis pub/sub channel whiteboard events are relayed on
+const relayChannel = "whiteboard:events"
+
+// relayedEvent is an event on relayChannel, with the project it belongs to
+type relayedEvent struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Event     Event     `json:"event"`
+}
+
+// UseEventRelay relays whiteboard events through Redis pub/sub, so that
+// subscribers on every instance receive them and not only those on the
+// instance that dispatched the event. RelayEvents must run on each instance.
+func (s *Service) UseEventRelay(client *redis.Client) {
+	s.relay = client
+}
+
+// publishEvent hands an event to every instance's subscribers, or to this
+// instance's when there is no relay
+func (s *Service) publishEvent(ctx context.Context, projectID uuid.UUID, event Event) error {
+	if s.relay == nil {
+		s.events.Publish(projectID, event)
+		return nil
+	}
+
+	payload, err := json.Marshal(relayedEvent{ProjectID: projectID, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to encode relayed event: %w", err)
+	}
+	if err := s.relay.Publish(ctx, relayChannel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to relay whiteboard event: %w", err)
+	}
+
+	return nil
+}
+
+// RelayEvents delivers events relayed through Redis to this instance's
+// subscribers until ctx is done. The Redis client resubscribes on its own
+// after connection errors; events published meanwhile are missed, as with a
+// full subscriber buffer.
+func (s *Service) RelayEvents(ctx context.Context) {
+	if s.relay == nil {
+		return
+	}
+
+	pubsub := s.relay.Subscribe(ctx, relayChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil && ctx.Err() == nil {
+		logger.Warn().Err(err).Msg("Failed to subscribe to whiteboard events, retrying in the background")
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var relayed relayedEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &relayed); err != nil {
+				logger.Warn().Err(err).Msg("Dropping malformed relayed whiteboard event")
+				continue
+			}
+			s.events.Publish(relayed.ProjectID, relayed.Event)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidOrder is returned for reorders listing a whiteboard twice or one
+// from another project
+var ErrInvalidOrder = errors.New("order must list whiteboards of this project, each once")
+
+// ReorderRequest is the request body for setting a project's tab order
+type ReorderRequest struct {
+	// IDs are whiteboard IDs in their new order. Whiteboards left out keep
+	// their relative order after the listed ones.
+	IDs []string `json:"ids"`
+}
+
+// Reorder sets a project's tab order in one bulk transaction, numbering the
+// listed whiteboards first. It returns ErrInvalidOrder if any ID isn't a
+// whiteboard of the project.
+func (r *Repository) Reorder(ctx context.Context, projectID uuid.UUID, ids []uuid.UUID) error {
+	return r.bulk.RunInTx(ctx, r.db, func(tx pgx.Tx) error {
+		// Lock the project's whiteboards so concurrent reorders apply one after the other
+		var listed int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FILTER (WHERE id = ANY($2))
+			FROM (SELECT id FROM whiteboards WHERE project_id = $1 AND deleted_at IS NULL FOR UPDATE) locked
+		`, projectID, ids).Scan(&listed)
+		if err != nil {
+			return fmt.Errorf("failed to lock whiteboards: %w", err)
+		}
+		if listed != len(ids) {
+			return ErrInvalidOrder
+		}
+
+		_, err = tx.Exec(ctx, `
+			WITH listed AS (
+				SELECT id, ord FROM unnest($2::uuid[]) WITH ORDINALITY AS t(id, ord)
+			), ranked AS (
+				SELECT w.id, ROW_NUMBER() OVER (ORDER BY l.ord ASC NULLS LAST, w.position ASC, w.created_at ASC, w.id ASC) - 1 AS position
+				FROM whiteboards w
+				LEFT JOIN listed l ON l.id = w.id
+				WHERE w.project_id = $1 AND w.deleted_at IS NULL
+			)
+			UPDATE whiteboards
+			SET position = ranked.position
+			FROM ranked
+			WHERE whiteboards.id = ranked.id AND whiteboards.position <> ranked.position
+		`, projectID, ids)
+		if err != nil {
+			return fmt.Errorf("failed to reorder whiteboards: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReorderWhiteboards sets the tab order of a project's whiteboards (owner or
+// editor) and returns them in the new order
+func (s *Service) ReorderWhiteboards(ctx context.Context, projectID, userID uuid.UUID, req *ReorderRequest) ([]*WhiteboardResponse, error) {
+	if err := s.checkEditAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for i, raw := range req.IDs {
+		id, err := uuid.Parse(raw)
+		if err != nil || seen[id] {
+			return nil, ErrInvalidOrder
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+
+	if err := s.repo.Reorder(ctx, projectID, ids); err != nil {
+		return nil, err
+	}
+
+	responses, _, err := s.GetProjectWhiteboards(ctx, projectID, userID, false)
+	return responses, err
+}
@@ -8,36 +8,131 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/bulk"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/compress"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/outbox"
 )
 
 // Repository handles database operations for whiteboards
 type Repository struct {
 	db *pgxpool.Pool
+	// compressThreshold is the canvas size from which data is stored
+	// compressed (0 stores every canvas as plain JSON)
+	compressThreshold int
+	// bulk bounds the transactions of bulk writes, like reorders
+	bulk *bulk.Guard
+}
+
+// NewRepository creates a new whiteboard repository that compresses canvas
+// data of at least compressThreshold bytes
+func NewRepository(db *pgxpool.Pool, compressThreshold int, bulkGuard *bulk.Guard) *Repository {
+	return &Repository{db: db, compressThreshold: compressThreshold, bulk: bulkGuard}
+}
+
+// whiteboardColumns is the column list scanned by scanWhiteboard.
+// The storage region comes from the owning project ("" means the default region).
+const whiteboardColumns = `id, project_id, name, data, data_compressed, data_encoding, COALESCE(content_hash, ''),
+	COALESCE((SELECT storage_region FROM projects WHERE projects.id = whiteboards.project_id), ''),
+	position, created_at, updated_at`
+
+// whiteboardMetadataColumns is whiteboardColumns without the canvas data, for
+// listings that don't need it; rows scan with a nil Data
+const whiteboardMetadataColumns = `id, project_id, name, NULL::jsonb, NULL::bytea, NULL::varchar, COALESCE(content_hash, ''),
+	COALESCE((SELECT storage_region FROM projects WHERE projects.id = whiteboards.project_id), ''),
+	position, created_at, updated_at`
+
+// whiteboardRow is a row selected with whiteboardColumns, scanned into
+// types that accept any stored value (NULLs included), so a corrupt row
+// fails in decode rather than in Scan. A failed Scan ends a pgx result set;
+// a failed decode only loses that row.
+type whiteboardRow struct {
+	id            uuid.UUID
+	projectID     uuid.UUID
+	name          pgtype.Text
+	stored        compress.Stored
+	contentHash   string
+	storageRegion string
+	position      pgtype.Int4
+	createdAt     pgtype.Timestamptz
+	updatedAt     pgtype.Timestamptz
+}
+
+func (w *whiteboardRow) scan(row pgx.Row) error {
+	return row.Scan(
+		&w.id,
+		&w.projectID,
+		&w.name,
+		&w.stored.Plain,
+		&w.stored.Compressed,
+		&w.stored.Encoding,
+		&w.contentHash,
+		&w.storageRegion,
+		&w.position,
+		&w.createdAt,
+		&w.updatedAt,
+	)
 }
 
-// NewRepository creates a new whiteboard repository
-func NewRepository(db *pgxpool.Pool) *Repository {
-	return &Repository{db: db}
+// decode checks the scanned values and decompresses the canvas data if it
+// was stored compressed
+func (w *whiteboardRow) decode() (*Whiteboard, error) {
+	if !w.name.Valid || !w.createdAt.Valid || !w.updatedAt.Valid {
+		return nil, fmt.Errorf("whiteboard %s: missing name or timestamps", w.id)
+	}
+
+	data, err := w.stored.Unpack()
+	if err != nil {
+		return nil, fmt.Errorf("whiteboard %s: %w", w.id, err)
+	}
+	if data != nil && !json.Valid(data) {
+		return nil, fmt.Errorf("whiteboard %s: canvas data is not valid JSON", w.id)
+	}
+
+	return &Whiteboard{
+		ID:            w.id,
+		ProjectID:     w.projectID,
+		Name:          w.name.String,
+		Data:          data,
+		ContentHash:   w.contentHash,
+		StorageRegion: w.storageRegion,
+		Position:      int(w.position.Int32),
+		CreatedAt:     w.createdAt.Time,
+		UpdatedAt:     w.updatedAt.Time,
+	}, nil
 }
 
-// FindByID finds a whiteboard by its ID
+// scanWhiteboard scans a row selected with whiteboardColumns, decompressing
+// its canvas data if it was stored compressed
+func scanWhiteboard(row pgx.Row) (*Whiteboard, error) {
+	var raw whiteboardRow
+	if err := raw.scan(row); err != nil {
+		return nil, err
+	}
+	return raw.decode()
+}
+
+// packData prepares canvas data for storage, compressing it if it's large
+func (r *Repository) packData(data json.RawMessage) (*compress.Stored, error) {
+	stored, err := compress.Pack(data, r.compressThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store whiteboard data: %w", err)
+	}
+	return stored, nil
+}
+
+// FindByID finds a whiteboard by its ID. Whiteboards in the trash aren't found.
 func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Whiteboard, error) {
 	query := `
-		SELECT id, project_id, name, data, created_at, updated_at
+		SELECT ` + whiteboardColumns + `
 		FROM whiteboards
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	var whiteboard Whiteboard
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&whiteboard.ID,
-		&whiteboard.ProjectID,
-		&whiteboard.Name,
-		&whiteboard.Data,
-		&whiteboard.CreatedAt,
-		&whiteboard.UpdatedAt,
-	)
+	whiteboard, err := scanWhiteboard(r.db.QueryRow(ctx, query, id))
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -46,184 +141,297 @@ func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*Whiteboard, e
 		return nil, fmt.Errorf("failed to find whiteboard by id: %w", err)
 	}
 
-	return &whiteboard, nil
+	return whiteboard, nil
 }
 
-// FindByProjectID finds all whiteboards for a project
-func (r *Repository) FindByProjectID(ctx context.Context, projectID uuid.UUID) ([]*Whiteboard, error) {
+// FindByIDs finds the whiteboards with the given IDs in one query; missing IDs are simply absent
+func (r *Repository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*Whiteboard, error) {
 	query := `
-		SELECT id, project_id, name, data, created_at, updated_at
+		SELECT ` + whiteboardColumns + `
 		FROM whiteboards
-		WHERE project_id = $1
-		ORDER BY updated_at DESC
+		WHERE id = ANY($1) AND deleted_at IS NULL
 	`
 
-	rows, err := r.db.Query(ctx, query, projectID)
+	rows, err := r.db.Query(ctx, query, ids)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find whiteboards by project id: %w", err)
+		return nil, fmt.Errorf("failed to find whiteboards by ids: %w", err)
 	}
 	defer rows.Close()
 
 	var whiteboards []*Whiteboard
 	for rows.Next() {
-		var whiteboard Whiteboard
-		err := rows.Scan(
-			&whiteboard.ID,
-			&whiteboard.ProjectID,
-			&whiteboard.Name,
-			&whiteboard.Data,
-			&whiteboard.CreatedAt,
-			&whiteboard.UpdatedAt,
-		)
+		whiteboard, err := scanWhiteboard(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan whiteboard: %w", err)
 		}
-		whiteboards = append(whiteboards, &whiteboard)
+		whiteboards = append(whiteboards, whiteboard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate whiteboards: %w", err)
 	}
 
 	return whiteboards, nil
 }
 
+// FindByProjectID finds all whiteboards for a project, in tab order. Their
+// canvas data is only read when withData is set.
+// Rows that can't be decoded (e.g. corrupt compressed data) are skipped and logged
+// so one bad whiteboard doesn't make the whole project unusable; the number
+// of skipped rows is returned alongside the results.
+func (r *Repository) FindByProjectID(ctx context.Context, projectID uuid.UUID, withData bool) ([]*Whiteboard, int, error) {
+	columns := whiteboardMetadataColumns
+	if withData {
+		columns = whiteboardColumns
+	}
+
+	query := `
+		SELECT ` + columns + `
+		FROM whiteboards
+		WHERE project_id = $1 AND deleted_at IS NULL
+		ORDER BY position ASC, created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find whiteboards by project id: %w", err)
+	}
+	defer rows.Close()
+
+	return collectWhiteboards(rows, projectID)
+}
+
+// collectWhiteboards reads a project's whiteboard rows, skipping (and
+// counting) the ones that scan but can't be decoded
+func collectWhiteboards(rows pgx.Rows, projectID uuid.UUID) ([]*Whiteboard, int, error) {
+	var whiteboards []*Whiteboard
+	skipped := 0
+	for rows.Next() {
+		var raw whiteboardRow
+		if err := raw.scan(rows); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan whiteboard: %w", err)
+		}
+		whiteboard, err := raw.decode()
+		if err != nil {
+			skipped++
+			logger.Warn().Err(err).Str("project_id", projectID.String()).Msg("Skipping unreadable whiteboard row")
+			continue
+		}
+		whiteboards = append(whiteboards, whiteboard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate whiteboards: %w", err)
+	}
+
+	return whiteboards, skipped, nil
+}
+
 // FindDefaultByProjectID finds or creates the default whiteboard for a project
 func (r *Repository) FindDefaultByProjectID(ctx context.Context, projectID uuid.UUID) (*Whiteboard, error) {
 	// First, try to find an existing whiteboard
 	query := `
-		SELECT id, project_id, name, data, created_at, updated_at
+		SELECT ` + whiteboardColumns + `
 		FROM whiteboards
-		WHERE project_id = $1
+		WHERE project_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at ASC
 		LIMIT 1
 	`
 
-	var whiteboard Whiteboard
-	err := r.db.QueryRow(ctx, query, projectID).Scan(
-		&whiteboard.ID,
-		&whiteboard.ProjectID,
-		&whiteboard.Name,
-		&whiteboard.Data,
-		&whiteboard.CreatedAt,
-		&whiteboard.UpdatedAt,
-	)
+	whiteboard, err := scanWhiteboard(r.db.QueryRow(ctx, query, projectID))
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		// Create a default whiteboard if none exists
-		return r.Create(ctx, projectID, "Main Canvas", json.RawMessage(`{}`))
+		data := json.RawMessage(`{}`)
+		hash, err := ContentHash(data)
+		if err != nil {
+			return nil, err
+		}
+		return r.Create(ctx, projectID, "Main Canvas", data, hash)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find default whiteboard: %w", err)
 	}
 
-	return &whiteboard, nil
+	return whiteboard, nil
 }
 
 // Create creates a new whiteboard
-func (r *Repository) Create(ctx context.Context, projectID uuid.UUID, name string, data json.RawMessage) (*Whiteboard, error) {
+func (r *Repository) Create(ctx context.Context, projectID uuid.UUID, name string, data json.RawMessage, contentHash string) (*Whiteboard, error) {
 	if data == nil || len(data) == 0 {
 		data = json.RawMessage(`{}`)
 	}
+	stored, err := r.packData(data)
+	if err != nil {
+		return nil, err
+	}
 
+	// New whiteboards inherit the project's unique-name setting and go last in tab order
 	query := `
-		INSERT INTO whiteboards (project_id, name, data)
-		VALUES ($1, $2, $3)
-		RETURNING id, project_id, name, data, created_at, updated_at
+		INSERT INTO whiteboards (project_id, name, data, data_compressed, data_encoding, content_hash, enforce_unique_name, position)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE((SELECT unique_whiteboard_names FROM projects WHERE id = $1), false),
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM whiteboards WHERE project_id = $1))
+		RETURNING ` + whiteboardColumns + `
 	`
 
-	var whiteboard Whiteboard
-	err := r.db.QueryRow(ctx, query, projectID, name, data).Scan(
-		&whiteboard.ID,
-		&whiteboard.ProjectID,
-		&whiteboard.Name,
-		&whiteboard.Data,
-		&whiteboard.CreatedAt,
-		&whiteboard.UpdatedAt,
-	)
+	return r.recordChange(ctx, EventWhiteboardCreated, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, projectID, name, stored.Plain, stored.Compressed, stored.Encoding, contentHash))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create whiteboard: %w", err)
+		}
+		return whiteboard, nil
+	})
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create whiteboard: %w", err)
+// Update updates a whiteboard. When data is set it is also stored as a new
+// version by authorID.
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, name *string, data *json.RawMessage, contentHash *string, authorID uuid.UUID) (*Whiteboard, error) {
+	stored := &compress.Stored{}
+	if data != nil {
+		var err error
+		if stored, err = r.packData(*data); err != nil {
+			return nil, err
+		}
 	}
 
-	return &whiteboard, nil
-}
-
-// Update updates a whiteboard
-func (r *Repository) Update(ctx context.Context, id uuid.UUID, name *string, data *json.RawMessage) (*Whiteboard, error) {
+	// $3 says whether data is set; when it is, all three data columns are replaced
 	query := `
 		UPDATE whiteboards
 		SET 
 			name = COALESCE($2, name),
-			data = COALESCE($3, data),
+			data = CASE WHEN $3::boolean THEN $4::jsonb ELSE data END,
+			data_compressed = CASE WHEN $3::boolean THEN $5::bytea ELSE data_compressed END,
+			data_encoding = CASE WHEN $3::boolean THEN $6::varchar ELSE data_encoding END,
+			content_hash = COALESCE($7, content_hash),
 			updated_at = NOW()
-		WHERE id = $1
-		RETURNING id, project_id, name, data, created_at, updated_at
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + whiteboardColumns + `
 	`
 
-	var whiteboard Whiteboard
-	err := r.db.QueryRow(ctx, query, id, name, data).Scan(
-		&whiteboard.ID,
-		&whiteboard.ProjectID,
-		&whiteboard.Name,
-		&whiteboard.Data,
-		&whiteboard.CreatedAt,
-		&whiteboard.UpdatedAt,
-	)
+	return r.recordChange(ctx, EventWhiteboardUpdated, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, id, name, data != nil, stored.Plain, stored.Compressed, stored.Encoding, contentHash))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update whiteboard: %w", err)
+		}
+		if data != nil {
+			if err := insertVersion(ctx, tx, whiteboard, authorID); err != nil {
+				return nil, err
+			}
+		}
+		return whiteboard, nil
+	})
+}
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
+// UpdateData updates only the canvas data of a whiteboard and stores it as a new version by authorID
+func (r *Repository) UpdateData(ctx context.Context, id uuid.UUID, data json.RawMessage, contentHash string, authorID uuid.UUID) (*Whiteboard, error) {
+	stored, err := r.packData(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update whiteboard: %w", err)
+		return nil, err
 	}
 
-	return &whiteboard, nil
-}
-
-// UpdateData updates only the canvas data of a whiteboard
-func (r *Repository) UpdateData(ctx context.Context, id uuid.UUID, data json.RawMessage) (*Whiteboard, error) {
 	query := `
 		UPDATE whiteboards
 		SET 
 			data = $2,
+			data_compressed = $3,
+			data_encoding = $4,
+			content_hash = $5,
 			updated_at = NOW()
-		WHERE id = $1
-		RETURNING id, project_id, name, data, created_at, updated_at
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + whiteboardColumns + `
 	`
 
-	var whiteboard Whiteboard
-	err := r.db.QueryRow(ctx, query, id, data).Scan(
-		&whiteboard.ID,
-		&whiteboard.ProjectID,
-		&whiteboard.Name,
-		&whiteboard.Data,
-		&whiteboard.CreatedAt,
-		&whiteboard.UpdatedAt,
-	)
+	return r.recordChange(ctx, EventWhiteboardUpdated, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, id, stored.Plain, stored.Compressed, stored.Encoding, contentHash))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update whiteboard data: %w", err)
+		}
+		if err := insertVersion(ctx, tx, whiteboard, authorID); err != nil {
+			return nil, err
+		}
+		return whiteboard, nil
+	})
+}
 
+// FindConflictingName finds another whiteboard in the project whose name matches
+// case-insensitively. Returns uuid.Nil if there is none.
+func (r *Repository) FindConflictingName(ctx context.Context, projectID uuid.UUID, name string, excludeID uuid.UUID) (uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM whiteboards
+		WHERE project_id = $1 AND LOWER(name) = LOWER($2) AND id <> $3 AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, projectID, name, excludeID).Scan(&id)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
+		return uuid.Nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update whiteboard data: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to find conflicting whiteboard name: %w", err)
 	}
 
-	return &whiteboard, nil
+	return id, nil
 }
 
-// Delete deletes a whiteboard
+// Delete moves a whiteboard to the trash. It's removed for good by PurgeDeleted.
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM whiteboards WHERE id = $1`
+	query := `UPDATE whiteboards SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING ` + whiteboardColumns
 
-	result, err := r.db.Exec(ctx, query, id)
+	whiteboard, err := r.recordChange(ctx, EventWhiteboardDeleted, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete whiteboard: %w", err)
+		}
+		return whiteboard, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete whiteboard: %w", err)
+		return err
 	}
 
-	if result.RowsAffected() == 0 {
+	if whiteboard == nil {
 		return fmt.Errorf("whiteboard not found")
 	}
 
 	return nil
 }
 
+// recordChange runs a whiteboard write in a transaction together with the
+// outbox event describing it, so the event is stored exactly when the change
+// is. Writes that match no row record nothing.
+func (r *Repository) recordChange(ctx context.Context, eventType string, write func(tx pgx.Tx) (*Whiteboard, error)) (*Whiteboard, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	whiteboard, err := write(tx)
+	if err != nil || whiteboard == nil {
+		return nil, err
+	}
+
+	if err := outbox.Insert(ctx, tx, eventType, whiteboard.ProjectID, newEvent(eventType, whiteboard)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit whiteboard change: %w", err)
+	}
+
+	return whiteboard, nil
+}
+
 // GetProjectOwner gets the owner of a project (for authorization)
 func (r *Repository) GetProjectOwner(ctx context.Context, projectID uuid.UUID) (uuid.UUID, error) {
 	query := `SELECT user_id FROM projects WHERE id = $1`
@@ -240,6 +448,22 @@ func (r *Repository) GetProjectOwner(ctx context.Context, projectID uuid.UUID) (
 	return ownerID, nil
 }
 
+// GetCollaboratorRole gets a user's collaborator role on a project, or "" if they aren't one
+func (r *Repository) GetCollaboratorRole(ctx context.Context, projectID, userID uuid.UUID) (string, error) {
+	query := `SELECT role FROM project_collaborators WHERE project_id = $1 AND user_id = $2`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, projectID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get collaborator role: %w", err)
+	}
+
+	return role, nil
+}
+
 // IsProjectPublic checks if a project is public
 func (r *Repository) IsProjectPublic(ctx context.Context, projectID uuid.UUID) (bool, error) {
 	query := `SELECT is_public FROM projects WHERE id = $1`
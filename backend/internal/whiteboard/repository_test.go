@@ -0,0 +1,99 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeRows is a pgx.Rows over whiteboardColumns values; nil values scan as zero
+type fakeRows struct {
+	rows    [][]any
+	next    int
+	scanErr error
+}
+
+func (f *fakeRows) Close()                                       {}
+func (f *fakeRows) Err() error                                   { return nil }
+func (f *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (f *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (f *fakeRows) Values() ([]any, error)                       { return f.rows[f.next-1], nil }
+func (f *fakeRows) RawValues() [][]byte                          { return nil }
+func (f *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (f *fakeRows) Next() bool {
+	if f.next >= len(f.rows) {
+		return false
+	}
+	f.next++
+	return true
+}
+
+func (f *fakeRows) Scan(dest ...any) error {
+	if f.scanErr != nil {
+		return f.scanErr
+	}
+	for i, value := range f.rows[f.next-1] {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		target.Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func whiteboardValues(id uuid.UUID, data json.RawMessage, compressed []byte, encoding *string) []any {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return []any{
+		id, uuid.New(), pgtype.Text{String: "Board", Valid: true},
+		data, compressed, encoding,
+		"hash", "", pgtype.Int4{Int32: 0, Valid: true}, now, now,
+	}
+}
+
+func TestCollectWhiteboardsSkipsUndecodableRows(t *testing.T) {
+	good, corrupt, unnamed, last := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	gzip := "gzip"
+
+	noName := whiteboardValues(unnamed, json.RawMessage(`{}`), nil, nil)
+	noName[2] = pgtype.Text{}
+
+	rows := &fakeRows{rows: [][]any{
+		whiteboardValues(good, json.RawMessage(`{"version":1}`), nil, nil),
+		whiteboardValues(corrupt, nil, []byte("not gzip"), &gzip),
+		noName,
+		whiteboardValues(last, json.RawMessage(`{}`), nil, nil),
+	}}
+
+	whiteboards, skipped, err := collectWhiteboards(rows, uuid.New())
+	if err != nil {
+		t.Fatalf("collectWhiteboards: %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want 2", skipped)
+	}
+	if len(whiteboards) != 2 || whiteboards[0].ID != good || whiteboards[1].ID != last {
+		t.Errorf("whiteboards = %v, want %s and %s", whiteboards, good, last)
+	}
+}
+
+func TestCollectWhiteboardsFailsOnScanErrors(t *testing.T) {
+	scanErr := errors.New("conn lost")
+	rows := &fakeRows{
+		rows:    [][]any{whiteboardValues(uuid.New(), json.RawMessage(`{}`), nil, nil)},
+		scanErr: scanErr,
+	}
+
+	if _, _, err := collectWhiteboards(rows, uuid.New()); !errors.Is(err, scanErr) {
+		t.Errorf("error = %v, want %v", err, scanErr)
+	}
+}
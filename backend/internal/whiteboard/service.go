@@ -5,8 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/activity"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/config"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/lru"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/outbox"
 )
 
 // Common errors
@@ -14,28 +23,115 @@ var (
 	ErrWhiteboardNotFound = errors.New("whiteboard not found")
 	ErrProjectNotFound    = errors.New("project not found")
 	ErrUnauthorized       = errors.New("unauthorized to access this whiteboard")
+	ErrCreateForbidden    = errors.New("your role in this project does not allow creating whiteboards")
+	ErrInvalidCanvas      = errors.New("invalid canvas data")
+)
+
+// uniqueNameIndex is the partial index enforcing unique names in opted-in projects
+const uniqueNameIndex = "idx_whiteboards_unique_name"
+
+// NameConflictError is returned when a project requires unique whiteboard names
+// and another whiteboard already uses the requested one
+type NameConflictError struct {
+	Name       string
+	ExistingID uuid.UUID
+}
+
+func (e *NameConflictError) Error() string {
+	return fmt.Sprintf("a whiteboard named %q already exists in this project", e.Name)
+}
+
+// projectRole is a user's role in a project, ordered from least to most privileged
+type projectRole int
+
+const (
+	roleNone projectRole = iota
+	roleViewer
+	roleEditor
+	roleOwner
 )
 
 // Service handles business logic for whiteboards
 type Service struct {
-	repo *Repository
+	repo           *Repository
+	createMinRole  projectRole
+	strictVersion  bool
+	shapeTypes     ShapeTypes
+	stripShapes    bool
+	hideForbidden  bool
+	canvasMaxBytes int
+	onSave         []func(ctx context.Context, whiteboardID uuid.UUID)
+	events         *Broker
+	relay          *redis.Client
+	access         *lru.Cache[uuid.UUID, projectAccess]
+	versionLimit   int
+	versionPageMax int
+	trashRetention time.Duration
+	liveLinkKeys   [][]byte
+	embedTTL       time.Duration
+	derivations    map[string]Derivation
+	activity       *activity.Recorder
+}
+
+// projectAccess is the project data access checks depend on
+type projectAccess struct {
+	ownerID  uuid.UUID
+	isPublic bool
 }
 
 // NewService creates a new whiteboard service
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, cfg *config.Config) *Service {
+	createMinRole := roleEditor
+	if cfg.WhiteboardCreateMinRole == "owner" {
+		createMinRole = roleOwner
+	}
+
+	s := &Service{
+		repo:           repo,
+		createMinRole:  createMinRole,
+		strictVersion:  cfg.CanvasStrictVersion,
+		shapeTypes:     NewShapeTypes(cfg.CanvasExtraShapeTypes),
+		stripShapes:    cfg.CanvasStripUnknownShapes,
+		hideForbidden:  cfg.HideForbidden,
+		canvasMaxBytes: cfg.CanvasMaxBytes,
+		events:         NewBroker(),
+		access:         lru.New[uuid.UUID, projectAccess](cfg.ProjectAccessCacheSize, time.Duration(cfg.ProjectAccessCacheTTLSeconds)*time.Second),
+		versionLimit:   cfg.WhiteboardVersionLimit,
+		versionPageMax: cfg.WhiteboardVersionPageMax,
+		trashRetention: time.Duration(cfg.WhiteboardTrashRetentionDays) * 24 * time.Hour,
+		liveLinkKeys:   liveLinkKeys(cfg.JWTSecret, cfg.JWTPreviousSecrets),
+		embedTTL:       time.Duration(cfg.EmbedTokenTTLHours) * time.Hour,
+		derivations:    make(map[string]Derivation),
+	}
+	s.RegisterDerivation("content_hash", s.deriveContentHash)
+
+	return s
+}
+
+// OnSave registers a hook that runs after a whiteboard's canvas data is written,
+// e.g. to queue a thumbnail render. Hooks must not block the request.
+func (s *Service) OnSave(fn func(ctx context.Context, whiteboardID uuid.UUID)) {
+	s.onSave = append(s.onSave, fn)
+}
+
+// SetActivityRecorder makes the service log whiteboard changes and canvas
+// saves to recorder. Live edits aren't logged; they're saved without a user.
+func (s *Service) SetActivityRecorder(recorder *activity.Recorder) {
+	s.activity = recorder
 }
 
-// GetProjectWhiteboards gets all whiteboards for a project
-func (s *Service) GetProjectWhiteboards(ctx context.Context, projectID, userID uuid.UUID) ([]*WhiteboardResponse, error) {
+// GetProjectWhiteboards gets all whiteboards for a project, along with the
+// number of whiteboards that could not be loaded. Canvas data is left out
+// (null) unless withData is set.
+func (s *Service) GetProjectWhiteboards(ctx context.Context, projectID, userID uuid.UUID, withData bool) ([]*WhiteboardResponse, int, error) {
 	// Check authorization
 	if err := s.checkProjectAccess(ctx, projectID, userID); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	whiteboards, err := s.repo.FindByProjectID(ctx, projectID)
+	whiteboards, skipped, err := s.repo.FindByProjectID(ctx, projectID, withData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get project whiteboards: %w", err)
+		return nil, 0, fmt.Errorf("failed to get project whiteboards: %w", err)
 	}
 
 	responses := make([]*WhiteboardResponse, len(whiteboards))
@@ -43,7 +139,7 @@ func (s *Service) GetProjectWhiteboards(ctx context.Context, projectID, userID u
 		responses[i] = w.ToResponse()
 	}
 
-	return responses, nil
+	return responses, skipped, nil
 }
 
 // GetWhiteboard gets a whiteboard by ID
@@ -64,6 +160,71 @@ func (s *Service) GetWhiteboard(ctx context.Context, whiteboardID, userID uuid.U
 	return whiteboard.ToResponse(), nil
 }
 
+// BatchGetWhiteboards fetches several whiteboards, returning those the user can
+// read and a status for every requested ID. Access is resolved once per project.
+func (s *Service) BatchGetWhiteboards(ctx context.Context, rawIDs []string, userID uuid.UUID) (*BatchGetResponse, error) {
+	ids := make([]uuid.UUID, 0, len(rawIDs))
+	for _, raw := range rawIDs {
+		if id, err := uuid.Parse(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	whiteboards, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get whiteboards: %w", err)
+	}
+
+	byID := make(map[uuid.UUID]*Whiteboard, len(whiteboards))
+	for _, w := range whiteboards {
+		byID[w.ID] = w
+	}
+
+	access := make(map[uuid.UUID]error)
+	response := &BatchGetResponse{
+		Whiteboards: []*WhiteboardResponse{},
+		Results:     make([]BatchGetResult, 0, len(rawIDs)),
+	}
+	returned := make(map[uuid.UUID]bool)
+
+	for _, raw := range rawIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			response.Results = append(response.Results, BatchGetResult{ID: raw, Status: BatchStatusInvalidID})
+			continue
+		}
+
+		w := byID[id]
+		if w == nil {
+			response.Results = append(response.Results, BatchGetResult{ID: raw, Status: BatchStatusNotFound})
+			continue
+		}
+
+		accessErr, checked := access[w.ProjectID]
+		if !checked {
+			accessErr = s.checkProjectAccess(ctx, w.ProjectID, userID)
+			access[w.ProjectID] = accessErr
+		}
+
+		switch {
+		case accessErr == nil:
+			response.Results = append(response.Results, BatchGetResult{ID: raw, Status: BatchStatusOK})
+			if !returned[id] {
+				response.Whiteboards = append(response.Whiteboards, w.ToResponse())
+				returned[id] = true
+			}
+		case errors.Is(accessErr, ErrUnauthorized):
+			response.Results = append(response.Results, BatchGetResult{ID: raw, Status: BatchStatusForbidden})
+		case errors.Is(accessErr, ErrWhiteboardNotFound), errors.Is(accessErr, ErrProjectNotFound):
+			response.Results = append(response.Results, BatchGetResult{ID: raw, Status: BatchStatusNotFound})
+		default:
+			return nil, accessErr
+		}
+	}
+
+	return response, nil
+}
+
 // GetDefaultWhiteboard gets or creates the default whiteboard for a project
 func (s *Service) GetDefaultWhiteboard(ctx context.Context, projectID, userID uuid.UUID) (*WhiteboardResponse, error) {
 	// Check authorization
@@ -81,8 +242,8 @@ func (s *Service) GetDefaultWhiteboard(ctx context.Context, projectID, userID uu
 
 // CreateWhiteboard creates a new whiteboard
 func (s *Service) CreateWhiteboard(ctx context.Context, projectID, userID uuid.UUID, req *CreateWhiteboardRequest) (*WhiteboardResponse, error) {
-	// Check authorization - only owner can create
-	if err := s.checkOwnership(ctx, projectID, userID); err != nil {
+	// Check authorization - minimum role is configurable
+	if err := s.checkCreatePermission(ctx, projectID, userID); err != nil {
 		return nil, err
 	}
 
@@ -91,14 +252,101 @@ func (s *Service) CreateWhiteboard(ctx context.Context, projectID, userID uuid.U
 		name = "Untitled"
 	}
 
-	whiteboard, err := s.repo.Create(ctx, projectID, name, req.Data)
+	data := req.Data
+	if len(data) == 0 {
+		data = json.RawMessage(`{}`)
+	}
+
+	data, hash, err := s.prepareCanvas(data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	whiteboard, err := s.repo.Create(ctx, projectID, name, data, hash)
+	if database.IsUniqueViolation(err, uniqueNameIndex) {
+		return nil, s.nameConflict(ctx, projectID, name, uuid.Nil)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create whiteboard: %w", err)
 	}
 
+	s.saved(ctx, whiteboard.ID)
+	s.activity.Record(ctx, projectID, userID, activity.WhiteboardCreated, map[string]interface{}{
+		"whiteboard_id": whiteboard.ID.String(),
+		"name":          whiteboard.Name,
+	})
 	return whiteboard.ToResponse(), nil
 }
 
+// duplicateSuffix marks the name of a duplicated whiteboard
+const duplicateSuffix = " (copy)"
+
+// maxDuplicateNameAttempts bounds the numbered names tried for a duplicate in
+// projects that enforce unique names
+const maxDuplicateNameAttempts = 10
+
+// DuplicateWhiteboard copies a whiteboard's canvas into a new whiteboard in the
+// same project, named after the original with a " (copy)" suffix ("(copy 2)"
+// and so on where unique names are enforced)
+func (s *Service) DuplicateWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID) (*WhiteboardResponse, error) {
+	existing, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	if err := s.checkCreatePermission(ctx, existing.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	// The stored canvas was validated when saved; only its timestamps start over
+	data, err := stampCanvasTimes(existing.Data, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	hash, err := ContentHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var name string
+	for attempt := 1; attempt <= maxDuplicateNameAttempts; attempt++ {
+		name = duplicateName(existing.Name, attempt)
+		whiteboard, err := s.repo.Create(ctx, existing.ProjectID, name, data, hash)
+		if database.IsUniqueViolation(err, uniqueNameIndex) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to duplicate whiteboard: %w", err)
+		}
+
+		s.saved(ctx, whiteboard.ID)
+		s.activity.Record(ctx, existing.ProjectID, userID, activity.WhiteboardCreated, map[string]interface{}{
+			"whiteboard_id": whiteboard.ID.String(),
+			"name":          whiteboard.Name,
+			"duplicate_of":  existing.ID.String(),
+		})
+		return whiteboard.ToResponse(), nil
+	}
+
+	return nil, s.nameConflict(ctx, existing.ProjectID, name, uuid.Nil)
+}
+
+// duplicateName names the attempt-th candidate for a copy of name, shortening
+// name so the result fits the 255-character column
+func duplicateName(name string, attempt int) string {
+	suffix := duplicateSuffix
+	if attempt > 1 {
+		suffix = fmt.Sprintf(" (copy %d)", attempt)
+	}
+	if runes := []rune(name); len(runes)+len(suffix) > 255 {
+		name = string(runes[:255-len(suffix)])
+	}
+	return name + suffix
+}
+
 // UpdateWhiteboard updates a whiteboard
 func (s *Service) UpdateWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID, req *UpdateWhiteboardRequest) (*WhiteboardResponse, error) {
 	// First get the whiteboard to check ownership
@@ -110,16 +358,36 @@ func (s *Service) UpdateWhiteboard(ctx context.Context, whiteboardID, userID uui
 		return nil, ErrWhiteboardNotFound
 	}
 
-	// Check authorization - only owner can update
-	if err := s.checkOwnership(ctx, existing.ProjectID, userID); err != nil {
+	// Check authorization - owner and editors can update
+	if err := s.checkEditAccess(ctx, existing.ProjectID, userID); err != nil {
 		return nil, err
 	}
 
-	whiteboard, err := s.repo.Update(ctx, whiteboardID, req.Name, req.Data)
+	data := req.Data
+	var hash *string
+	if data != nil {
+		prepared, contentHash, err := s.prepareCanvas(*data, existing.Data)
+		if err != nil {
+			return nil, err
+		}
+		data, hash = &prepared, &contentHash
+	}
+
+	whiteboard, err := s.repo.Update(ctx, whiteboardID, req.Name, data, hash, userID)
+	if database.IsUniqueViolation(err, uniqueNameIndex) {
+		return nil, s.nameConflict(ctx, existing.ProjectID, *req.Name, whiteboardID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update whiteboard: %w", err)
 	}
 
+	if data != nil {
+		s.saved(ctx, whiteboardID)
+	}
+	s.activity.Record(ctx, existing.ProjectID, userID, activity.WhiteboardUpdated, map[string]interface{}{
+		"whiteboard_id": whiteboardID.String(),
+		"fields":        req.fields(),
+	})
 	return whiteboard.ToResponse(), nil
 }
 
@@ -134,23 +402,72 @@ func (s *Service) SaveCanvasData(ctx context.Context, whiteboardID, userID uuid.
 		return nil, ErrWhiteboardNotFound
 	}
 
-	// Check authorization - only owner can update
-	if err := s.checkOwnership(ctx, existing.ProjectID, userID); err != nil {
+	// Check authorization - owner and editors can update
+	if err := s.checkEditAccess(ctx, existing.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	data, hash, err := s.prepareCanvas(data, existing.Data)
+	if err != nil {
 		return nil, err
 	}
 
-	whiteboard, err := s.repo.UpdateData(ctx, whiteboardID, data)
+	whiteboard, err := s.repo.UpdateData(ctx, whiteboardID, data, hash, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save canvas data: %w", err)
 	}
 
+	s.saved(ctx, whiteboardID)
+	s.canvasSaved(ctx, existing.ProjectID, whiteboardID, userID, "save")
+
 	return whiteboard.ToResponse(), nil
 }
 
+// saveLiveCanvas saves a live room's canvas as authorID, only if the stored
+// canvas still has content hash expectedHash; otherwise it returns
+// ErrCanvasChanged. The room checks edit access as each change arrives, so a
+// save doesn't fail because its last editor has since lost access.
+func (s *Service) saveLiveCanvas(ctx context.Context, whiteboardID, authorID uuid.UUID, data json.RawMessage, expectedHash string) (*Whiteboard, error) {
+	existing, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+	if existing.ContentHash != expectedHash {
+		return nil, ErrCanvasChanged
+	}
+
+	data, hash, err := s.prepareCanvas(data, existing.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	whiteboard, err := s.repo.UpdateDataIfMatch(ctx, whiteboardID, data, hash, expectedHash, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save canvas data: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, ErrCanvasChanged
+	}
+
+	s.saved(ctx, whiteboardID)
+	s.canvasSaved(ctx, existing.ProjectID, whiteboardID, authorID, "save")
+
+	return whiteboard, nil
+}
+
+// CheckCanvasEditAccess checks that a user may save a project's canvases (owner or editor),
+// for callers that do expensive work before saving
+func (s *Service) CheckCanvasEditAccess(ctx context.Context, projectID, userID uuid.UUID) error {
+	return s.checkEditAccess(ctx, projectID, userID)
+}
+
 // SaveCanvasDataByProject saves canvas data using project ID (creates default whiteboard if needed)
 func (s *Service) SaveCanvasDataByProject(ctx context.Context, projectID, userID uuid.UUID, data json.RawMessage) (*WhiteboardResponse, error) {
-	// Check authorization - only owner can update
-	if err := s.checkOwnership(ctx, projectID, userID); err != nil {
+	// Check authorization - owner and editors can update
+	if err := s.checkEditAccess(ctx, projectID, userID); err != nil {
 		return nil, err
 	}
 
@@ -160,16 +477,66 @@ func (s *Service) SaveCanvasDataByProject(ctx context.Context, projectID, userID
 		return nil, fmt.Errorf("failed to get default whiteboard: %w", err)
 	}
 
+	data, hash, err := s.prepareCanvas(data, whiteboard.Data)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update the data
-	updated, err := s.repo.UpdateData(ctx, whiteboard.ID, data)
+	updated, err := s.repo.UpdateData(ctx, whiteboard.ID, data, hash, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save canvas data: %w", err)
 	}
 
+	s.saved(ctx, whiteboard.ID)
+	s.canvasSaved(ctx, projectID, whiteboard.ID, userID, "save")
+
 	return updated.ToResponse(), nil
 }
 
-// DeleteWhiteboard deletes a whiteboard
+// LiveAccess checks that a user may open a whiteboard for live collaboration
+// and reports whether they may also edit it
+func (s *Service) LiveAccess(ctx context.Context, whiteboardID, userID uuid.UUID) (bool, error) {
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return false, ErrWhiteboardNotFound
+	}
+
+	if err := s.checkProjectAccess(ctx, whiteboard.ProjectID, userID); err != nil {
+		return false, err
+	}
+
+	return s.checkEditAccess(ctx, whiteboard.ProjectID, userID) == nil, nil
+}
+
+// ExportWhiteboard loads a whiteboard and returns its canvas upgraded to the current schema version
+func (s *Service) ExportWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID) (*Whiteboard, *CanvasData, error) {
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, nil, ErrWhiteboardNotFound
+	}
+
+	// Check authorization
+	if err := s.checkProjectAccess(ctx, whiteboard.ProjectID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	canvas, err := ParseCanvasData(whiteboard.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return whiteboard, canvas, nil
+}
+
+// DeleteWhiteboard moves a whiteboard to its project's trash, from which it
+// can be restored until the retention period passes
 func (s *Service) DeleteWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID) error {
 	// First get the whiteboard to check ownership
 	existing, err := s.repo.FindByID(ctx, whiteboardID)
@@ -185,44 +552,258 @@ func (s *Service) DeleteWhiteboard(ctx context.Context, whiteboardID, userID uui
 		return err
 	}
 
-	return s.repo.Delete(ctx, whiteboardID)
+	if err := s.repo.Delete(ctx, whiteboardID); err != nil {
+		return err
+	}
+
+	s.activity.Record(ctx, existing.ProjectID, userID, activity.WhiteboardDeleted, map[string]interface{}{
+		"whiteboard_id": whiteboardID.String(),
+		"name":          existing.Name,
+	})
+	return nil
 }
 
-// checkProjectAccess checks if a user has access to a project (owner or public)
-func (s *Service) checkProjectAccess(ctx context.Context, projectID, userID uuid.UUID) error {
-	ownerID, err := s.repo.GetProjectOwner(ctx, projectID)
+// SubscribeProjectEvents subscribes a user with access to a project to its
+// whiteboard events. Access is checked once, when the subscription starts.
+func (s *Service) SubscribeProjectEvents(ctx context.Context, projectID, userID uuid.UUID) (<-chan Event, func(), error) {
+	if err := s.checkProjectAccess(ctx, projectID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	events, cancel := s.events.Subscribe(projectID)
+	return events, cancel, nil
+}
+
+// prepareCanvas normalizes incoming canvas data, stamps its timestamps relative
+// to previous (the stored canvas, nil for a new whiteboard) and computes its content hash
+func (s *Service) prepareCanvas(data, previous json.RawMessage) (json.RawMessage, string, error) {
+	if err := checkCanvasSchema(data, s.canvasMaxBytes); err != nil {
+		return nil, "", err
+	}
+
+	data, err := normalizeCanvasVersion(data, s.strictVersion)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err = filterShapeTypes(data, s.shapeTypes, s.stripShapes)
 	if err != nil {
-		return ErrProjectNotFound
+		return nil, "", err
+	}
+
+	data, err = stampCanvasTimes(data, previous, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash, err := ContentHash(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, hash, nil
+}
+
+// PrepareCanvas validates and normalizes canvas data for a new whiteboard the
+// way a save does, e.g. for project imports, and returns the data to store and
+// its content hash
+func (s *Service) PrepareCanvas(data json.RawMessage) (json.RawMessage, string, error) {
+	return s.prepareCanvas(data, nil)
+}
+
+// projectRole resolves the role a user has in a project
+func (s *Service) projectRole(ctx context.Context, projectID, userID uuid.UUID) (projectRole, error) {
+	access, err := s.projectAccess(ctx, projectID)
+	if err != nil {
+		return roleNone, ErrProjectNotFound
 	}
 
 	// Owner always has access
-	if ownerID == userID {
-		return nil
+	if access.ownerID == userID {
+		return roleOwner, nil
 	}
 
-	// Check if project is public
-	isPublic, err := s.repo.IsProjectPublic(ctx, projectID)
+	// Collaborators aren't cached, so removing one takes effect immediately
+	collaboratorRole, err := s.repo.GetCollaboratorRole(ctx, projectID, userID)
 	if err != nil {
-		return ErrProjectNotFound
+		return roleNone, err
+	}
+	switch collaboratorRole {
+	case "editor":
+		return roleEditor, nil
+	case "viewer":
+		return roleViewer, nil
 	}
 
-	if isPublic {
-		return nil
+	// Anyone can view a public project
+	if access.isPublic {
+		return roleViewer, nil
+	}
+
+	return roleNone, nil
+}
+
+// projectAccess loads a project's owner and visibility, from the cache when
+// possible. Missing projects aren't cached.
+func (s *Service) projectAccess(ctx context.Context, projectID uuid.UUID) (projectAccess, error) {
+	if access, ok := s.access.Get(projectID); ok {
+		return access, nil
+	}
+
+	ownerID, err := s.repo.GetProjectOwner(ctx, projectID)
+	if err != nil {
+		return projectAccess{}, err
 	}
+	isPublic, err := s.repo.IsProjectPublic(ctx, projectID)
+	if err != nil {
+		return projectAccess{}, err
+	}
+
+	access := projectAccess{ownerID: ownerID, isPublic: isPublic}
+	s.access.Add(projectID, access)
+	return access, nil
+}
+
+// InvalidateProjectAccess drops cached access data for a project. It is
+// registered as a hook on project changes that affect who may access it.
+// Other instances see the change once their entry's TTL runs out.
+func (s *Service) InvalidateProjectAccess(_ context.Context, projectID uuid.UUID) {
+	s.access.Remove(projectID)
+}
+
+// errHidden stands in for ErrUnauthorized when HIDE_FORBIDDEN is on. It matches
+// both not-found errors so project- and whiteboard-scoped handlers answer 404.
+var errHidden = hiddenError{}
+
+type hiddenError struct{}
+
+func (hiddenError) Error() string { return "not found" }
+
+func (hiddenError) Is(target error) bool {
+	return target == ErrProjectNotFound || target == ErrWhiteboardNotFound
+}
 
+// forbidden returns the error for a user whose role is too low. Users with no
+// role at all can't know the project exists, so with HIDE_FORBIDDEN they get
+// a not-found error; users who can already see the project keep 403.
+func (s *Service) forbidden(role projectRole) error {
+	if s.hideForbidden && role == roleNone {
+		return errHidden
+	}
 	return ErrUnauthorized
 }
 
+// checkProjectAccess checks if a user can view a project (owner, collaborator or public)
+func (s *Service) checkProjectAccess(ctx context.Context, projectID, userID uuid.UUID) error {
+	role, err := s.projectRole(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if role < roleViewer {
+		return s.forbidden(role)
+	}
+
+	return nil
+}
+
 // checkOwnership checks if a user owns a project
 func (s *Service) checkOwnership(ctx context.Context, projectID, userID uuid.UUID) error {
-	ownerID, err := s.repo.GetProjectOwner(ctx, projectID)
+	role, err := s.projectRole(ctx, projectID, userID)
 	if err != nil {
-		return ErrProjectNotFound
+		return err
+	}
+
+	if role != roleOwner {
+		return s.forbidden(role)
+	}
+
+	return nil
+}
+
+// checkEditAccess checks if a user can change a project's canvases (owner or editor)
+func (s *Service) checkEditAccess(ctx context.Context, projectID, userID uuid.UUID) error {
+	role, err := s.projectRole(ctx, projectID, userID)
+	if err != nil {
+		return err
 	}
 
-	if ownerID != userID {
-		return ErrUnauthorized
+	if role < roleEditor {
+		return s.forbidden(role)
 	}
 
 	return nil
 }
+
+// checkCreatePermission checks if a user's role meets the configured minimum for creating whiteboards
+func (s *Service) checkCreatePermission(ctx context.Context, projectID, userID uuid.UUID) error {
+	role, err := s.projectRole(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if role == roleNone {
+		return s.forbidden(role)
+	}
+	if role < s.createMinRole {
+		return ErrCreateForbidden
+	}
+
+	return nil
+}
+
+// ValidateCanvas runs canvas validation on data without persisting anything
+func (s *Service) ValidateCanvas(data json.RawMessage) *ValidateCanvasResponse {
+	issues := ValidateCanvasData(data, s.strictVersion, s.shapeTypes)
+	if s.canvasMaxBytes > 0 && len(data) > s.canvasMaxBytes {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Code:     "too_large",
+			Message:  (&CanvasTooLargeError{Size: len(data), Limit: s.canvasMaxBytes}).Error(),
+		})
+	}
+	return &ValidateCanvasResponse{
+		Valid:  !hasErrors(issues),
+		Issues: issues,
+	}
+}
+
+// nameConflict builds a NameConflictError, looking up the whiteboard that holds the name
+func (s *Service) nameConflict(ctx context.Context, projectID uuid.UUID, name string, excludeID uuid.UUID) error {
+	existingID, err := s.repo.FindConflictingName(ctx, projectID, name, excludeID)
+	if err != nil {
+		return err
+	}
+	return &NameConflictError{Name: name, ExistingID: existingID}
+}
+
+// DeliverEvent is the outbox handler for whiteboard events: it forwards
+// them to the project's live subscribers. A relay failure is returned so the
+// dispatcher retries.
+func (s *Service) DeliverEvent(ctx context.Context, msg outbox.Message) error {
+	var event Event
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		// Retrying won't fix a malformed payload
+		logger.Warn().Err(err).Int64("event_id", msg.ID).Msg("Dropping malformed whiteboard event")
+		return nil
+	}
+
+	return s.publishEvent(ctx, msg.AggregateID, event)
+}
+
+// canvasSaved records a canvas save in the activity log; source says what
+// wrote the canvas ("save", "layout" or "restore")
+func (s *Service) canvasSaved(ctx context.Context, projectID, whiteboardID, userID uuid.UUID, source string) {
+	s.activity.Record(ctx, projectID, userID, activity.CanvasSaved, map[string]interface{}{
+		"whiteboard_id": whiteboardID.String(),
+		"source":        source,
+	})
+}
+
+// saved prunes old versions and runs the OnSave hooks for a whiteboard
+func (s *Service) saved(ctx context.Context, whiteboardID uuid.UUID) {
+	s.pruneVersions(ctx, whiteboardID)
+	for _, fn := range s.onSave {
+		fn(ctx, whiteboardID)
+	}
+}
@@ -0,0 +1,103 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// builtinShapeTypes are the shape types the editor, renderer and exporters support
+var builtinShapeTypes = []string{"rectangle", "ellipse", "line", "arrow", "text", "freedraw"}
+
+// ShapeTypes is the set of shape types accepted in saved canvases
+type ShapeTypes map[string]bool
+
+// NewShapeTypes returns the built-in shape types plus any extra ones, so new
+// types can be enabled by configuration as the frontend gains them
+func NewShapeTypes(extra []string) ShapeTypes {
+	types := make(ShapeTypes, len(builtinShapeTypes)+len(extra))
+	for _, t := range builtinShapeTypes {
+		types[t] = true
+	}
+	for _, t := range extra {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	return types
+}
+
+// RejectedShape identifies a shape whose type isn't allowed
+type RejectedShape struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+}
+
+// ShapeTypeError is returned when a canvas contains shapes of unknown types
+// and they are not being stripped
+type ShapeTypeError struct {
+	Rejected []RejectedShape
+}
+
+func (e *ShapeTypeError) Error() string {
+	return fmt.Sprintf("canvas contains %d shape(s) of unsupported types", len(e.Rejected))
+}
+
+// filterShapeTypes rejects canvases with shapes of unknown types, or with strip
+// set, removes those shapes. Data that is unchanged is returned as is.
+func filterShapeTypes(data json.RawMessage, types ShapeTypes, strip bool) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+	}
+
+	var version int
+	if raw, ok := doc["version"]; ok {
+		_ = json.Unmarshal(raw, &version)
+	}
+	// Canvases from a newer client may use types this server doesn't know yet;
+	// they are kept untouched (or refused) by the version check instead
+	if version > CurrentCanvasVersion {
+		return data, nil
+	}
+
+	raw, ok := doc["shapes"]
+	if !ok {
+		return data, nil
+	}
+	var shapes []Shape
+	if err := json.Unmarshal(raw, &shapes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCanvas, err)
+	}
+
+	var rejected []RejectedShape
+	kept := shapes[:0:0]
+	for i, shape := range shapes {
+		shapeType, _ := shape["type"].(string)
+		if types[shapeType] {
+			kept = append(kept, shape)
+			continue
+		}
+		id, _ := shape["id"].(string)
+		rejected = append(rejected, RejectedShape{Index: i, ID: id, Type: shapeType})
+	}
+
+	if len(rejected) == 0 {
+		return data, nil
+	}
+	if !strip {
+		return nil, &ShapeTypeError{Rejected: rejected}
+	}
+
+	logger.Warn().Int("stripped", len(rejected)).Str("type", rejected[0].Type).Msg("Stripping shapes of unsupported types from canvas")
+
+	shapesJSON, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	doc["shapes"] = shapesJSON
+	return json.Marshal(doc)
+}
@@ -0,0 +1,96 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNewShapeTypes(t *testing.T) {
+	types := NewShapeTypes([]string{" diamond ", "", "sticky"})
+
+	want := append([]string{"diamond", "sticky"}, builtinShapeTypes...)
+	for _, shapeType := range want {
+		if !types[shapeType] {
+			t.Errorf("%q is not allowed", shapeType)
+		}
+	}
+	if types[""] || types["image"] {
+		t.Errorf("types = %v, want only the built-in and extra types", types)
+	}
+}
+
+func TestFilterShapeTypesKeepsKnownTypes(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"rectangle"},{"id":"b","type":"arrow"}]}`)
+
+	got, err := filterShapeTypes(data, NewShapeTypes(nil), false)
+	if err != nil {
+		t.Fatalf("filterShapeTypes: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("data was rewritten: %s", got)
+	}
+}
+
+func TestFilterShapeTypesRejectsUnknownTypes(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"rectangle"},{"id":"b","type":"hexagon"},{"type":7}]}`)
+
+	_, err := filterShapeTypes(data, NewShapeTypes(nil), false)
+	var typeErr *ShapeTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("error = %v, want a ShapeTypeError", err)
+	}
+
+	want := []RejectedShape{{Index: 1, ID: "b", Type: "hexagon"}, {Index: 2, Type: ""}}
+	if len(typeErr.Rejected) != len(want) {
+		t.Fatalf("rejected = %+v, want %+v", typeErr.Rejected, want)
+	}
+	for i := range want {
+		if typeErr.Rejected[i] != want[i] {
+			t.Errorf("rejected[%d] = %+v, want %+v", i, typeErr.Rejected[i], want[i])
+		}
+	}
+}
+
+func TestFilterShapeTypesStripsUnknownTypes(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"rectangle"},{"id":"b","type":"hexagon"}],"viewport":{"zoom":2}}`)
+
+	got, err := filterShapeTypes(data, NewShapeTypes(nil), true)
+	if err != nil {
+		t.Fatalf("filterShapeTypes: %v", err)
+	}
+
+	var canvas CanvasData
+	if err := json.Unmarshal(got, &canvas); err != nil {
+		t.Fatalf("stripped data is not a canvas: %v", err)
+	}
+	if len(canvas.Shapes) != 1 || canvas.Shapes[0]["id"] != "a" {
+		t.Errorf("shapes = %v, want only shape a", canvas.Shapes)
+	}
+	if canvas.Viewport.Zoom != 2 {
+		t.Errorf("viewport zoom = %g, want other fields kept", canvas.Viewport.Zoom)
+	}
+}
+
+func TestFilterShapeTypesAllowsConfiguredTypes(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"diamond"}]}`)
+
+	if _, err := filterShapeTypes(data, NewShapeTypes(nil), false); err == nil {
+		t.Error("diamond accepted without being configured")
+	}
+	if _, err := filterShapeTypes(data, NewShapeTypes([]string{"diamond"}), false); err != nil {
+		t.Errorf("configured type rejected: %v", err)
+	}
+}
+
+func TestFilterShapeTypesLeavesNewerCanvasesAlone(t *testing.T) {
+	data := json.RawMessage(`{"version":99,"shapes":[{"id":"a","type":"hologram"}]}`)
+
+	got, err := filterShapeTypes(data, NewShapeTypes(nil), true)
+	if err != nil {
+		t.Fatalf("filterShapeTypes: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("newer canvas was rewritten: %s", got)
+	}
+}
@@ -0,0 +1,71 @@
+package whiteboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WhiteboardSummary is what sync clients compare to decide which whiteboards
+// to re-fetch: no canvas data, just its content hash
+type WhiteboardSummary struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentHash string    `json:"content_hash"`
+	Position    int       `json:"position"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SummaryResponse summarizes a project's whiteboards in tab order. ContentHash
+// covers all of them (IDs, names, order and canvases), so one comparison tells
+// a client whether anything in the project changed.
+type SummaryResponse struct {
+	ProjectID   string               `json:"project_id"`
+	ContentHash string               `json:"content_hash"`
+	Whiteboards []*WhiteboardSummary `json:"whiteboards"`
+}
+
+// GetProjectSummary returns the content hashes of a project's whiteboards
+// (owner, collaborators, or anyone for public projects)
+func (s *Service) GetProjectSummary(ctx context.Context, projectID, userID uuid.UUID) (*SummaryResponse, error) {
+	if err := s.checkProjectAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	whiteboards, _, err := s.repo.FindByProjectID(ctx, projectID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project whiteboards: %w", err)
+	}
+
+	summaries := make([]*WhiteboardSummary, len(whiteboards))
+	for i, w := range whiteboards {
+		summaries[i] = &WhiteboardSummary{
+			ID:          w.ID.String(),
+			Name:        w.Name,
+			ContentHash: w.ContentHash,
+			Position:    w.Position,
+			UpdatedAt:   w.UpdatedAt,
+		}
+	}
+
+	return &SummaryResponse{
+		ProjectID:   projectID.String(),
+		ContentHash: summaryHash(summaries),
+		Whiteboards: summaries,
+	}, nil
+}
+
+// summaryHash is the SHA-256 of each whiteboard's ID, position, name and
+// content hash, in tab order. Update times are left out so re-saving an
+// unchanged canvas doesn't change it.
+func summaryHash(summaries []*WhiteboardSummary) string {
+	h := sha256.New()
+	for _, w := range summaries {
+		fmt.Fprintf(h, "%s\x00%d\x00%q\x00%s\n", w.ID, w.Position, w.Name, w.ContentHash)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
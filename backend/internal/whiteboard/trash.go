@@ -0,0 +1,261 @@
+package whiteboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/activity"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/database"
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// purgeBatchSize is how many expired whiteboards one sweep statement removes,
+// so a large backlog doesn't hold locks for long
+const purgeBatchSize = 500
+
+// TrashedWhiteboard is a deleted whiteboard that can still be restored
+type TrashedWhiteboard struct {
+	ID        uuid.UUID
+	ProjectID uuid.UUID
+	Name      string
+	DeletedAt time.Time
+}
+
+// TrashedWhiteboardResponse is a whiteboard in a project's trash
+type TrashedWhiteboardResponse struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the whiteboard stops being restorable
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashListResponse is the response for listing a project's trash
+type TrashListResponse struct {
+	Whiteboards []*TrashedWhiteboardResponse `json:"whiteboards"`
+	Total       int                          `json:"total"`
+}
+
+// ==================== Repository ====================
+
+// FindTrash returns a project's whiteboards deleted after since, most recently deleted first
+func (r *Repository) FindTrash(ctx context.Context, projectID uuid.UUID, since time.Time) ([]*TrashedWhiteboard, error) {
+	query := `
+		SELECT id, project_id, name, deleted_at
+		FROM whiteboards
+		WHERE project_id = $1 AND deleted_at > $2
+		ORDER BY deleted_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trashed whiteboards: %w", err)
+	}
+	defer rows.Close()
+
+	trashed := []*TrashedWhiteboard{}
+	for rows.Next() {
+		var t TrashedWhiteboard
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Name, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed whiteboard: %w", err)
+		}
+		trashed = append(trashed, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate trashed whiteboards: %w", err)
+	}
+
+	return trashed, nil
+}
+
+// FindTrashedByID finds a whiteboard in the trash deleted after since
+func (r *Repository) FindTrashedByID(ctx context.Context, id uuid.UUID, since time.Time) (*TrashedWhiteboard, error) {
+	query := `
+		SELECT id, project_id, name, deleted_at
+		FROM whiteboards
+		WHERE id = $1 AND deleted_at > $2
+	`
+
+	var t TrashedWhiteboard
+	err := r.db.QueryRow(ctx, query, id, since).Scan(&t.ID, &t.ProjectID, &t.Name, &t.DeletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trashed whiteboard: %w", err)
+	}
+
+	return &t, nil
+}
+
+// Restore takes a whiteboard deleted after since out of the trash and puts it
+// last in tab order. Returns nil if there is no such whiteboard.
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID, since time.Time) (*Whiteboard, error) {
+	query := `
+		UPDATE whiteboards
+		SET
+			deleted_at = NULL,
+			position = (SELECT COALESCE(MAX(w.position) + 1, 0) FROM whiteboards w WHERE w.project_id = whiteboards.project_id AND w.deleted_at IS NULL),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at > $2
+		RETURNING ` + whiteboardColumns + `
+	`
+
+	// Live clients see a restored whiteboard the way they see a new one
+	return r.recordChange(ctx, EventWhiteboardCreated, func(tx pgx.Tx) (*Whiteboard, error) {
+		whiteboard, err := scanWhiteboard(tx.QueryRow(ctx, query, id, since))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore whiteboard: %w", err)
+		}
+		return whiteboard, nil
+	})
+}
+
+// PurgeDeleted permanently deletes whiteboards deleted before cutoff, in
+// batches, and returns how many were removed
+func (r *Repository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM whiteboards
+		WHERE id IN (
+			SELECT id FROM whiteboards
+			WHERE deleted_at < $1
+			LIMIT $2
+		)
+	`
+
+	var purged int64
+	for {
+		result, err := r.db.Exec(ctx, query, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge deleted whiteboards: %w", err)
+		}
+		purged += result.RowsAffected()
+		if result.RowsAffected() < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// ==================== Service ====================
+
+// ListTrash lists the whiteboards in a project's trash that can still be
+// restored. Like deleting, only the project owner can see them.
+func (s *Service) ListTrash(ctx context.Context, projectID, userID uuid.UUID) (*TrashListResponse, error) {
+	if err := s.checkOwnership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	trashed, err := s.repo.FindTrash(ctx, projectID, s.trashCutoff())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*TrashedWhiteboardResponse, len(trashed))
+	for i, t := range trashed {
+		responses[i] = &TrashedWhiteboardResponse{
+			ID:        t.ID.String(),
+			ProjectID: t.ProjectID.String(),
+			Name:      t.Name,
+			DeletedAt: t.DeletedAt,
+			PurgeAt:   t.DeletedAt.Add(s.trashRetention),
+		}
+	}
+
+	return &TrashListResponse{
+		Whiteboards: responses,
+		Total:       len(responses),
+	}, nil
+}
+
+// RestoreWhiteboard takes a whiteboard out of the trash (project owner only).
+// Whiteboards past the retention period are reported as not found.
+func (s *Service) RestoreWhiteboard(ctx context.Context, whiteboardID, userID uuid.UUID) (*WhiteboardResponse, error) {
+	cutoff := s.trashCutoff()
+	trashed, err := s.repo.FindTrashedByID(ctx, whiteboardID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	if trashed == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	if err := s.checkOwnership(ctx, trashed.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	whiteboard, err := s.repo.Restore(ctx, whiteboardID, cutoff)
+	if database.IsUniqueViolation(err, uniqueNameIndex) {
+		return nil, s.nameConflict(ctx, trashed.ProjectID, trashed.Name, whiteboardID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if whiteboard == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	s.saved(ctx, whiteboard.ID)
+	s.activity.Record(ctx, whiteboard.ProjectID, userID, activity.WhiteboardRestored, map[string]interface{}{
+		"whiteboard_id": whiteboard.ID.String(),
+		"name":          whiteboard.Name,
+	})
+	return whiteboard.ToResponse(), nil
+}
+
+// PurgeTrash permanently deletes whiteboards that have been in the trash
+// longer than the retention period
+func (s *Service) PurgeTrash(ctx context.Context) (int64, error) {
+	return s.repo.PurgeDeleted(ctx, s.trashCutoff())
+}
+
+// trashCutoff is the deletion time before which whiteboards can't be restored
+func (s *Service) trashCutoff() time.Time {
+	return time.Now().Add(-s.trashRetention)
+}
+
+// TrashSweeper periodically purges whiteboards past their trash retention
+type TrashSweeper struct {
+	service  *Service
+	interval time.Duration
+}
+
+// NewTrashSweeper creates a sweeper that purges expired whiteboards every interval
+func NewTrashSweeper(service *Service, interval time.Duration) *TrashSweeper {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &TrashSweeper{service: service, interval: interval}
+}
+
+// Run purges expired whiteboards once at start and then every interval, until ctx is cancelled
+func (t *TrashSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	logger.Info().Dur("interval", t.interval).Dur("retention", t.service.trashRetention).Msg("🗑️ Whiteboard trash sweeper started")
+
+	for {
+		purged, err := t.service.PurgeTrash(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error().Err(err).Msg("Failed to purge whiteboard trash")
+		}
+		if purged > 0 {
+			logger.Info().Int64("purged", purged).Msg("Purged expired whiteboards from the trash")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Canvas validation limits
+const (
+	MaxCanvasShapes = 10000
+	MaxTextLength   = 10000
+	// Viewport zoom bounds; 0 means unset and is replaced with 1 on migration
+	MinViewportZoom = 0.01
+	MaxViewportZoom = 100
+)
+
+// CanvasSchemaError is returned when saved canvas data is structurally invalid
+type CanvasSchemaError struct {
+	Issues []ValidationIssue
+}
+
+func (e *CanvasSchemaError) Error() string {
+	return "canvas failed validation: " + e.Issues[0].Message
+}
+
+// CanvasTooLargeError is returned when saved canvas data exceeds the size limit
+type CanvasTooLargeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *CanvasTooLargeError) Error() string {
+	return fmt.Sprintf("canvas is %d bytes, the maximum is %d", e.Size, e.Limit)
+}
+
+// checkCanvasSchema rejects canvas data over maxBytes (0 disables the limit)
+// or with structural errors: not a canvas object, an unsupported old version,
+// shapes that aren't an array, too many shapes, an out-of-range zoom or text
+// over MaxTextLength. Other shape contents are left to the type filter, so
+// saves stay as lenient as before.
+func checkCanvasSchema(data json.RawMessage, maxBytes int) error {
+	if maxBytes > 0 && len(data) > maxBytes {
+		return &CanvasTooLargeError{Size: len(data), Limit: maxBytes}
+	}
+
+	var canvas CanvasData
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		return &CanvasSchemaError{Issues: []ValidationIssue{invalidJSONIssue(err)}}
+	}
+
+	var issues []ValidationIssue
+	for _, issue := range validateCanvasStructure(&canvas, false) {
+		if issue.Severity == SeverityError {
+			issues = append(issues, issue)
+		}
+	}
+	// Renderers draw text shapes, so their length is bounded on save too
+	for i, shape := range canvas.Shapes {
+		if text, ok := shape["text"].(string); ok && utf8.RuneCountInString(text) > MaxTextLength {
+			id, _ := shape["id"].(string)
+			issues = append(issues, textTooLongIssue(id, i))
+		}
+	}
+	if len(issues) > 0 {
+		return &CanvasSchemaError{Issues: issues}
+	}
+	return nil
+}
+
+// validateCanvasStructure checks the canvas-level fields: version, shape count and viewport
+func validateCanvasStructure(canvas *CanvasData, strictVersion bool) []ValidationIssue {
+	var issues []ValidationIssue
+
+	if canvas.Version > CurrentCanvasVersion {
+		severity := SeverityWarning
+		if strictVersion {
+			severity = SeverityError
+		}
+		issues = append(issues, ValidationIssue{
+			Severity: severity,
+			Code:     "unsupported_version",
+			Message:  (&VersionError{Version: canvas.Version}).Error(),
+		})
+	} else if canvas.Version < MinCanvasVersion {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Code:     "unsupported_version",
+			Message:  (&VersionError{Version: canvas.Version}).Error(),
+		})
+	}
+
+	if len(canvas.Shapes) > MaxCanvasShapes {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Code:     "too_many_shapes",
+			Message:  fmt.Sprintf("canvas has %d shapes, the maximum is %d", len(canvas.Shapes), MaxCanvasShapes),
+		})
+	}
+
+	if zoom := canvas.Viewport.Zoom; zoom != 0 && (zoom < MinViewportZoom || zoom > MaxViewportZoom) {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Code:     "invalid_zoom",
+			Message:  fmt.Sprintf("viewport zoom %g is outside %g-%g", zoom, MinViewportZoom, float64(MaxViewportZoom)),
+		})
+	}
+
+	return issues
+}
+
+func invalidJSONIssue(err error) ValidationIssue {
+	return ValidationIssue{
+		Severity: SeverityError,
+		Code:     "invalid_json",
+		Message:  fmt.Sprintf("canvas is not valid canvas JSON: %v", err),
+	}
+}
+
+// Issue severities. Errors make a canvas invalid; warnings are informational.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a single problem found in canvas data
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ShapeID  string `json:"shape_id,omitempty"`
+	Index    *int   `json:"index,omitempty"`
+}
+
+// ValidateCanvasData checks canvas JSON for schema problems, the shape limit,
+// the viewport zoom, unknown shape types, connections to missing shapes and
+// unsafe text. It never modifies the data.
+func ValidateCanvasData(data json.RawMessage, strictVersion bool, types ShapeTypes) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	var canvas CanvasData
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		return append(issues, invalidJSONIssue(err))
+	}
+
+	issues = append(issues, validateCanvasStructure(&canvas, strictVersion)...)
+
+	// First pass: collect IDs so connections can be checked regardless of order
+	ids := make(map[string]int, len(canvas.Shapes))
+	for i, shape := range canvas.Shapes {
+		id, _ := shape["id"].(string)
+		if id == "" {
+			issues = append(issues, shapeIssue(SeverityError, "missing_id", "shape has no id", "", i))
+			continue
+		}
+		if _, dup := ids[id]; dup {
+			issues = append(issues, shapeIssue(SeverityError, "duplicate_id", fmt.Sprintf("shape id %q is used more than once", id), id, i))
+			continue
+		}
+		ids[id] = i
+	}
+
+	for i, shape := range canvas.Shapes {
+		id, _ := shape["id"].(string)
+		issues = append(issues, validateShape(shape, id, i, ids, types)...)
+	}
+
+	return issues
+}
+
+// validateShape checks a single shape's type, geometry, connections and text
+func validateShape(shape Shape, id string, index int, ids map[string]int, types ShapeTypes) []ValidationIssue {
+	var issues []ValidationIssue
+
+	shapeType, _ := shape["type"].(string)
+	if !types[shapeType] {
+		issues = append(issues, shapeIssue(SeverityError, "unknown_type", fmt.Sprintf("unknown shape type %q", shapeType), id, index))
+	}
+
+	for _, key := range []string{"x", "y", "width", "height"} {
+		v, present := shape[key]
+		if !present {
+			continue
+		}
+		if f, ok := v.(float64); !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			issues = append(issues, shapeIssue(SeverityError, "invalid_geometry", fmt.Sprintf("%s must be a finite number", key), id, index))
+		}
+	}
+
+	for _, target := range connectionTargets(shape) {
+		if _, ok := ids[target]; !ok {
+			issues = append(issues, shapeIssue(SeverityError, "dangling_connection", fmt.Sprintf("shape connects to missing shape %q", target), id, index))
+		} else if target == id {
+			issues = append(issues, shapeIssue(SeverityWarning, "self_connection", "shape connects to itself", id, index))
+		}
+	}
+
+	if text, ok := shape["text"].(string); ok {
+		if utf8.RuneCountInString(text) > MaxTextLength {
+			issues = append(issues, textTooLongIssue(id, index))
+		}
+		if hasControlChars(text) {
+			issues = append(issues, shapeIssue(SeverityWarning, "text_control_chars", "text contains control characters", id, index))
+		}
+	}
+
+	return issues
+}
+
+// connectionTargets returns the shape IDs a shape is bound to, from either
+// Excalidraw-style start/end bindings or a plain list of connected IDs
+func connectionTargets(shape Shape) []string {
+	var targets []string
+
+	for _, key := range []string{"startBinding", "endBinding"} {
+		switch b := shape[key].(type) {
+		case string:
+			if b != "" {
+				targets = append(targets, b)
+			}
+		case map[string]interface{}:
+			if elementID, ok := b["elementId"].(string); ok && elementID != "" {
+				targets = append(targets, elementID)
+			}
+		}
+	}
+
+	if connections, ok := shape["connections"].([]interface{}); ok {
+		for _, c := range connections {
+			if target, ok := c.(string); ok && target != "" {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	return targets
+}
+
+func hasControlChars(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r' {
+			return true
+		}
+	}
+	return false
+}
+
+func textTooLongIssue(shapeID string, index int) ValidationIssue {
+	return shapeIssue(SeverityError, "text_too_long", fmt.Sprintf("text exceeds %d characters", MaxTextLength), shapeID, index)
+}
+
+func shapeIssue(severity, code, message, shapeID string, index int) ValidationIssue {
+	return ValidationIssue{
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+		ShapeID:  shapeID,
+		Index:    &index,
+	}
+}
+
+// hasErrors reports whether any issue is an error
+func hasErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func issueCodes(issues []ValidationIssue) map[string]string {
+	codes := make(map[string]string, len(issues))
+	for _, issue := range issues {
+		codes[issue.Code] = issue.Severity
+	}
+	return codes
+}
+
+func TestCheckCanvasSchemaAcceptsValidCanvases(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"version":1,"shapes":[],"viewport":{"scrollX":0,"scrollY":0,"zoom":1}}`,
+		`{"version":1,"shapes":[{"id":"a","type":"rectangle","x":1,"y":2}],"viewport":{"zoom":100}}`,
+	} {
+		if err := checkCanvasSchema(json.RawMessage(data), 1<<20); err != nil {
+			t.Errorf("checkCanvasSchema(%s) = %v, want nil", data, err)
+		}
+	}
+}
+
+func TestCheckCanvasSchemaRejectsOversizedCanvases(t *testing.T) {
+	data := json.RawMessage(`{"version":1,"shapes":[{"id":"a","type":"text","text":"` + strings.Repeat("x", 200) + `"}]}`)
+
+	var tooLarge *CanvasTooLargeError
+	if err := checkCanvasSchema(data, 100); !errors.As(err, &tooLarge) {
+		t.Fatalf("error = %v, want a CanvasTooLargeError", err)
+	}
+	if tooLarge.Size != len(data) || tooLarge.Limit != 100 {
+		t.Errorf("error = %+v, want size %d and limit 100", tooLarge, len(data))
+	}
+
+	// A limit of 0 disables the check
+	if err := checkCanvasSchema(data, 0); err != nil {
+		t.Errorf("checkCanvasSchema with no limit = %v, want nil", err)
+	}
+}
+
+func TestCheckCanvasSchemaRejectsBrokenCanvases(t *testing.T) {
+	shapes := make([]string, MaxCanvasShapes+1)
+	for i := range shapes {
+		shapes[i] = fmt.Sprintf(`{"id":"s%d","type":"rectangle"}`, i)
+	}
+
+	tests := []struct {
+		name, data, code string
+	}{
+		{"not an object", `[1,2,3]`, "invalid_json"},
+		{"truncated", `{"version":1,"shapes":[`, "invalid_json"},
+		{"shapes not an array", `{"version":1,"shapes":{"id":"a"}}`, "invalid_json"},
+		{"string version", `{"version":"1"}`, "invalid_json"},
+		{"old version", `{"version":-1}`, "unsupported_version"},
+		{"zoom too small", `{"version":1,"viewport":{"zoom":0.001}}`, "invalid_zoom"},
+		{"zoom too large", `{"version":1,"viewport":{"zoom":1000}}`, "invalid_zoom"},
+		{"text too long", `{"version":1,"shapes":[{"id":"a","type":"text","text":"` + strings.Repeat("x", MaxTextLength+1) + `"}]}`, "text_too_long"},
+		{"too many shapes", `{"version":1,"shapes":[` + strings.Join(shapes, ",") + `]}`, "too_many_shapes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCanvasSchema(json.RawMessage(tt.data), 0)
+			var schemaErr *CanvasSchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("error = %v, want a CanvasSchemaError", err)
+			}
+			if _, ok := issueCodes(schemaErr.Issues)[tt.code]; !ok {
+				t.Errorf("issues = %+v, want %s", schemaErr.Issues, tt.code)
+			}
+		})
+	}
+}
+
+func TestCheckCanvasSchemaAllowsNewerVersions(t *testing.T) {
+	// Newer canvases are refused or kept by the version check, not the schema check
+	if err := checkCanvasSchema(json.RawMessage(`{"version":99}`), 0); err != nil {
+		t.Errorf("checkCanvasSchema(version 99) = %v, want nil", err)
+	}
+}
+
+func TestValidateCanvasData(t *testing.T) {
+	types := NewShapeTypes(nil)
+
+	valid := `{"version":1,"shapes":[{"id":"a","type":"rectangle"},{"id":"b","type":"arrow","startBinding":{"elementId":"a"}}]}`
+	if issues := ValidateCanvasData(json.RawMessage(valid), false, types); len(issues) != 0 {
+		t.Errorf("valid canvas has issues: %+v", issues)
+	}
+
+	broken := `{"version":1,"shapes":[` +
+		`{"type":"rectangle"},` +
+		`{"id":"a","type":"hexagon"},` +
+		`{"id":"a","type":"rectangle"},` +
+		`{"id":"b","type":"arrow","endBinding":"missing"},` +
+		`{"id":"c","type":"text","text":"bell\u0007"}` +
+		`],"viewport":{"zoom":500}}`
+	codes := issueCodes(ValidateCanvasData(json.RawMessage(broken), false, types))
+
+	want := map[string]string{
+		"missing_id":          SeverityError,
+		"unknown_type":        SeverityError,
+		"duplicate_id":        SeverityError,
+		"dangling_connection": SeverityError,
+		"text_control_chars":  SeverityWarning,
+		"invalid_zoom":        SeverityError,
+	}
+	for code, severity := range want {
+		if codes[code] != severity {
+			t.Errorf("issue %s has severity %q, want %q", code, codes[code], severity)
+		}
+	}
+
+	if issues := ValidateCanvasData(json.RawMessage(`not json`), false, types); !hasErrors(issues) {
+		t.Errorf("invalid JSON has no errors: %+v", issues)
+	}
+}
+
+func TestValidateCanvasDataStrictVersion(t *testing.T) {
+	data := json.RawMessage(`{"version":99}`)
+	types := NewShapeTypes(nil)
+
+	if codes := issueCodes(ValidateCanvasData(data, false, types)); codes["unsupported_version"] != SeverityWarning {
+		t.Errorf("lenient: unsupported_version = %q, want a warning", codes["unsupported_version"])
+	}
+	if codes := issueCodes(ValidateCanvasData(data, true, types)); codes["unsupported_version"] != SeverityError {
+		t.Errorf("strict: unsupported_version = %q, want an error", codes["unsupported_version"])
+	}
+}
@@ -0,0 +1,270 @@
+package whiteboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/AnupamSingh2004/SysDes/backend/internal/shared/logger"
+)
+
+// ErrVersionNotFound is returned when a version doesn't exist or belongs to another whiteboard
+var ErrVersionNotFound = errors.New("version not found")
+
+// VersionSummary describes a stored snapshot without its canvas data
+type VersionSummary struct {
+	ID          string `json:"id"`
+	ContentHash string `json:"content_hash,omitempty"`
+	// AuthorID and AuthorName are empty for versions whose author deleted their account
+	AuthorID   string    `json:"author_id,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Size       int       `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// VersionResponse is a stored snapshot with its canvas data
+type VersionResponse struct {
+	ID           string          `json:"id"`
+	WhiteboardID string          `json:"whiteboard_id"`
+	Data         json.RawMessage `json:"data"`
+	ContentHash  string          `json:"content_hash,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// VersionListResponse is one page of a whiteboard's snapshots, newest first.
+// Total counts every stored version.
+type VersionListResponse struct {
+	Versions []*VersionSummary `json:"versions"`
+	Total    int               `json:"total"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+// defaultVersionPageSize is the page size when the client doesn't ask for one
+const defaultVersionPageSize = 20
+
+// insertVersion snapshots a whiteboard's canvas data as part of the write in tx
+func insertVersion(ctx context.Context, tx pgx.Tx, w *Whiteboard, authorID uuid.UUID) error {
+	query := `
+		INSERT INTO whiteboard_versions (whiteboard_id, data, content_hash, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+	`
+
+	var author *uuid.UUID
+	if authorID != uuid.Nil {
+		author = &authorID
+	}
+
+	if _, err := tx.Exec(ctx, query, w.ID, w.Data, w.ContentHash, author); err != nil {
+		return fmt.Errorf("failed to store whiteboard version: %w", err)
+	}
+
+	return nil
+}
+
+// FindVersions returns one page of a whiteboard's snapshots, newest first,
+// plus how many there are in total. Canvas data is not loaded.
+func (r *Repository) FindVersions(ctx context.Context, whiteboardID uuid.UUID, limit, offset int) ([]*VersionSummary, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM whiteboard_versions WHERE whiteboard_id = $1`
+	if err := r.db.QueryRow(ctx, countQuery, whiteboardID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count whiteboard versions: %w", err)
+	}
+
+	query := `
+		SELECT v.id, COALESCE(v.content_hash, ''), v.created_by, COALESCE(u.name, ''),
+			octet_length(v.data::text), v.created_at
+		FROM whiteboard_versions v
+		LEFT JOIN users u ON u.id = v.created_by
+		WHERE v.whiteboard_id = $1
+		ORDER BY v.created_at DESC, v.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, whiteboardID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find whiteboard versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*VersionSummary{}
+	for rows.Next() {
+		var id uuid.UUID
+		var authorID *uuid.UUID
+		var v VersionSummary
+		if err := rows.Scan(&id, &v.ContentHash, &authorID, &v.AuthorName, &v.Size, &v.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan whiteboard version: %w", err)
+		}
+		v.ID = id.String()
+		if authorID != nil {
+			v.AuthorID = authorID.String()
+		}
+		versions = append(versions, &v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate whiteboard versions: %w", err)
+	}
+
+	return versions, total, nil
+}
+
+// FindVersion finds one of a whiteboard's snapshots. Returns nil if there is none.
+func (r *Repository) FindVersion(ctx context.Context, whiteboardID, versionID uuid.UUID) (*VersionResponse, error) {
+	query := `
+		SELECT id, whiteboard_id, data, COALESCE(content_hash, ''), created_at
+		FROM whiteboard_versions
+		WHERE id = $1 AND whiteboard_id = $2
+	`
+
+	var id, wbID uuid.UUID
+	var v VersionResponse
+	err := r.db.QueryRow(ctx, query, versionID, whiteboardID).Scan(&id, &wbID, &v.Data, &v.ContentHash, &v.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard version: %w", err)
+	}
+	v.ID = id.String()
+	v.WhiteboardID = wbID.String()
+
+	return &v, nil
+}
+
+// PruneVersions deletes all but a whiteboard's keep most recent snapshots
+func (r *Repository) PruneVersions(ctx context.Context, whiteboardID uuid.UUID, keep int) error {
+	query := `
+		DELETE FROM whiteboard_versions
+		WHERE whiteboard_id = $1 AND id NOT IN (
+			SELECT id FROM whiteboard_versions
+			WHERE whiteboard_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)
+	`
+
+	if _, err := r.db.Exec(ctx, query, whiteboardID, keep); err != nil {
+		return fmt.Errorf("failed to prune whiteboard versions: %w", err)
+	}
+
+	return nil
+}
+
+// ListVersions lists one page of a whiteboard's saved versions. A limit of 0
+// means the default page size; larger limits are capped at the configured maximum.
+func (s *Service) ListVersions(ctx context.Context, whiteboardID, userID uuid.UUID, limit, offset int) (*VersionListResponse, error) {
+	if err := s.checkWhiteboardAccess(ctx, whiteboardID, userID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultVersionPageSize
+	}
+	if s.versionPageMax > 0 && limit > s.versionPageMax {
+		limit = s.versionPageMax
+	}
+
+	versions, total, err := s.repo.FindVersions(ctx, whiteboardID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionListResponse{
+		Versions: versions,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}, nil
+}
+
+// GetVersion gets one saved version of a whiteboard, with its canvas data
+func (s *Service) GetVersion(ctx context.Context, whiteboardID, versionID, userID uuid.UUID) (*VersionResponse, error) {
+	if err := s.checkWhiteboardAccess(ctx, whiteboardID, userID); err != nil {
+		return nil, err
+	}
+
+	version, err := s.repo.FindVersion(ctx, whiteboardID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	return version, nil
+}
+
+// RestoreVersion copies a saved version back into the live whiteboard. The
+// restore is itself saved as a new version, so it can be undone the same way.
+func (s *Service) RestoreVersion(ctx context.Context, whiteboardID, versionID, userID uuid.UUID) (*WhiteboardResponse, error) {
+	existing, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	// Same rule as a canvas save
+	if err := s.checkEditAccess(ctx, existing.ProjectID, userID); err != nil {
+		return nil, err
+	}
+
+	version, err := s.repo.FindVersion(ctx, whiteboardID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	// Old snapshots are checked against today's canvas rules like any other save
+	data, hash, err := s.prepareCanvas(version.Data, existing.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	whiteboard, err := s.repo.UpdateData(ctx, whiteboardID, data, hash, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore whiteboard version: %w", err)
+	}
+	if whiteboard == nil {
+		return nil, ErrWhiteboardNotFound
+	}
+
+	s.saved(ctx, whiteboardID)
+	s.canvasSaved(ctx, existing.ProjectID, whiteboardID, userID, "restore")
+
+	return whiteboard.ToResponse(), nil
+}
+
+// checkWhiteboardAccess checks that a whiteboard exists and the user can view it
+func (s *Service) checkWhiteboardAccess(ctx context.Context, whiteboardID, userID uuid.UUID) error {
+	whiteboard, err := s.repo.FindByID(ctx, whiteboardID)
+	if err != nil {
+		return fmt.Errorf("failed to find whiteboard: %w", err)
+	}
+	if whiteboard == nil {
+		return ErrWhiteboardNotFound
+	}
+
+	return s.checkProjectAccess(ctx, whiteboard.ProjectID, userID)
+}
+
+// pruneVersions drops snapshots beyond the configured limit. A failed prune
+// only leaves extra history behind, so it is logged rather than failing the save.
+func (s *Service) pruneVersions(ctx context.Context, whiteboardID uuid.UUID) {
+	if s.versionLimit <= 0 {
+		return
+	}
+
+	if err := s.repo.PruneVersions(ctx, whiteboardID, s.versionLimit); err != nil {
+		logger.Ctx(ctx).Warn().Err(err).Str("whiteboard_id", whiteboardID.String()).Msg("Failed to prune whiteboard versions")
+	}
+}
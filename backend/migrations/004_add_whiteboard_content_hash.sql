@@ -0,0 +1,4 @@
+-- Migration: Add content_hash column to whiteboards
+-- SHA-256 of the canonicalized canvas data, used by sync clients for change detection
+
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
@@ -0,0 +1,12 @@
+-- Migration: Create project_collaborators table
+-- Users other than the owner who have been given access to a project
+
+CREATE TABLE IF NOT EXISTS project_collaborators (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'editor' CHECK (role IN ('editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_collaborators_user_id ON project_collaborators(user_id);
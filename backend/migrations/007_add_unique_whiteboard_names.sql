@@ -0,0 +1,10 @@
+-- Migration: Optional unique whiteboard names per project
+-- When a project opts in, its whiteboards are flagged and a partial unique
+-- index rejects case-insensitive duplicate names among them
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS unique_whiteboard_names BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS enforce_unique_name BOOLEAN NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whiteboards_unique_name
+    ON whiteboards(project_id, LOWER(name))
+    WHERE enforce_unique_name;
@@ -0,0 +1,6 @@
+-- Migration: Data-residency regions
+-- NULL means the server's default region. Assets record the region their
+-- bytes were written to, so reads keep working if a project's region changes.
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50);
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50);
@@ -0,0 +1,7 @@
+-- Migration: Whiteboard thumbnails
+-- Thumbnails are rendered by background workers and stored in the blob store.
+-- thumbnail_hash is the content_hash that was rendered, so stale thumbnails can be found.
+
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS thumbnail_key TEXT;
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS thumbnail_hash VARCHAR(64);
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS thumbnail_updated_at TIMESTAMP WITH TIME ZONE;
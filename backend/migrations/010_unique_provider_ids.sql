@@ -0,0 +1,7 @@
+-- Migration: Enforce unique OAuth provider IDs
+-- 001 declares these columns UNIQUE, but databases bootstrapped from older
+-- schemas may lack the constraint. These partial indexes guarantee a provider
+-- account can only ever be linked to one user.
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_github_id_unique ON users(github_id) WHERE github_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id_unique ON users(google_id) WHERE google_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Migration: Cascade whiteboard deletes from projects
+-- Databases created before whiteboards referenced projects with ON DELETE CASCADE
+-- can hold whiteboards whose project is gone. The constraint is (re)created
+-- NOT VALID so existing orphans don't block the migration; new rows are checked.
+-- After repairing orphans (POST /api/v1/admin/whiteboards/orphans/repair), run:
+--   ALTER TABLE whiteboards VALIDATE CONSTRAINT whiteboards_project_id_fkey;
+
+ALTER TABLE whiteboards DROP CONSTRAINT IF EXISTS whiteboards_project_id_fkey;
+ALTER TABLE whiteboards ADD CONSTRAINT whiteboards_project_id_fkey
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE NOT VALID;
@@ -0,0 +1,14 @@
+-- Migration: Create whiteboard_versions table
+-- Every canvas save also stores a snapshot here, so earlier versions can be
+-- viewed and restored. Only the most recent WHITEBOARD_VERSION_LIMIT
+-- snapshots per whiteboard are kept.
+
+CREATE TABLE IF NOT EXISTS whiteboard_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    whiteboard_id UUID NOT NULL REFERENCES whiteboards(id) ON DELETE CASCADE,
+    data JSONB NOT NULL,
+    content_hash VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_whiteboard_versions_whiteboard_id ON whiteboard_versions(whiteboard_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Migration: Record who saved each whiteboard version
+-- Versions outlive their authors' accounts, so the author is cleared rather than cascading.
+
+ALTER TABLE whiteboard_versions ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
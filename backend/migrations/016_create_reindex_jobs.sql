@@ -0,0 +1,23 @@
+-- Migration: Create reindex_jobs table
+-- Admin-triggered jobs that recompute one kind of derived data (content
+-- hashes, thumbnails, ...) across all whiteboards. cursor is the last
+-- whiteboard processed, so interrupted jobs resume where they stopped.
+
+CREATE TABLE IF NOT EXISTS reindex_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    derivation VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    batch_size INT NOT NULL,
+    cursor UUID,
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one active job per derivation
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reindex_jobs_active ON reindex_jobs(derivation) WHERE status IN ('queued', 'running');
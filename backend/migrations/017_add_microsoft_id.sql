@@ -0,0 +1,6 @@
+-- Migration: Add microsoft_id column to users table
+-- For logging in with Microsoft (personal and Azure AD accounts)
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS microsoft_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_microsoft_id_unique ON users(microsoft_id) WHERE microsoft_id IS NOT NULL;
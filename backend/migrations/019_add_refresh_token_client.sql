@@ -0,0 +1,6 @@
+-- Migration: Record the client each refresh token was issued to
+-- A refresh token family is one login session; the client details of its
+-- newest token show users where they're signed in.
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
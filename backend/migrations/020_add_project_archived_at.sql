@@ -0,0 +1,7 @@
+-- Migration: Add archived_at column to projects table
+-- Archived projects are hidden from the dashboard and their public links,
+-- and can be restored until they're deleted permanently.
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id_active ON projects(user_id) WHERE archived_at IS NULL;
@@ -0,0 +1,21 @@
+-- Migration: Add position column to whiteboards
+-- Whiteboards are listed in a user-defined tab order within their project.
+-- Existing whiteboards are numbered in creation order, only when the column is
+-- first added, so re-running this on an adopted database keeps the tab order.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'whiteboards' AND column_name = 'position') THEN
+        ALTER TABLE whiteboards ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+        UPDATE whiteboards w
+        SET position = ordered.position
+        FROM (
+            SELECT id, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY created_at ASC, id ASC) - 1 AS position
+            FROM whiteboards
+        ) ordered
+        WHERE w.id = ordered.id AND w.position <> ordered.position;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_whiteboards_project_position ON whiteboards(project_id, position);
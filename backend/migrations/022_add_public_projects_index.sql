@@ -0,0 +1,6 @@
+-- Migration: Index the public project gallery
+-- The gallery lists public, unarchived projects by most recent update.
+
+CREATE INDEX IF NOT EXISTS idx_projects_public_updated
+    ON projects(updated_at DESC, id DESC)
+    WHERE is_public = true AND archived_at IS NULL;
@@ -0,0 +1,9 @@
+-- Migration: Project thumbnails
+-- A project's thumbnail is uploaded by its owner or rendered from its default
+-- whiteboard, and stored in the blob store of the region it was written to.
+-- thumbnail_url (from the initial schema) is where clients fetch it.
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS thumbnail_url TEXT;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS thumbnail_key TEXT;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS thumbnail_region VARCHAR(50);
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS thumbnail_updated_at TIMESTAMP WITH TIME ZONE;
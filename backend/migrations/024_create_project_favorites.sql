@@ -0,0 +1,11 @@
+-- Migration: Create project_favorites table
+-- Projects a user has starred, so they can pin important ones in their list
+
+CREATE TABLE IF NOT EXISTS project_favorites (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, project_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_favorites_project_id ON project_favorites(project_id);
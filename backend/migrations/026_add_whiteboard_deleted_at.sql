@@ -0,0 +1,13 @@
+-- Migration: Soft-delete whiteboards
+-- Deleted whiteboards stay in the trash, restorable, until a background
+-- sweeper removes them once their retention period has passed.
+
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Names of whiteboards in the trash don't block new whiteboards from using them
+DROP INDEX IF EXISTS idx_whiteboards_unique_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whiteboards_unique_name
+    ON whiteboards(project_id, LOWER(name))
+    WHERE enforce_unique_name AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_whiteboards_deleted_at ON whiteboards(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Migration: Compress large whiteboard canvas data
+-- Canvases over the configured threshold are stored gzip-compressed in
+-- data_compressed, with data left NULL and data_encoding naming the
+-- compression. Existing rows keep their JSONB data and a NULL encoding, so
+-- they read as before and are compressed the next time they're saved.
+
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS data_compressed BYTEA;
+ALTER TABLE whiteboards ADD COLUMN IF NOT EXISTS data_encoding VARCHAR(16);
+
+ALTER TABLE whiteboards DROP CONSTRAINT IF EXISTS whiteboards_data_encoding_check;
+ALTER TABLE whiteboards ADD CONSTRAINT whiteboards_data_encoding_check
+    CHECK ((data_encoding IS NULL AND data_compressed IS NULL) OR (data_encoding = 'gzip' AND data_compressed IS NOT NULL));
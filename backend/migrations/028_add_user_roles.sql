@@ -0,0 +1,9 @@
+-- Migration: Add user roles
+-- Every user is a plain "user" unless promoted, e.g.
+--   UPDATE users SET role = 'admin' WHERE email = 'ops@example.com';
+-- A role change reaches the user's tokens the next time they refresh.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
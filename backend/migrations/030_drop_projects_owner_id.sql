@@ -0,0 +1,6 @@
+-- Migration: Drop projects.owner_id
+-- Databases created by an earlier 001 have both owner_id and user_id. The
+-- application only writes user_id, so inserts failed owner_id's NOT NULL;
+-- 003 already copied owner_id into user_id, so the column can go.
+
+ALTER TABLE projects DROP COLUMN IF EXISTS owner_id;
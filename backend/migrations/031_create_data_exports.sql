@@ -0,0 +1,22 @@
+-- Migration: Create data_exports table
+-- Data-portability exports built in the background, for accounts too large
+-- to export in one request. data is the gzip-compressed export JSON; finished
+-- exports are deleted once expires_at passes.
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    data BYTEA,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id, created_at DESC);
+
+-- At most one active export per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_active ON data_exports(user_id) WHERE status IN ('queued', 'running');
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations, applied in order by
+// internal/shared/database/migrate
+package migrations
+
+import "embed"
+
+// FS holds the migration files, named NNN_description.sql
+//
+//go:embed *.sql
+var FS embed.FS
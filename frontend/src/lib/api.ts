@@ -74,6 +74,10 @@ class ApiClient {
     return `${this.baseUrl}/auth/google`;
   }
 
+  getMicrosoftAuthUrl() {
+    return `${this.baseUrl}/auth/microsoft`;
+  }
+
   // ==================== Projects ====================
   
   async getProjects() {
@@ -105,14 +109,57 @@ class ApiClient {
   }
 
   async deleteProject(id: string) {
+    // Deleting archives the project, so it can still be restored
+    const project = await this.request<Project>(`/projects/${id}/archive`, {
+      method: 'POST',
+    });
+    return { project };
+  }
+
+  async unarchiveProject(id: string) {
+    const project = await this.request<Project>(`/projects/${id}/unarchive`, {
+      method: 'POST',
+    });
+    return { project };
+  }
+
+  async deleteProjectPermanently(id: string) {
     // Backend returns 204 No Content
-    await fetch(`${this.baseUrl}/projects/${id}`, {
+    await fetch(`${this.baseUrl}/projects/${id}?permanent=true`, {
       method: 'DELETE',
       credentials: 'include',
     });
     return { success: true };
   }
 
+  async setProjectFavorite(id: string, favorite: boolean) {
+    // Backend returns 204 No Content
+    await fetch(`${this.baseUrl}/projects/${id}/favorite`, {
+      method: favorite ? 'POST' : 'DELETE',
+      credentials: 'include',
+    });
+    return { success: true };
+  }
+
+  async getProjectActivity(id: string, params: { limit?: number; offset?: number } = {}) {
+    // Owner and collaborators only, newest first
+    const query = new URLSearchParams();
+    if (params.limit) query.set('limit', String(params.limit));
+    if (params.offset) query.set('offset', String(params.offset));
+    const suffix = query.toString() ? `?${query}` : '';
+    return this.request<ProjectActivityList>(`/projects/${id}/activity${suffix}`);
+  }
+
+  async getPublicProjects(params: { q?: string; limit?: number; offset?: number } = {}) {
+    // Public gallery, most recently updated first
+    const query = new URLSearchParams();
+    if (params.q) query.set('q', params.q);
+    if (params.limit) query.set('limit', String(params.limit));
+    if (params.offset) query.set('offset', String(params.offset));
+    const suffix = query.toString() ? `?${query}` : '';
+    return this.request<PublicProjectList>(`/public/projects${suffix}`);
+  }
+
   // Design versions
   async getVersions(projectId: string) {
     return this.request<{ versions: DesignVersion[] }>(`/projects/${projectId}/versions`);
@@ -132,9 +179,10 @@ class ApiClient {
     return this.request<Whiteboard>(`/projects/${projectId}/whiteboards/default`);
   }
 
-  // Get all whiteboards for a project
-  async getProjectWhiteboards(projectId: string) {
-    return this.request<{ whiteboards: Whiteboard[]; total: number }>(`/projects/${projectId}/whiteboards`);
+  // Get all whiteboards for a project; canvas data is null unless includeData is set
+  async getProjectWhiteboards(projectId: string, includeData = false) {
+    const query = includeData ? '?include=data' : '';
+    return this.request<{ whiteboards: Whiteboard[]; total: number }>(`/projects/${projectId}/whiteboards${query}`);
   }
 
   // Get a specific whiteboard
@@ -183,6 +231,18 @@ class ApiClient {
     return { success: true };
   }
 
+  // List a project's deleted whiteboards that can still be restored
+  async getWhiteboardTrash(projectId: string) {
+    return this.request<WhiteboardTrash>(`/projects/${projectId}/whiteboards/trash`);
+  }
+
+  // Restore a deleted whiteboard
+  async restoreDeletedWhiteboard(whiteboardId: string) {
+    return this.request<Whiteboard>(`/whiteboards/${whiteboardId}/restore`, {
+      method: 'POST',
+    });
+  }
+
   // AI Analysis
   async analyzeDesign(projectId: string, canvasData: object) {
     return this.request<{ suggestions: Suggestion[] }>(`/ai/analyze`, {
@@ -199,6 +259,7 @@ export interface User {
   email: string;
   name: string;
   avatar_url: string;
+  role?: 'user' | 'admin';
   created_at: string;
 }
 
@@ -217,6 +278,50 @@ export interface Project {
   is_public: boolean;
   created_at: string;
   updated_at: string;
+  // Server path of the project thumbnail, versioned so it can be cached
+  thumbnail_url?: string;
+  // Set on the project list: whether the user starred the project
+  is_favorite?: boolean;
+  // Set when viewing someone else's public project
+  author?: { name: string; avatar_url?: string };
+}
+
+export interface PublicProject {
+  id: string;
+  name: string;
+  description: string;
+  public_slug: string;
+  url: string;
+  default_whiteboard_id: string | null;
+  thumbnail_url?: string;
+  owner_name: string;
+  owner_avatar_url?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PublicProjectList {
+  projects: PublicProject[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
+export interface ProjectActivity {
+  id: number;
+  project_id: string;
+  user_id: string | null;
+  user_name?: string;
+  action: string;
+  metadata: Record<string, unknown>;
+  created_at: string;
+}
+
+export interface ProjectActivityList {
+  activity: ProjectActivity[];
+  total: number;
+  limit: number;
+  offset: number;
 }
 
 export interface DesignVersion {
@@ -245,6 +350,19 @@ export interface Whiteboard {
   updated_at: string;
 }
 
+export interface TrashedWhiteboard {
+  id: string;
+  project_id: string;
+  name: string;
+  deleted_at: string;
+  purge_at: string;
+}
+
+export interface WhiteboardTrash {
+  whiteboards: TrashedWhiteboard[];
+  total: number;
+}
+
 // Canvas document format for persistence
 export interface CanvasDocument {
   version: number;